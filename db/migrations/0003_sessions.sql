@@ -0,0 +1,26 @@
+-- token_version is embedded in the access and refresh tokens of a user, bumping it revokes all of them
+ALTER TABLE users
+    ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE refresh_tokens
+(
+    id            SERIAL PRIMARY KEY,
+    user_id       INTEGER                  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash    CHAR(64)                 NOT NULL UNIQUE, -- hex encoded SHA-256 of the token, the token itself is never stored
+    token_version INTEGER                  NOT NULL,        -- users.token_version when the token was issued
+    expires_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at    TIMESTAMP WITH TIME ZONE,                 -- set when the token is rotated or the session logged out
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_refresh_tokens_user ON refresh_tokens (user_id);
+
+-- Denylist of access tokens revoked before their expiry (e.g. on logout), by their jti claim.
+-- Expired rows are deleted by a background worker, the tokens are rejected by their exp claim anyway.
+CREATE TABLE revoked_access_tokens
+(
+    jti        VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_revoked_access_tokens_expires_at ON revoked_access_tokens (expires_at);
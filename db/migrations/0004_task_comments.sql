@@ -0,0 +1,15 @@
+CREATE TABLE task_comments
+(
+    id         SERIAL PRIMARY KEY,
+    task_id    INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    parent_id  INTEGER REFERENCES task_comments (id) ON DELETE CASCADE, -- the thread's first comment, NULL for it
+    author_id  INTEGER NOT NULL REFERENCES users (id),
+    body       TEXT    NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE -- deleted comments are kept, with an empty body, so their replies stay in place
+);
+
+-- Indices for listing the threads of a task and their replies
+CREATE INDEX idx_task_comments_task ON task_comments (task_id, created_at) WHERE parent_id IS NULL;
+CREATE INDEX idx_task_comments_parent ON task_comments (parent_id);
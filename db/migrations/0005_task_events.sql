@@ -0,0 +1,28 @@
+-- Activity history of the tasks. Events are written in the same transaction as the change they record,
+-- and never modified afterward.
+CREATE TABLE task_events
+(
+    id         BIGSERIAL PRIMARY KEY,
+    task_id    INTEGER     NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    actor_id   INTEGER     NOT NULL REFERENCES users (id),
+    type       VARCHAR(20) NOT NULL CHECK (type IN ('created', 'updated')),
+    field      VARCHAR(50), -- the changed column of an 'updated' event, e.g. 'status'
+    old_value  TEXT,
+    new_value  TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_task_events_task ON task_events (task_id, id);
+
+CREATE FUNCTION task_events_immutable() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'task events cannot be modified';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER task_events_immutable
+    BEFORE UPDATE
+    ON task_events
+    FOR EACH ROW
+EXECUTE FUNCTION task_events_immutable();
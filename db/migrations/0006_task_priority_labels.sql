@@ -0,0 +1,29 @@
+ALTER TABLE tasks
+    ADD COLUMN priority VARCHAR(10) NOT NULL DEFAULT 'normal' CHECK (priority IN ('low', 'normal', 'high', 'urgent'));
+
+-- Sorting by priority sorts by urgency, not alphabetically
+ALTER TABLE tasks
+    ADD COLUMN priority_rank SMALLINT GENERATED ALWAYS AS (
+        CASE priority WHEN 'low' THEN 0 WHEN 'normal' THEN 1 WHEN 'high' THEN 2 WHEN 'urgent' THEN 3 END
+        ) STORED;
+
+CREATE INDEX idx_tasks_priority ON tasks (priority);
+
+-- Labels are managed by each employer, for their own tasks
+CREATE TABLE labels
+(
+    id          SERIAL PRIMARY KEY,
+    employer_id INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name        VARCHAR(50) NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (employer_id, name)
+);
+
+CREATE TABLE task_labels
+(
+    task_id  INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    label_id INTEGER NOT NULL REFERENCES labels (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, label_id)
+);
+
+CREATE INDEX idx_task_labels_label ON task_labels (label_id);
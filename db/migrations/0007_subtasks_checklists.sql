@@ -0,0 +1,18 @@
+-- Subtasks: a task can be split into subtasks, which can be split further
+ALTER TABLE tasks
+    ADD COLUMN parent_id INTEGER REFERENCES tasks (id) ON DELETE CASCADE;
+
+CREATE INDEX idx_tasks_parent ON tasks (parent_id);
+
+-- Checklists: lightweight steps of a task, without status, assignee or history
+CREATE TABLE task_checklist_items
+(
+    id         SERIAL PRIMARY KEY,
+    task_id    INTEGER      NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    title      VARCHAR(255) NOT NULL,
+    done       BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_task_checklist_items_task ON task_checklist_items (task_id);
@@ -0,0 +1,12 @@
+-- Dependencies: a task cannot start until its blockers are completed
+CREATE TABLE task_dependencies
+(
+    task_id    INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    blocker_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, blocker_id),
+    CHECK (task_id <> blocker_id)
+);
+
+-- to find the tasks a task blocks, the primary key covers the other direction
+CREATE INDEX idx_task_dependencies_blocker ON task_dependencies (blocker_id);
@@ -0,0 +1,80 @@
+-- Workflows: the statuses of the tasks of an employer, and who can move a task from one status to another.
+-- The statuses are no longer a fixed list, the workflows check them.
+ALTER TABLE tasks
+    DROP CONSTRAINT tasks_status_check;
+
+DROP TYPE task_status;
+
+-- The workflow without employer is the default one, used by the employers without a workflow of their own
+CREATE TABLE workflows
+(
+    id          SERIAL PRIMARY KEY,
+    employer_id INTEGER UNIQUE REFERENCES users (id) ON DELETE CASCADE,
+    updated_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_workflows_default ON workflows ((employer_id IS NULL)) WHERE employer_id IS NULL;
+
+-- Active statuses are being worked on, terminal statuses are done, the others wait for work to start.
+-- The status at position 0 is the status of new tasks.
+CREATE TABLE workflow_statuses
+(
+    workflow_id INTEGER     NOT NULL REFERENCES workflows (id) ON DELETE CASCADE,
+    name        VARCHAR(20) NOT NULL,
+    position    SMALLINT    NOT NULL,
+    active      BOOLEAN     NOT NULL DEFAULT FALSE,
+    terminal    BOOLEAN     NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (workflow_id, name),
+    UNIQUE (workflow_id, position),
+    CHECK (NOT (active AND terminal))
+);
+
+-- One row per role allowed to move a task from a status to another
+CREATE TABLE workflow_transitions
+(
+    workflow_id INTEGER     NOT NULL,
+    from_status VARCHAR(20) NOT NULL,
+    to_status   VARCHAR(20) NOT NULL,
+    role        VARCHAR(20) NOT NULL CHECK (role IN ('employer', 'employee')),
+    PRIMARY KEY (workflow_id, from_status, to_status, role),
+    FOREIGN KEY (workflow_id, from_status) REFERENCES workflow_statuses (workflow_id, name) ON DELETE CASCADE,
+    FOREIGN KEY (workflow_id, to_status) REFERENCES workflow_statuses (workflow_id, name) ON DELETE CASCADE,
+    CHECK (from_status <> to_status)
+);
+
+-- The default workflow, the same as models.DefaultWorkflow
+INSERT INTO workflows (employer_id)
+VALUES (NULL);
+
+INSERT INTO workflow_statuses (workflow_id, name, position, active, terminal)
+SELECT id, s.name, s.position, s.active, s.terminal
+FROM workflows,
+     (VALUES ('pending', 0, FALSE, FALSE),
+             ('in_progress', 1, TRUE, FALSE),
+             ('completed', 2, FALSE, TRUE)) AS s (name, position, active, terminal)
+WHERE employer_id IS NULL;
+
+INSERT INTO workflow_transitions (workflow_id, from_status, to_status, role)
+SELECT id, t.from_status, t.to_status, t.role
+FROM workflows,
+     (VALUES ('pending', 'in_progress', 'employer'),
+             ('pending', 'in_progress', 'employee'),
+             ('pending', 'completed', 'employer'),
+             ('pending', 'completed', 'employee'),
+             ('in_progress', 'pending', 'employer'),
+             ('in_progress', 'pending', 'employee'),
+             ('in_progress', 'completed', 'employer'),
+             ('in_progress', 'completed', 'employee'),
+             ('completed', 'pending', 'employer'),
+             ('completed', 'in_progress', 'employer')) AS t (from_status, to_status, role)
+WHERE employer_id IS NULL;
+
+-- The statuses each employer's tasks can have: those of the employer's workflow, or of the default one.
+-- Queries join the tasks on (employer_id, status) to know whether a task is active or done.
+CREATE VIEW employer_task_statuses AS
+SELECT users.id AS employer_id, workflow_statuses.name, workflow_statuses.position,
+       workflow_statuses.active, workflow_statuses.terminal
+FROM users
+         JOIN workflows ON workflows.employer_id = users.id OR (workflows.employer_id IS NULL AND NOT EXISTS (
+    SELECT 1 FROM workflows own WHERE own.employer_id = users.id))
+         JOIN workflow_statuses ON workflow_statuses.workflow_id = workflows.id;
@@ -0,0 +1,27 @@
+-- Recurring tasks: a series creates a task for each occurrence of its recurrence rule, ahead of time
+CREATE TABLE task_series
+(
+    id              SERIAL PRIMARY KEY,
+    employer_id     INTEGER      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title           VARCHAR(255) NOT NULL,
+    description     TEXT,
+    assignee_id     INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    priority        VARCHAR(10)  NOT NULL DEFAULT 'normal' CHECK (priority IN ('low', 'normal', 'high', 'urgent')),
+    rrule           VARCHAR(255) NOT NULL,
+    timezone        VARCHAR(64)  NOT NULL DEFAULT 'UTC',
+    starts_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    state           VARCHAR(10)  NOT NULL DEFAULT 'active' CHECK (state IN ('active', 'paused', 'ended')),
+    generated_until TIMESTAMP WITH TIME ZONE NOT NULL, -- occurrences after it have no task yet
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_task_series_employer ON task_series (employer_id);
+-- the generator looks for the active series whose tasks are not created far enough ahead
+CREATE INDEX idx_task_series_generated_until ON task_series (generated_until) WHERE state = 'active';
+
+-- The due date of the tasks of a series is their occurrence, a series never creates two tasks for an occurrence
+ALTER TABLE tasks
+    ADD COLUMN series_id INTEGER REFERENCES task_series (id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_tasks_series_occurrence ON tasks (series_id, due_date);
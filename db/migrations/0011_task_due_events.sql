@@ -0,0 +1,15 @@
+-- Reminders and overdue notices of the tasks. The scheduler records an event once per task, kind and due date,
+-- so a task whose due date changes gets new ones, then delivers the events that are not notified yet.
+CREATE TABLE task_due_events
+(
+    id          BIGSERIAL PRIMARY KEY,
+    task_id     INTEGER     NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    kind        VARCHAR(10) NOT NULL CHECK (kind IN ('reminder', 'overdue')),
+    due_date    TIMESTAMP WITH TIME ZONE NOT NULL, -- the due date of the task when the event was recorded
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    notified_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_task_due_events_occurrence ON task_due_events (task_id, kind, due_date);
+-- the scheduler looks for the events to deliver
+CREATE INDEX idx_task_due_events_pending ON task_due_events (id) WHERE notified_at IS NULL;
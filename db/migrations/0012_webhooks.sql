@@ -0,0 +1,36 @@
+-- Webhooks: employers register endpoints that receive the events of their tasks
+CREATE TABLE webhooks
+(
+    id          SERIAL PRIMARY KEY,
+    employer_id INTEGER       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url         VARCHAR(2048) NOT NULL,
+    events      JSONB         NOT NULL, -- the event types the webhook subscribes to, e.g. ["task.created"]
+    secret      VARCHAR(100)  NOT NULL, -- signs the deliveries with HMAC-SHA256
+    active      BOOLEAN       NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhooks_employer ON webhooks (employer_id);
+
+-- The delivery log. Deliveries are created from the committed domain events of the outbox, see 0013_outbox_events,
+-- and are retried with an exponential backoff until they succeed or run out of attempts.
+CREATE TABLE webhook_deliveries
+(
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      INTEGER     NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id        VARCHAR(64) NOT NULL, -- the same for the redeliveries of an event
+    event           VARCHAR(50) NOT NULL,
+    payload         JSONB       NOT NULL,
+    status          VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    response_status INTEGER,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    delivered_at    TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, id);
+-- the dispatcher looks for the deliveries due for an attempt
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
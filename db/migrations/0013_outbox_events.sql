@@ -0,0 +1,20 @@
+-- The outbox of the domain events. Services record an event in the transaction of the change, so an event exists
+-- if and only if its change is committed, and the relay publishes the events to the subscribers afterwards.
+CREATE TABLE outbox_events
+(
+    id           BIGSERIAL PRIMARY KEY,
+    event_id     VARCHAR(64) NOT NULL UNIQUE, -- deduplication ID, the same each time the event is published
+    type         VARCHAR(50) NOT NULL,
+    employer_id  INTEGER,                     -- the employer whose tasks changed, NULL for the user events
+    actor_id     INTEGER,                     -- the user who made the change, NULL for public actions
+    payload      JSONB       NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    position     BIGINT UNIQUE,               -- the order of publication, set when the event is published
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Transactions commit in any order, so the events are numbered when published rather than when recorded
+CREATE SEQUENCE outbox_event_positions OWNED BY outbox_events.position;
+
+-- the relay looks for the events to publish
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events (id) WHERE published_at IS NULL;
@@ -0,0 +1,9 @@
+-- Full-text search of the tasks. The search vector is generated from the title, which weighs more, and the
+-- description, so it is kept up to date on every insert and update.
+ALTER TABLE tasks
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+        ) STORED;
+
+CREATE INDEX idx_tasks_search_vector ON tasks USING GIN (search_vector);
@@ -0,0 +1,4 @@
+-- The task lists are paginated by keyset, on (sort key, id), newest first by default. These indexes let the pages of
+-- the default order be read from where the previous one stopped, instead of scanning all the tasks of a user.
+CREATE INDEX idx_tasks_employer_created ON tasks (employer_id, created_at, id);
+CREATE INDEX idx_tasks_assignee_created ON tasks (assignee_id, created_at, id);
@@ -0,0 +1,44 @@
+-- When the tasks were done, to know whether they were done on time. A task is done once its status is terminal in
+-- the workflow of its employer: the trigger sets completed_at when it gets to a terminal status, keeps it while it
+-- moves between terminal statuses, and clears it when it is reopened.
+ALTER TABLE tasks
+    ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE;
+
+-- The tasks done already were done at their last status change, recorded in their history, or at their last update
+UPDATE tasks
+SET completed_at = COALESCE((SELECT MAX(task_events.created_at)
+                             FROM task_events
+                             WHERE task_events.task_id = tasks.id
+                               AND task_events.field = 'status'
+                               AND task_events.new_value = tasks.status), tasks.updated_at)
+WHERE EXISTS (SELECT 1
+              FROM employer_task_statuses
+              WHERE employer_task_statuses.employer_id = tasks.employer_id
+                AND employer_task_statuses.name = tasks.status
+                AND employer_task_statuses.terminal);
+
+CREATE FUNCTION tasks_completed_at() RETURNS trigger AS
+$$
+BEGIN
+    IF EXISTS (SELECT 1
+               FROM employer_task_statuses
+               WHERE employer_task_statuses.employer_id = NEW.employer_id
+                 AND employer_task_statuses.name = NEW.status
+                 AND employer_task_statuses.terminal) THEN
+        IF TG_OP = 'UPDATE' THEN
+            NEW.completed_at := COALESCE(OLD.completed_at, CURRENT_TIMESTAMP);
+        ELSE
+            NEW.completed_at := COALESCE(NEW.completed_at, CURRENT_TIMESTAMP);
+        END IF;
+    ELSE
+        NEW.completed_at := NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_completed_at
+    BEFORE INSERT OR UPDATE OF status
+    ON tasks
+    FOR EACH ROW
+EXECUTE FUNCTION tasks_completed_at();
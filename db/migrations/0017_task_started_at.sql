@@ -0,0 +1,52 @@
+-- When the work on the tasks started, to measure how long they take from start to done. A task is started once its
+-- status is active in the workflow of its employer: the trigger sets started_at the first time it gets to an active
+-- status, and keeps it from then on, even if the task is reopened, so that the time spent on it counts from the start.
+ALTER TABLE tasks
+    ADD COLUMN started_at TIMESTAMP WITH TIME ZONE;
+
+-- The tasks started already were started at their first change to an active status, recorded in their history, or,
+-- if they are active and their history does not tell, at their last update
+UPDATE tasks
+SET started_at = (SELECT MIN(task_events.created_at)
+                  FROM task_events
+                           JOIN employer_task_statuses
+                                ON employer_task_statuses.employer_id = tasks.employer_id
+                                    AND employer_task_statuses.name = task_events.new_value
+                  WHERE task_events.task_id = tasks.id
+                    AND task_events.field = 'status'
+                    AND employer_task_statuses.active);
+
+UPDATE tasks
+SET started_at = tasks.updated_at
+WHERE started_at IS NULL
+  AND EXISTS (SELECT 1
+              FROM employer_task_statuses
+              WHERE employer_task_statuses.employer_id = tasks.employer_id
+                AND employer_task_statuses.name = tasks.status
+                AND employer_task_statuses.active);
+
+CREATE FUNCTION tasks_started_at() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        NEW.started_at := OLD.started_at;
+    END IF;
+    IF NEW.started_at IS NULL AND EXISTS (SELECT 1
+                                          FROM employer_task_statuses
+                                          WHERE employer_task_statuses.employer_id = NEW.employer_id
+                                            AND employer_task_statuses.name = NEW.status
+                                            AND employer_task_statuses.active) THEN
+        NEW.started_at := CURRENT_TIMESTAMP;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_started_at
+    BEFORE INSERT OR UPDATE OF status
+    ON tasks
+    FOR EACH ROW
+EXECUTE FUNCTION tasks_started_at();
+
+-- The flow analytics read the tasks of an employer done in a range of dates
+CREATE INDEX idx_tasks_employer_completed ON tasks (employer_id, completed_at) WHERE completed_at IS NOT NULL;
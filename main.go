@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,9 +12,15 @@ import (
 	"github.com/llkhacquan/cisab/pkg/api"
 	"github.com/llkhacquan/cisab/pkg/config"
 	"github.com/llkhacquan/cisab/pkg/dbctx"
+	"github.com/llkhacquan/cisab/pkg/jwtkeys"
+	"github.com/llkhacquan/cisab/pkg/lifecycle"
+	"github.com/llkhacquan/cisab/pkg/notify"
+	"github.com/llkhacquan/cisab/pkg/outbox"
 	"github.com/llkhacquan/cisab/pkg/repo"
 	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/llkhacquan/cisab/pkg/stream"
 	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/llkhacquan/cisab/pkg/webhook"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -34,12 +41,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load the JWT signing keys
+	jwtKeys, err := jwtkeys.Load(appConfig.JWT)
+	if err != nil {
+		appLogger.Error("failed to load JWT keys", "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize database connection
 	dbConfig := appConfig.Database
 	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		dbConfig.Host, dbConfig.Port, dbConfig.User, dbConfig.Password, dbConfig.Name)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		appLogger.Error("failed to connect to database", "error", err)
 		os.Exit(1)
@@ -48,13 +62,40 @@ func main() {
 	// Initialize repositories
 	userRepo := repo.NewUserRepoImpl(dbctx.Get)
 	taskRepo := repo.NewTaskRepoImpl(dbctx.Get)
+	tokenRepo := repo.NewTokenRepoImpl(dbctx.Get)
+	commentRepo := repo.NewCommentRepoImpl(dbctx.Get)
+	taskEventRepo := repo.NewTaskEventRepoImpl(dbctx.Get)
+	labelRepo := repo.NewLabelRepoImpl(dbctx.Get)
+	checklistRepo := repo.NewChecklistRepoImpl(dbctx.Get)
+	taskDependencyRepo := repo.NewTaskDependencyRepoImpl(dbctx.Get)
+	workflowRepo := repo.NewWorkflowRepoImpl(dbctx.Get)
+	taskSeriesRepo := repo.NewTaskSeriesRepoImpl(dbctx.Get)
+	dueEventRepo := repo.NewTaskDueEventRepoImpl(dbctx.Get)
+	webhookRepo := repo.NewWebhookRepoImpl(dbctx.Get)
+	outboxRepo := repo.NewOutboxRepoImpl(dbctx.Get)
+
+	// Send the due date notifications by email when an SMTP server is configured, log them otherwise
+	var notifier notify.Notifier = notify.NewLogNotifier(appLogger)
+	if notificationsConfig := appConfig.Notifications; notificationsConfig.SMTP.Host != "" {
+		notifier = notify.NewSMTPNotifier(notificationsConfig.SMTP)
+	}
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, db, appLogger)
+
+	// The services record their domain events in the outbox, the relay publishes them to the subscribers
+	relay := outbox.NewRelay(outboxRepo, db, appLogger)
+	relay.Subscribe("webhooks", webhookDispatcher.HandleEvent)
+	// The event stream pushes the published task events to the connected clients
+	eventHub := stream.NewHub(outboxRepo, db, appLogger)
+	relay.Subscribe("event stream", eventHub.HandleEvent)
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, appConfig.JWT)
-	taskService := service.NewTaskService(taskRepo, userRepo)
+	userService := service.NewUserService(userRepo, tokenRepo, appConfig.JWT, jwtKeys, relay)
+	taskService := service.NewTaskService(taskRepo, userRepo, commentRepo, taskEventRepo, labelRepo, checklistRepo, taskDependencyRepo,
+		workflowRepo, taskSeriesRepo, dueEventRepo, notifier, webhookRepo, webhookDispatcher, relay)
 
 	// Create API server with services
-	apiServer := api.NewServer(userService, taskService, userRepo, appLogger, db, appConfig.JWT.Secret)
+	apiServer := api.NewServer(userService, taskService, userRepo, tokenRepo, appLogger, db, jwtKeys, eventHub,
+		api.WithHideInternalErrors(appConfig.IsProduction()))
 
 	// Configure the HTTP server
 	server := &http.Server{
@@ -64,11 +105,64 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// End the event streams when the shutdown starts, they would hold it for the whole grace period otherwise
+	server.RegisterOnShutdown(eventHub.Close)
+
+	// Manage the process lifecycle: on SIGTERM, drain in-flight requests, then close the database
+	manager := lifecycle.New(appLogger, time.Duration(appConfig.Server.ShutdownGracePeriodInSecond)*time.Second)
+	manager.OnShutdown("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+
+	// Create the tasks of the recurring series ahead of time. Each run is a transaction, and instances running
+	// concurrently skip the series another one is processing.
+	manager.GoEvery("recurring tasks", time.Minute, func(ctx context.Context) error {
+		return dbctx.Transaction(ctx, db, func(ctx context.Context) error {
+			response, err := taskService.GenerateRecurringTasks(ctx, service.GenerateRecurringTasksRequest{Now: time.Now()})
+			if err == nil && response.Tasks > 0 {
+				appLogger.Info("recurring tasks created", "series", response.Series, "tasks", response.Tasks)
+			}
+			return err
+		})
+	})
+
+	// Remind the tasks due soon and notify the overdue ones. The events are recorded once per due date of a task,
+	// and delivered again on the next runs if the notifier fails.
+	reminderLead := time.Duration(appConfig.Notifications.ReminderLeadInSecond) * time.Second
+	manager.GoEvery("due tasks", time.Duration(appConfig.Notifications.IntervalInSecond)*time.Second, func(ctx context.Context) error {
+		return dbctx.Transaction(ctx, db, func(ctx context.Context) error {
+			response, err := taskService.NotifyDueTasks(ctx, service.NotifyDueTasksRequest{Now: time.Now(), ReminderLead: reminderLead})
+			if err == nil && response.Failed > 0 {
+				appLogger.Warn("failed to deliver due task notifications", "failed", response.Failed, "notified", response.Notified)
+			}
+			return err
+		})
+	})
+
+	// Prune the denylist of revoked access tokens, the expired ones are rejected by their exp claim anyway
+	manager.GoEvery("revoked tokens", time.Hour, func(ctx context.Context) error {
+		return dbctx.Transaction(ctx, db, func(ctx context.Context) error {
+			response, err := userService.PruneRevokedTokens(ctx, service.PruneRevokedTokensRequest{Now: time.Now()})
+			if err == nil && response.Deleted > 0 {
+				appLogger.Info("expired revoked tokens deleted", "tokens", response.Deleted)
+			}
+			return err
+		})
+	})
+
+	// Publish the committed domain events, then send the webhook deliveries they create and retry the failed ones
+	manager.Go("outbox", relay.Run)
+	manager.Go("webhooks", webhookDispatcher.Run)
+	manager.Go("event stream", eventHub.Run)
 
 	// Start the server
 	appLogger.Info("server starting", "port", appConfig.Server.Port, "environment", appConfig.Environment)
-	if err := server.ListenAndServe(); err != nil {
-		appLogger.Error("server failed to start", "error", err)
+	if err := manager.Run(context.Background(), server); err != nil {
+		appLogger.Error("server stopped with error", "error", err)
 		os.Exit(1)
 	}
 }
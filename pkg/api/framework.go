@@ -1,10 +1,16 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/service"
 	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/pkg/errors"
 )
 
 // HandlerFunc is a custom function type for API handlers that returns data, status code, and optional error
@@ -16,20 +22,53 @@ type Endpoint struct {
 	Method  string
 	Path    string
 	Handler HandlerFunc
+	// Stream is set instead of Handler by the endpoints that write the response themselves,
+	// like the event stream. It runs once the roles are checked.
+	Stream http.HandlerFunc
+
+	// Public endpoints can be called without authentication. Every other endpoint
+	// is wrapped with HandlerOptions.Authenticate when it is registered.
+	Public bool
+	// Roles restricts an authenticated endpoint to users with one of these roles, any role is allowed if empty
+	Roles []models.UserRole
+
+	// The fields below only document the endpoint, they are used to generate the OpenAPI spec
+	Summary  string
+	Tags     []string
+	Request  interface{} // a value of the request body type, nil if the endpoint takes no body
+	Response interface{} // a value of the type returned in the "data" field of a successful response
+	Query    []QueryParam
+}
+
+// HandlerOptions controls how endpoint handlers turn results into responses
+type HandlerOptions struct {
+	// HideInternalErrors hides the underlying error text of 5xx responses from clients.
+	// It should be enabled in production.
+	HideInternalErrors bool
+
+	// Authenticate is the middleware that authenticates requests to non-public endpoints,
+	// and sets the authenticated user in the request context (see authctx).
+	Authenticate func(http.Handler) http.Handler
 }
 
 // HandleEndpoint creates an http.HandlerFunc from an Endpoint
-func HandleEndpoint(e Endpoint, log *logger.Logger) http.HandlerFunc {
+func HandleEndpoint(e Endpoint, log *logger.Logger, opts HandlerOptions) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// Enforce the role requirements of the endpoint
+		if err := checkRoles(r, e.Roles); err != nil {
+			writeError(w, r, err, log, opts)
+			return
+		}
+
+		if e.Stream != nil {
+			e.Stream(w, r)
+			return
+		}
+
 		// Execute the handler
 		data, err := e.Handler(r)
-
-		// Handle errors - always return 500 Internal Server Error for now
-		// Todo: we should have custom error handling here later
 		if err != nil {
-			log.Error("error in request handler", "path", r.URL.Path, "method", r.Method, "error", err.Error())
-			errorResp := ErrorResponse("Internal server error", err)
-			WriteJSON(w, http.StatusInternalServerError, errorResp, log)
+			writeError(w, r, err, log, opts)
 			return
 		}
 		// Else, return success response
@@ -37,11 +76,60 @@ func HandleEndpoint(e Endpoint, log *logger.Logger) http.HandlerFunc {
 	}
 }
 
-// RegisterEndpoints registers multiple endpoints with router
-func RegisterEndpoints(router *mux.Router, endpoints []Endpoint, log *logger.Logger) {
+// writeError maps err to a status code and an error response.
+// Service errors (possibly wrapped) are returned with their own status and error code,
+// anything else is treated as an internal server error.
+func writeError(w http.ResponseWriter, r *http.Request, err error, log *logger.Logger, opts HandlerOptions) {
+	var svcErr service.Error
+	if errors.As(err, &svcErr) && svcErr.Code >= 400 && svcErr.Code < 500 {
+		log.Info("request rejected", "path", r.URL.Path, "method", r.Method, "status", svcErr.Code, "error_code", svcErr.ErrorCode, "error", err.Error())
+		WriteJSON(w, svcErr.Code, ServiceErrorResponse(svcErr), log)
+		return
+	}
+
+	log.Error("error in request handler", "path", r.URL.Path, "method", r.Method, "error", err.Error())
+	errorResp := ErrorResponse("Internal server error", err)
+	errorResp.ErrorCode = service.ErrorCodeInternal
+	if opts.HideInternalErrors {
+		errorResp.Error = ""
+	}
+	WriteJSON(w, http.StatusInternalServerError, errorResp, log)
+}
+
+// checkRoles returns a forbidden_role error if roles is not empty and the authenticated user has none of them
+func checkRoles(r *http.Request, roles []models.UserRole) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	role := authctx.Get(r.Context()).User.Role
+	for _, allowed := range roles {
+		if role == allowed {
+			return nil
+		}
+	}
+	names := make([]string, len(roles))
+	for i, allowed := range roles {
+		names[i] = string(allowed) + "s"
+	}
+	return service.NewForbiddenRoleError("only " + strings.Join(names, " and ") + " can access this endpoint")
+}
+
+// RegisterEndpoints registers multiple endpoints with router.
+// Endpoints are authenticated unless they are explicitly marked as Public, so a new route
+// can never become public by accident. It panics if an endpoint needs authentication but
+// no authenticator is configured, since that is a programming error.
+func RegisterEndpoints(router *mux.Router, endpoints []Endpoint, log *logger.Logger, opts HandlerOptions) {
 	for _, endpoint := range endpoints {
-		handler := HandleEndpoint(endpoint, log)
-		router.HandleFunc(endpoint.Path, handler).Methods(endpoint.Method)
+		var handler http.Handler = HandleEndpoint(endpoint, log, opts)
+		if !endpoint.Public {
+			if opts.Authenticate == nil {
+				panic(fmt.Sprintf("endpoint %s %s requires authentication but no authenticator is configured", endpoint.Method, endpoint.Path))
+			}
+			handler = opts.Authenticate(handler)
+		} else if len(endpoint.Roles) > 0 {
+			panic(fmt.Sprintf("public endpoint %s %s cannot require roles", endpoint.Method, endpoint.Path))
+		}
+		router.Handle(endpoint.Path, handler).Methods(endpoint.Method)
 	}
 }
 
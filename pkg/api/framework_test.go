@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func serveEndpoint(t *testing.T, handler HandlerFunc, opts HandlerOptions) (int, Response) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	h := HandleEndpoint(Endpoint{Method: http.MethodGet, Path: "/", Handler: handler}, log, opts)
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var resp Response
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return rec.Code, resp
+}
+
+func TestHandleEndpoint_errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		opts          HandlerOptions
+		wantStatus    int
+		wantErrorCode string
+		wantError     string
+	}{
+		{
+			name:          "wrapped not found",
+			err:           errors.Wrap(service.ErrTaskNotFound, "failed to update task status"),
+			wantStatus:    http.StatusNotFound,
+			wantErrorCode: service.ErrorCodeTaskNotFound,
+			wantError:     "task not found",
+		},
+		{
+			name:          "forbidden role",
+			err:           errors.Wrap(service.NewForbiddenRoleError("only employers can create tasks"), "failed to create task"),
+			wantStatus:    http.StatusForbidden,
+			wantErrorCode: service.ErrorCodeForbiddenRole,
+			wantError:     "only employers can create tasks",
+		},
+		{
+			name:          "invalid input",
+			err:           service.NewInvalidInputError("invalid task ID"),
+			wantStatus:    http.StatusBadRequest,
+			wantErrorCode: service.ErrorCodeInvalidInput,
+			wantError:     "invalid task ID",
+		},
+		{
+			name:          "internal error is exposed outside production",
+			err:           errors.New("connection refused"),
+			wantStatus:    http.StatusInternalServerError,
+			wantErrorCode: service.ErrorCodeInternal,
+			wantError:     "connection refused",
+		},
+		{
+			name:          "internal error is hidden in production",
+			err:           errors.New("connection refused"),
+			opts:          HandlerOptions{HideInternalErrors: true},
+			wantStatus:    http.StatusInternalServerError,
+			wantErrorCode: service.ErrorCodeInternal,
+			wantError:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := serveEndpoint(t, func(r *http.Request) (interface{}, error) {
+				return nil, tt.err
+			}, tt.opts)
+			require.Equal(t, tt.wantStatus, status)
+			require.Equal(t, "error", resp.Status)
+			require.Equal(t, tt.wantErrorCode, resp.ErrorCode)
+			require.Equal(t, tt.wantError, resp.Error)
+		})
+	}
+}
+
+func TestRegisterEndpoints_auth(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	ok := func(r *http.Request) (interface{}, error) { return "ok", nil }
+
+	// authenticate accepts requests with an X-Role header as a user with that role
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := r.Header.Get("X-Role")
+			if role == "" {
+				respondWithError(w, service.ErrUnauthorized)
+				return
+			}
+			ctx := authctx.Set(r.Context(), authctx.AuthMD{User: models.User{ID: 1, Role: models.UserRole(role)}})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
+	router := mux.NewRouter()
+	RegisterEndpoints(router, []Endpoint{
+		{Method: http.MethodPost, Path: "/login", Handler: ok, Public: true},
+		// a path ending with a formerly public suffix must not be public
+		{Method: http.MethodGet, Path: "/admin/users", Handler: ok},
+		{Method: http.MethodGet, Path: "/tasks", Handler: ok, Roles: []models.UserRole{models.UserRoleEmployer}},
+	}, log, HandlerOptions{Authenticate: authenticate})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		role       string
+		wantStatus int
+	}{
+		{name: "public endpoint", method: http.MethodPost, path: "/login", wantStatus: http.StatusOK},
+		{name: "unauthenticated", method: http.MethodGet, path: "/admin/users", wantStatus: http.StatusUnauthorized},
+		{name: "authenticated", method: http.MethodGet, path: "/admin/users", role: "employee", wantStatus: http.StatusOK},
+		{name: "allowed role", method: http.MethodGet, path: "/tasks", role: "employer", wantStatus: http.StatusOK},
+		{name: "forbidden role", method: http.MethodGet, path: "/tasks", role: "employee", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.role != "" {
+				req.Header.Set("X-Role", tt.role)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			require.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+
+	t.Run("authenticated endpoints need an authenticator", func(t *testing.T) {
+		require.Panics(t, func() {
+			RegisterEndpoints(mux.NewRouter(), []Endpoint{{Method: http.MethodGet, Path: "/tasks", Handler: ok}}, log, HandlerOptions{})
+		})
+	})
+}
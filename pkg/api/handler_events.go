@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/llkhacquan/cisab/pkg/stream"
+	"github.com/pkg/errors"
+)
+
+// eventStreamHeartbeat is how often an idle event stream sends a comment, so the proxies keep the connection open
+const eventStreamHeartbeat = 15 * time.Second
+
+// EventStreamHandler handles GET requests to stream the task events visible to the user as server-sent events.
+// The ID of each event is its position: a client that reconnects sends the last ID it got in the Last-Event-ID header,
+// or the last_event_id query parameter, to get the events it missed first.
+// The stream ends when the access token expires, the client then reconnects with a new token.
+// curl -N http://localhost:8080/api/v1/events/stream \
+// -H "Authorization: Bearer your_jwt_token" \
+// -H "Last-Event-ID: 42"
+func (s *Server) EventStreamHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := authctx.Get(ctx)
+
+	// 1. Parse the position to resume from
+	lastEventID, err := lastEventIDFromRequest(r)
+	if err != nil {
+		writeError(w, r, err, s.logger, s.handlerOpts)
+		return
+	}
+
+	// 2. Lift the server timeouts, which are meant for regular requests: the stream stays open
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeError(w, r, errors.Wrap(err, "failed to clear the write deadline"), s.logger, s.handlerOpts)
+		return
+	}
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		writeError(w, r, errors.Wrap(err, "failed to clear the read deadline"), s.logger, s.handlerOpts)
+		return
+	}
+
+	// 3. Subscribe before replaying the missed events, so none is lost in between
+	subscription := s.events.Subscribe(auth.User)
+	defer s.events.Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable the buffering of nginx
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		s.logger.Error("failed to flush the event stream", "error", err.Error())
+		return
+	}
+
+	send := func(event stream.Event) error {
+		if err := writeServerSentEvent(w, event); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// 4. Replay the events the client missed
+	if lastEventID > 0 {
+		if lastEventID, err = s.events.Replay(ctx, subscription, lastEventID, send); err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("failed to replay the event stream", "user_id", auth.User.ID, "error", err.Error())
+			}
+			return
+		}
+	}
+
+	// 5. Stream the new events until the client disconnects or the token expires
+	var expired <-chan time.Time
+	if !auth.TokenExpiry.IsZero() {
+		timer := time.NewTimer(time.Until(auth.TokenExpiry))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired:
+			return
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return // the client is too slow, or the server is shutting down
+			}
+			if event.Position <= lastEventID {
+				continue // replayed already
+			}
+			if err := send(event); err != nil {
+				return
+			}
+			lastEventID = event.Position
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// lastEventIDFromRequest parses the position to resume the event stream from, 0 to only stream the new events
+func lastEventIDFromRequest(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	lastEventID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || lastEventID < 0 {
+		return 0, service.NewInvalidInputError("invalid last event ID")
+	}
+	return lastEventID, nil
+}
+
+// writeServerSentEvent writes an event in the text/event-stream format
+func writeServerSentEvent(w http.ResponseWriter, event stream.Event) error {
+	data, err := json.Marshal(event.Message)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode event")
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Position, event.Message.Event, data)
+	return err
+}
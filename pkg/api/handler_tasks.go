@@ -2,7 +2,10 @@ package api
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/llkhacquan/cisab/pkg/models"
@@ -18,7 +21,11 @@ import (
 //	-d '{
 //	  "title": "Task Title",
 //	  "description": "Task Description",
-//	  "due_date": "2023-04-15T00:00:00Z"
+//	  "due_date": "2023-04-15T00:00:00Z",
+//	  "priority": "high",
+//	  "label_ids": [1, 2],
+//	  "parent_id": 12,
+//	  "inherit_assignee": true
 //	}'
 func (s *Server) CreateTaskHandler(r *http.Request) (interface{}, error) {
 	// 1. Decode request
@@ -28,11 +35,6 @@ func (s *Server) CreateTaskHandler(r *http.Request) (interface{}, error) {
 		return nil, errors.Wrap(err, "invalid request body")
 	}
 
-	// Validate the required fields
-	if createRequest.Title == "" {
-		return nil, errors.New("title is required")
-	}
-
 	// 2. Call the business logic
 	response, err := s.taskService.CreateTask(r.Context(), createRequest)
 	if err != nil {
@@ -43,6 +45,13 @@ func (s *Server) CreateTaskHandler(r *http.Request) (interface{}, error) {
 	return response, nil
 }
 
+// UpdateTaskStatusBody is the request body of UpdateTaskStatusHandler, the task ID comes from the URL
+type UpdateTaskStatusBody struct {
+	Status string `json:"status" binding:"required,max=20"`
+	// Force completes the task even though some of its subtasks are not completed
+	Force bool `json:"force,omitempty"`
+}
+
 // UpdateTaskStatusHandler handles PATCH requests to update a task's status
 // curl -X PATCH http://localhost:8080/api/v1/tasks/{id}/status \
 // -H "Content-Type: application/json" \
@@ -57,26 +66,20 @@ func (s *Server) UpdateTaskStatusHandler(r *http.Request) (interface{}, error) {
 	taskIDStr := vars["id"]
 	taskID, err := strconv.Atoi(taskIDStr)
 	if err != nil {
-		return nil, errors.Wrap(err, "invalid task ID")
+		return nil, service.NewInvalidInputError("invalid task ID")
 	}
 
 	// 2. Decode request body
-	var updateRequest struct {
-		Status string `json:"status"`
-	}
+	var updateRequest UpdateTaskStatusBody
 	if err := ReadJSON(r, &updateRequest); err != nil {
 		return nil, errors.Wrap(err, "invalid request body")
 	}
 
-	// Validate status
-	if updateRequest.Status == "" {
-		return nil, errors.New("status is required")
-	}
-
 	// 3. Create service request
 	serviceRequest := service.UpdateTaskStatusRequest{
 		TaskID: models.TaskID(taskID),
 		Status: models.TaskStatus(updateRequest.Status),
+		Force:  updateRequest.Force,
 	}
 
 	// 4. Call the business logic
@@ -92,7 +95,7 @@ func (s *Server) UpdateTaskStatusHandler(r *http.Request) (interface{}, error) {
 // GetAssignedTasksHandler handles GET requests to retrieve tasks assigned to the authenticated employee
 // curl -X GET http://localhost:8080/api/v1/tasks/assigned \
 // -H "Authorization: Bearer your_jwt_token" \
-// "?status=pending&sort_by=created_at&sort_order=desc&limit=10&offset=0"
+// "?status=pending,in_progress&employer_id=2&priority=high&label_id=1&due_before=2023-05-01&q=login+page&sort=status:active_first,priority:desc&limit=10&cursor=eyJrIjoi..."
 func (s *Server) GetAssignedTasksHandler(r *http.Request) (interface{}, error) {
 	// Parse query parameters
 	query := r.URL.Query()
@@ -100,30 +103,26 @@ func (s *Server) GetAssignedTasksHandler(r *http.Request) (interface{}, error) {
 	// Build the request
 	request := service.GetAssignedTasksRequest{}
 
-	// Parse statuses filter
-	if statusStr := query.Get("status"); statusStr != "" {
-		request.Statuses = []models.TaskStatus{models.TaskStatus(statusStr)}
+	// Parse the filters
+	var err error
+	if request.TaskFilter, err = taskFilterFromQuery(r); err != nil {
+		return nil, err
 	}
 
 	// Parse sorting options
+	request.Sort = query.Get("sort")
 	request.SortBy = query.Get("sort_by")
 	request.SortOrder = query.Get("sort_order")
 
-	// Parse pagination options
-	if limitStr := query.Get("limit"); limitStr != "" {
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil {
-			return nil, errors.Wrap(err, "invalid limit parameter")
-		}
-		request.Limit = limit
+	// Parse pagination options, by offset or by cursor
+	if request.Limit, request.Offset, err = paginationFromQuery(r); err != nil {
+		return nil, err
 	}
-
-	if offsetStr := query.Get("offset"); offsetStr != "" {
-		offset, err := strconv.Atoi(offsetStr)
-		if err != nil {
-			return nil, errors.Wrap(err, "invalid offset parameter")
+	request.Cursor = query.Get("cursor")
+	if skipCountStr := query.Get("skip_count"); skipCountStr != "" {
+		if request.SkipCount, err = strconv.ParseBool(skipCountStr); err != nil {
+			return nil, service.NewInvalidInputError("invalid skip_count parameter")
 		}
-		request.Offset = offset
 	}
 
 	// Call the business logic
@@ -138,11 +137,36 @@ func (s *Server) GetAssignedTasksHandler(r *http.Request) (interface{}, error) {
 
 // GetEmployeeTaskSummaryHandler handles GET requests to retrieve task statistics for all employees
 // curl -X GET http://localhost:8080/api/v1/employee-summary \
-// -H "Authorization: Bearer your_jwt_token"
+// -H "Authorization: Bearer your_jwt_token" \
+// "?created_after=2023-04-01&created_before=2023-05-01&own_tasks=true&sort_by=on_time_rate&limit=20&offset=0"
 func (s *Server) GetEmployeeTaskSummaryHandler(r *http.Request) (interface{}, error) {
-	// Create an empty request
+	// Parse query parameters
+	query := r.URL.Query()
+
+	// Build the request
 	request := service.GetEmployeeTaskSummaryRequest{}
 
+	// Parse the tasks to count
+	var err error
+	if request.CreatedAfter, err = timeFromQuery(query, "created_after"); err != nil {
+		return nil, err
+	}
+	if request.CreatedBefore, err = timeFromQuery(query, "created_before"); err != nil {
+		return nil, err
+	}
+	if ownTasksStr := query.Get("own_tasks"); ownTasksStr != "" {
+		if request.OwnTasks, err = strconv.ParseBool(ownTasksStr); err != nil {
+			return nil, service.NewInvalidInputError("invalid own_tasks parameter")
+		}
+	}
+
+	// Parse sorting and pagination options
+	request.SortBy = query.Get("sort_by")
+	request.SortOrder = query.Get("sort_order")
+	if request.Limit, request.Offset, err = paginationFromQuery(r); err != nil {
+		return nil, err
+	}
+
 	// Call the business logic
 	response, err := s.taskService.GetEmployeeTaskSummary(r.Context(), request)
 	if err != nil {
@@ -153,6 +177,34 @@ func (s *Server) GetEmployeeTaskSummaryHandler(r *http.Request) (interface{}, er
 	return response, nil
 }
 
+// GetFlowAnalyticsHandler handles GET requests to retrieve the throughput, lead time and cycle time of the tasks
+// curl -X GET "http://localhost:8080/api/v1/analytics/flow?interval=month&completed_after=2023-01-01" \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) GetFlowAnalyticsHandler(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	request := service.GetFlowAnalyticsRequest{Interval: query.Get("interval")}
+
+	// Parse the range
+	var err error
+	if request.CompletedAfter, err = timeFromQuery(query, "completed_after"); err != nil {
+		return nil, err
+	}
+	if request.CompletedBefore, err = timeFromQuery(query, "completed_before"); err != nil {
+		return nil, err
+	}
+
+	response, err := s.taskService.GetFlowAnalytics(r.Context(), request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get flow analytics")
+	}
+	return response, nil
+}
+
+// AssignTaskBody is the request body of AssignTaskHandler, the task ID comes from the URL
+type AssignTaskBody struct {
+	AssigneeID int `json:"assignee_id" binding:"required,min=1"`
+}
+
 // AssignTaskHandler handles PATCH requests to assign a task to an employee
 // curl -X PATCH http://localhost:8080/api/v1/tasks/{id}/assign \
 // -H "Content-Type: application/json" \
@@ -167,22 +219,15 @@ func (s *Server) AssignTaskHandler(r *http.Request) (interface{}, error) {
 	taskIDStr := vars["id"]
 	taskID, err := strconv.Atoi(taskIDStr)
 	if err != nil {
-		return nil, errors.Wrap(err, "invalid task ID")
+		return nil, service.NewInvalidInputError("invalid task ID")
 	}
 
 	// 2. Decode request body
-	var assignRequest struct {
-		AssigneeID int `json:"assignee_id"`
-	}
+	var assignRequest AssignTaskBody
 	if err := ReadJSON(r, &assignRequest); err != nil {
 		return nil, errors.Wrap(err, "invalid request body")
 	}
 
-	// Validate assignee_id
-	if assignRequest.AssigneeID <= 0 {
-		return nil, errors.New("assignee_id is required and must be positive")
-	}
-
 	// 3. Create service request
 	serviceRequest := service.AssignTaskRequest{
 		TaskID:     models.TaskID(taskID),
@@ -202,7 +247,7 @@ func (s *Server) AssignTaskHandler(r *http.Request) (interface{}, error) {
 // GetTasksHandler handles GET requests to retrieve tasks with filtering and sorting
 // curl -X GET http://localhost:8080/api/v1/tasks \
 // -H "Authorization: Bearer your_jwt_token" \
-// "?status=pending&assignee_id=1&sort_by=due_date&sort_order=asc&limit=10&offset=0"
+// "?status=pending&assignee_id=1,2&unassigned=true&priority=urgent&label_id=1&created_after=2023-04-01T00:00:00Z&q=login+page&sort=due_date:asc&limit=10&cursor=eyJrIjoi...&skip_count=true"
 func (s *Server) GetTasksHandler(r *http.Request) (interface{}, error) {
 	// Parse query parameters
 	query := r.URL.Query()
@@ -210,48 +255,1076 @@ func (s *Server) GetTasksHandler(r *http.Request) (interface{}, error) {
 	// Build the request
 	request := service.GetTasksRequest{}
 
-	// Parse status filter
-	if status := query.Get("status"); status != "" {
-		request.Status = models.TaskStatus(status)
-	}
-
-	// Parse assignee filter
-	if assigneeIDStr := query.Get("assignee_id"); assigneeIDStr != "" {
-		assigneeID, err := strconv.Atoi(assigneeIDStr)
-		if err != nil {
-			return nil, errors.Wrap(err, "invalid assignee_id parameter")
-		}
-		id := models.UserID(assigneeID)
-		request.AssigneeID = &id
+	// Parse the filters
+	var err error
+	if request.TaskFilter, err = taskFilterFromQuery(r); err != nil {
+		return nil, err
 	}
 
 	// Parse sorting options
+	request.Sort = query.Get("sort")
 	request.SortBy = query.Get("sort_by")
 	request.SortOrder = query.Get("sort_order")
 
+	// Parse pagination options, by offset or by cursor
+	if request.Limit, request.Offset, err = paginationFromQuery(r); err != nil {
+		return nil, err
+	}
+	request.Cursor = query.Get("cursor")
+	if skipCountStr := query.Get("skip_count"); skipCountStr != "" {
+		if request.SkipCount, err = strconv.ParseBool(skipCountStr); err != nil {
+			return nil, service.NewInvalidInputError("invalid skip_count parameter")
+		}
+	}
+
+	// Call the business logic
+	response, err := s.taskService.GetTasks(r.Context(), request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get tasks")
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// taskIDFromURL parses the {id} path variable of the task endpoints
+func taskIDFromURL(r *http.Request) (models.TaskID, error) {
+	taskID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, service.NewInvalidInputError("invalid task ID")
+	}
+	return models.TaskID(taskID), nil
+}
+
+// commentIDFromURL parses the {commentID} path variable of the comment endpoints
+func commentIDFromURL(r *http.Request) (models.CommentID, error) {
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentID"])
+	if err != nil {
+		return 0, service.NewInvalidInputError("invalid comment ID")
+	}
+	return models.CommentID(commentID), nil
+}
+
+// CreateCommentBody is the request body of CreateCommentHandler, the task ID comes from the URL
+type CreateCommentBody struct {
+	ParentID *int   `json:"parent_id,omitempty" binding:"min=1"`
+	Body     string `json:"body" binding:"required,max=10000"`
+}
+
+// CreateCommentHandler handles POST requests to comment on a task
+// curl -X POST http://localhost:8080/api/v1/tasks/{id}/comments \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "body": "Can we move the deadline?",
+//	  "parent_id": 12
+//	}'
+func (s *Server) CreateCommentHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task ID from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Decode request body
+	var createRequest CreateCommentBody
+	if err := ReadJSON(r, &createRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 3. Create service request
+	serviceRequest := service.CreateCommentRequest{
+		TaskID: taskID,
+		Body:   createRequest.Body,
+	}
+	if createRequest.ParentID != nil {
+		parentID := models.CommentID(*createRequest.ParentID)
+		serviceRequest.ParentID = &parentID
+	}
+
+	// 4. Call the business logic
+	response, err := s.taskService.CreateComment(r.Context(), serviceRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create comment")
+	}
+
+	// 5. Return the response
+	return response, nil
+}
+
+// GetCommentsHandler handles GET requests to retrieve the comment threads of a task
+// curl -X GET http://localhost:8080/api/v1/tasks/{id}/comments \
+// -H "Authorization: Bearer your_jwt_token" \
+// "?limit=50&offset=0"
+func (s *Server) GetCommentsHandler(r *http.Request) (interface{}, error) {
+	// Extract task ID from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+	request := service.GetCommentsRequest{TaskID: taskID}
+
 	// Parse pagination options
-	if limitStr := query.Get("limit"); limitStr != "" {
-		limit, err := strconv.Atoi(limitStr)
+	if request.Limit, request.Offset, err = paginationFromQuery(r); err != nil {
+		return nil, err
+	}
+
+	// Call the business logic
+	response, err := s.taskService.GetComments(r.Context(), request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get comments")
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// UpdateCommentBody is the request body of UpdateCommentHandler, the task and comment IDs come from the URL
+type UpdateCommentBody struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+// UpdateCommentHandler handles PATCH requests to edit a comment
+// curl -X PATCH http://localhost:8080/api/v1/tasks/{id}/comments/{commentID} \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "body": "Can we move the deadline to Friday?"
+//	}'
+func (s *Server) UpdateCommentHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task and comment IDs from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+	commentID, err := commentIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Decode request body
+	var updateRequest UpdateCommentBody
+	if err := ReadJSON(r, &updateRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 3. Call the business logic
+	response, err := s.taskService.UpdateComment(r.Context(), service.UpdateCommentRequest{
+		TaskID:    taskID,
+		CommentID: commentID,
+		Body:      updateRequest.Body,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update comment")
+	}
+
+	// 4. Return the response
+	return response, nil
+}
+
+// DeleteCommentHandler handles DELETE requests to delete a comment
+// curl -X DELETE http://localhost:8080/api/v1/tasks/{id}/comments/{commentID} \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) DeleteCommentHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task and comment IDs from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+	commentID, err := commentIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.DeleteComment(r.Context(), service.DeleteCommentRequest{
+		TaskID:    taskID,
+		CommentID: commentID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete comment")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// GetTaskHistoryHandler handles GET requests to retrieve the activity history of a task
+// curl -X GET http://localhost:8080/api/v1/tasks/{id}/history \
+// -H "Authorization: Bearer your_jwt_token" \
+// "?limit=50&offset=0"
+func (s *Server) GetTaskHistoryHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task ID from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+	request := service.GetTaskHistoryRequest{TaskID: taskID}
+
+	// 2. Parse pagination options
+	if request.Limit, request.Offset, err = paginationFromQuery(r); err != nil {
+		return nil, err
+	}
+
+	// 3. Call the business logic
+	response, err := s.taskService.GetTaskHistory(r.Context(), request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task history")
+	}
+
+	// 4. Return the response
+	return response, nil
+}
+
+// taskFilterFromQuery parses the filters of the task lists. The filters with several values take them repeated,
+// status=pending&status=in_progress, or separated by commas, status=pending,in_progress.
+// The dates are in RFC 3339, or YYYY-MM-DD for midnight UTC.
+func taskFilterFromQuery(r *http.Request) (service.TaskFilter, error) {
+	query := r.URL.Query()
+	filter := service.TaskFilter{
+		Priority: models.TaskPriority(query.Get("priority")),
+		Query:    query.Get("q"),
+	}
+
+	for _, status := range queryValues(query, "status") {
+		filter.Statuses = append(filter.Statuses, models.TaskStatus(status))
+	}
+
+	var err error
+	if filter.AssigneeIDs, err = userIDsFromQuery(query, "assignee_id"); err != nil {
+		return service.TaskFilter{}, err
+	}
+	if filter.EmployerIDs, err = userIDsFromQuery(query, "employer_id"); err != nil {
+		return service.TaskFilter{}, err
+	}
+
+	if labelIDStr := query.Get("label_id"); labelIDStr != "" {
+		labelID, err := strconv.Atoi(labelIDStr)
 		if err != nil {
-			return nil, errors.Wrap(err, "invalid limit parameter")
+			return service.TaskFilter{}, service.NewInvalidInputError("invalid label_id parameter")
 		}
-		request.Limit = limit
+		id := models.LabelID(labelID)
+		filter.LabelID = &id
 	}
 
-	if offsetStr := query.Get("offset"); offsetStr != "" {
-		offset, err := strconv.Atoi(offsetStr)
+	if unassignedStr := query.Get("unassigned"); unassignedStr != "" {
+		if filter.Unassigned, err = strconv.ParseBool(unassignedStr); err != nil {
+			return service.TaskFilter{}, service.NewInvalidInputError("invalid unassigned parameter")
+		}
+	}
+	if overdueStr := query.Get("overdue"); overdueStr != "" {
+		if filter.Overdue, err = strconv.ParseBool(overdueStr); err != nil {
+			return service.TaskFilter{}, service.NewInvalidInputError("invalid overdue parameter")
+		}
+	}
+
+	if filter.DueBefore, err = timeFromQuery(query, "due_before"); err != nil {
+		return service.TaskFilter{}, err
+	}
+	if filter.DueAfter, err = timeFromQuery(query, "due_after"); err != nil {
+		return service.TaskFilter{}, err
+	}
+	if filter.CreatedBefore, err = timeFromQuery(query, "created_before"); err != nil {
+		return service.TaskFilter{}, err
+	}
+	if filter.CreatedAfter, err = timeFromQuery(query, "created_after"); err != nil {
+		return service.TaskFilter{}, err
+	}
+	return filter, nil
+}
+
+// queryValues returns the values of a query parameter, repeated or separated by commas
+func queryValues(query url.Values, name string) []string {
+	var values []string
+	for _, value := range query[name] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// userIDsFromQuery parses a query parameter with several user IDs
+func userIDsFromQuery(query url.Values, name string) ([]models.UserID, error) {
+	var ids []models.UserID
+	for _, value := range queryValues(query, name) {
+		id, err := strconv.Atoi(value)
 		if err != nil {
-			return nil, errors.Wrap(err, "invalid offset parameter")
+			return nil, service.NewInvalidInputError("invalid " + name + " parameter")
+		}
+		ids = append(ids, models.UserID(id))
+	}
+	return ids, nil
+}
+
+// timeFromQuery parses a date query parameter, in RFC 3339 or YYYY-MM-DD, it is nil if not set
+func timeFromQuery(query url.Values, name string) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, value); err != nil {
+			return nil, service.NewInvalidInputError("invalid " + name + " parameter, use RFC 3339 or YYYY-MM-DD")
+		}
+	}
+	return &t, nil
+}
+
+// paginationFromQuery parses the limit and offset query parameters, they are 0 if not set
+func paginationFromQuery(r *http.Request) (limit, offset int, _ error) {
+	query := r.URL.Query()
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			return 0, 0, service.NewInvalidInputError("invalid limit parameter")
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		var err error
+		if offset, err = strconv.Atoi(offsetStr); err != nil {
+			return 0, 0, service.NewInvalidInputError("invalid offset parameter")
 		}
-		request.Offset = offset
 	}
+	return limit, offset, nil
+}
+
+// labelIDFromURL parses the {id} path variable of the label endpoints
+func labelIDFromURL(r *http.Request) (models.LabelID, error) {
+	labelID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, service.NewInvalidInputError("invalid label ID")
+	}
+	return models.LabelID(labelID), nil
+}
+
+// CreateLabelHandler handles POST requests to create a label
+// curl -X POST http://localhost:8080/api/v1/labels \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "name": "backend"
+//	}'
+func (s *Server) CreateLabelHandler(r *http.Request) (interface{}, error) {
+	// 1. Decode request
+	var createRequest service.CreateLabelRequest
+	if err := ReadJSON(r, &createRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.CreateLabel(r.Context(), createRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create label")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
 
+// GetLabelsHandler handles GET requests to retrieve the labels of the authenticated employer
+// curl -X GET http://localhost:8080/api/v1/labels \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) GetLabelsHandler(r *http.Request) (interface{}, error) {
 	// Call the business logic
-	response, err := s.taskService.GetTasks(r.Context(), request)
+	response, err := s.taskService.GetLabels(r.Context(), service.GetLabelsRequest{})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get tasks")
+		return nil, errors.Wrap(err, "failed to get labels")
 	}
 
 	// Return the response
 	return response, nil
 }
+
+// UpdateLabelBody is the request body of UpdateLabelHandler, the label ID comes from the URL
+type UpdateLabelBody struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// UpdateLabelHandler handles PATCH requests to rename a label
+// curl -X PATCH http://localhost:8080/api/v1/labels/{id} \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "name": "frontend"
+//	}'
+func (s *Server) UpdateLabelHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract label ID from URL
+	labelID, err := labelIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Decode request body
+	var updateRequest UpdateLabelBody
+	if err := ReadJSON(r, &updateRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 3. Call the business logic
+	response, err := s.taskService.UpdateLabel(r.Context(), service.UpdateLabelRequest{
+		LabelID: labelID,
+		Name:    updateRequest.Name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update label")
+	}
+
+	// 4. Return the response
+	return response, nil
+}
+
+// DeleteLabelHandler handles DELETE requests to delete a label
+// curl -X DELETE http://localhost:8080/api/v1/labels/{id} \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) DeleteLabelHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract label ID from URL
+	labelID, err := labelIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.DeleteLabel(r.Context(), service.DeleteLabelRequest{LabelID: labelID})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete label")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// GetSubtasksHandler handles GET requests to retrieve the subtasks of a task
+// curl -X GET http://localhost:8080/api/v1/tasks/{id}/subtasks \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) GetSubtasksHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task ID from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.GetSubtasks(r.Context(), service.GetSubtasksRequest{TaskID: taskID})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get subtasks")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// checklistItemIDFromURL parses the {itemID} path variable of the checklist endpoints
+func checklistItemIDFromURL(r *http.Request) (models.ChecklistItemID, error) {
+	itemID, err := strconv.Atoi(mux.Vars(r)["itemID"])
+	if err != nil {
+		return 0, service.NewInvalidInputError("invalid checklist item ID")
+	}
+	return models.ChecklistItemID(itemID), nil
+}
+
+// CreateChecklistItemBody is the request body of CreateChecklistItemHandler, the task ID comes from the URL
+type CreateChecklistItemBody struct {
+	Title string `json:"title" binding:"required,max=255"`
+}
+
+// CreateChecklistItemHandler handles POST requests to add an item to the checklist of a task
+// curl -X POST http://localhost:8080/api/v1/tasks/{id}/checklist \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "title": "Write the migration"
+//	}'
+func (s *Server) CreateChecklistItemHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task ID from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Decode request body
+	var createRequest CreateChecklistItemBody
+	if err := ReadJSON(r, &createRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 3. Call the business logic
+	response, err := s.taskService.CreateChecklistItem(r.Context(), service.CreateChecklistItemRequest{
+		TaskID: taskID,
+		Title:  createRequest.Title,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create checklist item")
+	}
+
+	// 4. Return the response
+	return response, nil
+}
+
+// GetChecklistHandler handles GET requests to retrieve the checklist of a task
+// curl -X GET http://localhost:8080/api/v1/tasks/{id}/checklist \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) GetChecklistHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task ID from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.GetChecklist(r.Context(), service.GetChecklistRequest{TaskID: taskID})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get checklist")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// UpdateChecklistItemBody is the request body of UpdateChecklistItemHandler, the task and item IDs come from the URL.
+// Fields that are not set are left unchanged.
+type UpdateChecklistItemBody struct {
+	Title *string `json:"title,omitempty" binding:"min=1,max=255"`
+	Done  *bool   `json:"done,omitempty"`
+}
+
+// UpdateChecklistItemHandler handles PATCH requests to rename, check or uncheck a checklist item
+// curl -X PATCH http://localhost:8080/api/v1/tasks/{id}/checklist/{itemID} \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "done": true
+//	}'
+func (s *Server) UpdateChecklistItemHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task and item IDs from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := checklistItemIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Decode request body
+	var updateRequest UpdateChecklistItemBody
+	if err := ReadJSON(r, &updateRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 3. Call the business logic
+	response, err := s.taskService.UpdateChecklistItem(r.Context(), service.UpdateChecklistItemRequest{
+		TaskID: taskID,
+		ItemID: itemID,
+		Title:  updateRequest.Title,
+		Done:   updateRequest.Done,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update checklist item")
+	}
+
+	// 4. Return the response
+	return response, nil
+}
+
+// DeleteChecklistItemHandler handles DELETE requests to remove an item from the checklist of a task
+// curl -X DELETE http://localhost:8080/api/v1/tasks/{id}/checklist/{itemID} \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) DeleteChecklistItemHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task and item IDs from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := checklistItemIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.DeleteChecklistItem(r.Context(), service.DeleteChecklistItemRequest{
+		TaskID: taskID,
+		ItemID: itemID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete checklist item")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// blockerIDFromURL parses the {blockerID} path variable of the dependency endpoints
+func blockerIDFromURL(r *http.Request) (models.TaskID, error) {
+	blockerID, err := strconv.Atoi(mux.Vars(r)["blockerID"])
+	if err != nil {
+		return 0, service.NewInvalidInputError("invalid blocker ID")
+	}
+	return models.TaskID(blockerID), nil
+}
+
+// CreateTaskDependencyBody is the request body of CreateTaskDependencyHandler, the task ID comes from the URL
+type CreateTaskDependencyBody struct {
+	BlockerID models.TaskID `json:"blocker_id" binding:"required,min=1"`
+}
+
+// CreateTaskDependencyHandler handles POST requests to make a task wait for another task
+// curl -X POST http://localhost:8080/api/v1/tasks/{id}/dependencies \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "blocker_id": 2
+//	}'
+func (s *Server) CreateTaskDependencyHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task ID from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Decode request body
+	var createRequest CreateTaskDependencyBody
+	if err := ReadJSON(r, &createRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 3. Call the business logic
+	response, err := s.taskService.CreateTaskDependency(r.Context(), service.CreateTaskDependencyRequest{
+		TaskID:    taskID,
+		BlockerID: createRequest.BlockerID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create task dependency")
+	}
+
+	// 4. Return the response
+	return response, nil
+}
+
+// DeleteTaskDependencyHandler handles DELETE requests to remove a blocker of a task
+// curl -X DELETE http://localhost:8080/api/v1/tasks/{id}/dependencies/{blockerID} \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) DeleteTaskDependencyHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract task and blocker IDs from URL
+	taskID, err := taskIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+	blockerID, err := blockerIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.DeleteTaskDependency(r.Context(), service.DeleteTaskDependencyRequest{
+		TaskID:    taskID,
+		BlockerID: blockerID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete task dependency")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// GetWorkflowHandler handles GET requests to retrieve the workflow of the authenticated employer
+// curl -X GET http://localhost:8080/api/v1/workflow \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) GetWorkflowHandler(r *http.Request) (interface{}, error) {
+	// Call the business logic
+	response, err := s.taskService.GetWorkflow(r.Context(), service.GetWorkflowRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get workflow")
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// UpdateWorkflowHandler handles PUT requests to replace the workflow of the authenticated employer
+// curl -X PUT http://localhost:8080/api/v1/workflow \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "statuses": [
+//	    {"name": "pending"},
+//	    {"name": "in_progress", "active": true},
+//	    {"name": "in_review", "active": true},
+//	    {"name": "completed", "terminal": true}
+//	  ],
+//	  "transitions": [
+//	    {"from": "pending", "to": "in_progress", "roles": ["employee"]},
+//	    {"from": "in_progress", "to": "in_review", "roles": ["employee"]},
+//	    {"from": "in_review", "to": "completed", "roles": ["employer"]}
+//	  ]
+//	}'
+func (s *Server) UpdateWorkflowHandler(r *http.Request) (interface{}, error) {
+	// 1. Decode request
+	var updateRequest service.UpdateWorkflowRequest
+	if err := ReadJSON(r, &updateRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.UpdateWorkflow(r.Context(), updateRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update workflow")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// ResetWorkflowHandler handles DELETE requests to go back to the default workflow
+// curl -X DELETE http://localhost:8080/api/v1/workflow \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) ResetWorkflowHandler(r *http.Request) (interface{}, error) {
+	// Call the business logic
+	response, err := s.taskService.ResetWorkflow(r.Context(), service.ResetWorkflowRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reset workflow")
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// seriesIDFromURL parses the {id} path variable of the task series endpoints
+func seriesIDFromURL(r *http.Request) (models.TaskSeriesID, error) {
+	seriesID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, service.NewInvalidInputError("invalid task series ID")
+	}
+	return models.TaskSeriesID(seriesID), nil
+}
+
+// CreateTaskSeriesHandler handles POST requests to create a recurring task
+// curl -X POST http://localhost:8080/api/v1/task-series \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "title": "Weekly inspection",
+//	  "assignee_id": 3,
+//	  "rrule": "FREQ=WEEKLY;BYDAY=MO",
+//	  "timezone": "Europe/Paris",
+//	  "starts_at": "2026-11-02T09:00:00+01:00"
+//	}'
+func (s *Server) CreateTaskSeriesHandler(r *http.Request) (interface{}, error) {
+	// 1. Decode request
+	var createRequest service.CreateTaskSeriesRequest
+	if err := ReadJSON(r, &createRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.CreateTaskSeries(r.Context(), createRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create task series")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// GetTaskSeriesListHandler handles GET requests to retrieve the series of the authenticated employer
+// curl -X GET http://localhost:8080/api/v1/task-series \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) GetTaskSeriesListHandler(r *http.Request) (interface{}, error) {
+	// Call the business logic
+	response, err := s.taskService.GetTaskSeriesList(r.Context(), service.GetTaskSeriesListRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task series")
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// GetTaskSeriesHandler handles GET requests to retrieve a series
+// curl -X GET http://localhost:8080/api/v1/task-series/{id} \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) GetTaskSeriesHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract series ID from URL
+	seriesID, err := seriesIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.GetTaskSeries(r.Context(), service.GetTaskSeriesRequest{SeriesID: seriesID})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task series")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// UpdateTaskSeriesBody is the request body of UpdateTaskSeriesHandler, the series ID comes from the URL
+type UpdateTaskSeriesBody struct {
+	Title       *string    `json:"title,omitempty" binding:"min=1,max=255"`
+	Description *string    `json:"description,omitempty"`
+	AssigneeID  *int       `json:"assignee_id,omitempty" binding:"min=1"`
+	Priority    *string    `json:"priority,omitempty" binding:"oneof=low normal high urgent"`
+	RRule       *string    `json:"rrule,omitempty" binding:"min=1,max=255"`
+	Timezone    *string    `json:"timezone,omitempty" binding:"min=1,max=64"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+}
+
+// UpdateTaskSeriesHandler handles PATCH requests to change the template or the rule of a series
+// curl -X PATCH http://localhost:8080/api/v1/task-series/{id} \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "rrule": "FREQ=WEEKLY;BYDAY=MO,TH"
+//	}'
+func (s *Server) UpdateTaskSeriesHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract series ID from URL
+	seriesID, err := seriesIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Decode request body
+	var updateRequest UpdateTaskSeriesBody
+	if err := ReadJSON(r, &updateRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 3. Call the business logic
+	response, err := s.taskService.UpdateTaskSeries(r.Context(), service.UpdateTaskSeriesRequest{
+		SeriesID:    seriesID,
+		Title:       updateRequest.Title,
+		Description: updateRequest.Description,
+		AssigneeID:  updateRequest.AssigneeID,
+		Priority:    updateRequest.Priority,
+		RRule:       updateRequest.RRule,
+		Timezone:    updateRequest.Timezone,
+		StartsAt:    updateRequest.StartsAt,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update task series")
+	}
+
+	// 4. Return the response
+	return response, nil
+}
+
+// UpdateTaskSeriesStateBody is the request body of UpdateTaskSeriesStateHandler, the series ID comes from the URL
+type UpdateTaskSeriesStateBody struct {
+	State models.TaskSeriesState `json:"state" binding:"required,oneof=active paused ended"`
+}
+
+// UpdateTaskSeriesStateHandler handles PATCH requests to pause, resume or end a series
+// curl -X PATCH http://localhost:8080/api/v1/task-series/{id}/state \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "state": "paused"
+//	}'
+func (s *Server) UpdateTaskSeriesStateHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract series ID from URL
+	seriesID, err := seriesIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Decode request body
+	var updateRequest UpdateTaskSeriesStateBody
+	if err := ReadJSON(r, &updateRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 3. Call the business logic
+	response, err := s.taskService.UpdateTaskSeriesState(r.Context(), service.UpdateTaskSeriesStateRequest{
+		SeriesID: seriesID,
+		State:    updateRequest.State,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update task series state")
+	}
+
+	// 4. Return the response
+	return response, nil
+}
+
+// webhookIDFromURL parses the {id} path variable of the webhook endpoints
+func webhookIDFromURL(r *http.Request) (models.WebhookID, error) {
+	webhookID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, service.NewInvalidInputError("invalid webhook ID")
+	}
+	return models.WebhookID(webhookID), nil
+}
+
+// CreateWebhookHandler handles POST requests to register a webhook
+// curl -X POST http://localhost:8080/api/v1/webhooks \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "url": "https://example.com/hooks/tasks",
+//	  "events": ["task.created", "task.completed"]
+//	}'
+func (s *Server) CreateWebhookHandler(r *http.Request) (interface{}, error) {
+	// 1. Decode request
+	var createRequest service.CreateWebhookRequest
+	if err := ReadJSON(r, &createRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.CreateWebhook(r.Context(), createRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create webhook")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// GetWebhooksHandler handles GET requests to retrieve the webhooks of the authenticated employer
+// curl -X GET http://localhost:8080/api/v1/webhooks \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) GetWebhooksHandler(r *http.Request) (interface{}, error) {
+	// Call the business logic
+	response, err := s.taskService.GetWebhooks(r.Context(), service.GetWebhooksRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get webhooks")
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// UpdateWebhookBody is the request body of UpdateWebhookHandler, the webhook ID comes from the URL
+type UpdateWebhookBody struct {
+	URL    *string                   `json:"url,omitempty" binding:"url,max=2048"`
+	Events []models.WebhookEventType `json:"events,omitempty" binding:"max=10"`
+	Active *bool                     `json:"active,omitempty"`
+}
+
+// UpdateWebhookHandler handles PATCH requests to change a webhook
+// curl -X PATCH http://localhost:8080/api/v1/webhooks/{id} \
+// -H "Content-Type: application/json" \
+// -H "Authorization: Bearer your_jwt_token" \
+//
+//	-d '{
+//	  "active": false
+//	}'
+func (s *Server) UpdateWebhookHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract webhook ID from URL
+	webhookID, err := webhookIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Decode request body
+	var updateRequest UpdateWebhookBody
+	if err := ReadJSON(r, &updateRequest); err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 3. Call the business logic
+	response, err := s.taskService.UpdateWebhook(r.Context(), service.UpdateWebhookRequest{
+		WebhookID: webhookID,
+		URL:       updateRequest.URL,
+		Events:    updateRequest.Events,
+		Active:    updateRequest.Active,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update webhook")
+	}
+
+	// 4. Return the response
+	return response, nil
+}
+
+// DeleteWebhookHandler handles DELETE requests to delete a webhook
+// curl -X DELETE http://localhost:8080/api/v1/webhooks/{id} \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) DeleteWebhookHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract webhook ID from URL
+	webhookID, err := webhookIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.DeleteWebhook(r.Context(), service.DeleteWebhookRequest{WebhookID: webhookID})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete webhook")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// GetWebhookDeliveriesHandler handles GET requests to retrieve the delivery log of a webhook
+// curl -X GET http://localhost:8080/api/v1/webhooks/{id}/deliveries \
+// -H "Authorization: Bearer your_jwt_token" \
+// "?limit=50&offset=0"
+func (s *Server) GetWebhookDeliveriesHandler(r *http.Request) (interface{}, error) {
+	// Extract webhook ID from URL
+	webhookID, err := webhookIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+	request := service.GetWebhookDeliveriesRequest{WebhookID: webhookID}
+
+	// Parse pagination options
+	if request.Limit, request.Offset, err = paginationFromQuery(r); err != nil {
+		return nil, err
+	}
+
+	// Call the business logic
+	response, err := s.taskService.GetWebhookDeliveries(r.Context(), request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook deliveries")
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// RedeliverWebhookDeliveryHandler handles POST requests to send the event of a delivery again
+// curl -X POST http://localhost:8080/api/v1/webhooks/{id}/deliveries/{deliveryID}/redeliver \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) RedeliverWebhookDeliveryHandler(r *http.Request) (interface{}, error) {
+	// 1. Extract webhook and delivery IDs from URL
+	webhookID, err := webhookIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+	deliveryID, err := strconv.Atoi(mux.Vars(r)["deliveryID"])
+	if err != nil {
+		return nil, service.NewInvalidInputError("invalid delivery ID")
+	}
+
+	// 2. Call the business logic
+	response, err := s.taskService.RedeliverWebhookDelivery(r.Context(), service.RedeliverWebhookDeliveryRequest{
+		WebhookID:  webhookID,
+		DeliveryID: models.WebhookDeliveryID(deliveryID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to redeliver webhook delivery")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
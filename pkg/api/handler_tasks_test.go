@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskFilterFromQuery(t *testing.T) {
+	t.Run("all the filters", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/tasks?status=pending,in_progress&status=review&assignee_id=3&assignee_id=4"+
+			"&unassigned=true&employer_id=1,%202&priority=high&label_id=7&overdue=1&q=login"+
+			"&due_before=2023-05-01&due_after=2023-04-01T08:00:00%2B02:00&created_before=2023-04-10T00:00:00Z", nil)
+		filter, err := taskFilterFromQuery(r)
+		require.NoError(t, err)
+
+		labelID := models.LabelID(7)
+		dueBefore := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+		createdBefore := time.Date(2023, 4, 10, 0, 0, 0, 0, time.UTC)
+		require.Equal(t, []models.TaskStatus{"pending", "in_progress", "review"}, filter.Statuses)
+		require.Equal(t, []models.UserID{3, 4}, filter.AssigneeIDs)
+		require.True(t, filter.Unassigned)
+		require.Equal(t, []models.UserID{1, 2}, filter.EmployerIDs)
+		require.Equal(t, models.TaskPriorityHigh, filter.Priority)
+		require.Equal(t, &labelID, filter.LabelID)
+		require.True(t, filter.Overdue)
+		require.Equal(t, "login", filter.Query)
+		require.Equal(t, &dueBefore, filter.DueBefore)
+		require.True(t, filter.DueAfter.Equal(time.Date(2023, 4, 1, 6, 0, 0, 0, time.UTC)))
+		require.Equal(t, &createdBefore, filter.CreatedBefore)
+		require.Nil(t, filter.CreatedAfter)
+	})
+
+	t.Run("no filters", func(t *testing.T) {
+		filter, err := taskFilterFromQuery(httptest.NewRequest("GET", "/tasks", nil))
+		require.NoError(t, err)
+		require.Equal(t, service.TaskFilter{}, filter)
+	})
+
+	for _, query := range []string{"assignee_id=3,me", "employer_id=x", "label_id=x", "unassigned=maybe", "due_after=yesterday"} {
+		t.Run("invalid "+query, func(t *testing.T) {
+			_, err := taskFilterFromQuery(httptest.NewRequest("GET", "/tasks?"+query, nil))
+			var svcErr service.Error
+			require.True(t, errors.As(err, &svcErr), err)
+			require.Equal(t, service.ErrorCodeInvalidInput, svcErr.ErrorCode)
+		})
+	}
+}
@@ -17,7 +17,7 @@ func (s *Server) GetUserByIDHandler(r *http.Request) (interface{}, error) {
 	idStr := vars["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return nil, errors.Wrap(err, "invalid user ID")
+		return nil, service.NewInvalidInputError("invalid user ID")
 	}
 	var request = service.GetUserByIDRequest{
 		ID: models.UserID(id),
@@ -49,21 +49,6 @@ func (s *Server) CreateUserHandler(r *http.Request) (interface{}, error) {
 		return nil, errors.Wrap(err, "invalid request body")
 	}
 
-	// Validate the required fields
-	if createRequest.Email == "" || createRequest.Name == "" || createRequest.Password == "" {
-		return nil, errors.New("missing required fields")
-	}
-
-	// Validate password length
-	if len(createRequest.Password) < 8 {
-		return nil, errors.New("password must be at least 8 characters")
-	}
-
-	// Validate role
-	if createRequest.Role != models.UserRoleEmployee && createRequest.Role != models.UserRoleEmployer {
-		return nil, errors.New("invalid role")
-	}
-
 	// 2. Call the business logic
 	response, err := s.userService.CreateUser(r.Context(), createRequest)
 	if err != nil {
@@ -121,11 +106,6 @@ func (s *Server) LoginHandler(r *http.Request) (interface{}, error) {
 		return nil, errors.Wrap(err, "invalid request body")
 	}
 
-	// Validate the required fields
-	if loginRequest.Email == "" || loginRequest.Password == "" {
-		return nil, errors.New("missing required fields")
-	}
-
 	// 2. Call the business logic
 	response, err := s.userService.GetJWTToken(r.Context(), loginRequest)
 	if err != nil {
@@ -135,3 +115,72 @@ func (s *Server) LoginHandler(r *http.Request) (interface{}, error) {
 	// 3. Return the response
 	return response, nil
 }
+
+// RefreshTokenHandler handles POST requests to exchange a refresh token for new tokens
+// curl -X POST http://localhost:8080/api/v1/token/refresh \
+// -H "Content-Type: application/json" \
+// -d '{"refresh_token": "your_refresh_token"}'
+func (s *Server) RefreshTokenHandler(r *http.Request) (interface{}, error) {
+	// 1. Decode request
+	var refreshRequest service.RefreshTokenRequest
+	err := ReadJSON(r, &refreshRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid request body")
+	}
+
+	// 2. Call the business logic
+	response, err := s.userService.RefreshToken(r.Context(), refreshRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to refresh token")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// LogoutHandler handles POST requests to log out the current session, or all the sessions of the user
+// curl -X POST http://localhost:8080/api/v1/logout \
+// -H "Authorization: Bearer your_jwt_token" \
+// -H "Content-Type: application/json" \
+// -d '{"refresh_token": "your_refresh_token", "all_sessions": false}'
+func (s *Server) LogoutHandler(r *http.Request) (interface{}, error) {
+	// 1. Decode request, the body is optional
+	var logoutRequest service.LogoutRequest
+	if r.ContentLength != 0 {
+		if err := ReadJSON(r, &logoutRequest); err != nil {
+			return nil, errors.Wrap(err, "invalid request body")
+		}
+	}
+
+	// 2. Call the business logic
+	response, err := s.userService.Logout(r.Context(), logoutRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to log out")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
+
+// RevokeUserSessionsHandler handles POST requests to revoke all the tokens of a user (only accessible by employers)
+// curl -X POST http://localhost:8080/api/v1/users/{id}/revoke-sessions \
+// -H "Authorization: Bearer your_jwt_token"
+func (s *Server) RevokeUserSessionsHandler(r *http.Request) (interface{}, error) {
+	// 1. Decode request
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, service.NewInvalidInputError("invalid user ID")
+	}
+	request := service.RevokeUserSessionsRequest{
+		UserID: models.UserID(id),
+	}
+
+	// 2. Call the business logic
+	response, err := s.userService.RevokeUserSessions(r.Context(), request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to revoke user sessions")
+	}
+
+	// 3. Return the response
+	return response, nil
+}
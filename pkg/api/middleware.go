@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/llkhacquan/cisab/pkg/dbctx"
+	"github.com/llkhacquan/cisab/pkg/service"
 	"github.com/llkhacquan/cisab/pkg/utils/logger"
 	"gorm.io/gorm"
 )
@@ -49,26 +50,57 @@ func CorsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// DBTransactionMiddleware runs each request in a database transaction, committed if the response is 200 OK
+// and rolled back otherwise. The transaction is bound to the request context, so it is also rolled back
+// if the request is canceled, e.g. when a graceful shutdown runs out of time (see pkg/lifecycle).
+// The hooks registered with dbctx.AfterCommit run once the transaction is committed.
 func DBTransactionMiddleware(l *logger.Logger, db *gorm.DB) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Create a response recorder to capture the status code
 			rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
-			tx := db.Begin()
-			r = r.WithContext(dbctx.Set(r.Context(), tx))
+			tx := db.WithContext(r.Context()).Begin()
+			if err := tx.Error; err != nil {
+				l.Error("failed to begin transaction", "error", err)
+				respondWithError(w, service.NewCodedError(http.StatusInternalServerError, service.ErrorCodeInternal, "server error"))
+				return
+			}
+			// Roll back if the handler panics
+			finished := false
+			defer func() {
+				if !finished {
+					tx.Rollback()
+				}
+			}()
+
+			ctx, runAfterCommitHooks := dbctx.WithAfterCommit(r.Context())
+			r = r.WithContext(dbctx.Set(ctx, tx))
 			next.ServeHTTP(rec, r)
+			finished = true
 			if rec.statusCode != http.StatusOK {
 				tx.Rollback()
 			} else {
 				if err := tx.Commit().Error; err != nil {
 					http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 					l.Error("failed to commit transaction", "error", err)
+					return
 				}
+				runAfterCommitHooks()
 			}
 		})
 	}
 }
 
+// DBMiddleware binds db to the request context, without a transaction. It is for the long-running requests,
+// like the event stream, that must not hold a transaction open for as long as they run.
+func DBMiddleware(db *gorm.DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(dbctx.Set(r.Context(), db.WithContext(r.Context()))))
+		})
+	}
+}
+
 // responseRecorder is a custom http.ResponseWriter to capture the status code
 type responseRecorder struct {
 	http.ResponseWriter
@@ -1,40 +1,42 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/jwtkeys"
 	"github.com/llkhacquan/cisab/pkg/models"
 	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/llkhacquan/cisab/pkg/service"
 	"github.com/llkhacquan/cisab/pkg/utils/logger"
 	"github.com/pkg/errors"
 )
 
-// AuthMiddleware validates JWT tokens and sets user information in the request context
-func AuthMiddleware(log *logger.Logger, userRepo repo.UserRepo, jwtSecret string) func(http.Handler) http.Handler {
+// AuthMiddleware validates JWT tokens and sets user information in the request context.
+// It rejects every request without a valid token, so it must only wrap non-public endpoints (see RegisterEndpoints).
+//
+// Tokens are revoked either one by one on logout (their jti is on the denylist of tokenRepo), or all the tokens
+// of a user at once by bumping users.token_version, which no longer matches their ver claim.
+func AuthMiddleware(log *logger.Logger, userRepo repo.UserRepo, tokenRepo repo.TokenRepo, keys *jwtkeys.KeySet) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Skip auth for certain public endpoints
-			if isPublicEndpoint(r.URL.Path) {
-				next.ServeHTTP(w, r)
-				return
-			}
-
 			// Extract token from Authorization header
 			tokenString, err := extractTokenFromHeader(r)
 			if err != nil {
 				log.Info("authorization header invalid", "error", err.Error(), "path", r.URL.Path)
-				respondWithError(w, http.StatusUnauthorized, err.Error())
+				respondWithError(w, service.NewCodedError(http.StatusUnauthorized, service.ErrorCodeUnauthorized, err.Error()))
 				return
 			}
 
 			// Parse and validate the token
-			token, err := parseAndValidateToken(tokenString, jwtSecret)
+			token, err := parseAndValidateToken(tokenString, keys)
 			if err != nil {
 				log.Info("invalid JWT token", "error", err.Error(), "path", r.URL.Path)
-				respondWithError(w, http.StatusUnauthorized, "invalid token")
+				respondWithError(w, service.NewCodedError(http.StatusUnauthorized, service.ErrorCodeUnauthorized, "invalid token"))
 				return
 			}
 
@@ -42,7 +44,13 @@ func AuthMiddleware(log *logger.Logger, userRepo repo.UserRepo, jwtSecret string
 			userID, err := extractUserIDFromToken(token)
 			if err != nil {
 				log.Info("failed to extract user ID from token", "error", err.Error(), "path", r.URL.Path)
-				respondWithError(w, http.StatusUnauthorized, "invalid token claims")
+				respondWithError(w, service.NewCodedError(http.StatusUnauthorized, service.ErrorCodeUnauthorized, "invalid token claims"))
+				return
+			}
+			claims, err := extractTokenClaims(token)
+			if err != nil {
+				log.Info("failed to extract claims from token", "error", err.Error(), "path", r.URL.Path)
+				respondWithError(w, service.NewCodedError(http.StatusUnauthorized, service.ErrorCodeUnauthorized, "invalid token claims"))
 				return
 			}
 
@@ -50,19 +58,41 @@ func AuthMiddleware(log *logger.Logger, userRepo repo.UserRepo, jwtSecret string
 			user, err := userRepo.GetUserByID(r.Context(), userID)
 			if err != nil {
 				log.Error("failed to fetch user", "error", err.Error(), "user_id", userID, "path", r.URL.Path)
-				respondWithError(w, http.StatusInternalServerError, "server error")
+				respondWithError(w, service.NewCodedError(http.StatusInternalServerError, service.ErrorCodeInternal, "server error"))
 				return
 			}
 
 			if user == nil {
 				log.Info("user not found", "user_id", userID, "path", r.URL.Path)
-				respondWithError(w, http.StatusUnauthorized, "user not found")
+				respondWithError(w, service.NewCodedError(http.StatusUnauthorized, service.ErrorCodeUnauthorized, "user not found"))
 				return
 			}
 
+			// Reject revoked tokens
+			if claims.version != user.TokenVersion {
+				log.Info("token version revoked", "user_id", userID, "path", r.URL.Path)
+				respondWithError(w, service.NewCodedError(http.StatusUnauthorized, service.ErrorCodeUnauthorized, "token has been revoked"))
+				return
+			}
+			if claims.id != "" {
+				revoked, err := tokenRepo.IsAccessTokenRevoked(r.Context(), claims.id)
+				if err != nil {
+					log.Error("failed to check token revocation", "error", err.Error(), "user_id", userID, "path", r.URL.Path)
+					respondWithError(w, service.NewCodedError(http.StatusInternalServerError, service.ErrorCodeInternal, "server error"))
+					return
+				}
+				if revoked {
+					log.Info("token revoked", "user_id", userID, "path", r.URL.Path)
+					respondWithError(w, service.NewCodedError(http.StatusUnauthorized, service.ErrorCodeUnauthorized, "token has been revoked"))
+					return
+				}
+			}
+
 			// Create auth metadata and set it in the context
 			auth := authctx.AuthMD{
-				User: *user,
+				User:        *user,
+				TokenID:     claims.id,
+				TokenExpiry: claims.expiry,
 			}
 
 			// Set auth context and continue
@@ -72,28 +102,11 @@ func AuthMiddleware(log *logger.Logger, userRepo repo.UserRepo, jwtSecret string
 	}
 }
 
-// isPublicEndpoint determines if the path is a public endpoint that doesn't require authentication
-func isPublicEndpoint(path string) bool {
-	// Health check is outside the API router, so we don't need to include it here
-	publicPaths := []string{
-		"/login", // Login endpoint
-		"/users", // Allow creating new users without authentication
-	}
-
-	for _, pp := range publicPaths {
-		// Match paths that end with the publicPath
-		if strings.HasSuffix(path, pp) {
-			return true
-		}
-	}
-	return false
-}
-
-// respondWithError writes an error response
-func respondWithError(w http.ResponseWriter, statusCode int, message string) {
+// respondWithError writes an error response in the same envelope as HandleEndpoint
+func respondWithError(w http.ResponseWriter, err service.Error) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	_, _ = w.Write([]byte(`{"error":"` + message + `"}`))
+	w.WriteHeader(err.Code)
+	_ = json.NewEncoder(w).Encode(ServiceErrorResponse(err))
 }
 
 // extractUserIDFromToken extracts the user ID from JWT token claims
@@ -113,16 +126,44 @@ func extractUserIDFromToken(token *jwt.Token) (models.UserID, error) {
 	return models.UserID(int(userIDFloat)), nil
 }
 
-// parseAndValidateToken parses and validates a JWT token
-func parseAndValidateToken(tokenString, jwtSecret string) (*jwt.Token, error) {
-	// Parse the token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+// tokenClaims holds the claims of an access token used to check its revocation
+type tokenClaims struct {
+	id      string    // jti
+	version int       // ver, the users.token_version the token was issued with
+	expiry  time.Time // exp
+}
+
+// extractTokenClaims extracts the revocation-related claims from JWT token claims.
+// Tokens issued before these claims were introduced have no jti and version 0.
+func extractTokenClaims(token *jwt.Token) (tokenClaims, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return tokenClaims{}, errors.New("failed to extract JWT claims")
+	}
+
+	var result tokenClaims
+	if jti, ok := claims["jti"]; ok {
+		if result.id, ok = jti.(string); !ok {
+			return tokenClaims{}, errors.New("jti claim invalid")
 		}
-		return []byte(jwtSecret), nil
-	})
+	}
+	if ver, ok := claims["ver"]; ok {
+		v, ok := ver.(float64)
+		if !ok {
+			return tokenClaims{}, errors.New("ver claim invalid")
+		}
+		result.version = int(v)
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		result.expiry = time.Unix(int64(exp), 0)
+	}
+	return result, nil
+}
+
+// parseAndValidateToken parses and validates a JWT token
+func parseAndValidateToken(tokenString string, keys *jwtkeys.KeySet) (*jwt.Token, error) {
+	// Parse the token, the key set checks the signing method against the key of its kid header
+	token, err := jwt.Parse(tokenString, keys.Keyfunc)
 
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse token")
@@ -0,0 +1,389 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+// QueryParam documents a query parameter of an endpoint
+type QueryParam struct {
+	Name        string
+	Type        string // "string", "integer" or "boolean"
+	Description string
+	Enum        []string
+	Required    bool
+}
+
+// OpenAPI is the root of an OpenAPI 3.1 document.
+// Only the parts of the specification that we generate are modelled.
+type OpenAPI struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       OpenAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                      `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema        `json:"schemas"`
+	SecuritySchemes map[string]OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type OpenAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	Summary     string                     `json:"summary,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	OperationID string                     `json:"operationId,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+	Roles       []string                   `json:"x-roles,omitempty"` // the user roles allowed to call the operation
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPISchema is a JSON schema as used by OpenAPI 3.1
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 interface{}               `json:"type,omitempty"` // a type name, or a list of names for nullable types
+	Format               string                    `json:"format,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+}
+
+// enumValues lists the allowed values of the string types that are enums in the domain model
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeOf(models.UserRole("")): {
+		string(models.UserRoleEmployer), string(models.UserRoleEmployee),
+	},
+	reflect.TypeOf(models.TaskSeriesState("")): {
+		string(models.TaskSeriesStateActive), string(models.TaskSeriesStatePaused), string(models.TaskSeriesStateEnded),
+	},
+	reflect.TypeOf(models.WebhookEventType("")): {
+		string(models.WebhookEventTaskCreated), string(models.WebhookEventTaskAssigned), string(models.WebhookEventTaskCompleted),
+	},
+	reflect.TypeOf(models.WebhookDeliveryStatus("")): {
+		string(models.WebhookDeliveryPending), string(models.WebhookDeliverySucceeded), string(models.WebhookDeliveryFailed),
+	},
+}
+
+//go:embed swagger.html
+var swaggerUIPage string
+
+var pathParamRegexp = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// NewOpenAPI creates an empty OpenAPI document with the bearer auth scheme and the response envelope schemas
+func NewOpenAPI(title, version string) *OpenAPI {
+	spec := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info:    OpenAPIInfo{Title: title, Version: version},
+		Paths:   make(map[string]map[string]OpenAPIOperation),
+		Components: OpenAPIComponents{
+			Schemas: make(map[string]*OpenAPISchema),
+			SecuritySchemes: map[string]OpenAPISecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	spec.Components.Schemas["ErrorResponse"] = &OpenAPISchema{
+		Type: "object",
+		Properties: map[string]*OpenAPISchema{
+			"status":     {Type: "string", Enum: []string{"error"}},
+			"message":    {Type: "string"},
+			"error":      {Type: "string"},
+			"error_code": {Type: "string"},
+			"fields":     {Type: "array", Items: spec.schemaFor(reflect.TypeOf(Response{}.Fields).Elem())},
+		},
+		Required: []string{"status", "message"},
+	}
+	return spec
+}
+
+// AddEndpoints documents the endpoints, which are served under the given path prefix
+func (spec *OpenAPI) AddEndpoints(prefix string, endpoints []Endpoint) {
+	for _, e := range endpoints {
+		path := prefix + e.Path
+		op := OpenAPIOperation{
+			Summary:     e.Summary,
+			Tags:        e.Tags,
+			OperationID: operationID(e),
+			Responses: map[string]OpenAPIResponse{
+				"200":     {Description: "Successful response", Content: jsonContent(spec.envelopeSchema(e.Response))},
+				"default": {Description: "Error response", Content: jsonContent(&OpenAPISchema{Ref: "#/components/schemas/ErrorResponse"})},
+			},
+		}
+
+		if e.Stream != nil {
+			// The response is a stream of server-sent events, each with a data of the Response type
+			op.Responses["200"] = OpenAPIResponse{
+				Description: "Stream of server-sent events",
+				Content:     map[string]OpenAPIMediaType{"text/event-stream": {Schema: spec.schemaFor(reflect.TypeOf(e.Response))}},
+			}
+		}
+
+		// Path parameters are identifiers, e.g. /tasks/{id}
+		for _, match := range pathParamRegexp.FindAllStringSubmatch(e.Path, -1) {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name: match[1], In: "path", Required: true, Schema: &OpenAPISchema{Type: "integer"},
+			})
+		}
+		for _, q := range e.Query {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name: q.Name, In: "query", Description: q.Description, Required: q.Required,
+				Schema: &OpenAPISchema{Type: q.Type, Enum: q.Enum},
+			})
+		}
+
+		if e.Request != nil {
+			op.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content:  jsonContent(spec.schemaFor(reflect.TypeOf(e.Request))),
+			}
+		}
+		if !e.Public {
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+		}
+		for _, role := range e.Roles {
+			op.Roles = append(op.Roles, string(role))
+		}
+
+		openAPIPath := pathParamRegexp.ReplaceAllString(path, "{$1}")
+		if spec.Paths[openAPIPath] == nil {
+			spec.Paths[openAPIPath] = make(map[string]OpenAPIOperation)
+		}
+		spec.Paths[openAPIPath][strings.ToLower(e.Method)] = op
+	}
+}
+
+// envelopeSchema wraps the schema of the response data in the success Response envelope
+func (spec *OpenAPI) envelopeSchema(data interface{}) *OpenAPISchema {
+	envelope := &OpenAPISchema{
+		Type: "object",
+		Properties: map[string]*OpenAPISchema{
+			"status":  {Type: "string", Enum: []string{"success"}},
+			"message": {Type: "string"},
+		},
+		Required: []string{"status", "message"},
+	}
+	if data != nil {
+		envelope.Properties["data"] = spec.schemaFor(reflect.TypeOf(data))
+	}
+	return envelope
+}
+
+// schemaFor returns the schema of a Go type. Named struct types are registered as
+// components and referenced, so each one is described only once.
+func (spec *OpenAPI) schemaFor(t reflect.Type) *OpenAPISchema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema *OpenAPISchema
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		schema = &OpenAPISchema{Type: "string", Format: "date-time"}
+	case t == reflect.TypeOf(json.RawMessage{}):
+		schema = &OpenAPISchema{} // any JSON value
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name := t.Name()
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			// Register first so self-referencing types terminate
+			spec.Components.Schemas[name] = &OpenAPISchema{}
+			*spec.Components.Schemas[name] = *spec.structSchema(t)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	case t.Kind() == reflect.Struct:
+		schema = spec.structSchema(t)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		schema = &OpenAPISchema{Type: "array", Items: spec.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Map:
+		schema = &OpenAPISchema{Type: "object", AdditionalProperties: spec.schemaFor(t.Elem())}
+	case t.Kind() == reflect.String:
+		schema = &OpenAPISchema{Type: "string", Enum: enumValues[t]}
+	case t.Kind() == reflect.Bool:
+		schema = &OpenAPISchema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = &OpenAPISchema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = &OpenAPISchema{Type: "number"}
+	default:
+		schema = &OpenAPISchema{}
+	}
+
+	if nullable {
+		if typeName, ok := schema.Type.(string); ok {
+			schema.Type = []string{typeName, "null"}
+		}
+	}
+	return schema
+}
+
+// structSchema describes the JSON object of a struct, using its json and binding tags.
+// Only fields with a "required" binding rule are listed as required.
+func (spec *OpenAPI) structSchema(t reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			// Embedded structs are flattened by encoding/json
+			embedded := spec.structSchema(indirectType(field.Type))
+			for k, v := range embedded.Properties {
+				schema.Properties[k] = v
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := spec.schemaFor(field.Type)
+		if applyBindingRules(property, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	sort.Strings(schema.Required)
+	return schema
+}
+
+// applyBindingRules documents the validation rules of a binding tag (see Validate) on the schema.
+// It returns true if the field is required.
+func applyBindingRules(schema *OpenAPISchema, tag string) (required bool) {
+	if tag == "" || schema.Ref != "" {
+		return tag != "" && strings.Contains(tag, "required")
+	}
+	isString := schema.Type == "string" || reflect.DeepEqual(schema.Type, []string{"string", "null"})
+	for _, rule := range strings.Split(tag, ",") {
+		ruleName, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch ruleName {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "min", "max":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case isString && ruleName == "min":
+				schema.MinLength = intPtr(int(n))
+			case isString:
+				schema.MaxLength = intPtr(int(n))
+			case ruleName == "min":
+				schema.Minimum = &n
+			default:
+				schema.Maximum = &n
+			}
+		}
+	}
+	return required
+}
+
+// operationID derives the operation id from the handler method name,
+// e.g. "CreateTask" for Server.CreateTaskHandler
+func operationID(e Endpoint) string {
+	var handler interface{} = e.Handler
+	if e.Stream != nil {
+		handler = e.Stream
+	} else if e.Handler == nil {
+		return ""
+	}
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm") // method values get a "-fm" suffix
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimSuffix(name, "Handler")
+}
+
+func jsonContent(schema *OpenAPISchema) map[string]OpenAPIMediaType {
+	return map[string]OpenAPIMediaType{"application/json": {Schema: schema}}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// OpenAPIHandler serves the OpenAPI document as JSON
+func OpenAPIHandler(spec *OpenAPI) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(spec)
+	}
+}
+
+// SwaggerUIHandler serves a Swagger UI page that renders the document served at specURL
+func SwaggerUIHandler(specURL string) http.HandlerFunc {
+	page := strings.ReplaceAll(swaggerUIPage, "{{SPEC_URL}}", specURL)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(page))
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_OpenAPI(t *testing.T) {
+	// The routes only reference the services, so the spec can be served without any dependencies
+	server := NewServer(nil, nil, nil, nil, logger.New(logger.Config{Output: io.Discard}), nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var spec OpenAPI
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&spec))
+	require.Equal(t, "3.1.0", spec.OpenAPI)
+
+	t.Run("every endpoint is documented", func(t *testing.T) {
+		require.Contains(t, spec.Paths, "/health")
+		require.Contains(t, spec.Paths["/api/v1/tasks"], "post")
+		require.Contains(t, spec.Paths["/api/v1/tasks"], "get")
+		require.Contains(t, spec.Paths["/api/v1/tasks/{id}/status"], "patch")
+		require.Contains(t, spec.Paths["/api/v1/events/stream"], "get")
+	})
+
+	t.Run("event stream", func(t *testing.T) {
+		op := spec.Paths["/api/v1/events/stream"]["get"]
+		require.Equal(t, "EventStream", op.OperationID)
+		require.Equal(t, []map[string][]string{{"bearerAuth": {}}}, op.Security)
+		require.Equal(t, "#/components/schemas/EventMessage", op.Responses["200"].Content["text/event-stream"].Schema.Ref)
+	})
+
+	t.Run("operations", func(t *testing.T) {
+		op := spec.Paths["/api/v1/tasks/{id}/status"]["patch"]
+		require.Equal(t, "UpdateTaskStatus", op.OperationID)
+		require.Equal(t, []map[string][]string{{"bearerAuth": {}}}, op.Security)
+		require.Equal(t, "id", op.Parameters[0].Name)
+		require.Equal(t, "path", op.Parameters[0].In)
+		require.Equal(t, "#/components/schemas/UpdateTaskStatusBody", op.RequestBody.Content["application/json"].Schema.Ref)
+
+		login := spec.Paths["/api/v1/login"]["post"]
+		require.Empty(t, login.Security, "login is public")
+
+		var queryParams []string
+		for _, p := range spec.Paths["/api/v1/tasks"]["get"].Parameters {
+			queryParams = append(queryParams, p.Name)
+		}
+		require.Equal(t, []string{
+			"status", "assignee_id", "unassigned", "employer_id", "priority", "label_id", "overdue",
+			"due_before", "due_after", "created_before", "created_after", "q", "sort", "sort_by", "sort_order", "limit", "offset", "cursor", "skip_count",
+		}, queryParams)
+
+		// the values the service accepts, nothing else
+		var summarySortBy []string
+		for _, p := range spec.Paths["/api/v1/employee-summary"]["get"].Parameters {
+			if p.Name == "sort_by" {
+				summarySortBy = p.Schema.Enum
+			}
+		}
+		require.Equal(t, service.EmployeeSummarySortFields, summarySortBy)
+	})
+
+	t.Run("schemas are reflected from the service types", func(t *testing.T) {
+		createTask := spec.Components.Schemas["CreateTaskRequest"]
+		require.NotNil(t, createTask)
+		require.Equal(t, []string{"title"}, createTask.Required)
+		require.Equal(t, 255, *createTask.Properties["title"].MaxLength)
+		require.Equal(t, "date-time", createTask.Properties["due_date"].Format)
+
+		createUser := spec.Components.Schemas["CreateUserRequest"]
+		require.Equal(t, "email", createUser.Properties["email"].Format)
+		require.Equal(t, []string{"employer", "employee"}, createUser.Properties["role"].Enum)
+
+		require.Contains(t, spec.Components.Schemas, "Task")
+		require.Contains(t, spec.Components.Schemas, "User")
+		require.NotContains(t, spec.Components.Schemas["User"].Properties, "PasswordHash")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/llkhacquan/cisab/pkg/service"
 	"github.com/llkhacquan/cisab/pkg/utils/logger"
 	"github.com/pkg/errors"
 )
@@ -14,6 +15,10 @@ type Response struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	// ErrorCode is a stable machine-readable code (e.g. "task_not_found") that clients can branch on
+	ErrorCode string `json:"error_code,omitempty"`
+	// Fields lists every invalid field of a request that failed validation
+	Fields []service.FieldError `json:"fields,omitempty"`
 }
 
 // ErrorResponse creates an error response with given message and error
@@ -30,6 +35,17 @@ func ErrorResponse(message string, err error) Response {
 	}
 }
 
+// ServiceErrorResponse creates an error response from a service error, exposing its message and error code
+func ServiceErrorResponse(err service.Error) Response {
+	return Response{
+		Status:    "error",
+		Message:   err.Message,
+		Error:     err.Message,
+		ErrorCode: err.ErrorCode,
+		Fields:    err.Fields,
+	}
+}
+
 // SuccessResponse creates a success response with given message and data
 func SuccessResponse(message string, data interface{}) Response {
 	return Response{
@@ -50,13 +66,14 @@ func WriteJSON(w http.ResponseWriter, status int, response interface{}, log *log
 	}
 }
 
-// ReadJSON attempts to decode the request body into the provided destination
+// ReadJSON attempts to decode the request body into the provided destination,
+// then validates it against the `binding` tags of dst (see Validate).
 func ReadJSON(r *http.Request, dst interface{}) error {
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 
 	if err := decoder.Decode(dst); err != nil {
-		return errors.Wrap(err, "failed to decode body")
+		return errors.WithStack(service.NewCodedError(http.StatusBadRequest, service.ErrorCodeInvalidRequestBody, "failed to decode body: "+err.Error()))
 	}
-	return nil
+	return Validate(dst)
 }
@@ -4,12 +4,57 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/llkhacquan/cisab/pkg/jwtkeys"
+	"github.com/llkhacquan/cisab/pkg/models"
 	"github.com/llkhacquan/cisab/pkg/repo"
 	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/llkhacquan/cisab/pkg/stream"
 	"github.com/llkhacquan/cisab/pkg/utils/logger"
 	"gorm.io/gorm"
 )
 
+// apiPrefix is the path prefix of all API endpoints
+const apiPrefix = "/api/v1"
+
+// taskFilterQueryParams and listQueryParams document the query parameters shared by the task list endpoints.
+// The filters with several values take them repeated or separated by commas.
+var (
+	taskFilterQueryParams = []QueryParam{
+		{Name: "status", Type: "string", Description: "Filter by task statuses of the employer's workflow, e.g. pending,in_progress"},
+		{Name: "assignee_id", Type: "string", Description: "Filter by assignee IDs, e.g. 3,4"},
+		{Name: "unassigned", Type: "boolean", Description: "Only the unassigned tasks, or also them with assignee_id"},
+		{Name: "employer_id", Type: "string", Description: "Filter by the IDs of the employers who created the tasks, e.g. 1,2"},
+		{
+			Name: "priority", Type: "string", Description: "Filter by task priority",
+			Enum: []string{string(models.TaskPriorityLow), string(models.TaskPriorityNormal), string(models.TaskPriorityHigh), string(models.TaskPriorityUrgent)},
+		},
+		{Name: "label_id", Type: "integer", Description: "Filter by label"},
+		{Name: "overdue", Type: "boolean", Description: "Only the tasks past their due date and not in a terminal status"},
+		{Name: "due_before", Type: "string", Description: "Only the tasks due before this date, in RFC 3339 or YYYY-MM-DD"},
+		{Name: "due_after", Type: "string", Description: "Only the tasks due at or after this date, in RFC 3339 or YYYY-MM-DD"},
+		{Name: "created_before", Type: "string", Description: "Only the tasks created before this date, in RFC 3339 or YYYY-MM-DD"},
+		{Name: "created_after", Type: "string", Description: "Only the tasks created at or after this date, in RFC 3339 or YYYY-MM-DD"},
+		{
+			Name: "q", Type: "string",
+			Description: `Full-text search in the title and description: words, "quoted phrases", OR and -word. Matches are ranked by relevance unless sort or sort_by is set.`,
+		},
+	}
+	listQueryParams = []QueryParam{
+		{
+			Name: "sort", Type: "string",
+			Description: "Fields to sort by in turn, like status:active_first,due_date:asc, instead of sort_by. " +
+				"The fields are created_at, updated_at, due_date, priority and relevance, with q, followed by :asc or :desc, defaults to desc, " +
+				"and status, followed by :active_first, the default, or :done_first. The tasks without due date come last.",
+		},
+		{Name: "sort_by", Type: "string", Description: "Field to sort by, priority sorts by urgency and status by kind, desc being active first", Enum: []string{"created_at", "updated_at", "due_date", "status", "priority"}},
+		{Name: "sort_order", Type: "string", Description: "Sort order, defaults to desc", Enum: []string{"asc", "desc"}},
+		{Name: "limit", Type: "integer", Description: "Maximum number of tasks to return"},
+		{Name: "offset", Type: "integer", Description: "Number of tasks to skip"},
+		{Name: "cursor", Type: "string", Description: "The next_cursor of the previous page, instead of offset, with the same sort"},
+		{Name: "skip_count", Type: "boolean", Description: "Do not count the tasks, total_count is then omitted"},
+	}
+)
+
 // Server represents the HTTP server
 type Server struct {
 	router      *mux.Router
@@ -17,20 +62,38 @@ type Server struct {
 	userService service.UserService
 	taskService service.TaskService
 	userRepo    repo.UserRepo
+	tokenRepo   repo.TokenRepo
 	gormDB      *gorm.DB
-	jwtSecret   string
+	keys        *jwtkeys.KeySet
+	events      *stream.Hub
+	handlerOpts HandlerOptions
+}
+
+// ServerOption configures optional behaviour of the Server
+type ServerOption func(*Server)
+
+// WithHideInternalErrors hides the underlying error text of 5xx responses from clients
+func WithHideInternalErrors(hide bool) ServerOption {
+	return func(s *Server) {
+		s.handlerOpts.HideInternalErrors = hide
+	}
 }
 
 // NewServer creates a new HTTP server
-func NewServer(userService service.UserService, taskService service.TaskService, userRepo repo.UserRepo, log *logger.Logger, gormDB *gorm.DB, jwtSecret string) *Server {
+func NewServer(userService service.UserService, taskService service.TaskService, userRepo repo.UserRepo, tokenRepo repo.TokenRepo, log *logger.Logger, gormDB *gorm.DB, keys *jwtkeys.KeySet, events *stream.Hub, options ...ServerOption) *Server {
 	server := &Server{
 		router:      mux.NewRouter(),
 		logger:      log,
 		userService: userService,
 		taskService: taskService,
 		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
 		gormDB:      gormDB,
-		jwtSecret:   jwtSecret,
+		keys:        keys,
+		events:      events,
+	}
+	for _, option := range options {
+		option(server)
 	}
 
 	// Set up routes
@@ -54,85 +117,516 @@ func (s *Server) setupRoutes() {
 	// Non-API endpoints (health check)
 	healthEndpoint := []Endpoint{
 		{
-			Method:  http.MethodGet,
-			Path:    "/health",
-			Handler: s.HealthCheckHandler,
+			Method:   http.MethodGet,
+			Path:     "/health",
+			Handler:  s.HealthCheckHandler,
+			Public:   true,
+			Summary:  "Health check",
+			Tags:     []string{"health"},
+			Response: map[string]string{},
 		},
 	}
 
 	// Register health endpoint directly on main router
-	RegisterEndpoints(s.router, healthEndpoint, s.logger)
+	RegisterEndpoints(s.router, healthEndpoint, s.logger, s.handlerOpts)
+
+	apiOpts := s.handlerOpts
+	apiOpts.Authenticate = AuthMiddleware(s.logger, s.userRepo, s.tokenRepo, s.keys)
+
+	// Streaming endpoints stay open, so they run without a transaction. They are registered first,
+	// so they are matched before the API router.
+	streamRouter := s.router.PathPrefix(apiPrefix).Subrouter()
+	streamRouter.Use(DBMiddleware(s.gormDB))
+	streamEndpoints := []Endpoint{
+		{
+			Method:   http.MethodGet,
+			Path:     "/events/stream",
+			Stream:   s.EventStreamHandler,
+			Summary:  "Stream the task events visible to the user as server-sent events, resuming after the Last-Event-ID header",
+			Tags:     []string{"events"},
+			Response: stream.EventMessage{},
+			Query: []QueryParam{
+				{Name: "last_event_id", Type: "integer", Description: "Resume after this event, for clients that cannot send the Last-Event-ID header"},
+			},
+		},
+	}
+	RegisterEndpoints(streamRouter, streamEndpoints, s.logger, apiOpts)
 
 	// Create API router with middlewares
-	apiRouter := s.router.PathPrefix("/api/v1").Subrouter()
+	apiRouter := s.router.PathPrefix(apiPrefix).Subrouter()
 	apiRouter.Use(DBTransactionMiddleware(s.logger, s.gormDB))
-	apiRouter.Use(AuthMiddleware(s.logger, s.userRepo, s.jwtSecret))
 
 	// All API endpoints
 	apiEndpoints := []Endpoint{
-		// Public endpoints (authentication is handled by the middleware)
+		// Public endpoints, every other endpoint requires authentication
+		{
+			Method:   http.MethodPost,
+			Path:     "/login",
+			Handler:  s.LoginHandler,
+			Public:   true,
+			Summary:  "Authenticate with email and password and get a JWT token",
+			Tags:     []string{"auth"},
+			Request:  service.GetJWTRequest{},
+			Response: service.GetJWTResponse{},
+		},
 		{
-			Method:  http.MethodPost,
-			Path:    "/login",
-			Handler: s.LoginHandler,
+			Method:   http.MethodPost,
+			Path:     "/token/refresh",
+			Handler:  s.RefreshTokenHandler,
+			Public:   true,
+			Summary:  "Exchange a refresh token for a new access token and refresh token",
+			Tags:     []string{"auth"},
+			Request:  service.RefreshTokenRequest{},
+			Response: service.GetJWTResponse{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/logout",
+			Handler:  s.LogoutHandler,
+			Summary:  "Revoke the access token of the request and the given refresh token, or all the user's sessions",
+			Tags:     []string{"auth"},
+			Request:  service.LogoutRequest{},
+			Response: service.LogoutResponse{},
 		},
 		// User endpoints
 		{
-			Method:  http.MethodGet,
-			Path:    "/users/me",
-			Handler: s.GetMeHandler,
+			Method:   http.MethodGet,
+			Path:     "/users/me",
+			Handler:  s.GetMeHandler,
+			Summary:  "Get the authenticated user",
+			Tags:     []string{"users"},
+			Response: service.GetMeResponse{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/users/all",
+			Handler:  s.GetUsersHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "List all users",
+			Tags:     []string{"users"},
+			Response: service.GetUsersResponse{},
 		},
 		{
-			Method:  http.MethodGet,
-			Path:    "/users/all",
-			Handler: s.GetUsersHandler,
+			Method:   http.MethodGet,
+			Path:     "/users/{id}",
+			Handler:  s.GetUserByIDHandler,
+			Summary:  "Get a user by ID",
+			Tags:     []string{"users"},
+			Response: service.GetUserByIDResponse{},
 		},
 		{
-			Method:  http.MethodGet,
-			Path:    "/users/{id}",
-			Handler: s.GetUserByIDHandler,
+			Method:   http.MethodPost,
+			Path:     "/users/{id}/revoke-sessions",
+			Handler:  s.RevokeUserSessionsHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Revoke all the access and refresh tokens of a user",
+			Tags:     []string{"users"},
+			Response: service.RevokeUserSessionsResponse{},
 		},
 		{
-			Method:  http.MethodPost,
-			Path:    "/users",
-			Handler: s.CreateUserHandler,
+			Method:   http.MethodPost,
+			Path:     "/users",
+			Handler:  s.CreateUserHandler,
+			Public:   true,
+			Summary:  "Register a new user",
+			Tags:     []string{"users"},
+			Request:  service.CreateUserRequest{},
+			Response: service.CreateUserResponse{},
 		},
 		// Task endpoints
 		{
-			Method:  http.MethodPost,
-			Path:    "/tasks",
-			Handler: s.CreateTaskHandler,
+			Method:   http.MethodPost,
+			Path:     "/tasks",
+			Handler:  s.CreateTaskHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Create a task",
+			Tags:     []string{"tasks"},
+			Request:  service.CreateTaskRequest{},
+			Response: service.CreateTaskResponse{},
 		},
 		{
-			Method:  http.MethodPatch,
-			Path:    "/tasks/{id}/status",
-			Handler: s.UpdateTaskStatusHandler,
+			Method:   http.MethodPatch,
+			Path:     "/tasks/{id}/status",
+			Handler:  s.UpdateTaskStatusHandler,
+			Summary:  "Update the status of a task",
+			Tags:     []string{"tasks"},
+			Request:  UpdateTaskStatusBody{},
+			Response: service.UpdateTaskStatusResponse{},
 		},
 		{
-			Method:  http.MethodPatch,
-			Path:    "/tasks/{id}/assign",
-			Handler: s.AssignTaskHandler,
+			Method:   http.MethodPatch,
+			Path:     "/tasks/{id}/assign",
+			Handler:  s.AssignTaskHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Assign a task to an employee",
+			Tags:     []string{"tasks"},
+			Request:  AssignTaskBody{},
+			Response: service.AssignTaskResponse{},
 		},
 		{
-			Method:  http.MethodGet,
-			Path:    "/tasks/assigned",
-			Handler: s.GetAssignedTasksHandler,
+			Method:   http.MethodGet,
+			Path:     "/tasks/assigned",
+			Handler:  s.GetAssignedTasksHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployee},
+			Summary:  "List the tasks assigned to the authenticated employee",
+			Tags:     []string{"tasks"},
+			Response: service.GetAssignedTasksResponse{},
+			Query:    append(append([]QueryParam{}, taskFilterQueryParams...), listQueryParams...),
 		},
 		{
-			Method:  http.MethodGet,
-			Path:    "/tasks",
-			Handler: s.GetTasksHandler,
+			Method:   http.MethodGet,
+			Path:     "/tasks",
+			Handler:  s.GetTasksHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "List tasks with filtering, sorting and pagination",
+			Tags:     []string{"tasks"},
+			Response: service.GetTasksResponse{},
+			Query:    append(append([]QueryParam{}, taskFilterQueryParams...), listQueryParams...),
 		},
 		// Employee summary endpoint
 		{
-			Method:  http.MethodGet,
-			Path:    "/employee-summary",
-			Handler: s.GetEmployeeTaskSummaryHandler,
+			Method:   http.MethodGet,
+			Path:     "/employee-summary",
+			Handler:  s.GetEmployeeTaskSummaryHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Get task statistics for every employee",
+			Tags:     []string{"tasks"},
+			Response: service.GetEmployeeTaskSummaryResponse{},
+			Query: []QueryParam{
+				{Name: "created_after", Type: "string", Description: "Only count the tasks created at or after this date, in RFC 3339 or YYYY-MM-DD"},
+				{Name: "created_before", Type: "string", Description: "Only count the tasks created before this date, in RFC 3339 or YYYY-MM-DD"},
+				{Name: "own_tasks", Type: "boolean", Description: "Only count the tasks created by the employer"},
+				{
+					Name: "sort_by", Type: "string", Description: "Name or statistic to sort the employees by, defaults to name",
+					Enum: service.EmployeeSummarySortFields,
+				},
+				{Name: "sort_order", Type: "string", Description: "Sort order, defaults to asc by name and desc by statistic", Enum: []string{"asc", "desc"}},
+				{Name: "limit", Type: "integer", Description: "Maximum number of employees to return, all by default"},
+				{Name: "offset", Type: "integer", Description: "Number of employees to skip"},
+			},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/analytics/flow",
+			Handler:  s.GetFlowAnalyticsHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Get the throughput, lead time and cycle time of the tasks, team-wide and by employee",
+			Tags:     []string{"tasks"},
+			Response: service.GetFlowAnalyticsResponse{},
+			Query: []QueryParam{
+				{Name: "interval", Type: "string", Description: "Length of the periods, defaults to week", Enum: []string{"week", "month"}},
+				{Name: "completed_after", Type: "string", Description: "Only the tasks done at or after this date, in RFC 3339 or YYYY-MM-DD, defaults to the start of the 12th period before completed_before"},
+				{Name: "completed_before", Type: "string", Description: "Only the tasks done before this date, in RFC 3339 or YYYY-MM-DD, defaults to now"},
+			},
+		},
+		// Subtasks and checklists, only the task's employer and assignee can access them
+		{
+			Method:   http.MethodGet,
+			Path:     "/tasks/{id}/subtasks",
+			Handler:  s.GetSubtasksHandler,
+			Summary:  "List the subtasks of a task, oldest first",
+			Tags:     []string{"tasks"},
+			Response: service.GetSubtasksResponse{},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/tasks/{id}/checklist",
+			Handler:  s.CreateChecklistItemHandler,
+			Summary:  "Add an item to the checklist of a task",
+			Tags:     []string{"checklists"},
+			Request:  CreateChecklistItemBody{},
+			Response: service.CreateChecklistItemResponse{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/tasks/{id}/checklist",
+			Handler:  s.GetChecklistHandler,
+			Summary:  "Get the checklist of a task",
+			Tags:     []string{"checklists"},
+			Response: service.GetChecklistResponse{},
+		},
+		{
+			Method:   http.MethodPatch,
+			Path:     "/tasks/{id}/checklist/{itemID}",
+			Handler:  s.UpdateChecklistItemHandler,
+			Summary:  "Rename, check or uncheck a checklist item",
+			Tags:     []string{"checklists"},
+			Request:  UpdateChecklistItemBody{},
+			Response: service.UpdateChecklistItemResponse{},
+		},
+		{
+			Method:   http.MethodDelete,
+			Path:     "/tasks/{id}/checklist/{itemID}",
+			Handler:  s.DeleteChecklistItemHandler,
+			Summary:  "Remove an item from the checklist of a task",
+			Tags:     []string{"checklists"},
+			Response: service.DeleteChecklistItemResponse{},
+		},
+		// Dependencies, only the task's employer can change them
+		{
+			Method:   http.MethodPost,
+			Path:     "/tasks/{id}/dependencies",
+			Handler:  s.CreateTaskDependencyHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Make a task wait for another task to be completed",
+			Tags:     []string{"dependencies"},
+			Request:  CreateTaskDependencyBody{},
+			Response: service.CreateTaskDependencyResponse{},
+		},
+		{
+			Method:   http.MethodDelete,
+			Path:     "/tasks/{id}/dependencies/{blockerID}",
+			Handler:  s.DeleteTaskDependencyHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Remove a blocker of a task",
+			Tags:     []string{"dependencies"},
+			Response: service.DeleteTaskDependencyResponse{},
+		},
+		// Activity history, only the task's employer and assignee can read it
+		{
+			Method:   http.MethodGet,
+			Path:     "/tasks/{id}/history",
+			Handler:  s.GetTaskHistoryHandler,
+			Summary:  "List the changes of a task, oldest first",
+			Tags:     []string{"tasks"},
+			Response: service.GetTaskHistoryResponse{},
+			Query: []QueryParam{
+				{Name: "limit", Type: "integer", Description: "Maximum number of events to return, defaults to 50, at most 100"},
+				{Name: "offset", Type: "integer", Description: "Number of events to skip"},
+			},
+		},
+		// Label endpoints, each employer manages their own labels
+		{
+			Method:   http.MethodPost,
+			Path:     "/labels",
+			Handler:  s.CreateLabelHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Create a label",
+			Tags:     []string{"labels"},
+			Request:  service.CreateLabelRequest{},
+			Response: service.CreateLabelResponse{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/labels",
+			Handler:  s.GetLabelsHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "List the labels of the authenticated employer",
+			Tags:     []string{"labels"},
+			Response: service.GetLabelsResponse{},
+		},
+		{
+			Method:   http.MethodPatch,
+			Path:     "/labels/{id}",
+			Handler:  s.UpdateLabelHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Rename a label",
+			Tags:     []string{"labels"},
+			Request:  UpdateLabelBody{},
+			Response: service.UpdateLabelResponse{},
+		},
+		{
+			Method:   http.MethodDelete,
+			Path:     "/labels/{id}",
+			Handler:  s.DeleteLabelHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Delete a label, and remove it from its tasks",
+			Tags:     []string{"labels"},
+			Response: service.DeleteLabelResponse{},
+		},
+		// Workflow endpoints, each employer configures their own workflow
+		{
+			Method:   http.MethodGet,
+			Path:     "/workflow",
+			Handler:  s.GetWorkflowHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Get the workflow of the authenticated employer",
+			Tags:     []string{"workflow"},
+			Response: service.GetWorkflowResponse{},
+		},
+		{
+			Method:   http.MethodPut,
+			Path:     "/workflow",
+			Handler:  s.UpdateWorkflowHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Replace the workflow of the authenticated employer",
+			Tags:     []string{"workflow"},
+			Request:  service.UpdateWorkflowRequest{},
+			Response: service.UpdateWorkflowResponse{},
+		},
+		{
+			Method:   http.MethodDelete,
+			Path:     "/workflow",
+			Handler:  s.ResetWorkflowHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Go back to the default workflow",
+			Tags:     []string{"workflow"},
+			Response: service.ResetWorkflowResponse{},
+		},
+		// Recurring task endpoints, each employer manages their own series
+		{
+			Method:   http.MethodPost,
+			Path:     "/task-series",
+			Handler:  s.CreateTaskSeriesHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Create a recurring task, from a recurrence rule",
+			Tags:     []string{"recurring tasks"},
+			Request:  service.CreateTaskSeriesRequest{},
+			Response: service.CreateTaskSeriesResponse{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/task-series",
+			Handler:  s.GetTaskSeriesListHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "List the recurring tasks of the authenticated employer",
+			Tags:     []string{"recurring tasks"},
+			Response: service.GetTaskSeriesListResponse{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/task-series/{id}",
+			Handler:  s.GetTaskSeriesHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Get a recurring task",
+			Tags:     []string{"recurring tasks"},
+			Response: service.GetTaskSeriesResponse{},
+		},
+		{
+			Method:   http.MethodPatch,
+			Path:     "/task-series/{id}",
+			Handler:  s.UpdateTaskSeriesHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Change the template or the rule of a recurring task, its upcoming tasks are created again",
+			Tags:     []string{"recurring tasks"},
+			Request:  UpdateTaskSeriesBody{},
+			Response: service.UpdateTaskSeriesResponse{},
+		},
+		{
+			Method:   http.MethodPatch,
+			Path:     "/task-series/{id}/state",
+			Handler:  s.UpdateTaskSeriesStateHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Pause, resume or end a recurring task",
+			Tags:     []string{"recurring tasks"},
+			Request:  UpdateTaskSeriesStateBody{},
+			Response: service.UpdateTaskSeriesStateResponse{},
+		},
+		// Webhook endpoints, each employer manages their own webhooks
+		{
+			Method:   http.MethodPost,
+			Path:     "/webhooks",
+			Handler:  s.CreateWebhookHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Register a webhook, the response has the secret that signs its deliveries",
+			Tags:     []string{"webhooks"},
+			Request:  service.CreateWebhookRequest{},
+			Response: service.CreateWebhookResponse{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/webhooks",
+			Handler:  s.GetWebhooksHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "List the webhooks of the authenticated employer",
+			Tags:     []string{"webhooks"},
+			Response: service.GetWebhooksResponse{},
+		},
+		{
+			Method:   http.MethodPatch,
+			Path:     "/webhooks/{id}",
+			Handler:  s.UpdateWebhookHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Change the URL or the events of a webhook, or enable or disable it",
+			Tags:     []string{"webhooks"},
+			Request:  UpdateWebhookBody{},
+			Response: service.UpdateWebhookResponse{},
+		},
+		{
+			Method:   http.MethodDelete,
+			Path:     "/webhooks/{id}",
+			Handler:  s.DeleteWebhookHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Delete a webhook and its delivery log",
+			Tags:     []string{"webhooks"},
+			Response: service.DeleteWebhookResponse{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/webhooks/{id}/deliveries",
+			Handler:  s.GetWebhookDeliveriesHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "List the deliveries of a webhook, newest first",
+			Tags:     []string{"webhooks"},
+			Response: service.GetWebhookDeliveriesResponse{},
+			Query: []QueryParam{
+				{Name: "limit", Type: "integer", Description: "Maximum number of deliveries to return, defaults to 50, at most 100"},
+				{Name: "offset", Type: "integer", Description: "Number of deliveries to skip"},
+			},
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/webhooks/{id}/deliveries/{deliveryID}/redeliver",
+			Handler:  s.RedeliverWebhookDeliveryHandler,
+			Roles:    []models.UserRole{models.UserRoleEmployer},
+			Summary:  "Send the event of a delivery again, as a new delivery with the same event ID",
+			Tags:     []string{"webhooks"},
+			Response: service.RedeliverWebhookDeliveryResponse{},
+		},
+		// Comment endpoints, only the task's employer and assignee can access them
+		{
+			Method:   http.MethodPost,
+			Path:     "/tasks/{id}/comments",
+			Handler:  s.CreateCommentHandler,
+			Summary:  "Comment on a task, or reply to a comment",
+			Tags:     []string{"comments"},
+			Request:  CreateCommentBody{},
+			Response: service.CreateCommentResponse{},
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/tasks/{id}/comments",
+			Handler:  s.GetCommentsHandler,
+			Summary:  "List the comment threads of a task, oldest first",
+			Tags:     []string{"comments"},
+			Response: service.GetCommentsResponse{},
+			Query: []QueryParam{
+				{Name: "limit", Type: "integer", Description: "Maximum number of threads to return, defaults to 50, at most 100"},
+				{Name: "offset", Type: "integer", Description: "Number of threads to skip"},
+			},
+		},
+		{
+			Method:   http.MethodPatch,
+			Path:     "/tasks/{id}/comments/{commentID}",
+			Handler:  s.UpdateCommentHandler,
+			Summary:  "Edit a comment, only its author can edit it",
+			Tags:     []string{"comments"},
+			Request:  UpdateCommentBody{},
+			Response: service.UpdateCommentResponse{},
+		},
+		{
+			Method:   http.MethodDelete,
+			Path:     "/tasks/{id}/comments/{commentID}",
+			Handler:  s.DeleteCommentHandler,
+			Summary:  "Delete a comment, its author and the task's employer can delete it",
+			Tags:     []string{"comments"},
+			Response: service.DeleteCommentResponse{},
 		},
 	}
 
 	// Register all API endpoints
-	RegisterEndpoints(apiRouter, apiEndpoints, s.logger)
+	RegisterEndpoints(apiRouter, apiEndpoints, s.logger, apiOpts)
+
+	// Serve the OpenAPI spec generated from the endpoints above, and a Swagger UI page to browse it
+	spec := NewOpenAPI("cisab API", "1.0.0")
+	spec.AddEndpoints("", healthEndpoint)
+	spec.AddEndpoints(apiPrefix, streamEndpoints)
+	spec.AddEndpoints(apiPrefix, apiEndpoints)
+	s.router.HandleFunc("/openapi.json", OpenAPIHandler(spec)).Methods(http.MethodGet)
+	s.router.HandleFunc("/docs", SwaggerUIHandler("/openapi.json")).Methods(http.MethodGet)
+
+	// Publish the public keys that sign the access tokens, for other services to verify them
+	s.router.HandleFunc("/.well-known/jwks.json", jwtkeys.Handler(s.keys)).Methods(http.MethodGet)
 }
 
 // Router returns the server's router
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/pkg/errors"
+)
+
+// Validate checks v (a struct or a pointer to a struct) against the `binding` tags of its fields
+// and returns a service validation error listing every invalid field, or nil if v is valid.
+//
+// Supported rules, separated by commas:
+//   - required: the field must not be empty (zero value, nil pointer, or blank string)
+//   - email: the string must be a plain email address
+//   - url: the string must be an absolute http or https URL
+//   - min=N / max=N: the length of a string (in characters) or a slice, or the value of a number
+//   - maxbytes=N: the length of a string in bytes, for the limits of the encoded value, like bcrypt's
+//   - oneof=a b c: the value must be one of the space separated options
+//
+// Rules other than required are skipped for empty fields, so optional fields stay optional.
+func Validate(v interface{}) error {
+	var fieldErrors []service.FieldError
+	validateStruct(reflect.ValueOf(v), "", &fieldErrors)
+	if len(fieldErrors) > 0 {
+		return errors.WithStack(service.NewValidationError(fieldErrors))
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, fieldErrors *[]service.FieldError) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		value := v.Field(i)
+
+		if tag := field.Tag.Get("binding"); tag != "" {
+			if fieldErr := validateField(value, name, tag); fieldErr != nil {
+				*fieldErrors = append(*fieldErrors, *fieldErr)
+				continue
+			}
+		}
+
+		// Recurse into nested structs, but not into types like time.Time
+		if isNestedStruct(value) {
+			validateStruct(value, name, fieldErrors)
+		}
+	}
+}
+
+// validateField applies the rules of a binding tag and returns the first failing rule, if any
+func validateField(value reflect.Value, name, tag string) *service.FieldError {
+	empty := isEmpty(value)
+	for _, rule := range strings.Split(tag, ",") {
+		ruleName, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if ruleName == "required" {
+			if empty {
+				return &service.FieldError{Field: name, Rule: ruleName, Message: name + " is required"}
+			}
+			continue
+		}
+		if empty {
+			continue
+		}
+
+		value := reflect.Indirect(value)
+		var message string
+		switch ruleName {
+		case "email":
+			if !isEmail(value.String()) {
+				message = name + " must be a valid email address"
+			}
+		case "url":
+			if !isURL(value.String()) {
+				message = name + " must be an http or https URL"
+			}
+		case "min":
+			if size, limit, ok := measure(value, param); ok && size < limit {
+				message = fmt.Sprintf("%s must be at least %s", name, describeLimit(value, param))
+			}
+		case "max":
+			if size, limit, ok := measure(value, param); ok && size > limit {
+				message = fmt.Sprintf("%s must be at most %s", name, describeLimit(value, param))
+			}
+		case "maxbytes":
+			if limit, err := strconv.Atoi(param); err == nil && value.Kind() == reflect.String && len(value.String()) > limit {
+				message = fmt.Sprintf("%s must be at most %s bytes", name, param)
+			}
+		case "oneof":
+			options := strings.Fields(param)
+			if !containsString(options, fmt.Sprint(value.Interface())) {
+				message = fmt.Sprintf("%s must be one of: %s", name, strings.Join(options, ", "))
+			}
+		}
+		if message != "" {
+			return &service.FieldError{Field: name, Rule: ruleName, Message: message}
+		}
+	}
+	return nil
+}
+
+// measure returns the size of value used by the min and max rules, and the limit from the rule parameter
+func measure(value reflect.Value, param string) (size float64, limit float64, ok bool) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), limit, true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), limit, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), limit, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), limit, true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), limit, true
+	default:
+		return 0, 0, false
+	}
+}
+
+func describeLimit(value reflect.Value, param string) string {
+	switch value.Kind() {
+	case reflect.String:
+		return param + " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return param + " items"
+	default:
+		return param
+	}
+}
+
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isNestedStruct(value reflect.Value) bool {
+	t := value.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{})
+}
+
+// jsonFieldName returns the name of the field in JSON payloads
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func containsString(options []string, s string) bool {
+	for _, option := range options {
+		if option == s {
+			return true
+		}
+	}
+	return false
+}
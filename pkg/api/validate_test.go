@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldRules(t *testing.T, err error) map[string]string {
+	var svcErr service.Error
+	require.True(t, errors.As(err, &svcErr), "expected a service error, got %v", err)
+	require.Equal(t, service.ErrorCodeValidationFailed, svcErr.ErrorCode)
+	require.Equal(t, http.StatusBadRequest, svcErr.Code)
+
+	rules := make(map[string]string)
+	for _, f := range svcErr.Fields {
+		rules[f.Field] = f.Rule
+	}
+	return rules
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("valid create user request", func(t *testing.T) {
+		require.NoError(t, Validate(service.CreateUserRequest{
+			Name:     "John Doe",
+			Email:    "john.doe@example.com",
+			Password: "securepassword",
+			Role:     "employee",
+		}))
+	})
+
+	t.Run("every invalid field is listed", func(t *testing.T) {
+		err := Validate(&service.CreateUserRequest{
+			Name:     strings.Repeat("a", 201),
+			Email:    "John <john.doe@example.com>",
+			Password: "short",
+			Role:     "admin",
+		})
+		require.Equal(t, map[string]string{
+			"name":     "max",
+			"email":    "email",
+			"password": "min",
+			"role":     "oneof",
+		}, fieldRules(t, err))
+	})
+
+	t.Run("passwords fit bcrypt's 72 bytes", func(t *testing.T) {
+		request := service.CreateUserRequest{Name: "John Doe", Email: "john.doe@example.com", Role: "employee"}
+		request.Password = strings.Repeat("é", 36) // 72 bytes
+		require.NoError(t, Validate(request))
+
+		request.Password = strings.Repeat("é", 72) // 72 characters, 144 bytes
+		err := Validate(request)
+		require.Equal(t, map[string]string{"password": "maxbytes"}, fieldRules(t, err))
+	})
+
+	t.Run("required fields", func(t *testing.T) {
+		err := Validate(service.GetJWTRequest{Email: "  "})
+		require.Equal(t, map[string]string{
+			"email":    "required",
+			"password": "required",
+		}, fieldRules(t, err))
+	})
+
+	t.Run("optional fields are only checked when set", func(t *testing.T) {
+		require.NoError(t, Validate(service.CreateTaskRequest{Title: "Task"}))
+
+		zero := 0
+		err := Validate(service.CreateTaskRequest{
+			Title:      strings.Repeat("é", 256),
+			Status:     strings.Repeat("s", 21),
+			AssigneeID: &zero,
+		})
+		require.Equal(t, map[string]string{
+			"title":       "max",
+			"status":      "max",
+			"assignee_id": "min",
+		}, fieldRules(t, err))
+	})
+
+	t.Run("urls", func(t *testing.T) {
+		require.NoError(t, Validate(service.CreateWebhookRequest{
+			URL:    "https://example.com/hooks?source=tasks",
+			Events: []models.WebhookEventType{models.WebhookEventTaskCreated},
+		}))
+		for _, invalid := range []string{"example.com/hooks", "ftp://example.com", "https://", "http//example.com"} {
+			err := Validate(service.CreateWebhookRequest{URL: invalid, Events: []models.WebhookEventType{models.WebhookEventTaskCreated}})
+			require.Equal(t, map[string]string{"url": "url"}, fieldRules(t, err), invalid)
+		}
+	})
+}
+
+func TestReadJSON_validates(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(`{"description":"no title"}`))
+	var request service.CreateTaskRequest
+	err := ReadJSON(r, &request)
+	require.Equal(t, map[string]string{"title": "required"}, fieldRules(t, err))
+}
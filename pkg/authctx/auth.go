@@ -2,6 +2,7 @@ package authctx
 
 import (
 	"context"
+	"time"
 
 	"github.com/llkhacquan/cisab/pkg/models"
 )
@@ -22,4 +23,9 @@ func Set(ctx context.Context, value AuthMD) context.Context {
 
 type AuthMD struct {
 	User models.User
+
+	// TokenID and TokenExpiry are the jti and exp claims of the access token of the request,
+	// used to revoke it on logout
+	TokenID     string
+	TokenExpiry time.Time
 }
@@ -0,0 +1,219 @@
+// Package client is a typed Go client for the cisab REST API.
+//
+// It reuses the request and response types of pkg/service, so callers never
+// have to copy the API's payloads or its response envelope.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/pkg/errors"
+)
+
+const (
+	apiPrefix = "/api/v1"
+
+	// tokenRefreshLeeway is how long before its expiry a token is renewed
+	tokenRefreshLeeway = 30 * time.Second
+)
+
+// Client calls the cisab REST API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu           sync.Mutex
+	token        string
+	tokenExpiry  time.Time
+	refreshToken string // set by Login and Refresh, used to renew the token when it expires
+
+	renewMu sync.Mutex // serializes renewals, a refresh token can only be used once
+}
+
+// Option configures optional behaviour of the Client
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used to send requests, http.DefaultClient is used otherwise
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken sets the JWT token sent with every request, for callers that obtained a token elsewhere.
+// Such a token cannot be renewed by the client.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New creates a new Client for the API served at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// envelope mirrors api.Response, with the data left raw so it can be decoded into the expected type
+type envelope struct {
+	Status    string               `json:"status"`
+	Message   string               `json:"message"`
+	Data      json.RawMessage      `json:"data"`
+	Error     string               `json:"error"`
+	ErrorCode string               `json:"error_code"`
+	Fields    []service.FieldError `json:"fields"`
+}
+
+// do sends a request and decodes the data of the response into out (if not nil).
+// Authenticated requests get a valid token first, and are retried once with a new token if it was rejected.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}, authenticated bool) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return errors.Wrap(err, "failed to encode request body")
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		var token string
+		if authenticated {
+			var err error
+			if token, err = c.validToken(ctx, attempt > 0); err != nil {
+				return err
+			}
+		}
+
+		err := c.send(ctx, method, path, query, payload, token, out)
+		var apiErr *APIError
+		if authenticated && attempt == 0 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized && c.canRenew() {
+			continue
+		}
+		return err
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, payload []byte, token string, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send request %s %s", method, path)
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+		}
+		return errors.Wrap(err, "failed to decode response")
+	}
+	if resp.StatusCode != http.StatusOK || env.Status == "error" {
+		return newAPIError(resp.StatusCode, env)
+	}
+	if out != nil && len(env.Data) > 0 {
+		if err := json.Unmarshal(env.Data, out); err != nil {
+			return errors.Wrap(err, "failed to decode response data")
+		}
+	}
+	return nil
+}
+
+// validToken returns the current token, renewing it first with the refresh token if it is about to expire or if forced
+func (c *Client) validToken(ctx context.Context, force bool) (string, error) {
+	c.mu.Lock()
+	token, expiry, refreshToken := c.token, c.tokenExpiry, c.refreshToken
+	c.mu.Unlock()
+
+	if refreshToken == "" || (token != "" && !expiring(expiry) && !force) {
+		return token, nil
+	}
+
+	c.renewMu.Lock()
+	defer c.renewMu.Unlock()
+
+	// Another request may have renewed the token while this one was waiting
+	c.mu.Lock()
+	current, currentExpiry := c.token, c.tokenExpiry
+	c.mu.Unlock()
+	if current != token && !expiring(currentExpiry) {
+		return current, nil
+	}
+
+	resp, err := c.Refresh(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to renew token")
+	}
+	return resp.Token, nil
+}
+
+// expiring reports whether a token with the given expiry must be renewed
+func expiring(expiry time.Time) bool {
+	return !expiry.IsZero() && time.Until(expiry) < tokenRefreshLeeway
+}
+
+func (c *Client) canRenew() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshToken != ""
+}
+
+// setTokens stores the tokens of a login or refresh response
+func (c *Client) setTokens(response *service.GetJWTResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = response.Token
+	c.tokenExpiry = time.Unix(response.TokenExpiry, 0)
+	c.refreshToken = response.RefreshToken
+}
+
+// clearTokens forgets the tokens after a logout
+func (c *Client) clearTokens() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+	c.tokenExpiry = time.Time{}
+	c.refreshToken = ""
+}
+
+// Token returns the JWT token currently used by the client, empty if it is not logged in
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
@@ -0,0 +1,1209 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/api"
+	"github.com/llkhacquan/cisab/pkg/config"
+	"github.com/llkhacquan/cisab/pkg/dbctx"
+	"github.com/llkhacquan/cisab/pkg/jwtkeys"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/notify"
+	"github.com/llkhacquan/cisab/pkg/outbox"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/llkhacquan/cisab/pkg/service"
+	"github.com/llkhacquan/cisab/pkg/stream"
+	"github.com/llkhacquan/cisab/pkg/testutil"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/llkhacquan/cisab/pkg/webhook"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer starts an httptest server backed by the real api.Server and a fresh test database.
+// It returns the outbox relay too, so tests can subscribe to the domain events.
+func newTestServer(t *testing.T) (*httptest.Server, *outbox.Relay) {
+	db := testutil.CreateTestDB(t)
+	jwtConfig := config.JWTConfig{Secret: "test-secret", TTLInSecond: 3600, RefreshTTLInSecond: 86400}
+
+	userRepo := repo.NewUserRepoImpl(dbctx.Get)
+	taskRepo := repo.NewTaskRepoImpl(dbctx.Get)
+	tokenRepo := repo.NewTokenRepoImpl(dbctx.Get)
+	commentRepo := repo.NewCommentRepoImpl(dbctx.Get)
+	taskEventRepo := repo.NewTaskEventRepoImpl(dbctx.Get)
+	labelRepo := repo.NewLabelRepoImpl(dbctx.Get)
+	checklistRepo := repo.NewChecklistRepoImpl(dbctx.Get)
+	taskDependencyRepo := repo.NewTaskDependencyRepoImpl(dbctx.Get)
+	workflowRepo := repo.NewWorkflowRepoImpl(dbctx.Get)
+	taskSeriesRepo := repo.NewTaskSeriesRepoImpl(dbctx.Get)
+	dueEventRepo := repo.NewTaskDueEventRepoImpl(dbctx.Get)
+	webhookRepo := repo.NewWebhookRepoImpl(dbctx.Get)
+	outboxRepo := repo.NewOutboxRepoImpl(dbctx.Get)
+	keys, err := jwtkeys.Load(jwtConfig)
+	require.NoError(t, err)
+	log := logger.New(logger.Config{Output: io.Discard})
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, db, log, webhook.WithPrivateDestinations())
+	go func() { _ = webhookDispatcher.Run(t.Context()) }()
+	relay := outbox.NewRelay(outboxRepo, db, log)
+	relay.Subscribe("webhooks", webhookDispatcher.HandleEvent)
+	go func() { _ = relay.Run(t.Context()) }()
+	eventHub := stream.NewHub(outboxRepo, db, log)
+	relay.Subscribe("event stream", eventHub.HandleEvent)
+	go func() { _ = eventHub.Run(t.Context()) }()
+	userService := service.NewUserService(userRepo, tokenRepo, jwtConfig, keys, relay)
+	taskService := service.NewTaskService(taskRepo, userRepo, commentRepo, taskEventRepo, labelRepo, checklistRepo, taskDependencyRepo,
+		workflowRepo, taskSeriesRepo, dueEventRepo, notify.NewLogNotifier(log), webhookRepo, webhookDispatcher, relay)
+
+	server := httptest.NewServer(api.NewServer(userService, taskService, userRepo, tokenRepo, log, db, keys, eventHub).Router())
+	t.Cleanup(server.Close)
+	// the event streams end before the server closes, it waits for them otherwise
+	t.Cleanup(eventHub.Close)
+	return server, relay
+}
+
+// newLoggedInClient registers a user and returns a client logged in as that user
+func newLoggedInClient(t *testing.T, baseURL, email string, role models.UserRole) (*Client, models.User) {
+	ctx := t.Context()
+	c := New(baseURL)
+	created, err := c.CreateUser(ctx, service.CreateUserRequest{
+		Name:     string(role) + " " + email,
+		Email:    email,
+		Password: "securepassword",
+		Role:     role,
+	})
+	require.NoError(t, err)
+
+	_, err = c.Login(ctx, service.GetJWTRequest{Email: email, Password: "securepassword"})
+	require.NoError(t, err)
+	return c, created.User
+}
+
+func TestClient(t *testing.T) {
+	server, relay := newTestServer(t)
+	ctx := t.Context()
+
+	require.NoError(t, New(server.URL).Health(ctx))
+
+	employer, employerUser := newLoggedInClient(t, server.URL, "employer@example.com", models.UserRoleEmployer)
+	employee, employeeUser := newLoggedInClient(t, server.URL, "employee@example.com", models.UserRoleEmployee)
+
+	var taskID models.TaskID
+	t.Run("task lifecycle", func(t *testing.T) {
+		created, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Write the client"})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskStatusPending, created.Task.Status)
+		taskID = created.Task.ID
+
+		assigned, err := employer.AssignTask(ctx, service.AssignTaskRequest{TaskID: taskID, AssigneeID: employeeUser.ID})
+		require.NoError(t, err)
+		require.Equal(t, employeeUser.ID, *assigned.Task.AssigneeID)
+
+		tasks, err := employee.GetAssignedTasks(ctx, service.GetAssignedTasksRequest{TaskFilter: service.TaskFilter{Statuses: []models.TaskStatus{models.TaskStatusPending}}})
+		require.NoError(t, err)
+		require.Equal(t, 1, *tasks.TotalCount)
+		require.Equal(t, taskID, tasks.Tasks[0].ID)
+
+		updated, err := employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: taskID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskStatusCompleted, updated.Task.Status)
+
+		all, err := employer.GetTasks(ctx, service.GetTasksRequest{
+			TaskFilter: service.TaskFilter{AssigneeIDs: []models.UserID{employeeUser.ID}}, SortBy: "due_date", SortOrder: "asc",
+		})
+		require.NoError(t, err)
+		require.Len(t, all.Tasks, 1)
+
+		summary, err := employer.GetEmployeeTaskSummary(ctx, service.GetEmployeeTaskSummaryRequest{})
+		require.NoError(t, err)
+		require.Len(t, summary.Employees, 1)
+		require.Equal(t, 1, summary.Employees[0].Statistics.Completed)
+	})
+
+	t.Run("comments", func(t *testing.T) {
+		created, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Discuss me"})
+		require.NoError(t, err)
+		taskID := created.Task.ID
+
+		// the task is not assigned yet, so only its employer can comment
+		_, err = employee.CreateComment(ctx, service.CreateCommentRequest{TaskID: taskID, Body: "Not mine"})
+		require.True(t, errors.Is(err, service.NewForbiddenError("")), err)
+
+		_, err = employer.AssignTask(ctx, service.AssignTaskRequest{TaskID: taskID, AssigneeID: employeeUser.ID})
+		require.NoError(t, err)
+
+		thread, err := employer.CreateComment(ctx, service.CreateCommentRequest{TaskID: taskID, Body: "Can you start today?"})
+		require.NoError(t, err)
+		reply, err := employee.CreateComment(ctx, service.CreateCommentRequest{TaskID: taskID, ParentID: &thread.Comment.ID, Body: "Yes"})
+		require.NoError(t, err)
+		// replying to a reply stays in the thread
+		nested, err := employer.CreateComment(ctx, service.CreateCommentRequest{TaskID: taskID, ParentID: &reply.Comment.ID, Body: "Thanks"})
+		require.NoError(t, err)
+		require.Equal(t, thread.Comment.ID, *nested.Comment.ParentID)
+
+		_, err = employee.UpdateComment(ctx, service.UpdateCommentRequest{TaskID: taskID, CommentID: thread.Comment.ID, Body: "Edited"})
+		require.True(t, errors.Is(err, service.NewForbiddenError("")), err)
+		edited, err := employee.UpdateComment(ctx, service.UpdateCommentRequest{TaskID: taskID, CommentID: reply.Comment.ID, Body: "Yes, on it"})
+		require.NoError(t, err)
+		require.Equal(t, "Yes, on it", edited.Comment.Body)
+
+		// the employer moderates the comments of their tasks
+		deleted, err := employer.DeleteComment(ctx, service.DeleteCommentRequest{TaskID: taskID, CommentID: reply.Comment.ID})
+		require.NoError(t, err)
+		require.NotNil(t, deleted.Comment.DeletedAt)
+		_, err = employer.DeleteComment(ctx, service.DeleteCommentRequest{TaskID: taskID, CommentID: reply.Comment.ID})
+		require.True(t, errors.Is(err, service.ErrCommentNotFound), err)
+
+		comments, err := employee.GetComments(ctx, service.GetCommentsRequest{TaskID: taskID})
+		require.NoError(t, err)
+		require.Equal(t, 1, comments.TotalCount)
+		require.Len(t, comments.Comments[0].Replies, 2)
+		require.Empty(t, comments.Comments[0].Replies[0].Body)
+		require.Equal(t, "Thanks", comments.Comments[0].Replies[1].Body)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		created, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Track me"})
+		require.NoError(t, err)
+		taskID := created.Task.ID
+
+		_, err = employer.AssignTask(ctx, service.AssignTaskRequest{TaskID: taskID, AssigneeID: employeeUser.ID})
+		require.NoError(t, err)
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: taskID, Status: models.TaskStatusInProgress})
+		require.NoError(t, err)
+		// setting the same status again changes nothing
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: taskID, Status: models.TaskStatusInProgress})
+		require.NoError(t, err)
+		// a failed change is not recorded, its transaction is rolled back
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: taskID, Status: "done"})
+		require.Error(t, err)
+
+		history, err := employee.GetTaskHistory(ctx, service.GetTaskHistoryRequest{TaskID: taskID})
+		require.NoError(t, err)
+		require.Equal(t, 3, history.TotalCount)
+		require.Equal(t, models.TaskEventCreated, history.Events[0].Type)
+		require.Equal(t, employerUser.ID, history.Events[0].ActorID)
+
+		assigned := history.Events[1]
+		require.Equal(t, models.TaskFieldAssigneeID, assigned.Field)
+		require.Nil(t, assigned.OldValue)
+		require.Equal(t, strconv.Itoa(int(employeeUser.ID)), *assigned.NewValue)
+
+		started := history.Events[2]
+		require.Equal(t, employeeUser.ID, started.ActorID)
+		require.Equal(t, models.TaskFieldStatus, started.Field)
+		require.Equal(t, "pending", *started.OldValue)
+		require.Equal(t, "in_progress", *started.NewValue)
+
+		page, err := employee.GetTaskHistory(ctx, service.GetTaskHistoryRequest{TaskID: taskID, Limit: 1, Offset: 2})
+		require.NoError(t, err)
+		require.Equal(t, []models.TaskEvent{started}, page.Events)
+	})
+
+	t.Run("priority and labels", func(t *testing.T) {
+		backend, err := employer.CreateLabel(ctx, service.CreateLabelRequest{Name: "backend"})
+		require.NoError(t, err)
+		_, err = employer.CreateLabel(ctx, service.CreateLabelRequest{Name: "backend"})
+		require.True(t, errors.Is(err, service.ErrLabelTaken), err)
+		bug, err := employer.CreateLabel(ctx, service.CreateLabelRequest{Name: "bugfix"})
+		require.NoError(t, err)
+		renamed, err := employer.UpdateLabel(ctx, service.UpdateLabelRequest{LabelID: bug.Label.ID, Name: "bug"})
+		require.NoError(t, err)
+		require.Equal(t, "bug", renamed.Label.Name)
+
+		urgent, err := employer.CreateTask(ctx, service.CreateTaskRequest{
+			Title:      "Fix the outage",
+			AssigneeID: (*int)(&employeeUser.ID),
+			Priority:   string(models.TaskPriorityUrgent),
+			LabelIDs:   []models.LabelID{backend.Label.ID, bug.Label.ID},
+		})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskPriorityUrgent, urgent.Task.Priority)
+		require.Len(t, urgent.Task.Labels, 2)
+		low, err := employer.CreateTask(ctx, service.CreateTaskRequest{
+			Title:      "Refactor",
+			AssigneeID: (*int)(&employeeUser.ID),
+			Priority:   string(models.TaskPriorityLow),
+			LabelIDs:   []models.LabelID{backend.Label.ID},
+		})
+		require.NoError(t, err)
+		_, err = employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Unknown label", LabelIDs: []models.LabelID{9999}})
+		require.True(t, errors.Is(err, service.NewCodedError(http.StatusBadRequest, service.ErrorCodeLabelNotFound, "")), err)
+
+		// urgent first, then by decreasing urgency, not alphabetically
+		tasks, err := employee.GetAssignedTasks(ctx, service.GetAssignedTasksRequest{TaskFilter: service.TaskFilter{LabelID: &backend.Label.ID}, SortBy: "priority"})
+		require.NoError(t, err)
+		require.Equal(t, 2, *tasks.TotalCount)
+		require.Equal(t, urgent.Task.ID, tasks.Tasks[0].ID)
+		require.Equal(t, low.Task.ID, tasks.Tasks[1].ID)
+		require.Equal(t, []string{"backend", "bug"}, []string{tasks.Tasks[0].Labels[0].Name, tasks.Tasks[0].Labels[1].Name})
+
+		filtered, err := employer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: service.TaskFilter{Priority: models.TaskPriorityUrgent}})
+		require.NoError(t, err)
+		require.Equal(t, 1, *filtered.TotalCount)
+		require.Equal(t, urgent.Task.ID, filtered.Tasks[0].ID)
+
+		summary, err := employer.GetEmployeeTaskSummary(ctx, service.GetEmployeeTaskSummaryRequest{})
+		require.NoError(t, err)
+		require.Len(t, summary.Employees, 1)
+		require.Equal(t, []repo.LabelCount{
+			{LabelID: backend.Label.ID, Name: "backend", Count: 2},
+			{LabelID: bug.Label.ID, Name: "bug", Count: 1},
+		}, summary.Employees[0].Statistics.Labels)
+
+		// deleting a label removes it from its tasks
+		_, err = employer.DeleteLabel(ctx, bug.Label.ID)
+		require.NoError(t, err)
+		labels, err := employer.GetLabels(ctx)
+		require.NoError(t, err)
+		require.Len(t, labels.Labels, 1)
+		tasks, err = employee.GetAssignedTasks(ctx, service.GetAssignedTasksRequest{TaskFilter: service.TaskFilter{Priority: models.TaskPriorityUrgent}})
+		require.NoError(t, err)
+		require.Len(t, tasks.Tasks[0].Labels, 1)
+	})
+
+	t.Run("subtasks and checklists", func(t *testing.T) {
+		parent, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Release", AssigneeID: (*int)(&employeeUser.ID)})
+		require.NoError(t, err)
+		parentID := int(parent.Task.ID)
+		first, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Build", ParentID: &parentID, InheritAssignee: true})
+		require.NoError(t, err)
+		require.Equal(t, parent.Task.ID, *first.Task.ParentID)
+		require.Equal(t, employeeUser.ID, *first.Task.AssigneeID)
+		second, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Announce", ParentID: &parentID})
+		require.NoError(t, err)
+		require.Nil(t, second.Task.AssigneeID)
+
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: first.Task.ID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+
+		subtasks, err := employee.GetSubtasks(ctx, service.GetSubtasksRequest{TaskID: parent.Task.ID})
+		require.NoError(t, err)
+		require.Len(t, subtasks.Tasks, 2)
+		require.Equal(t, first.Task.ID, subtasks.Tasks[0].ID)
+
+		// the parent cannot be completed while a subtask is open, unless forced
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: parent.Task.ID, Status: models.TaskStatusCompleted})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeOpenSubtasks, apiErr.ErrorCode)
+		completed, err := employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: parent.Task.ID, Status: models.TaskStatusCompleted, Force: true})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskProgress{Subtasks: 2, CompletedSubtasks: 1, Percent: 50}, *completed.Task.Progress)
+
+		item, err := employee.CreateChecklistItem(ctx, service.CreateChecklistItemRequest{TaskID: parent.Task.ID, Title: "Tag the release"})
+		require.NoError(t, err)
+		_, err = employee.CreateChecklistItem(ctx, service.CreateChecklistItemRequest{TaskID: parent.Task.ID, Title: "Update the changelog"})
+		require.NoError(t, err)
+		done := true
+		checked, err := employee.UpdateChecklistItem(ctx, service.UpdateChecklistItemRequest{TaskID: parent.Task.ID, ItemID: item.Item.ID, Done: &done})
+		require.NoError(t, err)
+		require.True(t, checked.Item.Done)
+		require.Equal(t, "Tag the release", checked.Item.Title)
+
+		// items are only found through their task
+		_, err = employee.DeleteChecklistItem(ctx, service.DeleteChecklistItemRequest{TaskID: first.Task.ID, ItemID: item.Item.ID})
+		require.True(t, errors.Is(err, service.ErrChecklistItemNotFound), err)
+
+		checklist, err := employer.GetChecklist(ctx, service.GetChecklistRequest{TaskID: parent.Task.ID})
+		require.NoError(t, err)
+		require.Len(t, checklist.Items, 2)
+		require.Equal(t, 1, checklist.Done)
+	})
+
+	t.Run("dependencies", func(t *testing.T) {
+		design, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Design", AssigneeID: (*int)(&employeeUser.ID)})
+		require.NoError(t, err)
+		build, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Build", AssigneeID: (*int)(&employeeUser.ID)})
+		require.NoError(t, err)
+		ship, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Ship", AssigneeID: (*int)(&employeeUser.ID)})
+		require.NoError(t, err)
+
+		_, err = employer.CreateTaskDependency(ctx, service.CreateTaskDependencyRequest{TaskID: build.Task.ID, BlockerID: design.Task.ID})
+		require.NoError(t, err)
+		dependency, err := employer.CreateTaskDependency(ctx, service.CreateTaskDependencyRequest{TaskID: ship.Task.ID, BlockerID: build.Task.ID})
+		require.NoError(t, err)
+		require.Equal(t, build.Task.ID, dependency.Dependency.BlockerID)
+
+		// ship waits for build, which waits for design
+		_, err = employer.CreateTaskDependency(ctx, service.CreateTaskDependencyRequest{TaskID: design.Task.ID, BlockerID: ship.Task.ID})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeDependencyCycle, apiErr.ErrorCode)
+		_, err = employer.CreateTaskDependency(ctx, service.CreateTaskDependencyRequest{TaskID: design.Task.ID, BlockerID: design.Task.ID})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeDependencyCycle, apiErr.ErrorCode)
+
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: build.Task.ID, Status: models.TaskStatusInProgress})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeTaskBlocked, apiErr.ErrorCode)
+
+		assigned, err := employee.GetAssignedTasks(ctx, service.GetAssignedTasksRequest{})
+		require.NoError(t, err)
+		blocked := map[models.TaskID][]models.TaskID{}
+		for _, task := range assigned.Tasks {
+			blocked[task.ID] = task.BlockedBy
+		}
+		require.Empty(t, blocked[design.Task.ID])
+		require.Equal(t, []models.TaskID{design.Task.ID}, blocked[build.Task.ID])
+		require.Equal(t, []models.TaskID{build.Task.ID}, blocked[ship.Task.ID])
+
+		// completing the blocker unblocks the task
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: design.Task.ID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+		started, err := employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: build.Task.ID, Status: models.TaskStatusInProgress})
+		require.NoError(t, err)
+		require.False(t, started.Task.IsBlocked())
+
+		_, err = employer.DeleteTaskDependency(ctx, service.DeleteTaskDependencyRequest{TaskID: ship.Task.ID, BlockerID: build.Task.ID})
+		require.NoError(t, err)
+		_, err = employer.DeleteTaskDependency(ctx, service.DeleteTaskDependencyRequest{TaskID: ship.Task.ID, BlockerID: build.Task.ID})
+		require.True(t, errors.Is(err, service.ErrDependencyNotFound), err)
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: ship.Task.ID, Status: models.TaskStatusInProgress})
+		require.NoError(t, err)
+	})
+
+	t.Run("workflow", func(t *testing.T) {
+		both := []models.UserRole{models.UserRoleEmployer, models.UserRoleEmployee}
+		statuses := []models.WorkflowStatus{
+			{Name: models.TaskStatusPending},
+			{Name: models.TaskStatusInProgress, Active: true},
+			{Name: "in_review", Active: true},
+			{Name: models.TaskStatusCompleted, Terminal: true},
+		}
+		_, err := employer.UpdateWorkflow(ctx, service.UpdateWorkflowRequest{
+			Statuses: statuses,
+			Transitions: []models.WorkflowTransition{
+				{From: models.TaskStatusPending, To: models.TaskStatusInProgress, Roles: both},
+				{From: models.TaskStatusInProgress, To: "in_review", Roles: both},
+				{From: "in_review", To: models.TaskStatusInProgress, Roles: both},
+				// only the employer approves the review
+				{From: "in_review", To: models.TaskStatusCompleted, Roles: []models.UserRole{models.UserRoleEmployer}},
+			},
+		})
+		require.NoError(t, err)
+		workflow, err := employer.GetWorkflow(ctx)
+		require.NoError(t, err)
+		require.Equal(t, statuses, workflow.Workflow.Statuses)
+
+		created, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Review me", AssigneeID: (*int)(&employeeUser.ID)})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskStatusPending, created.Task.Status)
+		taskID := created.Task.ID
+
+		var apiErr *APIError
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: taskID, Status: models.TaskStatusCompleted})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeTransitionNotAllowed, apiErr.ErrorCode)
+		for _, status := range []models.TaskStatus{models.TaskStatusInProgress, "in_review"} {
+			_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: taskID, Status: status})
+			require.NoError(t, err)
+		}
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: taskID, Status: models.TaskStatusCompleted})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeTransitionNotAllowed, apiErr.ErrorCode)
+		completed, err := employer.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: taskID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskStatusCompleted, completed.Task.Status)
+
+		// the status of a task cannot be removed
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: taskID, Status: "unknown"})
+		require.Error(t, err)
+		_, err = employer.UpdateWorkflow(ctx, service.UpdateWorkflowRequest{Statuses: statuses[:2]})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeStatusInUse, apiErr.ErrorCode)
+		_, err = employer.UpdateWorkflow(ctx, service.UpdateWorkflowRequest{Statuses: append(statuses, statuses[0])})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeInvalidWorkflow, apiErr.ErrorCode)
+
+		reset, err := employer.ResetWorkflow(ctx)
+		require.NoError(t, err)
+		require.Equal(t, models.DefaultWorkflow().Statuses, reset.Workflow.Statuses)
+	})
+
+	t.Run("overdue tasks", func(t *testing.T) {
+		yesterday := time.Now().Add(-24 * time.Hour)
+		created, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Late", DueDate: &yesterday})
+		require.NoError(t, err)
+
+		overdue, err := employer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: service.TaskFilter{Overdue: true}})
+		require.NoError(t, err)
+		require.Equal(t, 1, *overdue.TotalCount)
+		require.Equal(t, created.Task.ID, overdue.Tasks[0].ID)
+
+		// completed tasks are not overdue
+		_, err = employer.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: created.Task.ID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+		overdue, err = employer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: service.TaskFilter{Overdue: true}})
+		require.NoError(t, err)
+		require.Zero(t, *overdue.TotalCount)
+	})
+
+	t.Run("recurring tasks", func(t *testing.T) {
+		var apiErr *APIError
+		_, err := employer.CreateTaskSeries(ctx, service.CreateTaskSeriesRequest{
+			Title: "Inspection", RRule: "FREQ=HOURLY", StartsAt: time.Now(),
+		})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeInvalidRecurrence, apiErr.ErrorCode)
+
+		// every week from the next hour: the next two occurrences are within the horizon
+		startsAt := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+		created, err := employer.CreateTaskSeries(ctx, service.CreateTaskSeriesRequest{
+			Title:      "Inspection",
+			AssigneeID: (*int)(&employeeUser.ID),
+			RRule:      "FREQ=DAILY;INTERVAL=7",
+			StartsAt:   startsAt,
+		})
+		require.NoError(t, err)
+		series := created.Series
+		require.Equal(t, models.TaskSeriesStateActive, series.State)
+
+		seriesTasks := func() []models.Task {
+			tasks, err := employer.GetTasks(ctx, service.GetTasksRequest{SortBy: "due_date", SortOrder: "asc"})
+			require.NoError(t, err)
+			var result []models.Task
+			for _, task := range tasks.Tasks {
+				if task.SeriesID != nil && *task.SeriesID == series.ID {
+					result = append(result, task)
+				}
+			}
+			return result
+		}
+		tasks := seriesTasks()
+		require.Len(t, tasks, 2)
+		require.True(t, startsAt.Equal(*tasks[0].DueDate))
+		require.True(t, startsAt.AddDate(0, 0, 7).Equal(*tasks[1].DueDate))
+		require.Equal(t, employeeUser.ID, *tasks[0].AssigneeID)
+
+		// editing the series creates again the upcoming tasks that have not started
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: tasks[0].ID, Status: models.TaskStatusInProgress})
+		require.NoError(t, err)
+		title := "Weekly inspection"
+		_, err = employer.UpdateTaskSeries(ctx, service.UpdateTaskSeriesRequest{SeriesID: series.ID, Title: &title})
+		require.NoError(t, err)
+		tasks = seriesTasks()
+		require.Len(t, tasks, 2)
+		require.Equal(t, "Inspection", tasks[0].Title)
+		require.Equal(t, title, tasks[1].Title)
+
+		paused, err := employer.UpdateTaskSeriesState(ctx, service.UpdateTaskSeriesStateRequest{SeriesID: series.ID, State: models.TaskSeriesStatePaused})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskSeriesStatePaused, paused.Series.State)
+		require.Len(t, seriesTasks(), 1)
+		_, err = employer.UpdateTaskSeriesState(ctx, service.UpdateTaskSeriesStateRequest{SeriesID: series.ID, State: models.TaskSeriesStateActive})
+		require.NoError(t, err)
+		require.Len(t, seriesTasks(), 2)
+
+		ended, err := employer.UpdateTaskSeriesState(ctx, service.UpdateTaskSeriesStateRequest{SeriesID: series.ID, State: models.TaskSeriesStateEnded})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskSeriesStateEnded, ended.Series.State)
+		require.Len(t, seriesTasks(), 1)
+		_, err = employer.UpdateTaskSeries(ctx, service.UpdateTaskSeriesRequest{SeriesID: series.ID, Title: &title})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeTaskSeriesEnded, apiErr.ErrorCode)
+
+		list, err := employer.GetTaskSeriesList(ctx)
+		require.NoError(t, err)
+		require.Len(t, list.Series, 1)
+		_, err = employee.GetTaskSeries(ctx, series.ID)
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeForbiddenRole, apiErr.ErrorCode)
+	})
+
+	t.Run("users", func(t *testing.T) {
+		me, err := employee.GetMe(ctx)
+		require.NoError(t, err)
+		require.Equal(t, employeeUser.ID, me.User.ID)
+
+		user, err := employer.GetUserByID(ctx, employeeUser.ID)
+		require.NoError(t, err)
+		require.Equal(t, "employee@example.com", user.User.Email)
+
+		users, err := employer.GetUsers(ctx)
+		require.NoError(t, err)
+		require.Len(t, users.Users, 2)
+	})
+
+	t.Run("webhooks", func(t *testing.T) {
+		var apiErr *APIError
+		_, err := employer.CreateWebhook(ctx, service.CreateWebhookRequest{
+			URL: "https://example.com/hooks", Events: []models.WebhookEventType{"task.deleted"},
+		})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeInvalidWebhookEvent, apiErr.ErrorCode)
+
+		// the receiver checks the signature of the deliveries, and fails them while failing is set
+		var secret atomic.Value
+		var failing atomic.Bool
+		received := make(chan webhook.Payload, 10)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil || webhook.Verify(secret.Load().(string), r.Header.Get(webhook.HeaderTimestamp),
+				r.Header.Get(webhook.HeaderSignature), body, time.Minute) != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if failing.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var payload webhook.Payload
+			if err := json.Unmarshal(body, &payload); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			received <- payload
+		}))
+		t.Cleanup(receiver.Close)
+		receive := func() webhook.Payload {
+			select {
+			case payload := <-received:
+				return payload
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "no delivery received")
+				return webhook.Payload{}
+			}
+		}
+
+		created, err := employer.CreateWebhook(ctx, service.CreateWebhookRequest{
+			URL:    receiver.URL,
+			Events: []models.WebhookEventType{models.WebhookEventTaskCreated, models.WebhookEventTaskCompleted},
+		})
+		require.NoError(t, err)
+		hook := created.Webhook
+		require.NotEmpty(t, hook.Secret)
+		secret.Store(hook.Secret)
+
+		task, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Hooked"})
+		require.NoError(t, err)
+		payload := receive()
+		require.Equal(t, models.WebhookEventTaskCreated, payload.Event)
+		require.Equal(t, task.Task.ID, payload.Data.Task.ID)
+
+		_, err = employer.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: task.Task.ID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+		payload = receive()
+		require.Equal(t, models.WebhookEventTaskCompleted, payload.Event)
+		require.Equal(t, models.TaskStatusCompleted, payload.Data.Task.Status)
+
+		// a failed attempt is logged and retried later, a redelivery sends the same event right away
+		failing.Store(true)
+		_, err = employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Missed"})
+		require.NoError(t, err)
+		var failed models.WebhookDelivery
+		require.Eventually(t, func() bool {
+			deliveries, err := employer.GetWebhookDeliveries(ctx, service.GetWebhookDeliveriesRequest{WebhookID: hook.ID})
+			if err != nil || deliveries.TotalCount != 3 {
+				return false
+			}
+			failed = deliveries.Deliveries[0]
+			return failed.Attempts > 0
+		}, 5*time.Second, 50*time.Millisecond)
+		require.Equal(t, models.WebhookDeliveryPending, failed.Status)
+		require.Equal(t, http.StatusInternalServerError, *failed.ResponseStatus)
+		require.NotEmpty(t, failed.LastError)
+
+		failing.Store(false)
+		redelivered, err := employer.RedeliverWebhookDelivery(ctx, service.RedeliverWebhookDeliveryRequest{
+			WebhookID: hook.ID, DeliveryID: failed.ID,
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, failed.ID, redelivered.Delivery.ID)
+		payload = receive()
+		require.Equal(t, failed.EventID, payload.ID)
+
+		// secrets are only returned on creation, and webhooks belong to their employer
+		list, err := employer.GetWebhooks(ctx)
+		require.NoError(t, err)
+		require.Len(t, list.Webhooks, 1)
+		require.Empty(t, list.Webhooks[0].Secret)
+		otherEmployer, _ := newLoggedInClient(t, server.URL, "hooks@example.com", models.UserRoleEmployer)
+		_, err = otherEmployer.GetWebhookDeliveries(ctx, service.GetWebhookDeliveriesRequest{WebhookID: hook.ID})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeWebhookNotFound, apiErr.ErrorCode)
+		_, err = employee.GetWebhooks(ctx)
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeForbiddenRole, apiErr.ErrorCode)
+
+		_, err = employer.DeleteWebhook(ctx, hook.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("domain events", func(t *testing.T) {
+		// the subscriber fails the first time it gets a status change, so the event is published again
+		published := make(chan models.OutboxEvent, 100)
+		var failed atomic.Bool
+		relay.Subscribe("test", func(ctx context.Context, event models.OutboxEvent) error {
+			if event.Type == models.DomainEventTaskStatusChanged && failed.CompareAndSwap(false, true) {
+				return errors.New("unavailable")
+			}
+			published <- event
+			return nil
+		})
+		next := func(employerID models.UserID) models.OutboxEvent {
+			for {
+				select {
+				case event := <-published:
+					if event.EmployerID != nil && *event.EmployerID == employerID {
+						return event
+					}
+				case <-time.After(10 * time.Second):
+					require.FailNow(t, "no event published")
+				}
+			}
+		}
+
+		eventsEmployer, eventsEmployerUser := newLoggedInClient(t, server.URL, "events@example.com", models.UserRoleEmployer)
+		created, err := eventsEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: "Eventful", AssigneeID: (*int)(&employeeUser.ID)})
+		require.NoError(t, err)
+		event := next(eventsEmployerUser.ID)
+		require.Equal(t, models.DomainEventTaskCreated, event.Type)
+		require.Equal(t, eventsEmployerUser.ID, *event.ActorID)
+		var data models.DomainEventData
+		require.NoError(t, json.Unmarshal(event.Payload, &data))
+		require.Equal(t, created.Task.ID, data.Task.ID)
+		event = next(eventsEmployerUser.ID)
+		require.Equal(t, models.DomainEventTaskAssigned, event.Type)
+
+		_, err = eventsEmployer.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: created.Task.ID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+		// the next change wakes the relay up, it publishes the failed event again first
+		_, err = eventsEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: "Next"})
+		require.NoError(t, err)
+		statusChanged := next(eventsEmployerUser.ID)
+		require.Equal(t, models.DomainEventTaskStatusChanged, statusChanged.Type)
+		require.NoError(t, json.Unmarshal(statusChanged.Payload, &data))
+		require.Equal(t, models.TaskFieldStatus, data.Change.Field)
+		require.Equal(t, string(models.TaskStatusCompleted), *data.Change.NewValue)
+		event = next(eventsEmployerUser.ID)
+		require.Equal(t, models.DomainEventTaskCompleted, event.Type)
+		require.NotEqual(t, statusChanged.EventID, event.EventID)
+		require.Greater(t, *event.Position, *statusChanged.Position)
+		require.Equal(t, models.DomainEventTaskCreated, next(eventsEmployerUser.ID).Type)
+	})
+
+	t.Run("event stream", func(t *testing.T) {
+		// the streams must outlive the read and write timeouts of the server
+		timeouts := httptest.NewUnstartedServer(server.Config.Handler)
+		timeouts.Config.ReadTimeout = time.Second
+		timeouts.Config.WriteTimeout = time.Second
+		timeouts.Start()
+		defer timeouts.Close()
+
+		streamEvents := func(c *Client, lastEventID int64) (<-chan stream.Event, func()) {
+			ctx, cancel := context.WithCancel(ctx)
+			events := make(chan stream.Event, 100)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_ = New(timeouts.URL, WithToken(c.Token())).StreamEvents(ctx, lastEventID, func(event stream.Event) error {
+					events <- event
+					return nil
+				})
+			}()
+			return events, func() {
+				cancel()
+				<-done
+			}
+		}
+		next := func(events <-chan stream.Event, taskID models.TaskID) stream.Event {
+			for {
+				select {
+				case event := <-events:
+					if event.Message.Data.Task.ID == taskID {
+						return event
+					}
+				case <-time.After(10 * time.Second):
+					require.FailNow(t, "no event streamed")
+				}
+			}
+		}
+
+		streamEmployer, _ := newLoggedInClient(t, server.URL, "stream@example.com", models.UserRoleEmployer)
+		employeeEvents, stopEmployee := streamEvents(employee, 0)
+		employerEvents, stopEmployer := streamEvents(streamEmployer, 0)
+		defer stopEmployer()
+		time.Sleep(2 * time.Second)
+
+		unassigned, err := streamEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: "Not for the employee"})
+		require.NoError(t, err)
+		created, err := streamEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: "Streamed", AssigneeID: (*int)(&employeeUser.ID)})
+		require.NoError(t, err)
+		require.Equal(t, models.DomainEventTaskCreated, next(employerEvents, unassigned.Task.ID).Message.Event)
+
+		event := next(employeeEvents, created.Task.ID)
+		require.Equal(t, models.DomainEventTaskCreated, event.Message.Event)
+		require.Equal(t, "Streamed", event.Message.Data.Task.Title)
+		event = next(employeeEvents, created.Task.ID)
+		require.Equal(t, models.DomainEventTaskAssigned, event.Message.Event)
+		require.Equal(t, strconv.Itoa(int(employeeUser.ID)), *event.Message.Data.Change.NewValue)
+
+		_, err = employee.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: created.Task.ID, Status: models.TaskStatusInProgress})
+		require.NoError(t, err)
+		statusChanged := next(employeeEvents, created.Task.ID)
+		require.Equal(t, models.DomainEventTaskStatusChanged, statusChanged.Message.Event)
+		require.Greater(t, statusChanged.Position, event.Position)
+		require.Equal(t, models.DomainEventTaskCreated, next(employerEvents, created.Task.ID).Message.Event)
+
+		// the employee misses an event while disconnected, and gets it first when resuming
+		stopEmployee()
+		_, err = streamEmployer.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: created.Task.ID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+		employeeEvents, stopEmployee = streamEvents(employee, statusChanged.Position)
+		defer stopEmployee()
+		event = next(employeeEvents, created.Task.ID)
+		require.Equal(t, models.DomainEventTaskStatusChanged, event.Message.Event)
+		require.Equal(t, string(models.TaskStatusCompleted), *event.Message.Data.Change.NewValue)
+		require.Empty(t, employeeEvents, "task.completed is not streamed")
+
+		err = New(server.URL).StreamEvents(ctx, 0, func(stream.Event) error { return nil })
+		require.True(t, errors.Is(err, service.ErrUnauthorized), err)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		created, err := employer.CreateTask(ctx, service.CreateTaskRequest{
+			Title: "Renew the certificates", Description: "The TLS certificates expire in May", AssigneeID: (*int)(&employeeUser.ID),
+		})
+		require.NoError(t, err)
+		_, err = employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Audit", Description: "List the certificate authorities"})
+		require.NoError(t, err)
+
+		found, err := employer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: service.TaskFilter{Query: "certificate"}})
+		require.NoError(t, err)
+		require.Equal(t, 2, *found.TotalCount)
+		require.Equal(t, created.Task.ID, found.Tasks[0].ID)
+		require.Equal(t, "Renew the <mark>certificates</mark>", found.Tasks[0].Match.Title)
+
+		assigned, err := employee.GetAssignedTasks(ctx, service.GetAssignedTasksRequest{TaskFilter: service.TaskFilter{Query: "certificate"}})
+		require.NoError(t, err)
+		require.Equal(t, 1, *assigned.TotalCount)
+		require.Equal(t, created.Task.ID, assigned.Tasks[0].ID)
+
+		_, err = employer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: service.TaskFilter{Query: strings.Repeat("a", 201)}})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, service.ErrorCodeInvalidInput, apiErr.ErrorCode)
+	})
+
+	t.Run("employers only assign their own tasks", func(t *testing.T) {
+		otherEmployer, _ := newLoggedInClient(t, server.URL, "other-assigner@example.com", models.UserRoleEmployer)
+		created, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Not theirs to assign"})
+		require.NoError(t, err)
+
+		_, err = otherEmployer.AssignTask(ctx, service.AssignTaskRequest{TaskID: created.Task.ID, AssigneeID: employeeUser.ID})
+		require.True(t, errors.Is(err, service.NewForbiddenError("")), err)
+
+		// The task is not assigned, its history has no assignment
+		history, err := employer.GetTaskHistory(ctx, service.GetTaskHistoryRequest{TaskID: created.Task.ID})
+		require.NoError(t, err)
+		for _, event := range history.Events {
+			require.NotEqual(t, models.TaskFieldAssigneeID, event.Field)
+		}
+	})
+
+	t.Run("tasks only wait for the tasks the user can see", func(t *testing.T) {
+		otherEmployer, _ := newLoggedInClient(t, server.URL, "other-blocker@example.com", models.UserRoleEmployer)
+		foreign, err := otherEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: "Foreign blocker"})
+		require.NoError(t, err)
+		own, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Waits", AssigneeID: (*int)(&employeeUser.ID)})
+		require.NoError(t, err)
+		blocker, err := employer.CreateTask(ctx, service.CreateTaskRequest{Title: "Not assigned"})
+		require.NoError(t, err)
+
+		_, err = employer.CreateTaskDependency(ctx, service.CreateTaskDependencyRequest{TaskID: own.Task.ID, BlockerID: foreign.Task.ID})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeBlockerNotFound, apiErr.ErrorCode)
+
+		// employees cannot reach the tasks that are not assigned to them through their own
+		_, err = employee.CreateTaskDependency(ctx, service.CreateTaskDependencyRequest{TaskID: own.Task.ID, BlockerID: blocker.Task.ID})
+		require.True(t, errors.As(err, &apiErr), err)
+		require.Equal(t, service.ErrorCodeForbiddenRole, apiErr.ErrorCode)
+	})
+
+	t.Run("employers list the tasks of every employer", func(t *testing.T) {
+		otherEmployer, otherEmployerUser := newLoggedInClient(t, server.URL, "own-tasks@example.com", models.UserRoleEmployer)
+		created, err := otherEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: "Renew the other certificates"})
+		require.NoError(t, err)
+
+		// Listed and found by the other employers too, and narrowed down by employer
+		byEmployer, err := employer.GetTasks(ctx, service.GetTasksRequest{
+			TaskFilter: service.TaskFilter{EmployerIDs: []models.UserID{otherEmployerUser.ID}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, *byEmployer.TotalCount)
+		require.Equal(t, created.Task.ID, byEmployer.Tasks[0].ID)
+		found, err := employer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: service.TaskFilter{Query: "other certificates"}})
+		require.NoError(t, err)
+		require.Equal(t, created.Task.ID, found.Tasks[0].ID)
+
+		all, err := otherEmployer.GetTasks(ctx, service.GetTasksRequest{})
+		require.NoError(t, err)
+		require.Greater(t, *all.TotalCount, 1)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		filterEmployer, filterEmployerUser := newLoggedInClient(t, server.URL, "filters@example.com", models.UserRoleEmployer)
+		soon, later := time.Now().Add(24*time.Hour), time.Now().Add(10*24*time.Hour)
+		dueSoon, err := filterEmployer.CreateTask(ctx, service.CreateTaskRequest{
+			Title: "Due soon", DueDate: &soon, AssigneeID: (*int)(&employeeUser.ID),
+		})
+		require.NoError(t, err)
+		unassigned, err := filterEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: "Unassigned", DueDate: &later})
+		require.NoError(t, err)
+		started, err := filterEmployer.CreateTask(ctx, service.CreateTaskRequest{
+			Title: "Started", Status: string(models.TaskStatusInProgress), AssigneeID: (*int)(&employeeUser.ID),
+		})
+		require.NoError(t, err)
+
+		getTasks := func(filter service.TaskFilter) []models.TaskID {
+			filter.EmployerIDs = []models.UserID{filterEmployerUser.ID}
+			tasks, err := filterEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: filter, SortBy: "created_at", SortOrder: "asc"})
+			require.NoError(t, err)
+			var ids []models.TaskID
+			for _, task := range tasks.Tasks {
+				ids = append(ids, task.ID)
+			}
+			return ids
+		}
+		require.Equal(t, []models.TaskID{dueSoon.Task.ID, started.Task.ID},
+			getTasks(service.TaskFilter{Statuses: []models.TaskStatus{models.TaskStatusInProgress, models.TaskStatusPending}, AssigneeIDs: []models.UserID{employeeUser.ID}}))
+		require.Equal(t, []models.TaskID{unassigned.Task.ID}, getTasks(service.TaskFilter{Unassigned: true}))
+		require.Equal(t, []models.TaskID{dueSoon.Task.ID}, getTasks(service.TaskFilter{DueBefore: &later}))
+		require.Equal(t, []models.TaskID{unassigned.Task.ID}, getTasks(service.TaskFilter{DueAfter: &later}))
+
+		// employees filter the tasks assigned to them by employer
+		assigned, err := employee.GetAssignedTasks(ctx, service.GetAssignedTasksRequest{
+			TaskFilter: service.TaskFilter{EmployerIDs: []models.UserID{filterEmployerUser.ID}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, *assigned.TotalCount)
+		// unknown assignees only narrow the employee's list down, they do not tell which users exist
+		assigned, err = employee.GetAssignedTasks(ctx, service.GetAssignedTasksRequest{
+			TaskFilter: service.TaskFilter{AssigneeIDs: []models.UserID{9999}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 0, *assigned.TotalCount)
+
+		require.Equal(t, []models.TaskID{dueSoon.Task.ID, started.Task.ID},
+			getTasks(service.TaskFilter{AssigneeIDs: []models.UserID{employeeUser.ID, employeeUser.ID}}))
+		_, err = filterEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: service.TaskFilter{AssigneeIDs: []models.UserID{employeeUser.ID, 9999}}})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, service.ErrorCodeAssigneeNotFound, apiErr.ErrorCode)
+		_, err = filterEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: service.TaskFilter{DueAfter: &later, DueBefore: &soon}})
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, service.ErrorCodeInvalidInput, apiErr.ErrorCode)
+	})
+
+	t.Run("cursor pagination", func(t *testing.T) {
+		pageEmployer, pageEmployerUser := newLoggedInClient(t, server.URL, "pages@example.com", models.UserRoleEmployer)
+		ownTasks := service.TaskFilter{EmployerIDs: []models.UserID{pageEmployerUser.ID}}
+		createTask := func(title string) models.TaskID {
+			created, err := pageEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: title})
+			require.NoError(t, err)
+			return created.Task.ID
+		}
+		var created []models.TaskID
+		for _, title := range []string{"One", "Two", "Three", "Four", "Five"} {
+			created = append(created, createTask(title))
+		}
+
+		// Newest first, the tasks created while paginating are not listed twice
+		first, err := pageEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: ownTasks, Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, 5, *first.TotalCount)
+		require.NotEmpty(t, first.NextCursor)
+		_ = createTask("Six")
+
+		listed := []models.TaskID{first.Tasks[0].ID, first.Tasks[1].ID}
+		cursor := first.NextCursor
+		for cursor != "" {
+			page, err := pageEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: ownTasks, Limit: 2, Cursor: cursor, SkipCount: true})
+			require.NoError(t, err)
+			require.Nil(t, page.TotalCount)
+			for _, task := range page.Tasks {
+				listed = append(listed, task.ID)
+			}
+			cursor = page.NextCursor
+		}
+		require.Equal(t, []models.TaskID{created[4], created[3], created[2], created[1], created[0]}, listed)
+
+		// A cursor only continues the list in the same order
+		_, err = pageEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: ownTasks, Limit: 2, Cursor: first.NextCursor, SortBy: "due_date"})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, service.ErrorCodeInvalidCursor, apiErr.ErrorCode)
+		_, err = pageEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: ownTasks, Limit: 2, Cursor: "not a cursor"})
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, service.ErrorCodeInvalidCursor, apiErr.ErrorCode)
+		_, err = pageEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: ownTasks, Limit: 2, Cursor: first.NextCursor, Offset: 2})
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, service.ErrorCodeInvalidInput, apiErr.ErrorCode)
+	})
+
+	t.Run("sorting by several fields", func(t *testing.T) {
+		sortEmployer, sortEmployerUser := newLoggedInClient(t, server.URL, "sorts@example.com", models.UserRoleEmployer)
+		ownTasks := service.TaskFilter{EmployerIDs: []models.UserID{sortEmployerUser.ID}}
+		tomorrow, nextWeek := time.Now().Add(24*time.Hour), time.Now().Add(7*24*time.Hour)
+		createTask := func(title string, status models.TaskStatus, dueDate *time.Time) models.TaskID {
+			created, err := sortEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: title, DueDate: dueDate})
+			require.NoError(t, err)
+			if status != models.TaskStatusPending {
+				_, err = sortEmployer.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: created.Task.ID, Status: status})
+				require.NoError(t, err)
+			}
+			return created.Task.ID
+		}
+		done := createTask("Done", models.TaskStatusCompleted, &tomorrow)
+		later := createTask("Later", models.TaskStatusPending, &nextWeek)
+		someday := createTask("Someday", models.TaskStatusPending, nil)
+		soon := createTask("Soon", models.TaskStatusPending, &tomorrow)
+		started := createTask("Started", models.TaskStatusInProgress, &nextWeek)
+
+		// The active tasks first, then the ones not started, then the done ones, by due date, the ones without last
+		var listed []models.TaskID
+		request := service.GetTasksRequest{TaskFilter: ownTasks, Sort: "status:active_first,due_date:asc", Limit: 2}
+		for {
+			page, err := sortEmployer.GetTasks(ctx, request)
+			require.NoError(t, err)
+			for _, task := range page.Tasks {
+				listed = append(listed, task.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			request.Cursor = page.NextCursor
+		}
+		require.Equal(t, []models.TaskID{started, soon, later, someday, done}, listed)
+
+		tasks, err := sortEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: ownTasks, Sort: "status:done_first"})
+		require.NoError(t, err)
+		require.Equal(t, done, tasks.Tasks[0].ID)
+		require.Equal(t, started, tasks.Tasks[1].ID)
+
+		var apiErr *APIError
+		for _, sort := range []string{"status:asc", "title", "due_date:up", "priority,priority", "relevance"} {
+			_, err = sortEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: ownTasks, Sort: sort})
+			require.True(t, errors.As(err, &apiErr), sort)
+			require.Equal(t, service.ErrorCodeInvalidInput, apiErr.ErrorCode, sort)
+		}
+		_, err = sortEmployer.GetTasks(ctx, service.GetTasksRequest{TaskFilter: ownTasks, Sort: "due_date", SortBy: "priority"})
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, service.ErrorCodeInvalidInput, apiErr.ErrorCode)
+	})
+
+	t.Run("employee summary", func(t *testing.T) {
+		summaryEmployer, _ := newLoggedInClient(t, server.URL, "summary@example.com", models.UserRoleEmployer)
+		_, busy := newLoggedInClient(t, server.URL, "busy@example.com", models.UserRoleEmployee)
+		_, idle := newLoggedInClient(t, server.URL, "idle@example.com", models.UserRoleEmployee)
+		yesterday, tomorrow := time.Now().Add(-24*time.Hour), time.Now().Add(24*time.Hour)
+		createTask := func(title string, status models.TaskStatus, dueDate *time.Time) {
+			created, err := summaryEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: title, DueDate: dueDate, AssigneeID: (*int)(&busy.ID)})
+			require.NoError(t, err)
+			if status != models.TaskStatusPending {
+				_, err = summaryEmployer.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: created.Task.ID, Status: status})
+				require.NoError(t, err)
+			}
+		}
+		createTask("On time", models.TaskStatusCompleted, &tomorrow)
+		createTask("Late", models.TaskStatusCompleted, &yesterday)
+		createTask("Overdue", models.TaskStatusPending, &yesterday)
+		createTask("Started", models.TaskStatusInProgress, nil)
+
+		statistics := func(summary *service.GetEmployeeTaskSummaryResponse, employeeID models.UserID) repo.TaskStatistics {
+			for _, employee := range summary.Employees {
+				if employee.Employee.ID == employeeID {
+					return employee.Statistics
+				}
+			}
+			t.Fatalf("employee %d is not in the summary", employeeID)
+			return repo.TaskStatistics{}
+		}
+
+		// Every employee is listed, the ones without tasks too
+		summary, err := summaryEmployer.GetEmployeeTaskSummary(ctx, service.GetEmployeeTaskSummaryRequest{OwnTasks: true, SortBy: "overdue"})
+		require.NoError(t, err)
+		require.Equal(t, len(summary.Employees), summary.TotalCount)
+		require.Equal(t, busy.ID, summary.Employees[0].Employee.ID)
+		stats := statistics(summary, busy.ID)
+		require.Equal(t, 4, stats.TotalTasks)
+		require.Equal(t, 1, stats.Active)
+		require.Equal(t, 2, stats.Terminal)
+		require.Equal(t, 1, stats.Overdue)
+		require.Equal(t, 1, stats.CompletedOnTime)
+		require.Equal(t, 0.5, *stats.OnTimeRate)
+		stats = statistics(summary, idle.ID)
+		require.Zero(t, stats.TotalTasks)
+		require.Nil(t, stats.OnTimeRate)
+
+		// The tasks created in a range, and a page of the employees
+		future := time.Now().Add(time.Hour)
+		summary, err = summaryEmployer.GetEmployeeTaskSummary(ctx, service.GetEmployeeTaskSummaryRequest{OwnTasks: true, CreatedAfter: &future})
+		require.NoError(t, err)
+		require.Zero(t, statistics(summary, busy.ID).TotalTasks)
+		page, err := summaryEmployer.GetEmployeeTaskSummary(ctx, service.GetEmployeeTaskSummaryRequest{Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Len(t, page.Employees, 1)
+		require.Equal(t, summary.TotalCount, page.TotalCount)
+		require.Equal(t, summary.Employees[1].Employee.ID, page.Employees[0].Employee.ID, "sorted by name")
+
+		_, err = summaryEmployer.GetEmployeeTaskSummary(ctx, service.GetEmployeeTaskSummaryRequest{SortBy: "email"})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, service.ErrorCodeInvalidInput, apiErr.ErrorCode)
+	})
+
+	t.Run("flow analytics", func(t *testing.T) {
+		flowEmployer, _ := newLoggedInClient(t, server.URL, "flow@example.com", models.UserRoleEmployer)
+		_, worker := newLoggedInClient(t, server.URL, "worker@example.com", models.UserRoleEmployee)
+		createTask := func(title string, statuses ...models.TaskStatus) {
+			created, err := flowEmployer.CreateTask(ctx, service.CreateTaskRequest{Title: title, AssigneeID: (*int)(&worker.ID)})
+			require.NoError(t, err)
+			for _, status := range statuses {
+				_, err = flowEmployer.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: created.Task.ID, Status: status})
+				require.NoError(t, err)
+			}
+		}
+		createTask("Worked on", models.TaskStatusInProgress, models.TaskStatusCompleted)
+		createTask("Done at once", models.TaskStatusCompleted)
+		createTask("Not done", models.TaskStatusInProgress)
+
+		// The last 12 weeks by default, the tasks were done in the last one
+		flow, err := flowEmployer.GetFlowAnalytics(ctx, service.GetFlowAnalyticsRequest{})
+		require.NoError(t, err)
+		require.Equal(t, "week", flow.Interval)
+		require.Len(t, flow.Team.Periods, 12)
+		require.Equal(t, time.Monday, flow.Team.Periods[0].Start.Weekday())
+		require.Equal(t, 2, flow.Team.Total.Throughput)
+		require.Equal(t, 2, flow.Team.Total.LeadTime.Count)
+		require.Equal(t, 1, flow.Team.Total.CycleTime.Count, "only the task worked on has a cycle time")
+		require.Equal(t, 2, flow.Team.Periods[11].Throughput)
+		require.Zero(t, flow.Team.Periods[0].Throughput)
+		require.Nil(t, flow.Team.Periods[0].LeadTime.AverageHours)
+		require.Len(t, flow.Employees, 1)
+		require.Equal(t, worker.ID, flow.Employees[0].Employee.ID)
+		require.Equal(t, 2, flow.Employees[0].Total.Throughput)
+		require.Len(t, flow.Employees[0].Periods, 12)
+
+		// By month, in a range
+		now := time.Now().UTC()
+		after := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -2, 0)
+		flow, err = flowEmployer.GetFlowAnalytics(ctx, service.GetFlowAnalyticsRequest{Interval: "month", CompletedAfter: &after})
+		require.NoError(t, err)
+		require.Len(t, flow.Team.Periods, 3)
+		require.Equal(t, 1, flow.Team.Periods[0].Start.Day())
+		require.Equal(t, 2, flow.Team.Total.Throughput)
+
+		longAgo := time.Now().AddDate(-3, 0, 0)
+		for _, request := range []service.GetFlowAnalyticsRequest{
+			{Interval: "day"},
+			{CompletedAfter: &longAgo}, // too many weeks
+			{CompletedAfter: &after, CompletedBefore: &longAgo},
+		} {
+			_, err = flowEmployer.GetFlowAnalytics(ctx, request)
+			var apiErr *APIError
+			require.True(t, errors.As(err, &apiErr))
+			require.Equal(t, service.ErrorCodeInvalidInput, apiErr.ErrorCode)
+		}
+	})
+
+	t.Run("errors are typed", func(t *testing.T) {
+		_, err := employer.UpdateTaskStatus(ctx, service.UpdateTaskStatusRequest{TaskID: 9999, Status: models.TaskStatusCompleted})
+		require.True(t, errors.Is(err, service.ErrTaskNotFound), err)
+
+		_, err = employee.CreateTask(ctx, service.CreateTaskRequest{Title: "Not allowed"})
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+		require.Equal(t, service.ErrorCodeForbiddenRole, apiErr.ErrorCode)
+
+		_, err = employer.CreateTask(ctx, service.CreateTaskRequest{})
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, service.ErrorCodeValidationFailed, apiErr.ErrorCode)
+		require.Equal(t, "title", apiErr.Fields[0].Field)
+
+		_, err = New(server.URL).Login(ctx, service.GetJWTRequest{Email: "employee@example.com", Password: "wrong-password"})
+		require.True(t, errors.Is(err, service.ErrInvalidCredentials), err)
+	})
+
+	t.Run("refresh tokens rotate", func(t *testing.T) {
+		c, _ := newLoggedInClient(t, server.URL, "rotate@example.com", models.UserRoleEmployee)
+		oldRefreshToken := c.refreshToken
+
+		refreshed, err := c.Refresh(ctx)
+		require.NoError(t, err)
+		require.NotEqual(t, oldRefreshToken, refreshed.RefreshToken)
+
+		// a refresh token can only be used once
+		reused := New(server.URL)
+		reused.refreshToken = oldRefreshToken
+		_, err = reused.Refresh(ctx)
+		require.True(t, errors.Is(err, service.ErrInvalidRefreshToken), err)
+
+		_, err = c.GetMe(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("logout revokes the session", func(t *testing.T) {
+		c, _ := newLoggedInClient(t, server.URL, "logout@example.com", models.UserRoleEmployee)
+		token, refreshToken := c.Token(), c.refreshToken
+
+		_, err := c.Logout(ctx, service.LogoutRequest{})
+		require.NoError(t, err)
+		require.Empty(t, c.Token())
+
+		_, err = New(server.URL, WithToken(token)).GetMe(ctx)
+		require.True(t, errors.Is(err, service.ErrUnauthorized), err)
+
+		reused := New(server.URL)
+		reused.refreshToken = refreshToken
+		_, err = reused.Refresh(ctx)
+		require.True(t, errors.Is(err, service.ErrInvalidRefreshToken), err)
+	})
+
+	t.Run("employers revoke all sessions of a user", func(t *testing.T) {
+		leaving, leavingUser := newLoggedInClient(t, server.URL, "leaving@example.com", models.UserRoleEmployee)
+		_, err := leaving.GetMe(ctx)
+		require.NoError(t, err)
+
+		_, err = leaving.RevokeUserSessions(ctx, leavingUser.ID)
+		require.True(t, errors.Is(err, service.NewForbiddenRoleError("")), err)
+
+		_, err = employer.RevokeUserSessions(ctx, leavingUser.ID)
+		require.NoError(t, err)
+
+		// the access token is rejected, and renewing it fails too
+		_, err = leaving.GetMe(ctx)
+		require.True(t, errors.Is(err, service.ErrInvalidRefreshToken), err)
+
+		_, err = employer.RevokeUserSessions(ctx, 9999)
+		require.True(t, errors.Is(err, service.ErrUserNotFound), err)
+
+		// employers cannot revoke the sessions of other employers
+		peer, peerUser := newLoggedInClient(t, server.URL, "peer@example.com", models.UserRoleEmployer)
+		_, err = employer.RevokeUserSessions(ctx, peerUser.ID)
+		require.True(t, errors.Is(err, service.NewForbiddenError("")), err)
+		_, err = peer.GetMe(ctx)
+		require.NoError(t, err, "the sessions of the employer are still valid")
+	})
+
+	t.Run("rejected tokens are renewed", func(t *testing.T) {
+		employee.mu.Lock()
+		employee.token = "expired-token"
+		employee.mu.Unlock()
+
+		_, err := employee.GetMe(ctx)
+		require.NoError(t, err)
+		require.NotEqual(t, "expired-token", employee.Token())
+	})
+}
@@ -0,0 +1,621 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/service"
+)
+
+// Health calls GET /health
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/health", nil, nil, nil, false)
+}
+
+// Login calls POST /api/v1/login. On success the client uses the returned token for all
+// authenticated calls, and renews it with the returned refresh token when it expires.
+func (c *Client) Login(ctx context.Context, request service.GetJWTRequest) (*service.GetJWTResponse, error) {
+	var response service.GetJWTResponse
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/login", nil, request, &response, false); err != nil {
+		return nil, err
+	}
+	c.setTokens(&response)
+	return &response, nil
+}
+
+// Refresh calls POST /api/v1/token/refresh with the client's refresh token, and uses the new tokens.
+// Authenticated calls refresh the token automatically, this is only needed to renew it ahead of time.
+func (c *Client) Refresh(ctx context.Context) (*service.GetJWTResponse, error) {
+	c.mu.Lock()
+	request := service.RefreshTokenRequest{RefreshToken: c.refreshToken}
+	c.mu.Unlock()
+
+	var response service.GetJWTResponse
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/token/refresh", nil, request, &response, false); err != nil {
+		return nil, err
+	}
+	c.setTokens(&response)
+	return &response, nil
+}
+
+// Logout calls POST /api/v1/logout, revoking the client's tokens, or all the sessions of the user
+// if request.AllSessions is set. The client's refresh token is sent if request.RefreshToken is empty.
+func (c *Client) Logout(ctx context.Context, request service.LogoutRequest) (*service.LogoutResponse, error) {
+	if request.RefreshToken == "" {
+		c.mu.Lock()
+		request.RefreshToken = c.refreshToken
+		c.mu.Unlock()
+	}
+
+	var response service.LogoutResponse
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/logout", nil, request, &response, true); err != nil {
+		return nil, err
+	}
+	c.clearTokens()
+	return &response, nil
+}
+
+// CreateUser calls POST /api/v1/users
+func (c *Client) CreateUser(ctx context.Context, request service.CreateUserRequest) (*service.CreateUserResponse, error) {
+	var response service.CreateUserResponse
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/users", nil, request, &response, false); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetMe calls GET /api/v1/users/me
+func (c *Client) GetMe(ctx context.Context) (*service.GetMeResponse, error) {
+	var response service.GetMeResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/users/me", nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetUsers calls GET /api/v1/users/all
+func (c *Client) GetUsers(ctx context.Context) (*service.GetUsersResponse, error) {
+	var response service.GetUsersResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/users/all", nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetUserByID calls GET /api/v1/users/{id}
+func (c *Client) GetUserByID(ctx context.Context, id models.UserID) (*service.GetUserByIDResponse, error) {
+	var response service.GetUserByIDResponse
+	path := apiPrefix + "/users/" + strconv.Itoa(int(id))
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// RevokeUserSessions calls POST /api/v1/users/{id}/revoke-sessions
+func (c *Client) RevokeUserSessions(ctx context.Context, id models.UserID) (*service.RevokeUserSessionsResponse, error) {
+	var response service.RevokeUserSessionsResponse
+	path := apiPrefix + "/users/" + strconv.Itoa(int(id)) + "/revoke-sessions"
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreateTask calls POST /api/v1/tasks
+func (c *Client) CreateTask(ctx context.Context, request service.CreateTaskRequest) (*service.CreateTaskResponse, error) {
+	var response service.CreateTaskResponse
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/tasks", nil, request, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateTaskStatus calls PATCH /api/v1/tasks/{id}/status
+func (c *Client) UpdateTaskStatus(ctx context.Context, request service.UpdateTaskStatusRequest) (*service.UpdateTaskStatusResponse, error) {
+	var response service.UpdateTaskStatusResponse
+	body := struct {
+		Status models.TaskStatus `json:"status"`
+		Force  bool              `json:"force,omitempty"`
+	}{Status: request.Status, Force: request.Force}
+	if err := c.do(ctx, http.MethodPatch, taskPath(request.TaskID, "/status"), nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// AssignTask calls PATCH /api/v1/tasks/{id}/assign
+func (c *Client) AssignTask(ctx context.Context, request service.AssignTaskRequest) (*service.AssignTaskResponse, error) {
+	var response service.AssignTaskResponse
+	body := struct {
+		AssigneeID models.UserID `json:"assignee_id"`
+	}{AssigneeID: request.AssigneeID}
+	if err := c.do(ctx, http.MethodPatch, taskPath(request.TaskID, "/assign"), nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreateComment calls POST /api/v1/tasks/{id}/comments
+func (c *Client) CreateComment(ctx context.Context, request service.CreateCommentRequest) (*service.CreateCommentResponse, error) {
+	var response service.CreateCommentResponse
+	body := struct {
+		ParentID *models.CommentID `json:"parent_id,omitempty"`
+		Body     string            `json:"body"`
+	}{ParentID: request.ParentID, Body: request.Body}
+	if err := c.do(ctx, http.MethodPost, taskPath(request.TaskID, "/comments"), nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetComments calls GET /api/v1/tasks/{id}/comments
+func (c *Client) GetComments(ctx context.Context, request service.GetCommentsRequest) (*service.GetCommentsResponse, error) {
+	query := listQuery("", "", request.Limit, request.Offset)
+
+	var response service.GetCommentsResponse
+	if err := c.do(ctx, http.MethodGet, taskPath(request.TaskID, "/comments"), query, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateComment calls PATCH /api/v1/tasks/{id}/comments/{commentID}
+func (c *Client) UpdateComment(ctx context.Context, request service.UpdateCommentRequest) (*service.UpdateCommentResponse, error) {
+	var response service.UpdateCommentResponse
+	body := struct {
+		Body string `json:"body"`
+	}{Body: request.Body}
+	path := taskPath(request.TaskID, "/comments/"+strconv.Itoa(int(request.CommentID)))
+	if err := c.do(ctx, http.MethodPatch, path, nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteComment calls DELETE /api/v1/tasks/{id}/comments/{commentID}
+func (c *Client) DeleteComment(ctx context.Context, request service.DeleteCommentRequest) (*service.DeleteCommentResponse, error) {
+	var response service.DeleteCommentResponse
+	path := taskPath(request.TaskID, "/comments/"+strconv.Itoa(int(request.CommentID)))
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetSubtasks calls GET /api/v1/tasks/{id}/subtasks
+func (c *Client) GetSubtasks(ctx context.Context, request service.GetSubtasksRequest) (*service.GetSubtasksResponse, error) {
+	var response service.GetSubtasksResponse
+	if err := c.do(ctx, http.MethodGet, taskPath(request.TaskID, "/subtasks"), nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreateChecklistItem calls POST /api/v1/tasks/{id}/checklist
+func (c *Client) CreateChecklistItem(ctx context.Context, request service.CreateChecklistItemRequest) (*service.CreateChecklistItemResponse, error) {
+	var response service.CreateChecklistItemResponse
+	body := struct {
+		Title string `json:"title"`
+	}{Title: request.Title}
+	if err := c.do(ctx, http.MethodPost, taskPath(request.TaskID, "/checklist"), nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetChecklist calls GET /api/v1/tasks/{id}/checklist
+func (c *Client) GetChecklist(ctx context.Context, request service.GetChecklistRequest) (*service.GetChecklistResponse, error) {
+	var response service.GetChecklistResponse
+	if err := c.do(ctx, http.MethodGet, taskPath(request.TaskID, "/checklist"), nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateChecklistItem calls PATCH /api/v1/tasks/{id}/checklist/{itemID}
+func (c *Client) UpdateChecklistItem(ctx context.Context, request service.UpdateChecklistItemRequest) (*service.UpdateChecklistItemResponse, error) {
+	var response service.UpdateChecklistItemResponse
+	body := struct {
+		Title *string `json:"title,omitempty"`
+		Done  *bool   `json:"done,omitempty"`
+	}{Title: request.Title, Done: request.Done}
+	path := taskPath(request.TaskID, "/checklist/"+strconv.Itoa(int(request.ItemID)))
+	if err := c.do(ctx, http.MethodPatch, path, nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteChecklistItem calls DELETE /api/v1/tasks/{id}/checklist/{itemID}
+func (c *Client) DeleteChecklistItem(ctx context.Context, request service.DeleteChecklistItemRequest) (*service.DeleteChecklistItemResponse, error) {
+	var response service.DeleteChecklistItemResponse
+	path := taskPath(request.TaskID, "/checklist/"+strconv.Itoa(int(request.ItemID)))
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreateTaskDependency calls POST /api/v1/tasks/{id}/dependencies
+func (c *Client) CreateTaskDependency(ctx context.Context, request service.CreateTaskDependencyRequest) (*service.CreateTaskDependencyResponse, error) {
+	var response service.CreateTaskDependencyResponse
+	body := struct {
+		BlockerID models.TaskID `json:"blocker_id"`
+	}{BlockerID: request.BlockerID}
+	if err := c.do(ctx, http.MethodPost, taskPath(request.TaskID, "/dependencies"), nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteTaskDependency calls DELETE /api/v1/tasks/{id}/dependencies/{blockerID}
+func (c *Client) DeleteTaskDependency(ctx context.Context, request service.DeleteTaskDependencyRequest) (*service.DeleteTaskDependencyResponse, error) {
+	var response service.DeleteTaskDependencyResponse
+	path := taskPath(request.TaskID, "/dependencies/"+strconv.Itoa(int(request.BlockerID)))
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetTaskHistory calls GET /api/v1/tasks/{id}/history
+func (c *Client) GetTaskHistory(ctx context.Context, request service.GetTaskHistoryRequest) (*service.GetTaskHistoryResponse, error) {
+	query := listQuery("", "", request.Limit, request.Offset)
+
+	var response service.GetTaskHistoryResponse
+	if err := c.do(ctx, http.MethodGet, taskPath(request.TaskID, "/history"), query, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetAssignedTasks calls GET /api/v1/tasks/assigned
+func (c *Client) GetAssignedTasks(ctx context.Context, request service.GetAssignedTasksRequest) (*service.GetAssignedTasksResponse, error) {
+	query := listQuery(request.SortBy, request.SortOrder, request.Limit, request.Offset)
+	setTaskFilter(query, request.TaskFilter)
+	setTaskPage(query, request.Sort, request.Cursor, request.SkipCount)
+
+	var response service.GetAssignedTasksResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/tasks/assigned", query, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetTasks calls GET /api/v1/tasks
+func (c *Client) GetTasks(ctx context.Context, request service.GetTasksRequest) (*service.GetTasksResponse, error) {
+	query := listQuery(request.SortBy, request.SortOrder, request.Limit, request.Offset)
+	setTaskFilter(query, request.TaskFilter)
+	setTaskPage(query, request.Sort, request.Cursor, request.SkipCount)
+
+	var response service.GetTasksResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/tasks", query, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetEmployeeTaskSummary calls GET /api/v1/employee-summary
+func (c *Client) GetEmployeeTaskSummary(ctx context.Context, request service.GetEmployeeTaskSummaryRequest) (*service.GetEmployeeTaskSummaryResponse, error) {
+	query := listQuery(request.SortBy, request.SortOrder, request.Limit, request.Offset)
+	if request.CreatedAfter != nil {
+		query.Set("created_after", request.CreatedAfter.Format(time.RFC3339Nano))
+	}
+	if request.CreatedBefore != nil {
+		query.Set("created_before", request.CreatedBefore.Format(time.RFC3339Nano))
+	}
+	if request.OwnTasks {
+		query.Set("own_tasks", "true")
+	}
+
+	var response service.GetEmployeeTaskSummaryResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/employee-summary", query, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetFlowAnalytics calls GET /api/v1/analytics/flow
+func (c *Client) GetFlowAnalytics(ctx context.Context, request service.GetFlowAnalyticsRequest) (*service.GetFlowAnalyticsResponse, error) {
+	query := url.Values{}
+	if request.Interval != "" {
+		query.Set("interval", request.Interval)
+	}
+	if request.CompletedAfter != nil {
+		query.Set("completed_after", request.CompletedAfter.Format(time.RFC3339Nano))
+	}
+	if request.CompletedBefore != nil {
+		query.Set("completed_before", request.CompletedBefore.Format(time.RFC3339Nano))
+	}
+
+	var response service.GetFlowAnalyticsResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/analytics/flow", query, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreateLabel calls POST /api/v1/labels
+func (c *Client) CreateLabel(ctx context.Context, request service.CreateLabelRequest) (*service.CreateLabelResponse, error) {
+	var response service.CreateLabelResponse
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/labels", nil, request, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetLabels calls GET /api/v1/labels
+func (c *Client) GetLabels(ctx context.Context) (*service.GetLabelsResponse, error) {
+	var response service.GetLabelsResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/labels", nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateLabel calls PATCH /api/v1/labels/{id}
+func (c *Client) UpdateLabel(ctx context.Context, request service.UpdateLabelRequest) (*service.UpdateLabelResponse, error) {
+	var response service.UpdateLabelResponse
+	body := struct {
+		Name string `json:"name"`
+	}{Name: request.Name}
+	path := apiPrefix + "/labels/" + strconv.Itoa(int(request.LabelID))
+	if err := c.do(ctx, http.MethodPatch, path, nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteLabel calls DELETE /api/v1/labels/{id}
+func (c *Client) DeleteLabel(ctx context.Context, id models.LabelID) (*service.DeleteLabelResponse, error) {
+	var response service.DeleteLabelResponse
+	path := apiPrefix + "/labels/" + strconv.Itoa(int(id))
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func taskPath(id models.TaskID, suffix string) string {
+	return apiPrefix + "/tasks/" + strconv.Itoa(int(id)) + suffix
+}
+
+// listQuery encodes the sorting and pagination parameters shared by the list endpoints
+func listQuery(sortBy, sortOrder string, limit, offset int) url.Values {
+	query := url.Values{}
+	if sortBy != "" {
+		query.Set("sort_by", sortBy)
+	}
+	if sortOrder != "" {
+		query.Set("sort_order", sortOrder)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	return query
+}
+
+// setTaskPage encodes the sorting by several fields and the keyset pagination of the task lists
+func setTaskPage(query url.Values, sort, cursor string, skipCount bool) {
+	if sort != "" {
+		query.Set("sort", sort)
+	}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if skipCount {
+		query.Set("skip_count", "true")
+	}
+}
+
+// setTaskFilter encodes the filters of the task lists
+func setTaskFilter(query url.Values, filter service.TaskFilter) {
+	for _, status := range filter.Statuses {
+		query.Add("status", string(status))
+	}
+	for _, assigneeID := range filter.AssigneeIDs {
+		query.Add("assignee_id", strconv.Itoa(int(assigneeID)))
+	}
+	if filter.Unassigned {
+		query.Set("unassigned", "true")
+	}
+	for _, employerID := range filter.EmployerIDs {
+		query.Add("employer_id", strconv.Itoa(int(employerID)))
+	}
+	if filter.Priority != "" {
+		query.Set("priority", string(filter.Priority))
+	}
+	if filter.LabelID != nil {
+		query.Set("label_id", strconv.Itoa(int(*filter.LabelID)))
+	}
+	if filter.Overdue {
+		query.Set("overdue", "true")
+	}
+	for name, t := range map[string]*time.Time{
+		"due_before":     filter.DueBefore,
+		"due_after":      filter.DueAfter,
+		"created_before": filter.CreatedBefore,
+		"created_after":  filter.CreatedAfter,
+	} {
+		if t != nil {
+			query.Set(name, t.Format(time.RFC3339Nano))
+		}
+	}
+	if filter.Query != "" {
+		query.Set("q", filter.Query)
+	}
+}
+
+// GetWorkflow calls GET /api/v1/workflow
+func (c *Client) GetWorkflow(ctx context.Context) (*service.GetWorkflowResponse, error) {
+	var response service.GetWorkflowResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/workflow", nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateWorkflow calls PUT /api/v1/workflow
+func (c *Client) UpdateWorkflow(ctx context.Context, request service.UpdateWorkflowRequest) (*service.UpdateWorkflowResponse, error) {
+	var response service.UpdateWorkflowResponse
+	if err := c.do(ctx, http.MethodPut, apiPrefix+"/workflow", nil, request, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ResetWorkflow calls DELETE /api/v1/workflow
+func (c *Client) ResetWorkflow(ctx context.Context) (*service.ResetWorkflowResponse, error) {
+	var response service.ResetWorkflowResponse
+	if err := c.do(ctx, http.MethodDelete, apiPrefix+"/workflow", nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreateTaskSeries calls POST /api/v1/task-series
+func (c *Client) CreateTaskSeries(ctx context.Context, request service.CreateTaskSeriesRequest) (*service.CreateTaskSeriesResponse, error) {
+	var response service.CreateTaskSeriesResponse
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/task-series", nil, request, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetTaskSeriesList calls GET /api/v1/task-series
+func (c *Client) GetTaskSeriesList(ctx context.Context) (*service.GetTaskSeriesListResponse, error) {
+	var response service.GetTaskSeriesListResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/task-series", nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetTaskSeries calls GET /api/v1/task-series/{id}
+func (c *Client) GetTaskSeries(ctx context.Context, id models.TaskSeriesID) (*service.GetTaskSeriesResponse, error) {
+	var response service.GetTaskSeriesResponse
+	if err := c.do(ctx, http.MethodGet, seriesPath(id, ""), nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateTaskSeries calls PATCH /api/v1/task-series/{id}
+func (c *Client) UpdateTaskSeries(ctx context.Context, request service.UpdateTaskSeriesRequest) (*service.UpdateTaskSeriesResponse, error) {
+	var response service.UpdateTaskSeriesResponse
+	body := struct {
+		Title       *string    `json:"title,omitempty"`
+		Description *string    `json:"description,omitempty"`
+		AssigneeID  *int       `json:"assignee_id,omitempty"`
+		Priority    *string    `json:"priority,omitempty"`
+		RRule       *string    `json:"rrule,omitempty"`
+		Timezone    *string    `json:"timezone,omitempty"`
+		StartsAt    *time.Time `json:"starts_at,omitempty"`
+	}{
+		Title:       request.Title,
+		Description: request.Description,
+		AssigneeID:  request.AssigneeID,
+		Priority:    request.Priority,
+		RRule:       request.RRule,
+		Timezone:    request.Timezone,
+		StartsAt:    request.StartsAt,
+	}
+	if err := c.do(ctx, http.MethodPatch, seriesPath(request.SeriesID, ""), nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateTaskSeriesState calls PATCH /api/v1/task-series/{id}/state
+func (c *Client) UpdateTaskSeriesState(ctx context.Context, request service.UpdateTaskSeriesStateRequest) (*service.UpdateTaskSeriesStateResponse, error) {
+	var response service.UpdateTaskSeriesStateResponse
+	body := struct {
+		State models.TaskSeriesState `json:"state"`
+	}{State: request.State}
+	if err := c.do(ctx, http.MethodPatch, seriesPath(request.SeriesID, "/state"), nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func seriesPath(id models.TaskSeriesID, suffix string) string {
+	return apiPrefix + "/task-series/" + strconv.Itoa(int(id)) + suffix
+}
+
+// CreateWebhook calls POST /api/v1/webhooks
+func (c *Client) CreateWebhook(ctx context.Context, request service.CreateWebhookRequest) (*service.CreateWebhookResponse, error) {
+	var response service.CreateWebhookResponse
+	if err := c.do(ctx, http.MethodPost, apiPrefix+"/webhooks", nil, request, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetWebhooks calls GET /api/v1/webhooks
+func (c *Client) GetWebhooks(ctx context.Context) (*service.GetWebhooksResponse, error) {
+	var response service.GetWebhooksResponse
+	if err := c.do(ctx, http.MethodGet, apiPrefix+"/webhooks", nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateWebhook calls PATCH /api/v1/webhooks/{id}
+func (c *Client) UpdateWebhook(ctx context.Context, request service.UpdateWebhookRequest) (*service.UpdateWebhookResponse, error) {
+	var response service.UpdateWebhookResponse
+	body := struct {
+		URL    *string                   `json:"url,omitempty"`
+		Events []models.WebhookEventType `json:"events,omitempty"`
+		Active *bool                     `json:"active,omitempty"`
+	}{
+		URL:    request.URL,
+		Events: request.Events,
+		Active: request.Active,
+	}
+	if err := c.do(ctx, http.MethodPatch, webhookPath(request.WebhookID, ""), nil, body, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteWebhook calls DELETE /api/v1/webhooks/{id}
+func (c *Client) DeleteWebhook(ctx context.Context, id models.WebhookID) (*service.DeleteWebhookResponse, error) {
+	var response service.DeleteWebhookResponse
+	if err := c.do(ctx, http.MethodDelete, webhookPath(id, ""), nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetWebhookDeliveries calls GET /api/v1/webhooks/{id}/deliveries
+func (c *Client) GetWebhookDeliveries(ctx context.Context, request service.GetWebhookDeliveriesRequest) (*service.GetWebhookDeliveriesResponse, error) {
+	query := listQuery("", "", request.Limit, request.Offset)
+
+	var response service.GetWebhookDeliveriesResponse
+	if err := c.do(ctx, http.MethodGet, webhookPath(request.WebhookID, "/deliveries"), query, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// RedeliverWebhookDelivery calls POST /api/v1/webhooks/{id}/deliveries/{deliveryID}/redeliver
+func (c *Client) RedeliverWebhookDelivery(ctx context.Context, request service.RedeliverWebhookDeliveryRequest) (*service.RedeliverWebhookDeliveryResponse, error) {
+	var response service.RedeliverWebhookDeliveryResponse
+	path := webhookPath(request.WebhookID, "/deliveries/"+strconv.Itoa(int(request.DeliveryID))+"/redeliver")
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func webhookPath(id models.WebhookID, suffix string) string {
+	return apiPrefix + "/webhooks/" + strconv.Itoa(int(id)) + suffix
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/llkhacquan/cisab/pkg/service"
+)
+
+// APIError is returned when the API responds with an error.
+//
+// It matches the service errors with the same error code, so callers can write
+// errors.Is(err, service.ErrTaskNotFound) just like the server does.
+type APIError struct {
+	StatusCode int                  // HTTP status code
+	ErrorCode  string               // stable machine-readable code, see the service.ErrorCode* constants
+	Message    string               // human-readable message
+	Fields     []service.FieldError // the invalid fields of a validation error
+}
+
+func newAPIError(statusCode int, env envelope) *APIError {
+	message := env.Error
+	if message == "" {
+		message = env.Message
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		ErrorCode:  env.ErrorCode,
+		Message:    message,
+		Fields:     env.Fields,
+	}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error %d (%s): %s", e.StatusCode, e.ErrorCode, e.Message)
+}
+
+// Is reports whether target is a service error with the same status and error code
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(service.Error)
+	if !ok {
+		return false
+	}
+	return t.Code == e.StatusCode && t.ErrorCode == e.ErrorCode
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/llkhacquan/cisab/pkg/stream"
+	"github.com/pkg/errors"
+)
+
+// maxEventSize is the maximum size of a line of the event stream
+const maxEventSize = 1 << 20
+
+// StreamEvents calls GET /api/v1/events/stream and calls handle with each event, until ctx is canceled, handle
+// returns an error, or the server ends the stream, which it does when the token expires. Call it again with the
+// Position of the last event handled as lastEventID to resume without missing any event, 0 to only get new events.
+func (c *Client) StreamEvents(ctx context.Context, lastEventID int64, handle func(stream.Event) error) error {
+	token, err := c.validToken(ctx, false)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+apiPrefix+"/events/stream", nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if lastEventID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(lastEventID, 10))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to open the event stream")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var env envelope
+		if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+			return &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+		}
+		return newAPIError(resp.StatusCode, env)
+	}
+	if err := readEvents(resp.Body, handle); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// readEvents reads a stream in the text/event-stream format, and calls handle with each event
+func readEvents(r io.Reader, handle func(stream.Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+
+	var event stream.Event
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line ends an event, the heartbeats have no data
+			if data == nil {
+				continue
+			}
+			if err := json.Unmarshal(data, &event.Message); err != nil {
+				return errors.Wrap(err, "failed to decode event")
+			}
+			if err := handle(event); err != nil {
+				return err
+			}
+			event, data = stream.Event{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // a comment, like the heartbeats
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			position, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, "invalid event ID %q", value)
+			}
+			event.Position = position
+		case "data":
+			if data != nil {
+				data = append(data, '\n')
+			}
+			data = append(data, value...)
+		}
+	}
+	return errors.Wrap(scanner.Err(), "failed to read the event stream")
+}
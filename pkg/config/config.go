@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -19,6 +20,9 @@ type Config struct {
 	// Database configuration
 	Database DatabaseConfig `yaml:"database"`
 
+	// Notifications configuration
+	Notifications NotificationsConfig `yaml:"notifications"`
+
 	// Environment (dev, staging, production)
 	Environment string `yaml:"environment"`
 }
@@ -27,11 +31,37 @@ type Config struct {
 type ServerConfig struct {
 	// Port to run the server on
 	Port int `yaml:"port"`
+	// ShutdownGracePeriodInSecond is how long in-flight requests get to finish on shutdown
+	// before they are canceled and their transactions rolled back
+	ShutdownGracePeriodInSecond int `yaml:"shutdown_grace_period"`
 }
 
 type JWTConfig struct {
-	Secret      string `yaml:"secret"`
-	TTLInSecond int    `yaml:"ttl"`
+	// Secret signs tokens with HS256 when no Keys are configured, e.g. in local development.
+	// It is not accepted anymore once Keys are configured.
+	Secret string `yaml:"secret"`
+	// Keys sign tokens with RS256 or EdDSA, their public keys are served at /.well-known/jwks.json
+	Keys []JWTKeyConfig `yaml:"keys"`
+	// TTLInSecond is the lifetime of access tokens. Keep it short, clients renew them with their refresh token.
+	TTLInSecond int `yaml:"ttl"`
+	// RefreshTTLInSecond is the lifetime of refresh tokens, i.e. how long a session lasts without logging in again
+	RefreshTTLInSecond int `yaml:"refresh_ttl"`
+}
+
+// JWTKeyConfig is a JWT signing key, see package jwtkeys
+type JWTKeyConfig struct {
+	// ID is the "kid" header of the tokens signed with the key, it must be unique
+	ID string `yaml:"id"`
+	// Algorithm is RS256 or EdDSA
+	Algorithm string `yaml:"algorithm"`
+	// PrivateKeyFile is the path to the PEM encoded private key
+	PrivateKeyFile string `yaml:"private_key_file"`
+	// PublicKeyFile is the path to the PEM encoded public key of a key that only verifies tokens,
+	// e.g. a retired key whose private key was destroyed. Only used without a PrivateKeyFile.
+	PublicKeyFile string `yaml:"public_key_file"`
+	// ActiveFrom is when the key starts signing tokens. The most recently activated key signs;
+	// the others are still published and accepted.
+	ActiveFrom time.Time `yaml:"active_from"`
 }
 
 // DatabaseConfig holds the database-related configuration
@@ -48,11 +78,39 @@ type DatabaseConfig struct {
 	Name string `yaml:"name"`
 }
 
+// NotificationsConfig holds the configuration of the due date reminders and overdue notices
+type NotificationsConfig struct {
+	// ReminderLeadInSecond is how long before their due date the tasks get a reminder
+	ReminderLeadInSecond int `yaml:"reminder_lead"`
+	// IntervalInSecond is how often the scheduler looks for due tasks
+	IntervalInSecond int `yaml:"interval"`
+	// SMTP sends the notifications by email. Without a host, they are only logged.
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig holds the configuration of the SMTP server that sends the emails
+type SMTPConfig struct {
+	// Host is the SMTP server host, emails are not sent if it is empty
+	Host string `yaml:"host"`
+	// Port is the SMTP server port
+	Port int `yaml:"port"`
+	// Username and Password authenticate to the server, no authentication if Username is empty
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// From is the sender address of the emails
+	From string `yaml:"from"`
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port: 8080,
+			Port:                        8080,
+			ShutdownGracePeriodInSecond: 20,
+		},
+		JWT: JWTConfig{
+			TTLInSecond:        900,
+			RefreshTTLInSecond: 30 * 24 * 3600,
 		},
 		Database: DatabaseConfig{
 			Host:     "localhost",
@@ -61,10 +119,22 @@ func DefaultConfig() *Config {
 			Password: "password",
 			Name:     "cisab",
 		},
+		Notifications: NotificationsConfig{
+			ReminderLeadInSecond: 24 * 3600,
+			IntervalInSecond:     60,
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+		},
 		Environment: "dev",
 	}
 }
 
+// IsProduction reports whether the application is running in the production environment
+func (c *Config) IsProduction() bool {
+	return c.Environment == "production"
+}
+
 // LoadConfig loads the configuration from the specified file path
 func LoadConfig(path string) (*Config, error) {
 	// Set default config
@@ -89,6 +159,13 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 
+	if grace := os.Getenv("SHUTDOWN_GRACE_PERIOD"); grace != "" {
+		var g int
+		if _, err := fmt.Sscanf(grace, "%d", &g); err == nil {
+			config.Server.ShutdownGracePeriodInSecond = g
+		}
+	}
+
 	if env := os.Getenv("ENVIRONMENT"); env != "" {
 		config.Environment = env
 	}
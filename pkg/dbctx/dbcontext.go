@@ -2,6 +2,7 @@ package dbctx
 
 import (
 	"context"
+	"sync"
 
 	"gorm.io/gorm"
 )
@@ -18,3 +19,53 @@ var Get = func(ctx context.Context) *gorm.DB {
 func Set(ctx context.Context, db *gorm.DB) context.Context {
 	return context.WithValue(ctx, dbKey, db)
 }
+
+// Transaction runs fn in a database transaction, set in the context of fn like the API does for each request.
+// The transaction is committed if fn returns nil, and rolled back otherwise. The AfterCommit hooks registered
+// by fn run once the transaction is committed.
+func Transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	ctx, runHooks := WithAfterCommit(ctx)
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(Set(ctx, tx))
+	})
+	if err == nil {
+		runHooks()
+	}
+	return err
+}
+
+var afterCommitKey = new(int)
+
+// afterCommitHooks are the functions to run once the transaction of a context is committed
+type afterCommitHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+// WithAfterCommit returns a context collecting the hooks registered with AfterCommit, and a function that runs them.
+// The owner of the transaction calls it once the transaction is committed, and never if it is rolled back.
+func WithAfterCommit(ctx context.Context) (context.Context, func()) {
+	hooks := &afterCommitHooks{}
+	return context.WithValue(ctx, afterCommitKey, hooks), func() {
+		hooks.mu.Lock()
+		registered := hooks.hooks
+		hooks.hooks = nil
+		hooks.mu.Unlock()
+		for _, hook := range registered {
+			hook()
+		}
+	}
+}
+
+// AfterCommit registers fn to run once the transaction of ctx is committed, e.g. to tell a worker about the rows
+// the transaction inserted. fn is not run if the transaction is rolled back. Without a transaction, fn runs right away.
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(afterCommitKey).(*afterCommitHooks)
+	if !ok {
+		fn()
+		return
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.hooks = append(hooks.hooks, fn)
+}
@@ -0,0 +1,31 @@
+package dbctx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAfterCommit(t *testing.T) {
+	t.Run("hooks run when the transaction commits", func(t *testing.T) {
+		ctx, runHooks := WithAfterCommit(context.Background())
+		var calls []int
+		AfterCommit(ctx, func() { calls = append(calls, 1) })
+		AfterCommit(ctx, func() { calls = append(calls, 2) })
+		require.Empty(t, calls)
+
+		runHooks()
+		require.Equal(t, []int{1, 2}, calls)
+
+		// hooks run once
+		runHooks()
+		require.Equal(t, []int{1, 2}, calls)
+	})
+
+	t.Run("without a transaction hooks run right away", func(t *testing.T) {
+		called := false
+		AfterCommit(context.Background(), func() { called = true })
+		require.True(t, called)
+	})
+}
@@ -0,0 +1,221 @@
+// Package jwtkeys signs and verifies the API's JWT access tokens.
+//
+// Tokens are signed with asymmetric keys (RS256 or EdDSA) identified by the "kid" header, so other services can
+// verify them with the public keys published at /.well-known/jwks.json, without sharing any secret.
+//
+// Keys are rotated on a schedule: each key has an activation time, and the most recently activated key signs new
+// tokens. Keys that are not active yet and keys that no longer sign are still published and accepted, so a new key
+// can be rolled out ahead of its activation, and tokens signed with the previous key stay valid until they expire.
+//
+// Without any configured key, tokens are signed with HS256 and the shared secret, e.g. for local development.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/llkhacquan/cisab/pkg/config"
+	"github.com/pkg/errors"
+)
+
+// Supported signing algorithms, as in the "alg" header of a token
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Key is a key used to sign or verify tokens
+type Key struct {
+	ID         string            // the "kid" header of the tokens it signs
+	Method     jwt.SigningMethod // RS256 or EdDSA
+	PrivateKey crypto.Signer     // nil for keys that only verify tokens
+	PublicKey  crypto.PublicKey
+	ActiveFrom time.Time // the key signs tokens from this time on, until a newer key is active
+}
+
+// KeySet holds the keys used to sign and verify tokens. It is safe for concurrent use.
+type KeySet struct {
+	keys       []Key
+	hmacSecret []byte
+	now        func() time.Time
+}
+
+// New creates a KeySet. hmacSecret is only used when keys is empty.
+func New(hmacSecret string, keys ...Key) (*KeySet, error) {
+	ids := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("key ID is required")
+		}
+		if ids[key.ID] {
+			return nil, errors.Errorf("duplicate key ID %s", key.ID)
+		}
+		ids[key.ID] = true
+		if err := checkKeyType(key); err != nil {
+			return nil, errors.Wrapf(err, "invalid key %s", key.ID)
+		}
+	}
+	if len(keys) == 0 && hmacSecret == "" {
+		return nil, errors.New("either signing keys or a secret are required")
+	}
+	return &KeySet{
+		keys:       keys,
+		hmacSecret: []byte(hmacSecret),
+		now:        time.Now,
+	}, nil
+}
+
+// Load creates a KeySet from the keys configured in cfg, reading their PEM files
+func Load(cfg config.JWTConfig) (*KeySet, error) {
+	keys := make([]Key, 0, len(cfg.Keys))
+	for _, keyConfig := range cfg.Keys {
+		key, err := loadKey(keyConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load key %s", keyConfig.ID)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 {
+		// the secret must not be accepted once the keys are configured, whoever knows it could forge tokens
+		return New("", keys...)
+	}
+	return New(cfg.Secret)
+}
+
+// Sign signs the claims with the current signing key
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if len(ks.keys) == 0 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.hmacSecret)
+	}
+
+	key, ok := ks.signingKey()
+	if !ok {
+		return "", errors.New("no active signing key")
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// Keyfunc returns the key to verify a token with, by its "kid" header. It is a jwt.Keyfunc.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if len(ks.keys) == 0 {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return ks.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range ks.keys {
+		if key.ID != kid {
+			continue
+		}
+		// Never let the token choose the algorithm, e.g. HS256 with the public key as the secret
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.PublicKey, nil
+	}
+	return nil, errors.Errorf("unknown key ID %q", kid)
+}
+
+// signingKey returns the key with a private key that was activated last
+func (ks *KeySet) signingKey() (Key, bool) {
+	now := ks.now()
+	var current Key
+	found := false
+	for _, key := range ks.keys {
+		if key.PrivateKey == nil || key.ActiveFrom.After(now) {
+			continue
+		}
+		if !found || !key.ActiveFrom.Before(current.ActiveFrom) {
+			current, found = key, true
+		}
+	}
+	return current, found
+}
+
+func loadKey(cfg config.JWTKeyConfig) (Key, error) {
+	key := Key{
+		ID:         cfg.ID,
+		ActiveFrom: cfg.ActiveFrom,
+	}
+	switch cfg.Algorithm {
+	case AlgorithmRS256:
+		key.Method = jwt.SigningMethodRS256
+	case AlgorithmEdDSA:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return Key{}, errors.Errorf("unsupported algorithm %q, expected %s or %s", cfg.Algorithm, AlgorithmRS256, AlgorithmEdDSA)
+	}
+
+	switch {
+	case cfg.PrivateKeyFile != "":
+		data, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return Key{}, errors.Wrap(err, "failed to read private key file")
+		}
+		if key.PrivateKey, err = parsePrivateKey(key.Method, data); err != nil {
+			return Key{}, err
+		}
+		key.PublicKey = key.PrivateKey.Public()
+	case cfg.PublicKeyFile != "":
+		data, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return Key{}, errors.Wrap(err, "failed to read public key file")
+		}
+		if key.PublicKey, err = parsePublicKey(key.Method, data); err != nil {
+			return Key{}, err
+		}
+	default:
+		return Key{}, errors.New("either private_key_file or public_key_file is required")
+	}
+	return key, nil
+}
+
+func parsePrivateKey(method jwt.SigningMethod, data []byte) (crypto.Signer, error) {
+	if method == jwt.SigningMethodRS256 {
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		return key, errors.Wrap(err, "failed to parse RSA private key")
+	}
+	key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse Ed25519 private key")
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("failed to parse Ed25519 private key")
+	}
+	return signer, nil
+}
+
+func parsePublicKey(method jwt.SigningMethod, data []byte) (crypto.PublicKey, error) {
+	if method == jwt.SigningMethodRS256 {
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		return key, errors.Wrap(err, "failed to parse RSA public key")
+	}
+	key, err := jwt.ParseEdPublicKeyFromPEM(data)
+	return key, errors.Wrap(err, "failed to parse Ed25519 public key")
+}
+
+// checkKeyType checks that the keys match the signing method
+func checkKeyType(key Key) error {
+	switch key.Method {
+	case jwt.SigningMethodRS256:
+		if _, ok := key.PublicKey.(*rsa.PublicKey); !ok {
+			return errors.New("RS256 requires an RSA key")
+		}
+	case jwt.SigningMethodEdDSA:
+		if _, ok := key.PublicKey.(ed25519.PublicKey); !ok {
+			return errors.New("EdDSA requires an Ed25519 key")
+		}
+	default:
+		return errors.Errorf("unsupported signing method %v", key.Method)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/llkhacquan/cisab/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+// writePrivateKey writes key as a PKCS #8 PEM file, like `openssl genpkey` does, and returns its path
+func writePrivateKey(t *testing.T, name string, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name+".pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+// writePublicKey writes key as a PKIX PEM file and returns its path
+func writePublicKey(t *testing.T, name string, key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name+".pub.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func verify(ks *KeySet, token string) (jwt.MapClaims, error) {
+	parsed, err := jwt.Parse(token, ks.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Claims.(jwt.MapClaims), nil
+}
+
+func TestKeySet_rotation(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	rotation := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	ks, err := Load(config.JWTConfig{
+		Secret: "ignored-once-keys-are-configured",
+		Keys: []config.JWTKeyConfig{
+			{ID: "2026-10", Algorithm: AlgorithmEdDSA, PrivateKeyFile: writePrivateKey(t, "ed", edKey)},
+			{ID: "2026-11", Algorithm: AlgorithmRS256, PrivateKeyFile: writePrivateKey(t, "rsa", rsaKey), ActiveFrom: rotation},
+		},
+	})
+	require.NoError(t, err)
+
+	// Before the rotation, the first key signs
+	ks.now = func() time.Time { return rotation.Add(-time.Hour) }
+	before, err := ks.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+	parsed, _, err := new(jwt.Parser).ParseUnverified(before, jwt.MapClaims{})
+	require.NoError(t, err)
+	require.Equal(t, "2026-10", parsed.Header["kid"])
+	require.Equal(t, AlgorithmEdDSA, parsed.Method.Alg())
+
+	// After the rotation, the second key signs, and tokens of the first key are still accepted
+	ks.now = func() time.Time { return rotation.Add(time.Hour) }
+	after, err := ks.Sign(jwt.MapClaims{"user_id": 2})
+	require.NoError(t, err)
+	parsed, _, err = new(jwt.Parser).ParseUnverified(after, jwt.MapClaims{})
+	require.NoError(t, err)
+	require.Equal(t, "2026-11", parsed.Header["kid"])
+	require.Equal(t, AlgorithmRS256, parsed.Method.Alg())
+
+	for token, userID := range map[string]float64{before: 1, after: 2} {
+		claims, err := verify(ks, token)
+		require.NoError(t, err)
+		require.Equal(t, userID, claims["user_id"])
+	}
+
+	t.Run("both keys are published", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		Handler(ks)(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var jwks JWKS
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&jwks))
+		require.Len(t, jwks.Keys, 2)
+		require.Equal(t, JWK{KeyType: "OKP", KeyID: "2026-10", Use: "sig", Algorithm: "EdDSA", Curve: "Ed25519",
+			X: jwks.Keys[0].X}, jwks.Keys[0])
+		require.Equal(t, "RSA", jwks.Keys[1].KeyType)
+		require.Equal(t, "AQAB", jwks.Keys[1].E)
+	})
+
+	t.Run("the secret is not accepted", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1}).
+			SignedString([]byte("ignored-once-keys-are-configured"))
+		require.NoError(t, err)
+		_, err = verify(ks, token)
+		require.Error(t, err)
+	})
+
+	t.Run("the algorithm must match the key", func(t *testing.T) {
+		// HS256 with the public key as the secret, the classic algorithm confusion attack
+		publicKey, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		require.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1})
+		token.Header["kid"] = "2026-11"
+		signed, err := token.SignedString(publicKey)
+		require.NoError(t, err)
+		_, err = verify(ks, signed)
+		require.Error(t, err)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"user_id": 1})
+		token.Header["kid"] = "2026-10"
+		signed, err := token.SignedString(otherKey)
+		require.NoError(t, err)
+		_, err = verify(ks, signed)
+		require.Error(t, err)
+
+		token.Header["kid"] = "unknown"
+		signed, err = token.SignedString(otherKey)
+		require.NoError(t, err)
+		_, err = verify(ks, signed)
+		require.Error(t, err)
+	})
+}
+
+func TestKeySet_verifyOnlyKeys(t *testing.T) {
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ks, err := Load(config.JWTConfig{
+		Keys: []config.JWTKeyConfig{
+			{ID: "retired", Algorithm: AlgorithmEdDSA, PublicKeyFile: writePublicKey(t, "retired", edPublic)},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = ks.Sign(jwt.MapClaims{"user_id": 1})
+	require.Error(t, err, "a key without its private key cannot sign")
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"user_id": 1})
+	token.Header["kid"] = "retired"
+	signed, err := token.SignedString(edKey)
+	require.NoError(t, err)
+	_, err = verify(ks, signed)
+	require.NoError(t, err)
+}
+
+func TestKeySet_secretFallback(t *testing.T) {
+	ks, err := Load(config.JWTConfig{Secret: "development-secret"})
+	require.NoError(t, err)
+
+	token, err := ks.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+	_, err = verify(ks, token)
+	require.NoError(t, err)
+	require.Empty(t, ks.JWKS().Keys)
+
+	other, err := New("another-secret")
+	require.NoError(t, err)
+	_, err = verify(other, token)
+	require.Error(t, err)
+}
+
+func TestLoad_errors(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edPath := writePrivateKey(t, "ed", edKey)
+
+	tests := []struct {
+		name string
+		cfg  config.JWTConfig
+	}{
+		{name: "no key and no secret", cfg: config.JWTConfig{}},
+		{name: "unsupported algorithm", cfg: config.JWTConfig{Keys: []config.JWTKeyConfig{
+			{ID: "a", Algorithm: "HS256", PrivateKeyFile: edPath},
+		}}},
+		{name: "algorithm does not match the key", cfg: config.JWTConfig{Keys: []config.JWTKeyConfig{
+			{ID: "a", Algorithm: AlgorithmRS256, PrivateKeyFile: edPath},
+		}}},
+		{name: "duplicate key ID", cfg: config.JWTConfig{Keys: []config.JWTKeyConfig{
+			{ID: "a", Algorithm: AlgorithmEdDSA, PrivateKeyFile: edPath},
+			{ID: "a", Algorithm: AlgorithmEdDSA, PrivateKeyFile: edPath},
+		}}},
+		{name: "missing key file", cfg: config.JWTConfig{Keys: []config.JWTKeyConfig{
+			{ID: "a", Algorithm: AlgorithmEdDSA, PrivateKeyFile: filepath.Join(t.TempDir(), "missing.pem")},
+		}}},
+		{name: "no key file", cfg: config.JWTConfig{Keys: []config.JWTKeyConfig{
+			{ID: "a", Algorithm: AlgorithmEdDSA},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.cfg)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,224 @@
+// Package lifecycle runs the server process: the HTTP server, background workers and shutdown hooks.
+//
+// On SIGINT or SIGTERM it stops accepting connections and waits for in-flight requests to finish,
+// up to a grace period. Requests still running after the grace period get their context canceled,
+// which rolls back their database transaction, and their connections are closed. Then the background
+// workers are stopped and the shutdown hooks (e.g. closing the database pool) run in reverse order.
+package lifecycle
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/pkg/errors"
+)
+
+// forcedCloseTimeout is how long requests canceled after the grace period get to roll back before the hooks run
+const forcedCloseTimeout = 5 * time.Second
+
+// Hook is a function run on shutdown. Its context expires after the grace period.
+type Hook func(ctx context.Context) error
+
+// Worker is a long-running background function. Its context is canceled when shutdown starts,
+// and it must return soon after.
+type Worker func(ctx context.Context) error
+
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+type namedWorker struct {
+	name   string
+	worker Worker
+}
+
+// Manager manages the lifecycle of the server process
+type Manager struct {
+	log         *logger.Logger
+	gracePeriod time.Duration
+
+	mu      sync.Mutex
+	hooks   []namedHook
+	workers []namedWorker
+
+	inFlight sync.WaitGroup // in-flight HTTP requests
+}
+
+// New creates a new Manager. gracePeriod is how long in-flight requests, workers and hooks get on shutdown.
+func New(log *logger.Logger, gracePeriod time.Duration) *Manager {
+	return &Manager{
+		log:         log,
+		gracePeriod: gracePeriod,
+	}
+}
+
+// OnShutdown registers a hook run on shutdown, after the HTTP server and the workers have stopped.
+// Hooks run in reverse registration order, like deferred calls, so resources registered first
+// (e.g. the database) are released last.
+func (m *Manager) OnShutdown(name string, hook Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, namedHook{name: name, hook: hook})
+}
+
+// Go registers a background worker, started by Run
+func (m *Manager) Go(name string, worker Worker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.workers = append(m.workers, namedWorker{name: name, worker: worker})
+}
+
+// GoEvery registers a background worker that runs task right away, then every interval.
+// A failed run is logged, and the task runs again at the next interval.
+func (m *Manager) GoEvery(name string, interval time.Duration, task func(ctx context.Context) error) {
+	m.Go(name, func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := task(ctx); err != nil && ctx.Err() == nil {
+				m.log.Error("background task failed", "worker", name, "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Run starts the HTTP server and the workers, and blocks until the process receives SIGINT or SIGTERM,
+// ctx is canceled, or the server fails. It then shuts everything down and returns the first error.
+func (m *Manager) Run(ctx context.Context, server *http.Server) error {
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen")
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return m.serve(ctx, server, ln)
+}
+
+func (m *Manager) serve(ctx context.Context, server *http.Server, ln net.Listener) error {
+	// Requests derive their context from requestsCtx, so it can cancel the ones outliving the grace period
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server.BaseContext = func(net.Listener) context.Context { return requestsCtx }
+	server.Handler = m.trackRequests(server.Handler)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	workersDone := m.startWorkers(workersCtx)
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		m.log.Info("shutdown started", "grace_period", m.gracePeriod.String())
+	case err := <-serverErr:
+		runErr = errors.Wrap(err, "server failed")
+		m.log.Error("server failed, shutting down", "error", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.gracePeriod)
+	defer cancel()
+
+	// 1. Stop accepting connections and drain in-flight requests
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		m.log.Error("grace period expired, canceling in-flight requests", "error", err)
+		cancelRequests()
+		_ = server.Close()
+		if !waitTimeout(&m.inFlight, forcedCloseTimeout) {
+			m.log.Error("in-flight requests did not return after being canceled")
+		}
+	}
+
+	// 2. Stop the background workers
+	stopWorkers()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		m.log.Error("background workers did not stop within the grace period")
+	}
+
+	// 3. Run the shutdown hooks, last registered first
+	m.mu.Lock()
+	hooks := append([]namedHook(nil), m.hooks...)
+	m.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].hook(shutdownCtx); err != nil {
+			m.log.Error("shutdown hook failed", "hook", hooks[i].name, "error", err)
+			if runErr == nil {
+				runErr = errors.Wrapf(err, "shutdown hook %s failed", hooks[i].name)
+			}
+		}
+	}
+
+	m.log.Info("shutdown completed")
+	return runErr
+}
+
+// startWorkers runs the registered workers and returns a channel closed once all of them returned
+func (m *Manager) startWorkers(ctx context.Context) <-chan struct{} {
+	m.mu.Lock()
+	workers := append([]namedWorker(nil), m.workers...)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w namedWorker) {
+			defer wg.Done()
+			if err := w.worker(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				m.log.Error("background worker failed", "worker", w.name, "error", err)
+			}
+		}(w)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// trackRequests counts in-flight requests, so a forced shutdown can wait for them to roll back
+func (m *Manager) trackRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Add(1)
+		defer m.inFlight.Done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// waitTimeout waits for wg and reports whether it finished before the timeout
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
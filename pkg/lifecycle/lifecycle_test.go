@@ -0,0 +1,148 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer serves handler with m until the returned cancel is called; done receives serve's result
+func startServer(t *testing.T, m *Manager, handler http.Handler) (url string, cancel context.CancelFunc, done <-chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- m.serve(ctx, &http.Server{Handler: handler}, ln)
+	}()
+	return "http://" + ln.Addr().String(), cancel, result
+}
+
+func newManager(gracePeriod time.Duration) *Manager {
+	return New(logger.New(logger.Config{Output: io.Discard}), gracePeriod)
+}
+
+func TestManager_drainsInFlightRequests(t *testing.T) {
+	m := newManager(5 * time.Second)
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+	url, cancel, done := startServer(t, m, handler)
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	res := <-responses
+	require.NoError(t, res.err)
+	require.Equal(t, "done", res.body)
+	require.NoError(t, <-done)
+}
+
+func TestManager_cancelsRequestsAfterGracePeriod(t *testing.T) {
+	m := newManager(100 * time.Millisecond)
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(canceled)
+	})
+	url, cancel, done := startServer(t, m, handler)
+
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request context was not canceled after the grace period")
+	}
+	require.NoError(t, <-done)
+}
+
+func TestManager_stopsWorkersThenRunsHooksInReverseOrder(t *testing.T) {
+	m := newManager(time.Second)
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, name)
+	}
+
+	m.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		record("worker")
+		return ctx.Err()
+	})
+	m.OnShutdown("database", func(ctx context.Context) error {
+		record("database")
+		return nil
+	})
+	m.OnShutdown("cache", func(ctx context.Context) error {
+		record("cache")
+		return nil
+	})
+
+	_, cancel, done := startServer(t, m, http.NotFoundHandler())
+	cancel()
+	require.NoError(t, <-done)
+	require.Equal(t, []string{"worker", "cache", "database"}, calls)
+}
+
+func TestManager_GoEvery(t *testing.T) {
+	m := newManager(time.Second)
+
+	runs := make(chan struct{}, 10)
+	m.GoEvery("ticker", 10*time.Millisecond, func(ctx context.Context) error {
+		runs <- struct{}{}
+		return errors.New("a failed run does not stop the worker")
+	})
+
+	_, cancel, done := startServer(t, m, http.NotFoundHandler())
+	for i := 0; i < 3; i++ {
+		select {
+		case <-runs:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the task did not run again")
+		}
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
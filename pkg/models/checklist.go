@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+type ChecklistItemID int
+
+// ChecklistItem is a step of a task, that is simply done or not
+type ChecklistItem struct {
+	ID        ChecklistItemID `json:"id" gorm:"primaryKey"`
+	TaskID    TaskID          `json:"task_id" gorm:"not null;index"`
+	Title     string          `json:"title" gorm:"not null"`
+	Done      bool            `json:"done" gorm:"not null;default:false"`
+	CreatedAt time.Time       `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time       `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName specifies the database table name
+func (ChecklistItem) TableName() string {
+	return "task_checklist_items"
+}
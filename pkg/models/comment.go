@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+type CommentID int
+
+// TaskComment is a comment on a task. Comments are threaded one level deep:
+// a reply's ParentID is the first comment of its thread.
+type TaskComment struct {
+	ID        CommentID  `json:"id" gorm:"primaryKey"`
+	TaskID    TaskID     `json:"task_id" gorm:"not null;index"`
+	ParentID  *CommentID `json:"parent_id,omitempty" gorm:"index"`
+	AuthorID  UserID     `json:"author_id" gorm:"not null"`
+	Body      string     `json:"body" gorm:"type:text;not null"`
+	CreatedAt time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // deleted comments are kept with an empty body, for their replies
+
+	// Replies of the first comment of a thread, oldest first (not stored in database)
+	Replies []TaskComment `json:"replies,omitempty" gorm:"-"`
+}
+
+// TableName specifies the database table name
+func (TaskComment) TableName() string {
+	return "task_comments"
+}
+
+// IsDeleted returns true if the comment has been deleted
+func (c *TaskComment) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+type LabelID int
+
+// Label categorizes tasks. Each employer manages their own labels.
+type Label struct {
+	ID         LabelID   `json:"id" gorm:"primaryKey"`
+	EmployerID UserID    `json:"employer_id" gorm:"not null;index"`
+	Name       string    `json:"name" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName specifies the database table name
+func (Label) TableName() string {
+	return "labels"
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type OutboxEventID int64
+
+// DomainEventType is a kind of change of the domain, published to the subscribers of the outbox
+type DomainEventType string
+
+const (
+	// DomainEventTaskCreated is recorded when a task is created, including the tasks of the recurring series
+	DomainEventTaskCreated DomainEventType = "task.created"
+	// DomainEventTaskAssigned is recorded when a task is assigned, including when it is created with an assignee
+	DomainEventTaskAssigned DomainEventType = "task.assigned"
+	// DomainEventTaskStatusChanged is recorded when the status of a task changes
+	DomainEventTaskStatusChanged DomainEventType = "task.status_changed"
+	// DomainEventTaskCompleted is recorded when a task moves to a terminal status of the workflow,
+	// after its DomainEventTaskStatusChanged event
+	DomainEventTaskCompleted DomainEventType = "task.completed"
+	// DomainEventUserCreated is recorded when a user registers
+	DomainEventUserCreated DomainEventType = "user.created"
+	// DomainEventUserSessionsRevoked is recorded when an employer revokes all the sessions of a user
+	DomainEventUserSessionsRevoked DomainEventType = "user.sessions_revoked"
+)
+
+// OutboxEvent is a domain event, recorded in the transaction of its change and published once it commits.
+// Events can be published more than once, subscribers ignore the events whose EventID they have seen.
+type OutboxEvent struct {
+	ID         OutboxEventID   `json:"id" gorm:"primaryKey"`
+	EventID    string          `json:"event_id" gorm:"not null"`
+	Type       DomainEventType `json:"type" gorm:"not null"`
+	EmployerID *UserID         `json:"employer_id,omitempty"` // the employer whose tasks changed, nil for the user events
+	ActorID    *UserID         `json:"actor_id,omitempty"`    // the user who made the change, nil for public actions
+	Payload    json.RawMessage `json:"payload" gorm:"type:jsonb;not null"`
+	CreatedAt  time.Time       `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	// Position orders the published events, it is set when the event is published
+	Position    *int64     `json:"position,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+// TableName specifies the database table name
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// DomainEventData is the payload of a domain event: the task or the user after the change, and the changed field
+type DomainEventData struct {
+	Task   *Task              `json:"task,omitempty"`
+	User   *User              `json:"user,omitempty"`
+	Change *DomainEventChange `json:"change,omitempty"` // set for task.assigned and task.status_changed
+}
+
+// DomainEventChange is the change of a field, like the updates of the task history
+type DomainEventChange struct {
+	Field    string  `json:"field"`
+	OldValue *string `json:"old_value"`
+	NewValue *string `json:"new_value"`
+}
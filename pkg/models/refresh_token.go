@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is a long-lived token exchanged for a new access token, see POST /api/v1/token/refresh.
+// Only the hash of the token is stored, and each token can be used once: refreshing revokes it and issues a new one.
+type RefreshToken struct {
+	ID           int        `json:"id" gorm:"primaryKey"`
+	UserID       UserID     `json:"user_id" gorm:"not null;index"`
+	TokenHash    string     `json:"-" gorm:"not null;uniqueIndex"`
+	TokenVersion int        `json:"-" gorm:"not null"` // users.token_version when the token was issued
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName specifies the database table name
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// IsActive reports whether the token can still be used at the given time
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
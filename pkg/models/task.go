@@ -18,21 +18,86 @@ const (
 	TaskStatusCompleted TaskStatus = "completed"
 )
 
+// TaskPriority represents the urgency of a task
+type TaskPriority string
+
+const (
+	// TaskPriorityLow represents a task that can wait
+	TaskPriorityLow TaskPriority = "low"
+	// TaskPriorityNormal is the priority of tasks created without one
+	TaskPriorityNormal TaskPriority = "normal"
+	// TaskPriorityHigh represents a task to do before the normal ones
+	TaskPriorityHigh TaskPriority = "high"
+	// TaskPriorityUrgent represents a task to do right away
+	TaskPriorityUrgent TaskPriority = "urgent"
+)
+
+// IsValid returns true if p is one of the known priorities
+func (p TaskPriority) IsValid() bool {
+	switch p {
+	case TaskPriorityLow, TaskPriorityNormal, TaskPriorityHigh, TaskPriorityUrgent:
+		return true
+	}
+	return false
+}
+
 // Task represents a task in the system
 type Task struct {
-	ID          TaskID     `json:"id" gorm:"primaryKey"`
-	Title       string     `json:"title" gorm:"not null"`
-	Description string     `json:"description" gorm:"type:text"`
-	Status      TaskStatus `json:"status" gorm:"not null;default:'pending'"`
-	DueDate     *time.Time `json:"due_date,omitempty" gorm:"index"`
-	EmployerID  UserID     `json:"employer_id" gorm:"not null;index"`
-	AssigneeID  *UserID    `json:"assignee_id,omitempty" gorm:"index"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt   time.Time  `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+	ID          TaskID        `json:"id" gorm:"primaryKey"`
+	Title       string        `json:"title" gorm:"not null"`
+	Description string        `json:"description" gorm:"type:text"`
+	Status      TaskStatus    `json:"status" gorm:"not null;default:'pending'"`
+	Priority    TaskPriority  `json:"priority" gorm:"not null;default:'normal'"`
+	DueDate     *time.Time    `json:"due_date,omitempty" gorm:"index"`
+	EmployerID  UserID        `json:"employer_id" gorm:"not null;index"`
+	AssigneeID  *UserID       `json:"assignee_id,omitempty" gorm:"index"`
+	ParentID    *TaskID       `json:"parent_id,omitempty" gorm:"index"` // set for subtasks
+	SeriesID    *TaskSeriesID `json:"series_id,omitempty"`              // set for the tasks created by a recurring series
+	CreatedAt   time.Time     `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time     `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+	// StartedAt is when the task first got to an active status, nil until it is started. The database sets it.
+	StartedAt *time.Time `json:"started_at,omitempty" gorm:"->"`
+	// CompletedAt is when the task got to a terminal status, nil while it is not done. The database sets it.
+	CompletedAt *time.Time `json:"completed_at,omitempty" gorm:"->"`
 
 	// Define relationships (not stored in database)
 	Employer *User `json:"employer,omitempty" gorm:"foreignKey:EmployerID"`
 	Assignee *User `json:"assignee,omitempty" gorm:"foreignKey:AssigneeID"`
+	// Labels of the task, stored in the task_labels table
+	Labels []Label `json:"labels,omitempty" gorm:"-"`
+	// Progress of the subtasks, derived from them, nil for tasks without subtasks
+	Progress *TaskProgress `json:"progress,omitempty" gorm:"-"`
+	// BlockedBy lists the blockers of the task that are not completed yet, stored in the task_dependencies table
+	BlockedBy []TaskID `json:"blocked_by,omitempty" gorm:"-"`
+	// Match is how the task matches a full-text search, only set in the search results
+	Match *TaskMatch `json:"match,omitempty" gorm:"-"`
+}
+
+// TaskMatch is how a task matches a full-text search. The highlights are HTML: their text is escaped,
+// and the matched words are wrapped in <mark> tags.
+type TaskMatch struct {
+	Rank    float64 `json:"rank"`    // relevance of the task to the search, higher is better
+	Title   string  `json:"title"`   // the title, with the matched words highlighted
+	Snippet string  `json:"snippet"` // the fragments of the description around the matched words, highlighted
+}
+
+// TaskProgress is the completion of the subtasks of a task
+type TaskProgress struct {
+	Subtasks          int `json:"subtasks"`
+	CompletedSubtasks int `json:"completed_subtasks"`
+	Percent           int `json:"percent"` // completed subtasks in percent, rounded down
+}
+
+// NewTaskProgress returns the progress of a task with the given number of subtasks, completed or not
+func NewTaskProgress(subtasks, completedSubtasks int) TaskProgress {
+	progress := TaskProgress{
+		Subtasks:          subtasks,
+		CompletedSubtasks: completedSubtasks,
+	}
+	if subtasks > 0 {
+		progress.Percent = completedSubtasks * 100 / subtasks
+	}
+	return progress
 }
 
 // TableName specifies the database table name
@@ -60,6 +125,11 @@ func (t *Task) IsCompleted() bool {
 	return t.Status == TaskStatusCompleted
 }
 
+// IsBlocked returns true if the task cannot start because some of its blockers are not completed
+func (t *Task) IsBlocked() bool {
+	return len(t.BlockedBy) > 0
+}
+
 // IsOverdue returns true if the task has a due date and it has passed
 func (t *Task) IsOverdue() bool {
 	return t.DueDate != nil && time.Now().After(*t.DueDate) && !t.IsCompleted()
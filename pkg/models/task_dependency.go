@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// TaskDependency means that a task cannot start until its blocker is completed
+type TaskDependency struct {
+	TaskID    TaskID    `json:"task_id" gorm:"primaryKey"`
+	BlockerID TaskID    `json:"blocker_id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName specifies the database table name
+func (TaskDependency) TableName() string {
+	return "task_dependencies"
+}
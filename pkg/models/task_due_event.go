@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+type TaskDueEventID int64
+
+// TaskDueEventKind is why a task due date needs attention
+type TaskDueEventKind string
+
+const (
+	// TaskDueEventReminder records that a task is due soon
+	TaskDueEventReminder TaskDueEventKind = "reminder"
+	// TaskDueEventOverdue records that a task is past its due date and not done
+	TaskDueEventOverdue TaskDueEventKind = "overdue"
+)
+
+// TaskDueEvent is a reminder or an overdue notice of a task, recorded once per due date of the task
+type TaskDueEvent struct {
+	ID         TaskDueEventID   `json:"id" gorm:"primaryKey"`
+	TaskID     TaskID           `json:"task_id" gorm:"not null"`
+	Kind       TaskDueEventKind `json:"kind" gorm:"not null"`
+	DueDate    time.Time        `json:"due_date" gorm:"not null"`
+	CreatedAt  time.Time        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	NotifiedAt *time.Time       `json:"notified_at,omitempty"` // nil until the event is delivered
+}
+
+// TableName specifies the database table name
+func (TaskDueEvent) TableName() string {
+	return "task_due_events"
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+type TaskEventID int64
+
+// TaskEventType represents the kind of change recorded by a task event
+type TaskEventType string
+
+const (
+	// TaskEventCreated records the creation of a task
+	TaskEventCreated TaskEventType = "created"
+	// TaskEventUpdated records the change of one field of a task
+	TaskEventUpdated TaskEventType = "updated"
+)
+
+// Fields of a task whose changes are recorded by TaskEventUpdated events
+const (
+	TaskFieldStatus     = "status"
+	TaskFieldAssigneeID = "assignee_id"
+)
+
+// TaskEvent is an entry of the activity history of a task. Events are immutable.
+type TaskEvent struct {
+	ID        TaskEventID   `json:"id" gorm:"primaryKey"`
+	TaskID    TaskID        `json:"task_id" gorm:"not null;index"`
+	ActorID   UserID        `json:"actor_id" gorm:"not null"` // the user who made the change
+	Type      TaskEventType `json:"type" gorm:"not null"`
+	Field     string        `json:"field,omitempty"`     // set for TaskEventUpdated events
+	OldValue  *string       `json:"old_value,omitempty"` // nil when the field had no value, e.g. an unassigned task
+	NewValue  *string       `json:"new_value,omitempty"`
+	CreatedAt time.Time     `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName specifies the database table name
+func (TaskEvent) TableName() string {
+	return "task_events"
+}
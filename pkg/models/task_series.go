@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+type TaskSeriesID int
+
+// TaskSeriesState represents whether a series still creates tasks
+type TaskSeriesState string
+
+const (
+	// TaskSeriesStateActive represents a series that creates its upcoming tasks
+	TaskSeriesStateActive TaskSeriesState = "active"
+	// TaskSeriesStatePaused represents a series that creates no task until it is resumed
+	TaskSeriesStatePaused TaskSeriesState = "paused"
+	// TaskSeriesStateEnded represents a series that will never create a task again
+	TaskSeriesStateEnded TaskSeriesState = "ended"
+)
+
+// TaskSeries is the template of a recurring task. Its tasks are created ahead of time, one for each occurrence of
+// its recurrence rule, with the occurrence as due date.
+type TaskSeries struct {
+	ID          TaskSeriesID `json:"id" gorm:"primaryKey"`
+	EmployerID  UserID       `json:"employer_id" gorm:"not null;index"`
+	Title       string       `json:"title" gorm:"not null"`
+	Description string       `json:"description" gorm:"type:text"`
+	AssigneeID  *UserID      `json:"assignee_id,omitempty"`
+	Priority    TaskPriority `json:"priority" gorm:"not null;default:'normal'"`
+	// RRule is the recurrence rule, e.g. "FREQ=WEEKLY;BYDAY=MO", see package rrule
+	RRule string `json:"rrule" gorm:"column:rrule;not null"`
+	// Timezone is the IANA time zone the rule is computed in, e.g. "Europe/Paris"
+	Timezone string `json:"timezone" gorm:"not null;default:'UTC'"`
+	// StartsAt is the start of the rule, its occurrences have the same time of day
+	StartsAt time.Time       `json:"starts_at" gorm:"not null"`
+	State    TaskSeriesState `json:"state" gorm:"not null;default:'active'"`
+	// GeneratedUntil is the time the tasks of the series were created up to, occurrences after it have no task yet
+	GeneratedUntil time.Time `json:"generated_until" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName specifies the database table name
+func (TaskSeries) TableName() string {
+	return "task_series"
+}
+
+// IsActive returns true if the series creates its upcoming tasks
+func (s *TaskSeries) IsActive() bool {
+	return s.State == TaskSeriesStateActive
+}
@@ -23,6 +23,7 @@ type User struct {
 	PasswordHash string    `json:"-" gorm:"not null"`
 	Name         string    `json:"name" gorm:"not null"`
 	Role         UserRole  `json:"role" gorm:"type:user_role;not null"`
+	TokenVersion int       `json:"-" gorm:"not null;default:0"` // bumped to revoke all the tokens of the user
 	CreatedAt    time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
 }
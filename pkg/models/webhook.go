@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookID int
+
+type WebhookDeliveryID int64
+
+// WebhookEventType is a change of a task that webhooks can subscribe to
+type WebhookEventType string
+
+const (
+	// WebhookEventTaskCreated is sent when a task is created
+	WebhookEventTaskCreated WebhookEventType = "task.created"
+	// WebhookEventTaskAssigned is sent when a task is assigned, including when it is created with an assignee
+	WebhookEventTaskAssigned WebhookEventType = "task.assigned"
+	// WebhookEventTaskCompleted is sent when a task moves to a terminal status of the workflow
+	WebhookEventTaskCompleted WebhookEventType = "task.completed"
+)
+
+// IsValid checks if the event type is one of the known types
+func (e WebhookEventType) IsValid() bool {
+	switch e {
+	case WebhookEventTaskCreated, WebhookEventTaskAssigned, WebhookEventTaskCompleted:
+		return true
+	}
+	return false
+}
+
+// Webhook is an endpoint of an employer that receives the events of its tasks, signed with its secret
+type Webhook struct {
+	ID         WebhookID          `json:"id" gorm:"primaryKey"`
+	EmployerID UserID             `json:"employer_id" gorm:"not null;index"`
+	URL        string             `json:"url" gorm:"column:url;not null"`
+	Events     []WebhookEventType `json:"events" gorm:"serializer:json;not null"`
+	// Secret signs the deliveries, it is only returned when the webhook is created
+	Secret    string    `json:"secret,omitempty" gorm:"not null"`
+	Active    bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName specifies the database table name
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// WebhookDeliveryStatus represents where a delivery is in its attempts
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending represents a delivery waiting for its first attempt or for a retry
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded represents a delivery the endpoint accepted with a 2xx response
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryFailed represents a delivery that failed all its attempts
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is the sending of an event to a webhook, with the outcome of its last attempt.
+// A redelivery is a new delivery of the same event.
+type WebhookDelivery struct {
+	ID        WebhookDeliveryID `json:"id" gorm:"primaryKey"`
+	WebhookID WebhookID         `json:"webhook_id" gorm:"not null"`
+	// EventID identifies the event, it is the same for the redeliveries so receivers can ignore duplicates
+	EventID       string                `json:"event_id" gorm:"not null"`
+	Event         WebhookEventType      `json:"event" gorm:"not null"`
+	Payload       json.RawMessage       `json:"payload" gorm:"type:jsonb;not null"`
+	Status        WebhookDeliveryStatus `json:"status" gorm:"not null;default:'pending'"`
+	Attempts      int                   `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt time.Time             `json:"next_attempt_at" gorm:"not null"`
+	// ResponseStatus is the HTTP status of the last attempt, nil if it got no response
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// TableName specifies the database table name
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type WorkflowID int
+
+// Workflow defines the statuses of the tasks of an employer, and who can move a task from one status to another.
+// Employers without a workflow of their own use the default workflow, see DefaultWorkflow.
+type Workflow struct {
+	ID         WorkflowID `json:"id"`
+	EmployerID *UserID    `json:"employer_id"` // nil for the default workflow
+	// Statuses in display order, the first one is the status of new tasks
+	Statuses    []WorkflowStatus     `json:"statuses"`
+	Transitions []WorkflowTransition `json:"transitions"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// WorkflowStatus is a status of a workflow.
+// Tasks in an active status are being worked on, tasks in a terminal status are done.
+// A status that is neither, like "pending", is waiting for work to start.
+type WorkflowStatus struct {
+	Name     TaskStatus `json:"name"`
+	Active   bool       `json:"active"`
+	Terminal bool       `json:"terminal"`
+}
+
+// WorkflowTransition allows users of the given roles to move a task from a status to another
+type WorkflowTransition struct {
+	From  TaskStatus `json:"from"`
+	To    TaskStatus `json:"to"`
+	Roles []UserRole `json:"roles"`
+}
+
+// DefaultWorkflow returns the workflow of the employers that did not configure one.
+// It is the same as the default workflow stored by the migrations.
+func DefaultWorkflow() Workflow {
+	both := []UserRole{UserRoleEmployer, UserRoleEmployee}
+	return Workflow{
+		Statuses: []WorkflowStatus{
+			{Name: TaskStatusPending},
+			{Name: TaskStatusInProgress, Active: true},
+			{Name: TaskStatusCompleted, Terminal: true},
+		},
+		Transitions: []WorkflowTransition{
+			{From: TaskStatusPending, To: TaskStatusInProgress, Roles: both},
+			{From: TaskStatusPending, To: TaskStatusCompleted, Roles: both},
+			{From: TaskStatusInProgress, To: TaskStatusPending, Roles: both},
+			{From: TaskStatusInProgress, To: TaskStatusCompleted, Roles: both},
+			// only the employer can reopen a completed task
+			{From: TaskStatusCompleted, To: TaskStatusPending, Roles: []UserRole{UserRoleEmployer}},
+			{From: TaskStatusCompleted, To: TaskStatusInProgress, Roles: []UserRole{UserRoleEmployer}},
+		},
+	}
+}
+
+// InitialStatus returns the status of new tasks
+func (w *Workflow) InitialStatus() TaskStatus {
+	if len(w.Statuses) == 0 {
+		return ""
+	}
+	return w.Statuses[0].Name
+}
+
+// Status returns the status with the given name, and false if the workflow has no such status
+func (w *Workflow) Status(name TaskStatus) (WorkflowStatus, bool) {
+	for _, status := range w.Statuses {
+		if status.Name == name {
+			return status, true
+		}
+	}
+	return WorkflowStatus{}, false
+}
+
+// IsTerminal returns true if name is a terminal status of the workflow
+func (w *Workflow) IsTerminal(name TaskStatus) bool {
+	status, ok := w.Status(name)
+	return ok && status.Terminal
+}
+
+// CanTransition returns true if users of the role can move a task from a status to another
+func (w *Workflow) CanTransition(from, to TaskStatus, role UserRole) bool {
+	for _, transition := range w.Transitions {
+		if transition.From != from || transition.To != to {
+			continue
+		}
+		for _, r := range transition.Roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// statusNameRegexp matches the status names, that fit the status column of the tasks
+var statusNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]{0,19}$`)
+
+// Validate checks that the workflow is consistent: its statuses are unique and well named,
+// and its transitions connect two different statuses of the workflow.
+func (w *Workflow) Validate() error {
+	if len(w.Statuses) == 0 {
+		return errors.New("a workflow needs at least one status")
+	}
+	names := make(map[TaskStatus]bool, len(w.Statuses))
+	for _, status := range w.Statuses {
+		if !statusNameRegexp.MatchString(string(status.Name)) {
+			return errors.Errorf("invalid status name %q: use lowercase letters, digits and underscores, at most 20 characters", status.Name)
+		}
+		if names[status.Name] {
+			return errors.Errorf("duplicate status %q", status.Name)
+		}
+		if status.Active && status.Terminal {
+			return errors.Errorf("status %q cannot be both active and terminal", status.Name)
+		}
+		names[status.Name] = true
+	}
+
+	type edge struct{ from, to TaskStatus }
+	edges := make(map[edge]bool, len(w.Transitions))
+	for _, transition := range w.Transitions {
+		if !names[transition.From] || !names[transition.To] {
+			return errors.Errorf("transition from %q to %q uses an unknown status", transition.From, transition.To)
+		}
+		if transition.From == transition.To {
+			return errors.Errorf("transition from %q to itself", transition.From)
+		}
+		if edges[edge{transition.From, transition.To}] {
+			return errors.Errorf("duplicate transition from %q to %q", transition.From, transition.To)
+		}
+		edges[edge{transition.From, transition.To}] = true
+		if len(transition.Roles) == 0 {
+			return errors.Errorf("transition from %q to %q has no role", transition.From, transition.To)
+		}
+		for _, role := range transition.Roles {
+			if role != UserRoleEmployer && role != UserRoleEmployee {
+				return errors.Errorf("transition from %q to %q has an unknown role %q", transition.From, transition.To, role)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflow_CanTransition(t *testing.T) {
+	workflow := DefaultWorkflow()
+	require.NoError(t, workflow.Validate())
+	require.Equal(t, TaskStatusPending, workflow.InitialStatus())
+	require.True(t, workflow.IsTerminal(TaskStatusCompleted))
+	require.False(t, workflow.IsTerminal(TaskStatusInProgress))
+
+	require.True(t, workflow.CanTransition(TaskStatusPending, TaskStatusInProgress, UserRoleEmployee))
+	require.True(t, workflow.CanTransition(TaskStatusCompleted, TaskStatusPending, UserRoleEmployer))
+	require.False(t, workflow.CanTransition(TaskStatusCompleted, TaskStatusPending, UserRoleEmployee))
+	require.False(t, workflow.CanTransition(TaskStatusPending, "in_review", UserRoleEmployer))
+}
+
+func TestWorkflow_Validate(t *testing.T) {
+	statuses := []WorkflowStatus{{Name: "todo"}, {Name: "done", Terminal: true}}
+	tests := []struct {
+		name     string
+		workflow Workflow
+	}{
+		{name: "no status", workflow: Workflow{}},
+		{name: "invalid name", workflow: Workflow{Statuses: []WorkflowStatus{{Name: "In Review"}}}},
+		{name: "name too long", workflow: Workflow{Statuses: []WorkflowStatus{{Name: "waiting_for_the_customer"}}}},
+		{name: "duplicate status", workflow: Workflow{Statuses: []WorkflowStatus{{Name: "todo"}, {Name: "todo"}}}},
+		{name: "active and terminal", workflow: Workflow{Statuses: []WorkflowStatus{{Name: "done", Active: true, Terminal: true}}}},
+		{name: "unknown status", workflow: Workflow{Statuses: statuses, Transitions: []WorkflowTransition{
+			{From: "todo", To: "doing", Roles: []UserRole{UserRoleEmployee}},
+		}}},
+		{name: "transition to itself", workflow: Workflow{Statuses: statuses, Transitions: []WorkflowTransition{
+			{From: "todo", To: "todo", Roles: []UserRole{UserRoleEmployee}},
+		}}},
+		{name: "duplicate transition", workflow: Workflow{Statuses: statuses, Transitions: []WorkflowTransition{
+			{From: "todo", To: "done", Roles: []UserRole{UserRoleEmployee}},
+			{From: "todo", To: "done", Roles: []UserRole{UserRoleEmployer}},
+		}}},
+		{name: "no role", workflow: Workflow{Statuses: statuses, Transitions: []WorkflowTransition{
+			{From: "todo", To: "done"},
+		}}},
+		{name: "unknown role", workflow: Workflow{Statuses: statuses, Transitions: []WorkflowTransition{
+			{From: "todo", To: "done", Roles: []UserRole{"admin"}},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.workflow.Validate())
+		})
+	}
+}
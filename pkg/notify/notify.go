@@ -0,0 +1,67 @@
+// Package notify delivers the reminders and overdue notices of the tasks to their employer and assignee.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+)
+
+// Notification tells users that a task is due soon, or overdue
+type Notification struct {
+	Kind       models.TaskDueEventKind
+	Task       models.Task
+	Recipients []models.User
+}
+
+// Subject returns a one-line summary of the notification
+func (n Notification) Subject() string {
+	if n.Kind == models.TaskDueEventOverdue {
+		return fmt.Sprintf("Overdue: %s", n.Task.Title)
+	}
+	return fmt.Sprintf("Reminder: %s is due soon", n.Task.Title)
+}
+
+// Body returns the text of the notification
+func (n Notification) Body() string {
+	dueDate := "without a due date"
+	if n.Task.DueDate != nil {
+		dueDate = n.Task.DueDate.UTC().Format(time.RFC1123)
+	}
+	if n.Kind == models.TaskDueEventOverdue {
+		return fmt.Sprintf("The task #%d %q was due on %s and is not done yet.\n", n.Task.ID, n.Task.Title, dueDate)
+	}
+	return fmt.Sprintf("The task #%d %q is due on %s.\n", n.Task.ID, n.Task.Title, dueDate)
+}
+
+// Notifier delivers notifications. Notify returns an error if the notification could not be delivered,
+// the scheduler tries again later.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+var _ Notifier = (*LogNotifier)(nil)
+
+// LogNotifier logs the notifications, it is used when no other notifier is configured
+type LogNotifier struct {
+	logger *logger.Logger
+}
+
+// NewLogNotifier creates a notifier that logs the notifications
+func NewLogNotifier(logger *logger.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Notify logs the notification
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	recipients := make([]string, 0, len(notification.Recipients))
+	for _, recipient := range notification.Recipients {
+		recipients = append(recipients, recipient.Email)
+	}
+	n.logger.InfoContext(ctx, notification.Subject(),
+		"kind", notification.Kind, "task_id", notification.Task.ID, "recipients", recipients)
+	return nil
+}
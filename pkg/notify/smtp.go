@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/config"
+	"github.com/pkg/errors"
+)
+
+// smtpTimeout bounds the delivery of an email, from the connection to the end of the session
+const smtpTimeout = 30 * time.Second
+
+var _ Notifier = (*SMTPNotifier)(nil)
+
+// SMTPNotifier sends the notifications by email, one email per notification to all its recipients.
+// It upgrades the connection with STARTTLS when the server supports it.
+type SMTPNotifier struct {
+	config config.SMTPConfig
+}
+
+// NewSMTPNotifier creates a notifier that sends emails through the given SMTP server
+func NewSMTPNotifier(config config.SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{config: config}
+}
+
+// Notify sends the notification by email
+func (n *SMTPNotifier) Notify(ctx context.Context, notification Notification) error {
+	to := make([]string, 0, len(notification.Recipients))
+	for _, recipient := range notification.Recipients {
+		to = append(to, recipient.Email)
+	}
+	if len(to) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port)))
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the SMTP server")
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "failed to set the SMTP deadline")
+	}
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "failed to start the SMTP session")
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.config.Host}); err != nil {
+			return errors.Wrap(err, "failed to start TLS")
+		}
+	}
+	if n.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)); err != nil {
+			return errors.Wrap(err, "failed to authenticate to the SMTP server")
+		}
+	}
+	if err := client.Mail(n.config.From); err != nil {
+		return errors.Wrap(err, "failed to set the sender")
+	}
+	for _, address := range to {
+		if err := client.Rcpt(address); err != nil {
+			return errors.Wrapf(err, "failed to add the recipient %s", address)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "failed to start the email")
+	}
+	if _, err := writer.Write(n.message(notification, to)); err != nil {
+		return errors.Wrap(err, "failed to write the email")
+	}
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, "failed to send the email")
+	}
+	return client.Quit()
+}
+
+// message returns the email of a notification, in the format of RFC 5322
+func (n *SMTPNotifier) message(notification Notification, to []string) []byte {
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Subject()))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(notification.Body(), "\n", "\r\n"))
+	return message.Bytes()
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/config"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmail is an email received by the fake SMTP server
+type fakeEmail struct {
+	from string
+	to   []string
+	data string
+}
+
+// startFakeSMTPServer starts an SMTP server on a local port that accepts every email, and sends them to the channel.
+// It rejects the recipients in reject.
+func startFakeSMTPServer(t *testing.T, reject string) (config.SMTPConfig, <-chan fakeEmail) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	emails := make(chan fakeEmail, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeSMTP(conn, reject, emails)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return config.SMTPConfig{Host: "127.0.0.1", Port: addr.Port, From: "tasks@example.com"}, emails
+}
+
+func serveFakeSMTP(conn net.Conn, reject string, emails chan<- fakeEmail) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost fake SMTP")
+	var email fakeEmail
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimSpace(line)
+		switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			email = fakeEmail{from: strings.Trim(strings.TrimPrefix(command, "MAIL FROM:"), "<>")}
+			reply("250 OK")
+		case "RCPT":
+			address := strings.Trim(strings.TrimPrefix(command, "RCPT TO:"), "<>")
+			if address == reject {
+				reply("550 no such user")
+				continue
+			}
+			email.to = append(email.to, address)
+			reply("250 OK")
+		case "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			email.data = data.String()
+			emails <- email
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	ctx := context.Background()
+	dueDate := time.Date(2026, 1, 14, 9, 30, 0, 0, time.UTC)
+	notification := Notification{
+		Kind: models.TaskDueEventOverdue,
+		Task: models.Task{ID: 42, Title: "Inspection", DueDate: &dueDate},
+		Recipients: []models.User{
+			{Email: "employee@example.com"},
+			{Email: "employer@example.com"},
+		},
+	}
+
+	t.Run("send", func(t *testing.T) {
+		smtpConfig, emails := startFakeSMTPServer(t, "")
+		require.NoError(t, NewSMTPNotifier(smtpConfig).Notify(ctx, notification))
+
+		email := <-emails
+		require.Equal(t, "tasks@example.com", email.from)
+		require.Equal(t, []string{"employee@example.com", "employer@example.com"}, email.to)
+		require.Contains(t, email.data, "Subject: Overdue: Inspection\r\n")
+		require.Contains(t, email.data, "To: employee@example.com, employer@example.com\r\n")
+		require.Contains(t, email.data, "\r\n\r\nThe task #42 \"Inspection\" was due on Wed, 14 Jan 2026 09:30:00 UTC and is not done yet.\r\n")
+	})
+
+	t.Run("rejected recipient", func(t *testing.T) {
+		smtpConfig, _ := startFakeSMTPServer(t, "employer@example.com")
+		err := NewSMTPNotifier(smtpConfig).Notify(ctx, notification)
+		require.ErrorContains(t, err, "employer@example.com")
+	})
+
+	t.Run("server down", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		port := listener.Addr().(*net.TCPAddr).Port
+		require.NoError(t, listener.Close())
+
+		smtpConfig := config.SMTPConfig{Host: "127.0.0.1", Port: port, From: "tasks@example.com"}
+		require.Error(t, NewSMTPNotifier(smtpConfig).Notify(ctx, notification))
+	})
+
+	t.Run("no recipients", func(t *testing.T) {
+		smtpConfig := config.SMTPConfig{Host: "127.0.0.1", Port: 1, From: "tasks@example.com"}
+		require.NoError(t, NewSMTPNotifier(smtpConfig).Notify(ctx, Notification{Kind: models.TaskDueEventReminder}))
+	})
+}
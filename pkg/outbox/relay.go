@@ -0,0 +1,167 @@
+// Package outbox publishes the domain events of the services to in-process subscribers.
+//
+// Services record the events with Relay.Record, in the transaction of their change: an event exists if and only if its
+// change is committed. The relay publishes the committed events in order, at least once: an event whose publication
+// fails is published again, to all the subscribers, so subscribers ignore the events whose EventID they have seen.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/dbctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// batchSize is the number of events published in one transaction
+	batchSize = 100
+	// pollInterval is how often the relay looks for the events recorded by the other instances, and retries the
+	// events it failed to publish. Events recorded by the same instance are published right away, see Wake.
+	pollInterval = 5 * time.Second
+)
+
+// Subscriber handles a published event. It runs in the transaction that marks the event published, so the rows it
+// writes are committed with the publication, and rolled back with it if the publication fails.
+type Subscriber func(ctx context.Context, event models.OutboxEvent) error
+
+type subscription struct {
+	name       string
+	subscriber Subscriber
+}
+
+// Relay records the domain events in the outbox and publishes them to the subscribers
+type Relay struct {
+	outboxRepo    repo.OutboxRepo
+	db            *gorm.DB
+	logger        *logger.Logger
+	mu            sync.Mutex
+	subscriptions []subscription
+	wake          chan struct{}
+}
+
+// NewRelay creates a relay, Run starts it
+func NewRelay(outboxRepo repo.OutboxRepo, db *gorm.DB, logger *logger.Logger) *Relay {
+	return &Relay{
+		outboxRepo: outboxRepo,
+		db:         db,
+		logger:     logger,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Subscribe registers a subscriber, it gets the events published from now on
+func (r *Relay) Subscribe(name string, subscriber Subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions = append(r.subscriptions, subscription{name: name, subscriber: subscriber})
+}
+
+// NewEvent creates an event of the given type, with a new deduplication ID
+func NewEvent(eventType models.DomainEventType, employerID, actorID *models.UserID, data models.DomainEventData) (models.OutboxEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return models.OutboxEvent{}, errors.Wrap(err, "failed to encode domain event")
+	}
+	id := make([]byte, 16)
+	// rand.Read never returns an error, see its documentation
+	_, _ = rand.Read(id)
+	return models.OutboxEvent{
+		EventID:    hex.EncodeToString(id),
+		Type:       eventType,
+		EmployerID: employerID,
+		ActorID:    actorID,
+		Payload:    payload,
+	}, nil
+}
+
+// Record records events in the transaction of ctx, they are published once it commits
+func (r *Relay) Record(ctx context.Context, events ...models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := r.outboxRepo.CreateEvents(ctx, events...); err != nil {
+		return err
+	}
+	dbctx.AfterCommit(ctx, r.Wake)
+	return nil
+}
+
+// Wake tells the relay that there are new events to publish. It does not block.
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default: // the relay is already woken up
+	}
+}
+
+// Run publishes the events until ctx is canceled
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			published, err := r.PublishPending(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.logger.Error("failed to publish outbox events", "error", err)
+			}
+			if err != nil || published < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending publishes a batch of the events that are not published yet, oldest first, and returns their number.
+// If a subscriber fails, the whole batch is rolled back and published again on the next run. Nothing is published
+// while another relay holds the lock.
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	subscriptions := append([]subscription(nil), r.subscriptions...)
+	r.mu.Unlock()
+
+	published := 0
+	err := dbctx.Transaction(ctx, r.db, func(ctx context.Context) error {
+		locked, err := r.outboxRepo.LockRelay(ctx)
+		if err != nil || !locked {
+			return err
+		}
+		events, err := r.outboxRepo.GetUnpublishedEvents(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, event := range events {
+			position, err := r.outboxRepo.MarkEventPublished(ctx, event.ID, now)
+			if err != nil {
+				return err
+			}
+			event.Position, event.PublishedAt = &position, &now
+			for _, s := range subscriptions {
+				if err := s.subscriber(ctx, event); err != nil {
+					return errors.Wrapf(err, "subscriber %s failed on event %s", s.name, event.EventID)
+				}
+			}
+		}
+		published = len(events)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, nil
+}
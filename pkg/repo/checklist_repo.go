@@ -0,0 +1,20 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+type ChecklistRepo interface {
+	// GetChecklistItemByID retrieves a checklist item by its ID, return nil if not found
+	GetChecklistItemByID(ctx context.Context, id models.ChecklistItemID) (*models.ChecklistItem, error)
+	// GetChecklistItems retrieves the checklist of a task, in creation order
+	GetChecklistItems(ctx context.Context, taskID models.TaskID) ([]models.ChecklistItem, error)
+	// CreateChecklistItem adds an item to the checklist of a task
+	CreateChecklistItem(ctx context.Context, item models.ChecklistItem) (models.ChecklistItem, error)
+	// UpdateChecklistItem updates the title and/or the done flag of an item, nil values are left unchanged
+	UpdateChecklistItem(ctx context.Context, id models.ChecklistItemID, title *string, done *bool) (_updated bool, _ error)
+	// DeleteChecklistItem deletes an item
+	DeleteChecklistItem(ctx context.Context, id models.ChecklistItemID) (_deleted bool, _ error)
+}
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ ChecklistRepo = (*checklistRepoImpl)(nil)
+
+type checklistRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewChecklistRepoImpl creates a new checklist repository implementation
+func NewChecklistRepoImpl(db func(ctx context.Context) *gorm.DB) *checklistRepoImpl {
+	return &checklistRepoImpl{db: db}
+}
+
+// GetChecklistItemByID retrieves a checklist item by its ID, return nil if not found
+func (r *checklistRepoImpl) GetChecklistItemByID(ctx context.Context, id models.ChecklistItemID) (*models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	if err := r.db(ctx).Table("task_checklist_items").Where("id = ?", id).Limit(1).Scan(&item).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get checklist item by id")
+	}
+	if item.ID == 0 {
+		return nil, nil // item not found
+	}
+	return &item, nil
+}
+
+// GetChecklistItems retrieves the checklist of a task, in creation order
+func (r *checklistRepoImpl) GetChecklistItems(ctx context.Context, taskID models.TaskID) ([]models.ChecklistItem, error) {
+	items := []models.ChecklistItem{}
+	if err := r.db(ctx).Where("task_id = ?", taskID).Order("id ASC").Find(&items).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get checklist items")
+	}
+	return items, nil
+}
+
+// CreateChecklistItem adds an item to the checklist of a task
+func (r *checklistRepoImpl) CreateChecklistItem(ctx context.Context, item models.ChecklistItem) (models.ChecklistItem, error) {
+	if err := r.db(ctx).Create(&item).Error; err != nil {
+		return models.ChecklistItem{}, errors.Wrap(err, "failed to create checklist item")
+	}
+	return item, nil
+}
+
+// UpdateChecklistItem updates the title and/or the done flag of an item
+func (r *checklistRepoImpl) UpdateChecklistItem(ctx context.Context, id models.ChecklistItemID, title *string, done *bool) (_updated bool, _ error) {
+	updates := map[string]interface{}{}
+	if title != nil {
+		updates["title"] = *title
+	}
+	if done != nil {
+		updates["done"] = *done
+	}
+	if len(updates) == 0 {
+		return false, nil
+	}
+
+	result := r.db(ctx).Model(&models.ChecklistItem{}).
+		Where("id = ?", id).
+		Updates(updates)
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to update checklist item")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteChecklistItem deletes an item
+func (r *checklistRepoImpl) DeleteChecklistItem(ctx context.Context, id models.ChecklistItemID) (_deleted bool, _ error) {
+	result := r.db(ctx).Where("id = ?", id).Delete(&models.ChecklistItem{})
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to delete checklist item")
+	}
+	return result.RowsAffected > 0, nil
+}
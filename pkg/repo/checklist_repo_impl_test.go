@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checklistRepoImpl(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	r := NewChecklistRepoImpl(taskRepo.db)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	task := createTestTask(t, ctx, taskRepo, "Release", "", employer.ID, nil)
+	other := createTestTask(t, ctx, taskRepo, "Other", "", employer.ID, nil)
+
+	tag, err := r.CreateChecklistItem(ctx, models.ChecklistItem{TaskID: task.ID, Title: "Tag the release"})
+	require.NoError(t, err)
+	require.NotEmpty(t, tag.ID)
+	require.False(t, tag.Done)
+	changelog, err := r.CreateChecklistItem(ctx, models.ChecklistItem{TaskID: task.ID, Title: "Update the changelog"})
+	require.NoError(t, err)
+
+	t.Run("items of a task, in creation order", func(t *testing.T) {
+		items, err := r.GetChecklistItems(ctx, task.ID)
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.Equal(t, tag.ID, items[0].ID)
+		require.Equal(t, changelog.ID, items[1].ID)
+
+		items, err = r.GetChecklistItems(ctx, other.ID)
+		require.NoError(t, err)
+		require.NotNil(t, items)
+		require.Empty(t, items)
+	})
+
+	t.Run("check an item", func(t *testing.T) {
+		done := true
+		updated, err := r.UpdateChecklistItem(ctx, tag.ID, nil, &done)
+		require.NoError(t, err)
+		require.True(t, updated)
+
+		item, err := r.GetChecklistItemByID(ctx, tag.ID)
+		require.NoError(t, err)
+		require.True(t, item.Done)
+		require.Equal(t, "Tag the release", item.Title)
+	})
+
+	t.Run("rename an item", func(t *testing.T) {
+		title := "Tag and sign the release"
+		updated, err := r.UpdateChecklistItem(ctx, tag.ID, &title, nil)
+		require.NoError(t, err)
+		require.True(t, updated)
+
+		item, err := r.GetChecklistItemByID(ctx, tag.ID)
+		require.NoError(t, err)
+		require.True(t, item.Done)
+		require.Equal(t, title, item.Title)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		deleted, err := r.DeleteChecklistItem(ctx, changelog.ID)
+		require.NoError(t, err)
+		require.True(t, deleted)
+
+		item, err := r.GetChecklistItemByID(ctx, changelog.ID)
+		require.NoError(t, err)
+		require.Nil(t, item)
+
+		deleted, err = r.DeleteChecklistItem(ctx, changelog.ID)
+		require.NoError(t, err)
+		require.False(t, deleted)
+	})
+}
@@ -0,0 +1,28 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+type CommentRepo interface {
+	// GetCommentByID retrieves a comment by its ID, return nil if not found
+	GetCommentByID(ctx context.Context, id models.CommentID) (*models.TaskComment, error)
+	// CreateComment creates a new comment
+	CreateComment(ctx context.Context, comment models.TaskComment) (models.TaskComment, error)
+	// UpdateCommentBody updates the body of a comment that is not deleted
+	UpdateCommentBody(ctx context.Context, id models.CommentID, body string) (_updated bool, _ error)
+	// DeleteComment deletes a comment: its body is cleared but it is kept, so its replies stay in their thread
+	DeleteComment(ctx context.Context, id models.CommentID) (_deleted bool, _ error)
+
+	// GetComments retrieves the threads of a task, oldest first, each with all its replies.
+	// The total and the pagination count threads, not replies.
+	GetComments(ctx context.Context, options GetCommentsOptions) (_total int, _ []models.TaskComment, _ error)
+}
+
+type GetCommentsOptions struct {
+	TaskID models.TaskID
+	Offset int // offset for pagination
+	Limit  int // limit for pagination
+}
@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ CommentRepo = (*commentRepoImpl)(nil)
+
+type commentRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewCommentRepoImpl creates a new comment repository implementation
+func NewCommentRepoImpl(db func(ctx context.Context) *gorm.DB) *commentRepoImpl {
+	return &commentRepoImpl{db: db}
+}
+
+// GetCommentByID retrieves a comment by its ID, return nil if not found
+func (r *commentRepoImpl) GetCommentByID(ctx context.Context, id models.CommentID) (*models.TaskComment, error) {
+	var comment models.TaskComment
+	if err := r.db(ctx).Table("task_comments").Where("id = ?", id).Limit(1).Scan(&comment).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get comment by id")
+	}
+	if comment.ID == 0 {
+		return nil, nil // comment not found
+	}
+	return &comment, nil
+}
+
+// CreateComment creates a new comment
+func (r *commentRepoImpl) CreateComment(ctx context.Context, comment models.TaskComment) (models.TaskComment, error) {
+	if err := r.db(ctx).Create(&comment).Error; err != nil {
+		return models.TaskComment{}, errors.Wrap(err, "failed to create comment")
+	}
+	return comment, nil
+}
+
+// UpdateCommentBody updates the body of a comment that is not deleted
+func (r *commentRepoImpl) UpdateCommentBody(ctx context.Context, id models.CommentID, body string) (_updated bool, _ error) {
+	result := r.db(ctx).Model(&models.TaskComment{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Update("body", body)
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to update comment")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteComment clears the body of a comment and marks it deleted
+func (r *commentRepoImpl) DeleteComment(ctx context.Context, id models.CommentID) (_deleted bool, _ error) {
+	result := r.db(ctx).Model(&models.TaskComment{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"body":       "",
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+		})
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to delete comment")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetComments retrieves the threads of a task with their replies
+func (r *commentRepoImpl) GetComments(ctx context.Context, options GetCommentsOptions) (int, []models.TaskComment, error) {
+	db := r.db(ctx).Model(&models.TaskComment{}).Where("task_id = ? AND parent_id IS NULL", options.TaskID)
+
+	// Count total threads (before pagination)
+	var totalCount int64
+	if err := db.Count(&totalCount).Error; err != nil {
+		return 0, nil, errors.Wrap(err, "failed to count comments")
+	}
+
+	// Fetch the page of threads
+	db = db.Order("created_at ASC").Order("id ASC")
+	if options.Limit > 0 {
+		db = db.Limit(options.Limit)
+	}
+	if options.Offset > 0 {
+		db = db.Offset(options.Offset)
+	}
+	var threads []models.TaskComment
+	if err := db.Find(&threads).Error; err != nil {
+		return 0, nil, errors.Wrap(err, "failed to get comments")
+	}
+	if len(threads) == 0 {
+		return int(totalCount), threads, nil
+	}
+
+	// Fetch the replies of these threads in one query
+	threadIDs := make([]models.CommentID, len(threads))
+	index := make(map[models.CommentID]int, len(threads))
+	for i, thread := range threads {
+		threadIDs[i] = thread.ID
+		index[thread.ID] = i
+	}
+	var replies []models.TaskComment
+	err := r.db(ctx).Where("parent_id IN (?)", threadIDs).
+		Order("created_at ASC").Order("id ASC").
+		Find(&replies).Error
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to get comment replies")
+	}
+	for _, reply := range replies {
+		i := index[*reply.ParentID]
+		threads[i].Replies = append(threads[i].Replies, reply)
+	}
+
+	return int(totalCount), threads, nil
+}
@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/testutil"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupCommentTestRepo sets up a comment repository and a task to comment on with a test database
+func setupCommentTestRepo(t *testing.T) (context.Context, *commentRepoImpl, models.Task, models.User) {
+	db := testutil.CreateTestDB(t)
+	getDB := func(ctx context.Context) *gorm.DB {
+		return db.WithContext(ctx)
+	}
+	ctx := t.Context()
+	userRepo := NewUserRepoImpl(getDB)
+	taskRepo := NewTaskRepoImpl(getDB)
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	task := createTestTask(t, ctx, taskRepo, "Task", "Description", employer.ID, nil)
+	return ctx, NewCommentRepoImpl(getDB), task, employer
+}
+
+func createTestComment(t *testing.T, ctx context.Context, r *commentRepoImpl, task models.Task, author models.User, parentID *models.CommentID, body string) models.TaskComment {
+	comment, err := r.CreateComment(ctx, models.TaskComment{
+		TaskID:   task.ID,
+		ParentID: parentID,
+		AuthorID: author.ID,
+		Body:     body,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, comment.ID)
+	return comment
+}
+
+func Test_commentRepoImpl_GetComments(t *testing.T) {
+	ctx, r, task, author := setupCommentTestRepo(t)
+
+	first := createTestComment(t, ctx, r, task, author, nil, "first thread")
+	second := createTestComment(t, ctx, r, task, author, nil, "second thread")
+	createTestComment(t, ctx, r, task, author, &first.ID, "reply 1")
+	createTestComment(t, ctx, r, task, author, &second.ID, "reply 2")
+	createTestComment(t, ctx, r, task, author, &first.ID, "reply 3")
+
+	t.Run("threads with their replies", func(t *testing.T) {
+		total, threads, err := r.GetComments(ctx, GetCommentsOptions{TaskID: task.ID})
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		require.Len(t, threads, 2)
+		require.Equal(t, first.ID, threads[0].ID)
+		require.Len(t, threads[0].Replies, 2)
+		require.Equal(t, "reply 1", threads[0].Replies[0].Body)
+		require.Equal(t, "reply 3", threads[0].Replies[1].Body)
+		require.Len(t, threads[1].Replies, 1)
+	})
+
+	t.Run("pagination counts threads", func(t *testing.T) {
+		total, threads, err := r.GetComments(ctx, GetCommentsOptions{TaskID: task.ID, Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		require.Len(t, threads, 1)
+		require.Equal(t, second.ID, threads[0].ID)
+		require.Len(t, threads[0].Replies, 1)
+	})
+
+	t.Run("other task", func(t *testing.T) {
+		total, threads, err := r.GetComments(ctx, GetCommentsOptions{TaskID: task.ID + 1})
+		require.NoError(t, err)
+		require.Equal(t, 0, total)
+		require.Empty(t, threads)
+	})
+}
+
+func Test_commentRepoImpl_UpdateAndDelete(t *testing.T) {
+	ctx, r, task, author := setupCommentTestRepo(t)
+	comment := createTestComment(t, ctx, r, task, author, nil, "original")
+
+	updated, err := r.UpdateCommentBody(ctx, comment.ID, "edited")
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	deleted, err := r.DeleteComment(ctx, comment.ID)
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	fetched, err := r.GetCommentByID(ctx, comment.ID)
+	require.NoError(t, err)
+	require.NotNil(t, fetched)
+	require.True(t, fetched.IsDeleted())
+	require.Empty(t, fetched.Body)
+
+	// deleted comments can be neither edited nor deleted again
+	updated, err = r.UpdateCommentBody(ctx, comment.ID, "edited again")
+	require.NoError(t, err)
+	require.False(t, updated)
+	deleted, err = r.DeleteComment(ctx, comment.ID)
+	require.NoError(t, err)
+	require.False(t, deleted)
+
+	notFound, err := r.GetCommentByID(ctx, models.CommentID(999))
+	require.NoError(t, err)
+	require.Nil(t, notFound)
+}
@@ -0,0 +1,26 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+)
+
+// ErrDuplicateLabel is returned when an employer already has a label with the same name
+var ErrDuplicateLabel = errors.New("duplicate label")
+
+type LabelRepo interface {
+	// GetLabelByID retrieves a label by its ID, return nil if not found
+	GetLabelByID(ctx context.Context, id models.LabelID) (*models.Label, error)
+	// GetLabelsByIDs retrieves the labels with the given IDs, unknown IDs are skipped
+	GetLabelsByIDs(ctx context.Context, ids []models.LabelID) ([]models.Label, error)
+	// GetLabels retrieves the labels of an employer, by name
+	GetLabels(ctx context.Context, employerID models.UserID) ([]models.Label, error)
+	// CreateLabel creates a new label. It returns ErrDuplicateLabel if the employer already has a label with this name.
+	CreateLabel(ctx context.Context, label models.Label) (models.Label, error)
+	// UpdateLabelName renames a label. It returns ErrDuplicateLabel if the employer already has a label with this name.
+	UpdateLabelName(ctx context.Context, id models.LabelID, name string) (_updated bool, _ error)
+	// DeleteLabel deletes a label, and removes it from its tasks
+	DeleteLabel(ctx context.Context, id models.LabelID) (_deleted bool, _ error)
+}
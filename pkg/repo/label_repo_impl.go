@@ -0,0 +1,87 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ LabelRepo = (*labelRepoImpl)(nil)
+
+type labelRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewLabelRepoImpl creates a new label repository implementation
+func NewLabelRepoImpl(db func(ctx context.Context) *gorm.DB) *labelRepoImpl {
+	return &labelRepoImpl{db: db}
+}
+
+// GetLabelByID retrieves a label by its ID, return nil if not found
+func (r *labelRepoImpl) GetLabelByID(ctx context.Context, id models.LabelID) (*models.Label, error) {
+	var label models.Label
+	if err := r.db(ctx).Table("labels").Where("id = ?", id).Limit(1).Scan(&label).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get label by id")
+	}
+	if label.ID == 0 {
+		return nil, nil // label not found
+	}
+	return &label, nil
+}
+
+// GetLabelsByIDs retrieves the labels with the given IDs
+func (r *labelRepoImpl) GetLabelsByIDs(ctx context.Context, ids []models.LabelID) ([]models.Label, error) {
+	var labels []models.Label
+	if len(ids) == 0 {
+		return labels, nil
+	}
+	if err := r.db(ctx).Where("id IN (?)", ids).Order("name ASC").Find(&labels).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get labels by ids")
+	}
+	return labels, nil
+}
+
+// GetLabels retrieves the labels of an employer
+func (r *labelRepoImpl) GetLabels(ctx context.Context, employerID models.UserID) ([]models.Label, error) {
+	var labels []models.Label
+	if err := r.db(ctx).Where("employer_id = ?", employerID).Order("name ASC").Find(&labels).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get labels")
+	}
+	return labels, nil
+}
+
+// CreateLabel creates a new label
+func (r *labelRepoImpl) CreateLabel(ctx context.Context, label models.Label) (models.Label, error) {
+	if err := r.db(ctx).Create(&label).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return models.Label{}, ErrDuplicateLabel
+		}
+		return models.Label{}, errors.Wrap(err, "failed to create label")
+	}
+	return label, nil
+}
+
+// UpdateLabelName renames a label
+func (r *labelRepoImpl) UpdateLabelName(ctx context.Context, id models.LabelID, name string) (_updated bool, _ error) {
+	result := r.db(ctx).Model(&models.Label{}).
+		Where("id = ?", id).
+		Update("name", name)
+	if err := result.Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return false, ErrDuplicateLabel
+		}
+		return false, errors.Wrap(err, "failed to update label")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteLabel deletes a label, task_labels rows are deleted by cascade
+func (r *labelRepoImpl) DeleteLabel(ctx context.Context, id models.LabelID) (_deleted bool, _ error) {
+	result := r.db(ctx).Where("id = ?", id).Delete(&models.Label{})
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to delete label")
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/testutil"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func Test_labelRepoImpl(t *testing.T) {
+	db := testutil.CreateTestDB(t)
+	getDB := func(ctx context.Context) *gorm.DB {
+		return db.WithContext(ctx)
+	}
+	ctx := t.Context()
+	userRepo := NewUserRepoImpl(getDB)
+	r := NewLabelRepoImpl(getDB)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	other := createTestUserForTask(t, ctx, userRepo, "other@example.com", "Other", models.UserRoleEmployer)
+
+	frontend, err := r.CreateLabel(ctx, models.Label{EmployerID: employer.ID, Name: "frontend"})
+	require.NoError(t, err)
+	require.NotEmpty(t, frontend.ID)
+	backend, err := r.CreateLabel(ctx, models.Label{EmployerID: employer.ID, Name: "backend"})
+	require.NoError(t, err)
+	// names are unique per employer
+	_, err = r.CreateLabel(ctx, models.Label{EmployerID: other.ID, Name: "backend"})
+	require.NoError(t, err)
+
+	t.Run("labels of an employer, by name", func(t *testing.T) {
+		labels, err := r.GetLabels(ctx, employer.ID)
+		require.NoError(t, err)
+		require.Len(t, labels, 2)
+		require.Equal(t, "backend", labels[0].Name)
+		require.Equal(t, "frontend", labels[1].Name)
+	})
+
+	t.Run("labels by IDs", func(t *testing.T) {
+		labels, err := r.GetLabelsByIDs(ctx, []models.LabelID{frontend.ID, 9999})
+		require.NoError(t, err)
+		require.Len(t, labels, 1)
+		require.Equal(t, frontend.ID, labels[0].ID)
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		updated, err := r.UpdateLabelName(ctx, frontend.ID, "web")
+		require.NoError(t, err)
+		require.True(t, updated)
+
+		label, err := r.GetLabelByID(ctx, frontend.ID)
+		require.NoError(t, err)
+		require.Equal(t, "web", label.Name)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		deleted, err := r.DeleteLabel(ctx, backend.ID)
+		require.NoError(t, err)
+		require.True(t, deleted)
+
+		label, err := r.GetLabelByID(ctx, backend.ID)
+		require.NoError(t, err)
+		require.Nil(t, label)
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		_, err := r.CreateLabel(ctx, models.Label{EmployerID: employer.ID, Name: "web"})
+		require.ErrorIs(t, err, ErrDuplicateLabel)
+	})
+}
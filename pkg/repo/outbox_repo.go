@@ -0,0 +1,27 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+// OutboxRepo stores the domain events until the relay publishes them
+type OutboxRepo interface {
+	// CreateEvents records events in the transaction of ctx
+	CreateEvents(ctx context.Context, events ...models.OutboxEvent) error
+	// LockRelay takes the relay lock until the end of the transaction, so only one relay publishes at a time and
+	// the positions follow the order of publication. It returns false if another relay holds the lock.
+	LockRelay(ctx context.Context) (_locked bool, _ error)
+	// GetUnpublishedEvents retrieves up to limit events that are not published yet, oldest first
+	GetUnpublishedEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	// MarkEventPublished gives the next position to an event, records when it was published, and returns its position.
+	// Positions only grow, but have gaps: the positions of the transactions that roll back are not used.
+	MarkEventPublished(ctx context.Context, id models.OutboxEventID, publishedAt time.Time) (_position int64, _ error)
+	// GetPublishedEvents retrieves up to limit published events of the given types, in the order of their positions,
+	// starting after afterPosition
+	GetPublishedEvents(ctx context.Context, types []models.DomainEventType, afterPosition int64, limit int) ([]models.OutboxEvent, error)
+	// GetLastEventPosition returns the position of the last published event, 0 if no event is published yet
+	GetLastEventPosition(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,98 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ OutboxRepo = (*outboxRepoImpl)(nil)
+
+// outboxRelayLockKey is the key of the advisory lock held by the relay that publishes
+const outboxRelayLockKey = 7_240_001
+
+type outboxRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewOutboxRepoImpl creates a new outbox repository implementation
+func NewOutboxRepoImpl(db func(ctx context.Context) *gorm.DB) *outboxRepoImpl {
+	return &outboxRepoImpl{db: db}
+}
+
+// CreateEvents records events
+func (r *outboxRepoImpl) CreateEvents(ctx context.Context, events ...models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := r.db(ctx).Create(&events).Error; err != nil {
+		return errors.Wrap(err, "failed to create outbox events")
+	}
+	return nil
+}
+
+// LockRelay takes the relay lock until the end of the transaction
+func (r *outboxRepoImpl) LockRelay(ctx context.Context) (_locked bool, _ error) {
+	var locked bool
+	if err := r.db(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLockKey).Scan(&locked).Error; err != nil {
+		return false, errors.Wrap(err, "failed to lock the outbox relay")
+	}
+	return locked, nil
+}
+
+// GetUnpublishedEvents retrieves the events that are not published yet, oldest first
+func (r *outboxRepoImpl) GetUnpublishedEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db(ctx).
+		Where("published_at IS NULL").
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get unpublished outbox events")
+	}
+	return events, nil
+}
+
+// MarkEventPublished gives the next position to an event, records when it was published, and returns its position
+func (r *outboxRepoImpl) MarkEventPublished(ctx context.Context, id models.OutboxEventID, publishedAt time.Time) (_position int64, _ error) {
+	var position int64
+	err := r.db(ctx).Raw(`UPDATE outbox_events
+		SET position = nextval('outbox_event_positions'), published_at = ?
+		WHERE id = ? AND published_at IS NULL
+		RETURNING position`, publishedAt, id).Scan(&position).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to mark outbox event published")
+	}
+	if position == 0 {
+		return 0, errors.Errorf("outbox event %d is published already", id)
+	}
+	return position, nil
+}
+
+// GetPublishedEvents retrieves the published events of the given types after a position, in the order of their positions
+func (r *outboxRepoImpl) GetPublishedEvents(ctx context.Context, types []models.DomainEventType, afterPosition int64, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db(ctx).
+		Where("position > ?", afterPosition).
+		Where("type IN ?", types).
+		Order("position ASC").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get published outbox events")
+	}
+	return events, nil
+}
+
+// GetLastEventPosition returns the position of the last published event
+func (r *outboxRepoImpl) GetLastEventPosition(ctx context.Context) (int64, error) {
+	var position int64
+	if err := r.db(ctx).Model(&models.OutboxEvent{}).Select("COALESCE(MAX(position), 0)").Scan(&position).Error; err != nil {
+		return 0, errors.Wrap(err, "failed to get the last outbox event position")
+	}
+	return position, nil
+}
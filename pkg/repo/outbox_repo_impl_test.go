@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func Test_outboxRepoImpl(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	r := NewOutboxRepoImpl(taskRepo.db)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+
+	payload := json.RawMessage(`{"task":{"id":1}}`)
+	require.NoError(t, r.CreateEvents(ctx,
+		models.OutboxEvent{EventID: "first", Type: models.DomainEventTaskCreated, EmployerID: &employer.ID, ActorID: &employer.ID, Payload: payload},
+		models.OutboxEvent{EventID: "second", Type: models.DomainEventTaskAssigned, EmployerID: &employer.ID, Payload: payload},
+	))
+
+	t.Run("unpublished events", func(t *testing.T) {
+		events, err := r.GetUnpublishedEvents(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, "first", events[0].EventID)
+		require.Equal(t, employer.ID, *events[0].ActorID)
+		require.JSONEq(t, string(payload), string(events[0].Payload))
+		require.Nil(t, events[0].Position)
+		require.Equal(t, "second", events[1].EventID)
+		require.Nil(t, events[1].ActorID)
+	})
+
+	t.Run("publish", func(t *testing.T) {
+		events, err := r.GetUnpublishedEvents(ctx, 10)
+		require.NoError(t, err)
+
+		// the positions follow the order of publication
+		second, err := r.MarkEventPublished(ctx, events[1].ID, time.Now())
+		require.NoError(t, err)
+		first, err := r.MarkEventPublished(ctx, events[0].ID, time.Now())
+		require.NoError(t, err)
+		require.Greater(t, first, second)
+
+		_, err = r.MarkEventPublished(ctx, events[0].ID, time.Now())
+		require.Error(t, err, "an event is published once")
+
+		events, err = r.GetUnpublishedEvents(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, events)
+	})
+
+	t.Run("published events", func(t *testing.T) {
+		last, err := r.GetLastEventPosition(ctx)
+		require.NoError(t, err)
+
+		types := []models.DomainEventType{models.DomainEventTaskCreated, models.DomainEventTaskAssigned}
+		events, err := r.GetPublishedEvents(ctx, types, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, "second", events[0].EventID, "in the order of publication")
+		require.Equal(t, "first", events[1].EventID)
+		require.Equal(t, last, *events[1].Position)
+
+		events, err = r.GetPublishedEvents(ctx, types, *events[0].Position, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "first", events[0].EventID)
+
+		events, err = r.GetPublishedEvents(ctx, []models.DomainEventType{models.DomainEventTaskAssigned}, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "second", events[0].EventID)
+	})
+
+	t.Run("one relay at a time", func(t *testing.T) {
+		db := taskRepo.db(ctx)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			locked, err := NewOutboxRepoImpl(func(context.Context) *gorm.DB { return tx }).LockRelay(ctx)
+			require.NoError(t, err)
+			require.True(t, locked)
+
+			return db.Transaction(func(other *gorm.DB) error {
+				locked, err := NewOutboxRepoImpl(func(context.Context) *gorm.DB { return other }).LockRelay(ctx)
+				require.NoError(t, err)
+				require.False(t, locked, "another transaction holds the lock")
+				return nil
+			})
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("duplicate event IDs", func(t *testing.T) {
+		err := r.CreateEvents(ctx, models.OutboxEvent{EventID: "first", Type: models.DomainEventTaskCreated, Payload: payload})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+type TaskDependencyRepo interface {
+	// LockTaskDependencies locks the dependencies of the tasks of an employer until the end of the transaction.
+	// Checking that a new dependency does not make a cycle, then adding it, must happen under this lock,
+	// otherwise two concurrent dependencies could close a cycle that neither of them sees.
+	LockTaskDependencies(ctx context.Context, employerID models.UserID) error
+	// GetTaskDependency retrieves the dependency of a task on a blocker, return nil if not found
+	GetTaskDependency(ctx context.Context, taskID, blockerID models.TaskID) (*models.TaskDependency, error)
+	// CreateTaskDependency adds a dependency
+	CreateTaskDependency(ctx context.Context, dependency models.TaskDependency) (models.TaskDependency, error)
+	// DeleteTaskDependency removes the dependency of a task on a blocker
+	DeleteTaskDependency(ctx context.Context, taskID, blockerID models.TaskID) (_deleted bool, _ error)
+	// IsBlockedBy returns true if the task depends on the blocker, directly or through other tasks
+	IsBlockedBy(ctx context.Context, taskID, blockerID models.TaskID) (bool, error)
+}
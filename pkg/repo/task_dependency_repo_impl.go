@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ TaskDependencyRepo = (*taskDependencyRepoImpl)(nil)
+
+// taskDependencyLockClass is the first key of the advisory locks of LockTaskDependencies, the employer ID is the second
+const taskDependencyLockClass = 1
+
+type taskDependencyRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewTaskDependencyRepoImpl creates a new task dependency repository implementation
+func NewTaskDependencyRepoImpl(db func(ctx context.Context) *gorm.DB) *taskDependencyRepoImpl {
+	return &taskDependencyRepoImpl{db: db}
+}
+
+// LockTaskDependencies locks the dependencies of the tasks of an employer until the end of the transaction
+func (r *taskDependencyRepoImpl) LockTaskDependencies(ctx context.Context, employerID models.UserID) error {
+	if err := r.db(ctx).Exec("SELECT pg_advisory_xact_lock(?, ?)", taskDependencyLockClass, employerID).Error; err != nil {
+		return errors.Wrap(err, "failed to lock task dependencies")
+	}
+	return nil
+}
+
+// GetTaskDependency retrieves the dependency of a task on a blocker, return nil if not found
+func (r *taskDependencyRepoImpl) GetTaskDependency(ctx context.Context, taskID, blockerID models.TaskID) (*models.TaskDependency, error) {
+	var dependency models.TaskDependency
+	err := r.db(ctx).Table("task_dependencies").
+		Where("task_id = ? AND blocker_id = ?", taskID, blockerID).
+		Limit(1).
+		Scan(&dependency).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task dependency")
+	}
+	if dependency.TaskID == 0 {
+		return nil, nil // dependency not found
+	}
+	return &dependency, nil
+}
+
+// CreateTaskDependency adds a dependency
+func (r *taskDependencyRepoImpl) CreateTaskDependency(ctx context.Context, dependency models.TaskDependency) (models.TaskDependency, error) {
+	if err := r.db(ctx).Create(&dependency).Error; err != nil {
+		return models.TaskDependency{}, errors.Wrap(err, "failed to create task dependency")
+	}
+	return dependency, nil
+}
+
+// DeleteTaskDependency removes the dependency of a task on a blocker
+func (r *taskDependencyRepoImpl) DeleteTaskDependency(ctx context.Context, taskID, blockerID models.TaskID) (_deleted bool, _ error) {
+	result := r.db(ctx).Where("task_id = ? AND blocker_id = ?", taskID, blockerID).Delete(&models.TaskDependency{})
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to delete task dependency")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// IsBlockedBy returns true if the task depends on the blocker, directly or through other tasks
+func (r *taskDependencyRepoImpl) IsBlockedBy(ctx context.Context, taskID, blockerID models.TaskID) (bool, error) {
+	// UNION, not UNION ALL, so the walk ends even if the graph already has a cycle
+	var blocked bool
+	err := r.db(ctx).Raw(`
+	WITH RECURSIVE blockers(id) AS (
+		SELECT blocker_id FROM task_dependencies WHERE task_id = ?
+		UNION
+		SELECT d.blocker_id FROM task_dependencies d JOIN blockers b ON d.task_id = b.id
+	)
+	SELECT EXISTS (SELECT 1 FROM blockers WHERE id = ?)`, taskID, blockerID).
+		Scan(&blocked).Error
+	if err != nil {
+		return false, errors.Wrap(err, "failed to walk task dependencies")
+	}
+	return blocked, nil
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_taskDependencyRepoImpl(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	r := NewTaskDependencyRepoImpl(taskRepo.db)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	design := createTestTask(t, ctx, taskRepo, "Design", "", employer.ID, nil)
+	build := createTestTask(t, ctx, taskRepo, "Build", "", employer.ID, nil)
+	ship := createTestTask(t, ctx, taskRepo, "Ship", "", employer.ID, nil)
+
+	require.NoError(t, r.LockTaskDependencies(ctx, employer.ID))
+	dependency, err := r.CreateTaskDependency(ctx, models.TaskDependency{TaskID: build.ID, BlockerID: design.ID})
+	require.NoError(t, err)
+	require.Equal(t, design.ID, dependency.BlockerID)
+	_, err = r.CreateTaskDependency(ctx, models.TaskDependency{TaskID: ship.ID, BlockerID: build.ID})
+	require.NoError(t, err)
+
+	t.Run("get", func(t *testing.T) {
+		dependency, err := r.GetTaskDependency(ctx, build.ID, design.ID)
+		require.NoError(t, err)
+		require.NotNil(t, dependency)
+		require.False(t, dependency.CreatedAt.IsZero())
+
+		dependency, err = r.GetTaskDependency(ctx, design.ID, build.ID)
+		require.NoError(t, err)
+		require.Nil(t, dependency)
+	})
+
+	t.Run("blocked directly and transitively", func(t *testing.T) {
+		blocked, err := r.IsBlockedBy(ctx, build.ID, design.ID)
+		require.NoError(t, err)
+		require.True(t, blocked)
+
+		blocked, err = r.IsBlockedBy(ctx, ship.ID, design.ID)
+		require.NoError(t, err)
+		require.True(t, blocked)
+
+		blocked, err = r.IsBlockedBy(ctx, design.ID, ship.ID)
+		require.NoError(t, err)
+		require.False(t, blocked)
+	})
+
+	t.Run("a task cannot block itself", func(t *testing.T) {
+		_, err := r.CreateTaskDependency(ctx, models.TaskDependency{TaskID: design.ID, BlockerID: design.ID})
+		require.Error(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		deleted, err := r.DeleteTaskDependency(ctx, ship.ID, build.ID)
+		require.NoError(t, err)
+		require.True(t, deleted)
+
+		blocked, err := r.IsBlockedBy(ctx, ship.ID, design.ID)
+		require.NoError(t, err)
+		require.False(t, blocked)
+
+		deleted, err = r.DeleteTaskDependency(ctx, ship.ID, build.ID)
+		require.NoError(t, err)
+		require.False(t, deleted)
+	})
+}
@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+// TaskDueEventRepo stores the reminders and overdue notices of the tasks, until they are delivered
+type TaskDueEventRepo interface {
+	// RecordDueEvents records a reminder for the tasks due between now and remindUntil, and an overdue notice
+	// for the tasks due before now. Tasks in a terminal status are skipped, and so are tasks that already have
+	// an event of the kind for their current due date. It returns the number of recorded events.
+	RecordDueEvents(ctx context.Context, now, remindUntil time.Time) (_recorded int, _ error)
+	// LockPendingDueEvents retrieves up to limit events that are not delivered yet, oldest first, and locks them
+	// until the end of the transaction. Events locked by another transaction are skipped, and so are the events
+	// that became irrelevant: their task is done, or due at another date now.
+	LockPendingDueEvents(ctx context.Context, limit int) ([]models.TaskDueEvent, error)
+	// MarkDueEventNotified records that an event was delivered
+	MarkDueEventNotified(ctx context.Context, id models.TaskDueEventID, notifiedAt time.Time) (_updated bool, _ error)
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ TaskDueEventRepo = (*taskDueEventRepoImpl)(nil)
+
+type taskDueEventRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewTaskDueEventRepoImpl creates a new task due event repository implementation
+func NewTaskDueEventRepoImpl(db func(ctx context.Context) *gorm.DB) *taskDueEventRepoImpl {
+	return &taskDueEventRepoImpl{db: db}
+}
+
+// RecordDueEvents records the reminders and overdue notices of the tasks that have none for their due date
+func (r *taskDueEventRepoImpl) RecordDueEvents(ctx context.Context, now, remindUntil time.Time) (int, error) {
+	// idx_task_due_events_occurrence makes the insert a no-op for the tasks that have the event already
+	result := r.db(ctx).Exec(`
+		INSERT INTO task_due_events (task_id, kind, due_date)
+		SELECT tasks.id, CASE WHEN tasks.due_date < ? THEN ? ELSE ? END, tasks.due_date
+		FROM tasks `+joinTaskStatuses+`
+		WHERE tasks.due_date <= ? AND employer_task_statuses.terminal IS NOT TRUE
+		ON CONFLICT DO NOTHING`,
+		now, models.TaskDueEventOverdue, models.TaskDueEventReminder, remindUntil)
+	if err := result.Error; err != nil {
+		return 0, errors.Wrap(err, "failed to record task due events")
+	}
+	return int(result.RowsAffected), nil
+}
+
+// LockPendingDueEvents retrieves and locks the events to deliver, oldest first
+func (r *taskDueEventRepoImpl) LockPendingDueEvents(ctx context.Context, limit int) ([]models.TaskDueEvent, error) {
+	var events []models.TaskDueEvent
+	err := r.db(ctx).Table("task_due_events").
+		Select("task_due_events.*").
+		Joins("JOIN tasks ON tasks.id = task_due_events.task_id AND tasks.due_date = task_due_events.due_date").
+		Joins(joinTaskStatuses).
+		Where("task_due_events.notified_at IS NULL AND employer_task_statuses.terminal IS NOT TRUE").
+		Order("task_due_events.id ASC").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "task_due_events"}, Options: "SKIP LOCKED"}).
+		Scan(&events).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pending task due events")
+	}
+	return events, nil
+}
+
+// MarkDueEventNotified records that an event was delivered
+func (r *taskDueEventRepoImpl) MarkDueEventNotified(ctx context.Context, id models.TaskDueEventID, notifiedAt time.Time) (_updated bool, _ error) {
+	result := r.db(ctx).Model(&models.TaskDueEvent{}).Where("id = ?", id).Update("notified_at", notifiedAt)
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to mark task due event as notified")
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_taskDueEventRepoImpl(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	r := NewTaskDueEventRepoImpl(taskRepo.db)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	employee := createTestUserForTask(t, ctx, userRepo, "employee@example.com", "Employee", models.UserRoleEmployee)
+
+	now := time.Now()
+	createTask := func(title string, status models.TaskStatus, dueDate *time.Time) models.Task {
+		task, err := taskRepo.CreateTask(ctx, models.Task{
+			Title:      title,
+			Status:     status,
+			DueDate:    dueDate,
+			EmployerID: employer.ID,
+			AssigneeID: &employee.ID,
+		})
+		require.NoError(t, err)
+		return task
+	}
+	at := func(d time.Duration) *time.Time {
+		date := now.Add(d)
+		return &date
+	}
+	soon := createTask("Soon", models.TaskStatusPending, at(time.Hour))
+	createTask("Later", models.TaskStatusPending, at(72*time.Hour))
+	overdue := createTask("Overdue", models.TaskStatusInProgress, at(-time.Hour))
+	createTask("Done", models.TaskStatusCompleted, at(-time.Hour))
+	createTask("No due date", models.TaskStatusPending, nil)
+
+	t.Run("overdue filter", func(t *testing.T) {
+		total, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, OverdueAt: now})
+		require.NoError(t, err)
+		require.Equal(t, 1, total)
+		require.Equal(t, overdue.ID, tasks[0].ID)
+	})
+
+	t.Run("record once", func(t *testing.T) {
+		recorded, err := r.RecordDueEvents(ctx, now, now.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 2, recorded)
+
+		recorded, err = r.RecordDueEvents(ctx, now, now.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, recorded, "the tasks have their events already")
+	})
+
+	t.Run("deliver", func(t *testing.T) {
+		events, err := r.LockPendingDueEvents(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, soon.ID, events[0].TaskID)
+		require.Equal(t, models.TaskDueEventReminder, events[0].Kind)
+		require.Equal(t, overdue.ID, events[1].TaskID)
+		require.Equal(t, models.TaskDueEventOverdue, events[1].Kind)
+
+		updated, err := r.MarkDueEventNotified(ctx, events[0].ID, now)
+		require.NoError(t, err)
+		require.True(t, updated)
+
+		events, err = r.LockPendingDueEvents(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, overdue.ID, events[0].TaskID)
+	})
+
+	t.Run("a new due date gets new events", func(t *testing.T) {
+		require.NoError(t, taskRepo.db(ctx).Model(&models.Task{}).Where("id = ?", soon.ID).Update("due_date", at(-time.Minute)).Error)
+
+		recorded, err := r.RecordDueEvents(ctx, now, now.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 1, recorded)
+	})
+
+	t.Run("the events of done tasks are not delivered", func(t *testing.T) {
+		_, err := taskRepo.UpdateTaskStatus(ctx, overdue.ID, models.TaskStatusCompleted)
+		require.NoError(t, err)
+
+		events, err := r.LockPendingDueEvents(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, soon.ID, events[0].TaskID)
+		require.Equal(t, models.TaskDueEventOverdue, events[0].Kind)
+	})
+}
@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+// TaskEventRepo stores the activity history of the tasks. Events can only be added, never modified.
+type TaskEventRepo interface {
+	// CreateTaskEvents records events, in the order given
+	CreateTaskEvents(ctx context.Context, events ...models.TaskEvent) error
+
+	// GetTaskEvents retrieves the events of a task, oldest first
+	GetTaskEvents(ctx context.Context, options GetTaskEventsOptions) (_total int, _ []models.TaskEvent, _ error)
+}
+
+type GetTaskEventsOptions struct {
+	TaskID models.TaskID
+	Offset int // offset for pagination
+	Limit  int // limit for pagination
+}
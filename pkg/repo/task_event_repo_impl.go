@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ TaskEventRepo = (*taskEventRepoImpl)(nil)
+
+type taskEventRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewTaskEventRepoImpl creates a new task event repository implementation
+func NewTaskEventRepoImpl(db func(ctx context.Context) *gorm.DB) *taskEventRepoImpl {
+	return &taskEventRepoImpl{db: db}
+}
+
+// CreateTaskEvents records events, in the order given
+func (r *taskEventRepoImpl) CreateTaskEvents(ctx context.Context, events ...models.TaskEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := r.db(ctx).Create(&events).Error; err != nil {
+		return errors.Wrap(err, "failed to create task events")
+	}
+	return nil
+}
+
+// GetTaskEvents retrieves the events of a task, oldest first
+func (r *taskEventRepoImpl) GetTaskEvents(ctx context.Context, options GetTaskEventsOptions) (int, []models.TaskEvent, error) {
+	db := r.db(ctx).Model(&models.TaskEvent{}).Where("task_id = ?", options.TaskID)
+
+	// Count total results (before pagination)
+	var totalCount int64
+	if err := db.Count(&totalCount).Error; err != nil {
+		return 0, nil, errors.Wrap(err, "failed to count task events")
+	}
+
+	// ids are increasing within a transaction too, unlike created_at
+	db = db.Order("id ASC")
+	if options.Limit > 0 {
+		db = db.Limit(options.Limit)
+	}
+	if options.Offset > 0 {
+		db = db.Offset(options.Offset)
+	}
+	var events []models.TaskEvent
+	if err := db.Find(&events).Error; err != nil {
+		return 0, nil, errors.Wrap(err, "failed to get task events")
+	}
+
+	return int(totalCount), events, nil
+}
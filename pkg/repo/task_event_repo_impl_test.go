@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/testutil"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func Test_taskEventRepoImpl(t *testing.T) {
+	db := testutil.CreateTestDB(t)
+	getDB := func(ctx context.Context) *gorm.DB {
+		return db.WithContext(ctx)
+	}
+	ctx := t.Context()
+	userRepo := NewUserRepoImpl(getDB)
+	taskRepo := NewTaskRepoImpl(getDB)
+	r := NewTaskEventRepoImpl(getDB)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	task := createTestTask(t, ctx, taskRepo, "Task", "Description", employer.ID, nil)
+	other := createTestTask(t, ctx, taskRepo, "Other", "Description", employer.ID, nil)
+
+	pending, inProgress := "pending", "in_progress"
+	require.NoError(t, r.CreateTaskEvents(ctx,
+		models.TaskEvent{TaskID: task.ID, ActorID: employer.ID, Type: models.TaskEventCreated},
+		models.TaskEvent{TaskID: task.ID, ActorID: employer.ID, Type: models.TaskEventUpdated,
+			Field: models.TaskFieldStatus, OldValue: &pending, NewValue: &inProgress},
+	))
+	require.NoError(t, r.CreateTaskEvents(ctx,
+		models.TaskEvent{TaskID: other.ID, ActorID: employer.ID, Type: models.TaskEventCreated},
+	))
+	require.NoError(t, r.CreateTaskEvents(ctx))
+
+	t.Run("events of a task, oldest first", func(t *testing.T) {
+		total, events, err := r.GetTaskEvents(ctx, GetTaskEventsOptions{TaskID: task.ID})
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		require.Len(t, events, 2)
+		require.Equal(t, models.TaskEventCreated, events[0].Type)
+		require.Nil(t, events[0].OldValue)
+		require.Equal(t, models.TaskFieldStatus, events[1].Field)
+		require.Equal(t, "pending", *events[1].OldValue)
+		require.Equal(t, "in_progress", *events[1].NewValue)
+		require.False(t, events[1].CreatedAt.IsZero())
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		total, events, err := r.GetTaskEvents(ctx, GetTaskEventsOptions{TaskID: task.ID, Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		require.Len(t, events, 1)
+		require.Equal(t, models.TaskEventUpdated, events[0].Type)
+	})
+
+	t.Run("events are immutable", func(t *testing.T) {
+		err := db.Model(&models.TaskEvent{}).Where("task_id = ?", task.ID).Update("new_value", "completed").Error
+		require.Error(t, err)
+	})
+}
@@ -2,43 +2,239 @@ package repo
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/llkhacquan/cisab/pkg/models"
 )
 
 type TaskRepo interface {
-	// GetTaskByID retrieves a task by its ID, return nil if not found
+	// GetTaskByID retrieves a task by its ID, with its labels, progress and open blockers, return nil if not found
 	GetTaskByID(ctx context.Context, id models.TaskID) (*models.Task, error)
-	// CreateTask creates a new task.
+	// GetTaskByIDForUpdate retrieves a task by its ID and locks it until the end of the transaction,
+	// so concurrent changes of the task are recorded in order. It returns nil if not found.
+	GetTaskByIDForUpdate(ctx context.Context, id models.TaskID) (*models.Task, error)
+	// CreateTask creates a new task, with its labels.
 	CreateTask(ctx context.Context, task models.Task) (models.Task, error)
 	// UpdateTaskStatus updates a task's status.
 	UpdateTaskStatus(ctx context.Context, id models.TaskID, status models.TaskStatus) (_updated bool, _ error)
 	// AssignTask assigns a task to an employee (assigneeID)
 	AssignTask(ctx context.Context, id models.TaskID, assigneeID models.UserID) (_updated bool, _ error)
+	// CountOpenSubtasks counts the subtasks of a task that are not done, i.e. not in a terminal status
+	CountOpenSubtasks(ctx context.Context, id models.TaskID) (int, error)
+	// CountOpenBlockers counts the blockers of a task that are not done
+	CountOpenBlockers(ctx context.Context, id models.TaskID) (int, error)
+	// GetTaskStatuses returns the statuses the tasks of an employer are in
+	GetTaskStatuses(ctx context.Context, employerID models.UserID) ([]models.TaskStatus, error)
 
-	// GetTasks retrieves all tasks satisfying the given criteria.
+	// GetTasks retrieves all tasks satisfying the given criteria, with their labels, progress and open blockers.
+	// The total is the number of tasks before pagination, it is 0 if options.SkipCount is set.
 	GetTasks(ctx context.Context, options GetTasksOptions) (_total int, _ []models.Task, _ error)
-	// GetTaskStatistics retrieves the number of tasks for each user.
-	// If no userIDs are provided, it retrieves statistics for all users.
-	GetTaskStatistics(ctx context.Context, userIDs ...models.UserID) (map[models.UserID]TaskStatistics, error)
+	// GetTaskPosition returns the position of a task in the order of sort, to get the tasks after it.
+	// Sorting by relevance needs the search query.
+	GetTaskPosition(ctx context.Context, id models.TaskID, sort TaskSort, searchQuery string) (TaskPosition, error)
+	// GetEmployeeSummaries returns the statistics of the tasks assigned to each employee, including the employees
+	// without tasks. The total is the number of employees.
+	GetEmployeeSummaries(ctx context.Context, options GetEmployeeSummariesOptions) (_total int, _ []EmployeeSummary, _ error)
+	// GetFlowMetrics measures the flow of the tasks of an employer done in a range of times: how many were done and
+	// how long they took, team-wide and by assignee, in each period of the range and over the whole range.
+	// The team comes first, then the assignees by ID, the whole range before the periods, which are in order.
+	GetFlowMetrics(ctx context.Context, options GetFlowMetricsOptions) ([]FlowMetricsGroup, error)
 }
 
 type GetTasksOptions struct {
+	// EmployerID and AssigneeID scope the tasks to the ones a user can see, the filters below narrow them down
+	EmployerID models.UserID // if not set, all employers are included
+	AssigneeID models.UserID // if not set, all assignees are included
+
 	// Filter by status
-	Status     models.TaskStatus // if not set, all statuses are included
-	EmployerID models.UserID     // if not set, all employers are included
-	AssigneeID models.UserID     // if not set, all assignees are included
+	Statuses    []models.TaskStatus // if not set, all statuses are included
+	Priority    models.TaskPriority // if not set, all priorities are included
+	LabelID     models.LabelID      // if set, only tasks with this label are included
+	EmployerIDs []models.UserID     // if set, only the tasks created by one of these employers are included
+	AssigneeIDs []models.UserID     // if set, only the tasks assigned to one of these users are included
+	Unassigned  bool                // if set, the unassigned tasks are included, and only them unless AssigneeIDs is set
+	ParentID    models.TaskID       // if set, only the subtasks of this task are included
+	OverdueAt   time.Time           // if set, only the tasks due before it and not in a terminal status are included
+	// DueBefore, DueAfter, CreatedBefore and CreatedAfter are ranges of due dates and creation times, the "after"
+	// bounds are inclusive and the "before" ones exclusive. The tasks without due date are not in any due range.
+	DueBefore     time.Time
+	DueAfter      time.Time
+	CreatedBefore time.Time
+	CreatedAfter  time.Time
+	// SearchQuery is a full-text search in the title and the description, in the syntax of web search engines.
+	// If set, only the matching tasks are included, with their Match, ranked by relevance unless Sort is set.
+	SearchQuery string
+
+	// Sort sorts the tasks by its fields in turn, and then by ID, so that each task has a position to paginate from
+	// with After. Without Sort, the matches of a SearchQuery are sorted by relevance, the other tasks by ID.
+	Sort TaskSort
+	// After only includes the tasks after this position in the order of Sort, for keyset pagination
+	After *TaskPosition
+	// SkipCount skips counting the tasks, which takes as long as getting all of them
+	SkipCount bool
+	Offset    int // offset for pagination
+	Limit     int // limit for pagination
+}
+
+// TaskSortKey is a key to sort the tasks by
+type TaskSortKey string
+
+const (
+	TaskSortCreatedAt TaskSortKey = "created_at"
+	TaskSortUpdatedAt TaskSortKey = "updated_at"
+	// TaskSortDueDate sorts the tasks without due date last, in both directions
+	TaskSortDueDate TaskSortKey = "due_date"
+	// TaskSortStatusActiveFirst sorts the tasks in an active status of their workflow first, then the ones not
+	// started, then the done ones, in a terminal status. The statuses of a kind are in the order of the workflow.
+	TaskSortStatusActiveFirst TaskSortKey = "status_active_first"
+	// TaskSortStatusDoneFirst sorts the done tasks first, then the active ones, then the ones not started
+	TaskSortStatusDoneFirst TaskSortKey = "status_done_first"
+	TaskSortPriority        TaskSortKey = "priority" // by urgency, not alphabetically
+	// TaskSortRelevance sorts the matches of the SearchQuery by their Match.Rank
+	TaskSortRelevance TaskSortKey = "relevance"
+)
+
+// TaskSortField is a key of a TaskSort, and its direction
+type TaskSortField struct {
+	Key  TaskSortKey
+	Desc bool
+}
+
+// TaskSort is the order of a task list: by each field in turn, and then by ID, in the direction of the last field
+type TaskSort []TaskSortField
+
+// String returns s as "key:asc,key:desc", two sorts with the same string are the same order
+func (s TaskSort) String() string {
+	fields := make([]string, len(s))
+	for i, field := range s {
+		direction := "asc"
+		if field.Desc {
+			direction = "desc"
+		}
+		fields[i] = string(field.Key) + ":" + direction
+	}
+	return strings.Join(fields, ",")
+}
+
+// TaskPosition is the position of a task in the order of a TaskSort, see GetTaskPosition
+type TaskPosition struct {
+	// Values are the values of the task the sort compares, in the order of the sort: a time.Time for the dates,
+	// an int64 for the statuses and the priority and a float64 for the relevance. A due date comes with a bool
+	// before it, whether the task has none, in which case the due date is nil.
+	Values []interface{}
+	ID     models.TaskID
+}
+
+// GetEmployeeSummariesOptions are the tasks to count in the employee summaries, and the order of the employees
+type GetEmployeeSummariesOptions struct {
+	EmployerID models.UserID // if set, only the tasks of this employer are counted
+	// CreatedAfter and CreatedBefore are a range of creation times of the tasks to count, the "after" bound is
+	// inclusive and the "before" one exclusive
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	OverdueAt     time.Time // the tasks due before it and not in a terminal status are overdue
+
+	// SortBy sorts the employees by a statistic, and then by ID. The employees without on-time rate come last.
+	SortBy EmployeeSummarySortKey
+	Desc   bool
+	Offset int // offset for pagination
+	Limit  int // limit for pagination
+}
+
+// EmployeeSummarySortKey is a statistic to sort the employee summaries by
+type EmployeeSummarySortKey string
 
-	// this is sql-like syntax, it might not be supported by all databases,
-	// but for the sake of this assignment, we'll assume it's supported.
-	OrderBy []string // the input should be in the format of "column_name ASC|DESC"
-	Offset  int      // offset for pagination
-	Limit   int      // limit for pagination
+const (
+	EmployeeSummarySortName            EmployeeSummarySortKey = "name"
+	EmployeeSummarySortTotalTasks      EmployeeSummarySortKey = "total_tasks"
+	EmployeeSummarySortActive          EmployeeSummarySortKey = "active"
+	EmployeeSummarySortTerminal        EmployeeSummarySortKey = "terminal"
+	EmployeeSummarySortPending         EmployeeSummarySortKey = "pending"
+	EmployeeSummarySortInProgress      EmployeeSummarySortKey = "in_progress"
+	EmployeeSummarySortCompleted       EmployeeSummarySortKey = "completed"
+	EmployeeSummarySortOverdue         EmployeeSummarySortKey = "overdue"
+	EmployeeSummarySortCompletedOnTime EmployeeSummarySortKey = "completed_on_time"
+	EmployeeSummarySortOnTimeRate      EmployeeSummarySortKey = "on_time_rate"
+)
+
+// EmployeeSummary is the statistics of the tasks assigned to an employee
+type EmployeeSummary struct {
+	Employee   models.User
+	Statistics TaskStatistics
 }
 
 type TaskStatistics struct {
 	TotalTasks int `json:"total_tasks"`
+	// Active and Terminal count the tasks in an active or a terminal status of their workflow
+	Active   int `json:"active"`
+	Terminal int `json:"terminal"`
+	// Overdue counts the tasks past their due date and not in a terminal status
+	Overdue int `json:"overdue"`
+	// CompletedOnTime counts the tasks in a terminal status that got there by their due date, and OnTimeRate is the
+	// share of them among the tasks in a terminal status with a due date, nil if there are none
+	CompletedOnTime int      `json:"completed_on_time"`
+	OnTimeRate      *float64 `json:"on_time_rate"`
+	// Statuses counts the tasks by status
+	Statuses map[models.TaskStatus]int `json:"statuses"`
+
+	// Pending, InProgress and Completed count the tasks by the kind of their status, whatever the workflow: waiting
+	// for work to start, active or terminal. They are named after the statuses of the default workflow.
 	Pending    int `json:"pending"`
 	InProgress int `json:"in_progress"`
 	Completed  int `json:"completed"`
+
+	// Labels counts the tasks by label, the tasks without labels are not counted
+	Labels []LabelCount `json:"labels"`
+}
+
+type LabelCount struct {
+	LabelID models.LabelID `json:"label_id"`
+	Name    string         `json:"name"`
+	Count   int            `json:"count"`
+}
+
+// FlowInterval is the length of the periods the flow metrics are measured in
+type FlowInterval string
+
+const (
+	FlowIntervalWeek  FlowInterval = "week"  // from Monday, in UTC
+	FlowIntervalMonth FlowInterval = "month" // from the first of the month, in UTC
+)
+
+// GetFlowMetricsOptions are the tasks to measure the flow of, and the periods to measure it in
+type GetFlowMetricsOptions struct {
+	EmployerID models.UserID // only the tasks of this employer are measured
+	// CompletedAfter and CompletedBefore are the range of times the tasks were done in, the "after" bound is
+	// inclusive and the "before" one exclusive
+	CompletedAfter  time.Time
+	CompletedBefore time.Time
+	Interval        FlowInterval
+}
+
+// FlowMetricsGroup is the flow metrics of the tasks done by an assignee, or by the whole team, in a period or over
+// the whole range
+type FlowMetricsGroup struct {
+	Assignee    *models.User // nil for the team, which includes the unassigned tasks
+	PeriodStart *time.Time   // nil for the whole range
+	Metrics     FlowMetrics
+}
+
+// FlowMetrics measures the flow of tasks: how many were done, and how long they took
+type FlowMetrics struct {
+	// Throughput is the number of tasks done
+	Throughput int `json:"throughput"`
+	// LeadTime is the time from the creation of the tasks to when they were done
+	LeadTime DurationStatistics `json:"lead_time"`
+	// CycleTime is the time from when the tasks were started to when they were done, the tasks done without ever
+	// being in an active status are not counted
+	CycleTime DurationStatistics `json:"cycle_time"`
+}
+
+// DurationStatistics sums up durations, in hours. The average and the percentiles are nil if there are none.
+type DurationStatistics struct {
+	Count        int      `json:"count"`
+	AverageHours *float64 `json:"average_hours"`
+	P50Hours     *float64 `json:"p50_hours"` // the median
+	P90Hours     *float64 `json:"p90_hours"`
 }
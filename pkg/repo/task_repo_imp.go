@@ -2,14 +2,37 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"time"
 
 	"github.com/llkhacquan/cisab/pkg/models"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var _ TaskRepo = (*taskRepoImpl)(nil)
 
+// joinTaskStatuses joins the statuses of the workflow of each task, to know whether a task is active or done.
+// Tasks are done once their status is terminal.
+const joinTaskStatuses = "LEFT JOIN employer_task_statuses ON employer_task_statuses.employer_id = tasks.employer_id AND employer_task_statuses.name = tasks.status"
+
+// searchQuery parses a full-text search, in the syntax of web search engines: words, "quoted phrases", OR and -word
+const searchQuery = "websearch_to_tsquery('english', ?)"
+
+// The headlines of the full-text search mark the matched words with private use characters, which are stripped from
+// the tasks beforehand, so the headlines can be escaped before the marks are turned into HTML tags (see highlight)
+const (
+	searchStartSel         = "\uE000"
+	searchStopSel          = "\uE001"
+	titleHeadlineOptions   = `HighlightAll=true, StartSel="` + searchStartSel + `", StopSel="` + searchStopSel + `"`
+	snippetHeadlineOptions = `MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=" … ", StartSel="` + searchStartSel + `", StopSel="` + searchStopSel + `"`
+)
+
 type taskRepoImpl struct {
 	db func(ctx context.Context) *gorm.DB
 }
@@ -28,14 +51,51 @@ func (r *taskRepoImpl) GetTaskByID(ctx context.Context, id models.TaskID) (*mode
 	if task.ID == 0 {
 		return nil, nil // Task not found
 	}
-	return &task, nil
+	tasks := []models.Task{task}
+	if err := r.attachLabels(ctx, tasks); err != nil {
+		return nil, err
+	}
+	if err := r.attachProgress(ctx, tasks); err != nil {
+		return nil, err
+	}
+	if err := r.attachBlockers(ctx, tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
 }
 
-// CreateTask creates a new task
+// GetTaskByIDForUpdate retrieves a task by its ID and locks it, return nil if not found
+func (r *taskRepoImpl) GetTaskByIDForUpdate(ctx context.Context, id models.TaskID) (*models.Task, error) {
+	var tasks []models.Task
+	err := r.db(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		Limit(1).
+		Find(&tasks).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task by id for update")
+	}
+	if len(tasks) == 0 {
+		return nil, nil // Task not found
+	}
+	return &tasks[0], nil
+}
+
+// CreateTask creates a new task, with its labels
 func (r *taskRepoImpl) CreateTask(ctx context.Context, task models.Task) (models.Task, error) {
 	if err := r.db(ctx).Create(&task).Error; err != nil {
 		return models.Task{}, errors.Wrap(err, "failed to create task")
 	}
+	if len(task.Labels) == 0 {
+		return task, nil
+	}
+
+	taskLabels := make([]map[string]interface{}, len(task.Labels))
+	for i, label := range task.Labels {
+		taskLabels[i] = map[string]interface{}{"task_id": task.ID, "label_id": label.ID}
+	}
+	if err := r.db(ctx).Table("task_labels").Create(taskLabels).Error; err != nil {
+		return models.Task{}, errors.Wrap(err, "failed to add task labels")
+	}
 	return task, nil
 }
 
@@ -65,13 +125,61 @@ func (r *taskRepoImpl) AssignTask(ctx context.Context, id models.TaskID, assigne
 	return result.RowsAffected > 0, nil
 }
 
+// CountOpenSubtasks counts the subtasks of a task that are not done
+func (r *taskRepoImpl) CountOpenSubtasks(ctx context.Context, id models.TaskID) (int, error) {
+	var count int64
+	err := r.db(ctx).Table("tasks").
+		Joins(joinTaskStatuses).
+		Where("tasks.parent_id = ? AND employer_task_statuses.terminal IS NOT TRUE", id).
+		Count(&count).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to count open subtasks")
+	}
+	return int(count), nil
+}
+
+// CountOpenBlockers counts the blockers of a task that are not done
+func (r *taskRepoImpl) CountOpenBlockers(ctx context.Context, id models.TaskID) (int, error) {
+	var count int64
+	err := r.db(ctx).Table("task_dependencies").
+		Joins("JOIN tasks ON tasks.id = task_dependencies.blocker_id").
+		Joins(joinTaskStatuses).
+		Where("task_dependencies.task_id = ? AND employer_task_statuses.terminal IS NOT TRUE", id).
+		Count(&count).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to count open blockers")
+	}
+	return int(count), nil
+}
+
+// GetTaskStatuses returns the statuses the tasks of an employer are in
+func (r *taskRepoImpl) GetTaskStatuses(ctx context.Context, employerID models.UserID) ([]models.TaskStatus, error) {
+	var statuses []models.TaskStatus
+	err := r.db(ctx).Model(&models.Task{}).
+		Where("employer_id = ?", employerID).
+		Distinct().
+		Pluck("status", &statuses).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task statuses")
+	}
+	return statuses, nil
+}
+
 // GetTasks retrieves all tasks satisfying the given criteria
 func (r *taskRepoImpl) GetTasks(ctx context.Context, options GetTasksOptions) (int, []models.Task, error) {
 	db := r.db(ctx)
 
 	// Apply filters
-	if options.Status != "" {
-		db = db.Where("status = ?", options.Status)
+	if len(options.Statuses) > 0 {
+		db = db.Where("status IN ?", options.Statuses)
+	}
+
+	if options.Priority != "" {
+		db = db.Where("priority = ?", options.Priority)
+	}
+
+	if options.LabelID != 0 {
+		db = db.Where("id IN (SELECT task_id FROM task_labels WHERE label_id = ?)", options.LabelID)
 	}
 
 	if options.EmployerID != 0 {
@@ -82,15 +190,70 @@ func (r *taskRepoImpl) GetTasks(ctx context.Context, options GetTasksOptions) (i
 		db = db.Where("assignee_id = ?", options.AssigneeID)
 	}
 
+	if len(options.EmployerIDs) > 0 {
+		db = db.Where("employer_id IN ?", options.EmployerIDs)
+	}
+
+	switch {
+	case len(options.AssigneeIDs) > 0 && options.Unassigned:
+		db = db.Where("(assignee_id IN ? OR assignee_id IS NULL)", options.AssigneeIDs)
+	case len(options.AssigneeIDs) > 0:
+		db = db.Where("assignee_id IN ?", options.AssigneeIDs)
+	case options.Unassigned:
+		db = db.Where("assignee_id IS NULL")
+	}
+
+	if options.ParentID != 0 {
+		db = db.Where("parent_id = ?", options.ParentID)
+	}
+
+	if !options.OverdueAt.IsZero() {
+		db = db.Where("due_date < ?", options.OverdueAt).
+			Where("id IN (SELECT tasks.id FROM tasks " + joinTaskStatuses + " WHERE employer_task_statuses.terminal IS NOT TRUE)")
+	}
+
+	if !options.DueBefore.IsZero() {
+		db = db.Where("due_date < ?", options.DueBefore)
+	}
+	if !options.DueAfter.IsZero() {
+		db = db.Where("due_date >= ?", options.DueAfter)
+	}
+	if !options.CreatedBefore.IsZero() {
+		db = db.Where("created_at < ?", options.CreatedBefore)
+	}
+	if !options.CreatedAfter.IsZero() {
+		db = db.Where("created_at >= ?", options.CreatedAfter)
+	}
+
+	if options.SearchQuery != "" {
+		db = db.Where("search_vector @@ "+searchQuery, options.SearchQuery)
+	}
+
 	// Count total results (before pagination)
 	var totalCount int64
-	if err := db.Model(&models.Task{}).Count(&totalCount).Error; err != nil {
-		return 0, nil, errors.Wrap(err, "failed to count tasks")
+	if !options.SkipCount {
+		if err := db.Model(&models.Task{}).Count(&totalCount).Error; err != nil {
+			return 0, nil, errors.Wrap(err, "failed to count tasks")
+		}
 	}
 
 	// Apply sorting, pagination, and other options
-	for _, orderBy := range options.OrderBy {
-		db = db.Order(orderBy)
+	sort := options.Sort
+	if len(sort) == 0 && options.SearchQuery != "" {
+		// Rank by relevance, newest first among equals
+		sort = TaskSort{{Key: TaskSortRelevance, Desc: true}}
+	}
+	terms, err := sort.searchTerms(options.SearchQuery)
+	if err != nil {
+		return 0, nil, err
+	}
+	db = db.Order(orderBy(terms))
+	if options.After != nil {
+		if len(options.After.Values) != len(terms)-1 {
+			return 0, nil, errors.New("the position is not in the order of the sort")
+		}
+		condition, vars := keysetCondition(terms, append(append([]interface{}{}, options.After.Values...), options.After.ID))
+		db = db.Where(condition, vars...)
 	}
 	if options.Limit > 0 {
 		db = db.Limit(options.Limit)
@@ -103,44 +266,625 @@ func (r *taskRepoImpl) GetTasks(ctx context.Context, options GetTasksOptions) (i
 	if err := db.Find(&tasks).Error; err != nil {
 		return 0, nil, errors.Wrap(err, "failed to get tasks")
 	}
+	if err := r.attachLabels(ctx, tasks); err != nil {
+		return 0, nil, err
+	}
+	if err := r.attachProgress(ctx, tasks); err != nil {
+		return 0, nil, err
+	}
+	if err := r.attachBlockers(ctx, tasks); err != nil {
+		return 0, nil, err
+	}
+	if options.SearchQuery != "" {
+		if err := r.attachMatches(ctx, tasks, options.SearchQuery); err != nil {
+			return 0, nil, err
+		}
+	}
 
 	return int(totalCount), tasks, nil
 }
 
-// GetTaskStatistics retrieves the number of tasks for each user
-func (r *taskRepoImpl) GetTaskStatistics(ctx context.Context, userIDs ...models.UserID) (map[models.UserID]TaskStatistics, error) {
-	result := make(map[models.UserID]TaskStatistics)
+// GetTaskPosition returns the position of a task in the order of sort
+func (r *taskRepoImpl) GetTaskPosition(ctx context.Context, id models.TaskID, sort TaskSort, searchQuery string) (TaskPosition, error) {
+	terms, err := sort.searchTerms(searchQuery)
+	if err != nil {
+		return TaskPosition{}, err
+	}
+	terms = terms[:len(terms)-1] // the ID is known
+
+	exprs := make([]string, len(terms))
+	values := make([]interface{}, len(terms))
+	var vars []interface{}
+	for i, term := range terms {
+		exprs[i] = term.expr
+		vars = append(vars, term.vars...)
+		values[i] = term.kind.dest()
+	}
+	if len(terms) > 0 {
+		err = r.db(ctx).Raw("SELECT "+strings.Join(exprs, ", ")+" FROM tasks WHERE tasks.id = ?", append(vars, id)...).
+			Row().Scan(values...)
+		if err != nil {
+			return TaskPosition{}, errors.Wrap(err, "failed to get task position")
+		}
+	}
+	for i, term := range terms {
+		values[i] = term.kind.value(values[i])
+	}
+	return TaskPosition{Values: values, ID: id}, nil
+}
+
+// DecodePosition decodes a position in the order of s from the JSON of its values
+func (s TaskSort) DecodePosition(values []json.RawMessage, id models.TaskID) (TaskPosition, error) {
+	terms, err := s.terms("")
+	if err != nil {
+		return TaskPosition{}, err
+	}
+	terms = terms[:len(terms)-1]
+	if len(values) != len(terms) {
+		return TaskPosition{}, errors.New("the position is not in the order of the sort")
+	}
+	position := TaskPosition{Values: make([]interface{}, len(terms)), ID: id}
+	for i, term := range terms {
+		dest := term.kind.dest()
+		if err := json.Unmarshal(values[i], dest); err != nil {
+			return TaskPosition{}, errors.Wrap(err, "failed to decode position")
+		}
+		if term.kind == sortTermTime && !dest.(*sql.NullTime).Valid {
+			return TaskPosition{}, errors.New("failed to decode position: missing time")
+		}
+		position.Values[i] = term.kind.value(dest)
+	}
+	return position, nil
+}
+
+// sortTermKind is the type of the values of a sortTerm
+type sortTermKind int
+
+const (
+	sortTermTime sortTermKind = iota
+	sortTermOptionalTime
+	sortTermBool
+	sortTermInt
+	sortTermFloat
+)
+
+// dest returns a pointer to scan or decode a value of kind k into
+func (k sortTermKind) dest() interface{} {
+	switch k {
+	case sortTermTime, sortTermOptionalTime:
+		return new(sql.NullTime)
+	case sortTermBool:
+		return new(bool)
+	case sortTermInt:
+		return new(int64)
+	default:
+		return new(float64)
+	}
+}
+
+// value returns the value of a dest of kind k, a nil interface for a missing time
+func (k sortTermKind) value(dest interface{}) interface{} {
+	switch v := dest.(type) {
+	case *sql.NullTime:
+		if !v.Valid {
+			return nil
+		}
+		return v.Time
+	case *bool:
+		return *v
+	case *int64:
+		return *v
+	case *float64:
+		return *v
+	}
+	return nil
+}
+
+// sortTerm is an SQL expression the tasks are sorted by
+type sortTerm struct {
+	expr string
+	vars []interface{}
+	desc bool
+	kind sortTermKind
+}
+
+// statusRank ranks the status of a task by the kind of the status in the workflow of the task, and then by its
+// position in the workflow. The statuses missing from the workflow come after the ones not started.
+func statusRank(active, terminal, notStarted int) string {
+	return fmt.Sprintf(`COALESCE((SELECT CASE WHEN s.active THEN %d WHEN s.terminal THEN %d ELSE %d END + s.position
+	FROM employer_task_statuses s WHERE s.employer_id = tasks.employer_id AND s.name = tasks.status), %d)`,
+		active*maxWorkflowPositions, terminal*maxWorkflowPositions, notStarted*maxWorkflowPositions,
+		notStarted*maxWorkflowPositions+maxWorkflowPositions-1)
+}
+
+// maxWorkflowPositions is more than the number of statuses of a workflow, so the ranks of the kinds do not overlap
+const maxWorkflowPositions = 100
+
+// searchTerms returns the terms of the sort of the tasks matching a search query, see terms.
+// Sorting by relevance needs one.
+func (s TaskSort) searchTerms(search string) ([]sortTerm, error) {
+	for _, field := range s {
+		if field.Key == TaskSortRelevance && search == "" {
+			return nil, errors.New("sorting by relevance needs a search query")
+		}
+	}
+	return s.terms(search)
+}
+
+// terms returns the terms of the sort, the last one is the ID
+func (s TaskSort) terms(search string) ([]sortTerm, error) {
+	terms := make([]sortTerm, 0, len(s)+2)
+	for _, field := range s {
+		switch field.Key {
+		case TaskSortCreatedAt:
+			terms = append(terms, sortTerm{expr: "tasks.created_at", desc: field.Desc, kind: sortTermTime})
+		case TaskSortUpdatedAt:
+			terms = append(terms, sortTerm{expr: "tasks.updated_at", desc: field.Desc, kind: sortTermTime})
+		case TaskSortDueDate:
+			// The tasks without due date come last in both directions
+			terms = append(terms,
+				sortTerm{expr: "(tasks.due_date IS NULL)", kind: sortTermBool},
+				sortTerm{expr: "tasks.due_date", desc: field.Desc, kind: sortTermOptionalTime})
+		case TaskSortStatusActiveFirst:
+			terms = append(terms, sortTerm{expr: statusRank(0, 2, 1), desc: field.Desc, kind: sortTermInt})
+		case TaskSortStatusDoneFirst:
+			terms = append(terms, sortTerm{expr: statusRank(1, 0, 2), desc: field.Desc, kind: sortTermInt})
+		case TaskSortPriority:
+			terms = append(terms, sortTerm{expr: "tasks.priority_rank", desc: field.Desc, kind: sortTermInt})
+		case TaskSortRelevance:
+			terms = append(terms, sortTerm{expr: "ts_rank_cd(tasks.search_vector, " + searchQuery + ")",
+				vars: []interface{}{search}, desc: field.Desc, kind: sortTermFloat})
+		default:
+			return nil, errors.Errorf("unknown sort key %q", field.Key)
+		}
+	}
+	idDesc := len(s) > 0 && s[len(s)-1].Desc
+	return append(terms, sortTerm{expr: "tasks.id", desc: idDesc, kind: sortTermInt}), nil
+}
+
+// orderBy returns the ORDER BY clause of the terms of a sort
+func orderBy(terms []sortTerm) clause.OrderBy {
+	exprs := make([]string, len(terms))
+	var vars []interface{}
+	for i, term := range terms {
+		exprs[i] = term.expr + " ASC"
+		if term.desc {
+			exprs[i] = term.expr + " DESC"
+		}
+		vars = append(vars, term.vars...)
+	}
+	return clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(exprs, ", "), Vars: vars, WithoutParentheses: true}}
+}
+
+// keysetCondition returns the condition of the tasks after the values of the terms of a sort. When all the terms
+// are in the same direction, it is a row comparison, which the indexes help with. Otherwise, a task is after the
+// values if it is after them on a term, and equal to them on the terms before.
+func keysetCondition(terms []sortTerm, values []interface{}) (string, []interface{}) {
+	uniform := true
+	for _, term := range terms {
+		uniform = uniform && term.desc == terms[0].desc && term.kind != sortTermOptionalTime
+	}
+	if uniform {
+		exprs := make([]string, len(terms))
+		var vars []interface{}
+		for i, term := range terms {
+			exprs[i] = term.expr
+			vars = append(vars, term.vars...)
+		}
+		operator := " > "
+		if terms[0].desc {
+			operator = " < "
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(terms)), ", ")
+		return "(" + strings.Join(exprs, ", ") + ")" + operator + "(" + placeholders + ")", append(vars, values...)
+	}
+
+	var conditions []string
+	var vars []interface{}
+	for i, term := range terms {
+		if values[i] == nil {
+			continue // nothing is after a missing due date among the tasks without due date
+		}
+		var condition []string
+		for j, before := range terms[:i] {
+			if before.kind == sortTermOptionalTime {
+				condition = append(condition, before.expr+" IS NOT DISTINCT FROM ?")
+			} else {
+				condition = append(condition, before.expr+" = ?")
+			}
+			vars = append(append(vars, before.vars...), values[j])
+		}
+		operator := " > ?"
+		if term.desc {
+			operator = " < ?"
+		}
+		condition = append(condition, term.expr+operator)
+		vars = append(append(vars, term.vars...), values[i])
+		conditions = append(conditions, "("+strings.Join(condition, " AND ")+")")
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", vars
+}
+
+// employeeSummarySortColumns are the columns of the employee summary the employees are sorted by
+var employeeSummarySortColumns = map[EmployeeSummarySortKey]string{
+	EmployeeSummarySortName:            "users.name",
+	EmployeeSummarySortTotalTasks:      "counts.total_tasks",
+	EmployeeSummarySortActive:          "counts.active",
+	EmployeeSummarySortTerminal:        "counts.terminal",
+	EmployeeSummarySortPending:         "counts.pending",
+	EmployeeSummarySortInProgress:      "counts.active",
+	EmployeeSummarySortCompleted:       "counts.terminal",
+	EmployeeSummarySortOverdue:         "counts.overdue",
+	EmployeeSummarySortCompletedOnTime: "counts.completed_on_time",
+	EmployeeSummarySortOnTimeRate:      "on_time_rate",
+}
+
+// GetEmployeeSummaries returns the statistics of the tasks of every employee, with a single query
+func (r *taskRepoImpl) GetEmployeeSummaries(ctx context.Context, options GetEmployeeSummariesOptions) (int, []EmployeeSummary, error) {
+	column, ok := employeeSummarySortColumns[options.SortBy]
+	if !ok {
+		return 0, nil, errors.Errorf("unknown sort key %q", options.SortBy)
+	}
+	direction := " ASC"
+	if options.Desc {
+		direction = " DESC"
+	}
+
+	// The tasks to count, with the kind of their status in their workflow
+	var conditions []string
+	var vars []interface{}
+	if options.EmployerID != 0 {
+		conditions = append(conditions, "tasks.employer_id = ?")
+		vars = append(vars, options.EmployerID)
+	}
+	if !options.CreatedAfter.IsZero() {
+		conditions = append(conditions, "tasks.created_at >= ?")
+		vars = append(vars, options.CreatedAfter)
+	}
+	if !options.CreatedBefore.IsZero() {
+		conditions = append(conditions, "tasks.created_at < ?")
+		vars = append(vars, options.CreatedBefore)
+	}
+	scoped := `SELECT tasks.id, tasks.assignee_id, tasks.status, tasks.due_date, tasks.completed_at,
+	COALESCE(employer_task_statuses.active, FALSE) AS active,
+	COALESCE(employer_task_statuses.terminal, FALSE) AS terminal
+FROM tasks ` + joinTaskStatuses + `
+WHERE ` + strings.Join(append([]string{"tasks.assignee_id IS NOT NULL"}, conditions...), " AND ")
+	vars = append(vars, options.OverdueAt)
+
+	query := `WITH scoped AS (` + scoped + `),
+counts AS (
+	SELECT users.id AS employee_id,
+		COUNT(scoped.id) AS total_tasks,
+		COUNT(*) FILTER (WHERE scoped.active) AS active,
+		COUNT(*) FILTER (WHERE scoped.terminal) AS terminal,
+		COUNT(*) FILTER (WHERE NOT scoped.active AND NOT scoped.terminal) AS pending,
+		COUNT(*) FILTER (WHERE NOT scoped.terminal AND scoped.due_date < ?) AS overdue,
+		COUNT(*) FILTER (WHERE scoped.terminal AND scoped.due_date IS NOT NULL) AS completed_with_due_date,
+		COUNT(*) FILTER (WHERE scoped.terminal AND scoped.completed_at <= scoped.due_date) AS completed_on_time
+	FROM users
+	LEFT JOIN scoped ON scoped.assignee_id = users.id
+	WHERE users.role = 'employee'
+	GROUP BY users.id
+)
+SELECT users.id, users.email, users.name, users.role, users.created_at, users.updated_at,
+	counts.total_tasks, counts.active, counts.terminal, counts.pending, counts.overdue, counts.completed_on_time,
+	counts.completed_on_time::FLOAT8 / NULLIF(counts.completed_with_due_date, 0) AS on_time_rate,
+	COALESCE((SELECT jsonb_object_agg(by_status.status, by_status.count)
+		FROM (SELECT scoped.status, COUNT(*) AS count FROM scoped WHERE scoped.assignee_id = users.id GROUP BY scoped.status) by_status),
+		'{}') AS statuses,
+	COALESCE((SELECT jsonb_agg(jsonb_build_object('label_id', labels.id, 'name', labels.name, 'count', by_label.count) ORDER BY labels.name)
+		FROM (SELECT task_labels.label_id, COUNT(*) AS count FROM scoped
+			JOIN task_labels ON task_labels.task_id = scoped.id
+			WHERE scoped.assignee_id = users.id
+			GROUP BY task_labels.label_id) by_label
+		JOIN labels ON labels.id = by_label.label_id),
+		'[]') AS labels,
+	COUNT(*) OVER () AS total_count
+FROM counts
+JOIN users ON users.id = counts.employee_id
+ORDER BY ` + column + direction + ` NULLS LAST, users.id` + direction
+	if options.Limit > 0 {
+		query += " LIMIT ?"
+		vars = append(vars, options.Limit)
+	}
+	if options.Offset > 0 {
+		query += " OFFSET ?"
+		vars = append(vars, options.Offset)
+	}
+
+	var rows []struct {
+		models.User
+		TotalTasks      int
+		Active          int
+		Terminal        int
+		Pending         int
+		Overdue         int
+		CompletedOnTime int
+		OnTimeRate      *float64
+		Statuses        string
+		Labels          string
+		TotalCount      int
+	}
+	if err := r.db(ctx).Raw(query, vars...).Scan(&rows).Error; err != nil {
+		return 0, nil, errors.Wrap(err, "failed to get employee summaries")
+	}
+
+	summaries := make([]EmployeeSummary, len(rows))
+	for i, row := range rows {
+		stats := TaskStatistics{
+			TotalTasks:      row.TotalTasks,
+			Active:          row.Active,
+			Terminal:        row.Terminal,
+			Pending:         row.Pending,
+			InProgress:      row.Active,
+			Completed:       row.Terminal,
+			Overdue:         row.Overdue,
+			CompletedOnTime: row.CompletedOnTime,
+			OnTimeRate:      row.OnTimeRate,
+		}
+		if err := json.Unmarshal([]byte(row.Statuses), &stats.Statuses); err != nil {
+			return 0, nil, errors.Wrap(err, "failed to decode status counts")
+		}
+		if err := json.Unmarshal([]byte(row.Labels), &stats.Labels); err != nil {
+			return 0, nil, errors.Wrap(err, "failed to decode label counts")
+		}
+		summaries[i] = EmployeeSummary{Employee: row.User, Statistics: stats}
+	}
+	if len(rows) > 0 {
+		return rows[0].TotalCount, summaries, nil
+	}
+
+	// The page is past the last employee, count them apart
+	var total int64
+	if options.Offset > 0 {
+		if err := r.db(ctx).Model(&models.User{}).Where("role = ?", models.UserRoleEmployee).Count(&total).Error; err != nil {
+			return 0, nil, errors.Wrap(err, "failed to count employees")
+		}
+	}
+	return int(total), summaries, nil
+}
+
+// GetFlowMetrics measures the flow of the tasks of an employer done in a range, with a single query grouping them by
+// assignee and period in turn. The durations are computed from the times the database keeps, see migration 0017.
+func (r *taskRepoImpl) GetFlowMetrics(ctx context.Context, options GetFlowMetricsOptions) ([]FlowMetricsGroup, error) {
+	if options.Interval != FlowIntervalWeek && options.Interval != FlowIntervalMonth {
+		return nil, errors.Errorf("unknown flow interval %q", options.Interval)
+	}
+
+	query := `WITH done AS (
+	SELECT tasks.assignee_id,
+		date_trunc(?, tasks.completed_at AT TIME ZONE 'UTC') AS period_start,
+		(EXTRACT(EPOCH FROM tasks.completed_at - tasks.created_at) / 3600)::FLOAT8 AS lead_time,
+		(EXTRACT(EPOCH FROM tasks.completed_at - tasks.started_at) / 3600)::FLOAT8 AS cycle_time
+	FROM tasks
+	WHERE tasks.employer_id = ? AND tasks.completed_at >= ? AND tasks.completed_at < ?
+)
+SELECT GROUPING(done.assignee_id) = 1 AS team, done.assignee_id, done.period_start,
+	COUNT(*) AS throughput,
+	COUNT(done.lead_time) AS lead_time_count,
+	AVG(done.lead_time) AS lead_time_average,
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY done.lead_time) AS lead_time_p50,
+	percentile_cont(0.9) WITHIN GROUP (ORDER BY done.lead_time) AS lead_time_p90,
+	COUNT(done.cycle_time) AS cycle_time_count,
+	AVG(done.cycle_time) AS cycle_time_average,
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY done.cycle_time) AS cycle_time_p50,
+	percentile_cont(0.9) WITHIN GROUP (ORDER BY done.cycle_time) AS cycle_time_p90
+FROM done
+GROUP BY GROUPING SETS ((), (done.period_start), (done.assignee_id), (done.assignee_id, done.period_start))
+HAVING GROUPING(done.assignee_id) = 1 OR done.assignee_id IS NOT NULL
+ORDER BY team DESC, done.assignee_id, GROUPING(done.period_start) DESC, done.period_start`
+	vars := []interface{}{string(options.Interval), options.EmployerID, options.CompletedAfter, options.CompletedBefore}
 
 	var rows []struct {
-		AssigneeID models.UserID
-		TotalTasks int
-		Pending    int
-		InProgress int
-		Completed  int
-	}
-	tx := r.db(ctx).Table("tasks").Select(`
-	assignee_id,
-	COUNT(*) as total_tasks,
-	SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END) as pending,
-	SUM(CASE WHEN status = 'in_progress' THEN 1 ELSE 0 END) as in_progress,
-	SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END) as completed`)
-	if len(userIDs) > 0 {
-		tx = tx.Where("assignee_id IN (?)", userIDs)
-	}
-	if err := tx.Group("assignee_id").Scan(&rows).Error; err != nil {
-		return nil, errors.Wrap(err, "failed to get task statistics")
-	}
-	tx = tx.Group("assignee_id")
-	if err := tx.Scan(&rows).Error; err != nil {
-		return nil, errors.Wrap(err, "failed to get task statistics")
+		Team             bool
+		AssigneeID       *models.UserID
+		PeriodStart      *time.Time
+		Throughput       int
+		LeadTimeCount    int
+		LeadTimeAverage  *float64
+		LeadTimeP50      *float64 `gorm:"column:lead_time_p50"`
+		LeadTimeP90      *float64 `gorm:"column:lead_time_p90"`
+		CycleTimeCount   int
+		CycleTimeAverage *float64
+		CycleTimeP50     *float64 `gorm:"column:cycle_time_p50"`
+		CycleTimeP90     *float64 `gorm:"column:cycle_time_p90"`
+	}
+	if err := r.db(ctx).Raw(query, vars...).Scan(&rows).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get flow metrics")
 	}
+
+	// Get the assignees, with one query
+	var assigneeIDs []models.UserID
 	for _, row := range rows {
-		result[row.AssigneeID] = TaskStatistics{
-			TotalTasks: row.TotalTasks,
-			Pending:    row.Pending,
-			InProgress: row.InProgress,
-			Completed:  row.Completed,
+		if !row.Team && row.PeriodStart == nil {
+			assigneeIDs = append(assigneeIDs, *row.AssigneeID)
 		}
 	}
-	return result, nil
+	assignees := make(map[models.UserID]*models.User, len(assigneeIDs))
+	if len(assigneeIDs) > 0 {
+		var users []models.User
+		if err := r.db(ctx).Where("id IN ?", assigneeIDs).Find(&users).Error; err != nil {
+			return nil, errors.Wrap(err, "failed to get assignees")
+		}
+		for i := range users {
+			assignees[users[i].ID] = &users[i]
+		}
+	}
+
+	groups := make([]FlowMetricsGroup, len(rows))
+	for i, row := range rows {
+		groups[i] = FlowMetricsGroup{
+			PeriodStart: row.PeriodStart,
+			Metrics: FlowMetrics{
+				Throughput: row.Throughput,
+				LeadTime: DurationStatistics{
+					Count:        row.LeadTimeCount,
+					AverageHours: row.LeadTimeAverage,
+					P50Hours:     row.LeadTimeP50,
+					P90Hours:     row.LeadTimeP90,
+				},
+				CycleTime: DurationStatistics{
+					Count:        row.CycleTimeCount,
+					AverageHours: row.CycleTimeAverage,
+					P50Hours:     row.CycleTimeP50,
+					P90Hours:     row.CycleTimeP90,
+				},
+			},
+		}
+		if !row.Team {
+			groups[i].Assignee = assignees[*row.AssigneeID]
+		}
+	}
+	return groups, nil
+}
+
+// attachLabels sets the labels of the tasks, with one query
+func (r *taskRepoImpl) attachLabels(ctx context.Context, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	taskIDs := make([]models.TaskID, len(tasks))
+	index := make(map[models.TaskID]int, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+		index[task.ID] = i
+	}
+
+	var rows []struct {
+		TaskID     models.TaskID
+		ID         models.LabelID
+		EmployerID models.UserID
+		Name       string
+		CreatedAt  time.Time
+	}
+	err := r.db(ctx).Table("labels").
+		Select("task_labels.task_id, labels.id, labels.employer_id, labels.name, labels.created_at").
+		Joins("JOIN task_labels ON task_labels.label_id = labels.id").
+		Where("task_labels.task_id IN (?)", taskIDs).
+		Order("labels.name ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to get task labels")
+	}
+	for _, row := range rows {
+		i := index[row.TaskID]
+		tasks[i].Labels = append(tasks[i].Labels, models.Label{
+			ID:         row.ID,
+			EmployerID: row.EmployerID,
+			Name:       row.Name,
+			CreatedAt:  row.CreatedAt,
+		})
+	}
+	return nil
+}
+
+// attachProgress sets the progress of the tasks that have subtasks, with one query. Done subtasks count as completed.
+func (r *taskRepoImpl) attachProgress(ctx context.Context, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	taskIDs := make([]models.TaskID, len(tasks))
+	index := make(map[models.TaskID]int, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+		index[task.ID] = i
+	}
+
+	var rows []struct {
+		ParentID  models.TaskID
+		Subtasks  int
+		Completed int
+	}
+	err := r.db(ctx).Table("tasks").
+		Select("tasks.parent_id, COUNT(*) AS subtasks, SUM(CASE WHEN employer_task_statuses.terminal THEN 1 ELSE 0 END) AS completed").
+		Joins(joinTaskStatuses).
+		Where("tasks.parent_id IN (?)", taskIDs).
+		Group("tasks.parent_id").
+		Scan(&rows).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to get task progress")
+	}
+	for _, row := range rows {
+		progress := models.NewTaskProgress(row.Subtasks, row.Completed)
+		tasks[index[row.ParentID]].Progress = &progress
+	}
+	return nil
+}
+
+// attachBlockers sets the blockers of the tasks that are not done yet, with one query
+func (r *taskRepoImpl) attachBlockers(ctx context.Context, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	taskIDs := make([]models.TaskID, len(tasks))
+	index := make(map[models.TaskID]int, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+		index[task.ID] = i
+	}
+
+	var rows []struct {
+		TaskID    models.TaskID
+		BlockerID models.TaskID
+	}
+	err := r.db(ctx).Table("task_dependencies").
+		Select("task_dependencies.task_id, task_dependencies.blocker_id").
+		Joins("JOIN tasks ON tasks.id = task_dependencies.blocker_id").
+		Joins(joinTaskStatuses).
+		Where("task_dependencies.task_id IN (?) AND employer_task_statuses.terminal IS NOT TRUE", taskIDs).
+		Order("task_dependencies.blocker_id ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to get task blockers")
+	}
+	for _, row := range rows {
+		i := index[row.TaskID]
+		tasks[i].BlockedBy = append(tasks[i].BlockedBy, row.BlockerID)
+	}
+	return nil
+}
+
+// attachMatches sets how the tasks match a full-text search, with one query
+func (r *taskRepoImpl) attachMatches(ctx context.Context, tasks []models.Task, query string) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	taskIDs := make([]models.TaskID, len(tasks))
+	index := make(map[models.TaskID]int, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+		index[task.ID] = i
+	}
+
+	var rows []struct {
+		ID                  models.TaskID
+		SearchRank          float64
+		TitleHeadline       string
+		DescriptionHeadline string
+	}
+	err := r.db(ctx).Table("tasks").
+		Select(`id, ts_rank_cd(search_vector, `+searchQuery+`) AS search_rank,
+	ts_headline('english', translate(title, ?, ''), `+searchQuery+`, ?) AS title_headline,
+	ts_headline('english', translate(COALESCE(description, ''), ?, ''), `+searchQuery+`, ?) AS description_headline`,
+			query, searchStartSel+searchStopSel, query, titleHeadlineOptions,
+			searchStartSel+searchStopSel, query, snippetHeadlineOptions).
+		Where("id IN (?)", taskIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to get task search matches")
+	}
+	for _, row := range rows {
+		tasks[index[row.ID]].Match = &models.TaskMatch{
+			Rank:    row.SearchRank,
+			Title:   highlight(row.TitleHeadline),
+			Snippet: highlight(row.DescriptionHeadline),
+		}
+	}
+	return nil
+}
+
+// highlight escapes a headline of the full-text search, and wraps its matched words in <mark> tags
+func highlight(headline string) string {
+	return strings.NewReplacer(searchStartSel, "<mark>", searchStopSel, "</mark>").Replace(html.EscapeString(headline))
 }
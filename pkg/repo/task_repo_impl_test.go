@@ -2,6 +2,8 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -131,6 +133,20 @@ func Test_taskRepoImpl_GetTaskByID(t *testing.T) {
 		require.NoError(t, err)
 		require.Nil(t, task)
 	})
+
+	t.Run("get task for update", func(t *testing.T) {
+		employer := createTestUserForTask(t, ctx, userRepo, "employer-lock@example.com", "Employer Lock", models.UserRoleEmployer)
+		createdTask := createTestTask(t, ctx, taskRepo, "Task for update", "This is a test task for GetByIDForUpdate", employer.ID, nil)
+
+		task, err := taskRepo.GetTaskByIDForUpdate(ctx, createdTask.ID)
+		require.NoError(t, err)
+		require.NotNil(t, task)
+		require.Equal(t, createdTask.ID, task.ID)
+
+		task, err = taskRepo.GetTaskByIDForUpdate(ctx, models.TaskID(9999))
+		require.NoError(t, err)
+		require.Nil(t, task)
+	})
 }
 
 func Test_taskRepoImpl_UpdateTaskStatus(t *testing.T) {
@@ -284,7 +300,7 @@ func Test_taskRepoImpl_GetTasks(t *testing.T) {
 
 	t.Run("filter by status", func(t *testing.T) {
 		count, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{
-			Status: models.TaskStatusInProgress,
+			Statuses: []models.TaskStatus{models.TaskStatusInProgress},
 		})
 		require.NoError(t, err)
 		require.Equal(t, 2, count)
@@ -299,7 +315,7 @@ func Test_taskRepoImpl_GetTasks(t *testing.T) {
 	t.Run("filter by employer and status", func(t *testing.T) {
 		count, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{
 			EmployerID: employer1.ID,
-			Status:     models.TaskStatusCompleted,
+			Statuses:   []models.TaskStatus{models.TaskStatusCompleted},
 		})
 		require.NoError(t, err)
 		require.Equal(t, 1, count)
@@ -337,7 +353,7 @@ func Test_taskRepoImpl_GetTasks(t *testing.T) {
 
 	t.Run("order by created_at", func(t *testing.T) {
 		count, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{
-			OrderBy: []string{"created_at ASC"},
+			Sort: TaskSort{{Key: TaskSortCreatedAt}},
 		})
 		require.NoError(t, err)
 		require.Equal(t, 5, count)
@@ -350,89 +366,721 @@ func Test_taskRepoImpl_GetTasks(t *testing.T) {
 	})
 }
 
-func Test_taskRepoImpl_GetTaskStatistics(t *testing.T) {
+// employeeStatistics returns the statistics of an employee from the employee summaries
+func employeeStatistics(t *testing.T, ctx context.Context, taskRepo *taskRepoImpl, options GetEmployeeSummariesOptions, employeeID models.UserID) TaskStatistics {
+	if options.SortBy == "" {
+		options.SortBy = EmployeeSummarySortName
+	}
+	if options.OverdueAt.IsZero() {
+		options.OverdueAt = time.Now()
+	}
+	_, summaries, err := taskRepo.GetEmployeeSummaries(ctx, options)
+	require.NoError(t, err)
+	for _, summary := range summaries {
+		if summary.Employee.ID == employeeID {
+			return summary.Statistics
+		}
+	}
+	t.Fatalf("employee %d is not in the summaries", employeeID)
+	return TaskStatistics{}
+}
+
+func Test_taskRepoImpl_GetEmployeeSummaries(t *testing.T) {
 	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
 
 	// Create users
 	employer := createTestUserForTask(t, ctx, userRepo, "employer10@example.com", "Employer 10", models.UserRoleEmployer)
+	otherEmployer := createTestUserForTask(t, ctx, userRepo, "employer11@example.com", "Employer 11", models.UserRoleEmployer)
 	employee1 := createTestUserForTask(t, ctx, userRepo, "employee10@example.com", "Employee 10", models.UserRoleEmployee)
 	employee2 := createTestUserForTask(t, ctx, userRepo, "employee11@example.com", "Employee 11", models.UserRoleEmployee)
+	employee3 := createTestUserForTask(t, ctx, userRepo, "employee12@example.com", "Employee 12", models.UserRoleEmployee)
+
+	now := time.Now()
+	yesterday, tomorrow := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+	createTask := func(employerID, assigneeID models.UserID, status models.TaskStatus, dueDate *time.Time) models.Task {
+		task, err := taskRepo.CreateTask(ctx, models.Task{Title: "Stat Task", EmployerID: employerID, AssigneeID: &assigneeID, DueDate: dueDate})
+		require.NoError(t, err)
+		if status != models.TaskStatusPending {
+			_, err = taskRepo.UpdateTaskStatus(ctx, task.ID, status)
+			require.NoError(t, err)
+		}
+		return task
+	}
+
+	// Tasks of employee1: one done on time, one done late, one overdue, one started
+	onTime := createTask(employer.ID, employee1.ID, models.TaskStatusCompleted, &tomorrow)
+	createTask(employer.ID, employee1.ID, models.TaskStatusCompleted, &yesterday)
+	createTask(employer.ID, employee1.ID, models.TaskStatusPending, &yesterday)
+	createTask(employer.ID, employee1.ID, models.TaskStatusInProgress, nil)
+	// Tasks of employee2, one of another employer
+	createTask(employer.ID, employee2.ID, models.TaskStatusInProgress, &tomorrow)
+	createTask(otherEmployer.ID, employee2.ID, models.TaskStatusPending, nil)
+
+	t.Run("every employee is summarized", func(t *testing.T) {
+		total, summaries, err := taskRepo.GetEmployeeSummaries(ctx, GetEmployeeSummariesOptions{OverdueAt: now, SortBy: EmployeeSummarySortName})
+		require.NoError(t, err)
+		require.Equal(t, 3, total)
+		require.Equal(t, []models.UserID{employee1.ID, employee2.ID, employee3.ID},
+			[]models.UserID{summaries[0].Employee.ID, summaries[1].Employee.ID, summaries[2].Employee.ID})
+		require.Equal(t, "employee10@example.com", summaries[0].Employee.Email)
+
+		// Check employee1 stats
+		rate := 0.5
+		require.Equal(t, TaskStatistics{
+			TotalTasks:      4,
+			Active:          1,
+			Terminal:        2,
+			Overdue:         1,
+			CompletedOnTime: 1,
+			OnTimeRate:      &rate,
+			Statuses:        map[models.TaskStatus]int{models.TaskStatusPending: 1, models.TaskStatusInProgress: 1, models.TaskStatusCompleted: 2},
+			Pending:         1,
+			InProgress:      1,
+			Completed:       2,
+			Labels:          []LabelCount{},
+		}, summaries[0].Statistics)
+
+		// Check employee2 stats
+		require.Equal(t, 2, summaries[1].Statistics.TotalTasks)
+		require.Equal(t, 1, summaries[1].Statistics.Pending)
+		require.Equal(t, 1, summaries[1].Statistics.InProgress)
+		require.Nil(t, summaries[1].Statistics.OnTimeRate)
+
+		// employee3 has no tasks
+		require.Zero(t, summaries[2].Statistics.TotalTasks)
+		require.Empty(t, summaries[2].Statistics.Statuses)
+	})
+
+	t.Run("the tasks are done when they get to a terminal status", func(t *testing.T) {
+		task, err := taskRepo.GetTaskByID(ctx, onTime.ID)
+		require.NoError(t, err)
+		require.NotNil(t, task.CompletedAt)
+
+		_, err = taskRepo.UpdateTaskStatus(ctx, onTime.ID, models.TaskStatusInProgress)
+		require.NoError(t, err)
+		task, err = taskRepo.GetTaskByID(ctx, onTime.ID)
+		require.NoError(t, err)
+		require.Nil(t, task.CompletedAt)
+
+		// Only the late task is done now
+		stats := employeeStatistics(t, ctx, taskRepo, GetEmployeeSummariesOptions{OverdueAt: now}, employee1.ID)
+		require.Equal(t, 1, stats.Terminal)
+		require.Equal(t, 0.0, *stats.OnTimeRate)
+	})
+
+	t.Run("only the tasks of an employer", func(t *testing.T) {
+		stats := employeeStatistics(t, ctx, taskRepo, GetEmployeeSummariesOptions{EmployerID: otherEmployer.ID, OverdueAt: now}, employee2.ID)
+		require.Equal(t, 1, stats.TotalTasks)
+		require.Equal(t, 1, stats.Pending)
+	})
+
+	t.Run("only the tasks created in a range", func(t *testing.T) {
+		stats := employeeStatistics(t, ctx, taskRepo, GetEmployeeSummariesOptions{CreatedAfter: tomorrow, OverdueAt: now}, employee1.ID)
+		require.Zero(t, stats.TotalTasks)
+		stats = employeeStatistics(t, ctx, taskRepo, GetEmployeeSummariesOptions{CreatedBefore: tomorrow, OverdueAt: now}, employee1.ID)
+		require.Equal(t, 4, stats.TotalTasks)
+	})
+
+	t.Run("sort by a statistic and paginate", func(t *testing.T) {
+		options := GetEmployeeSummariesOptions{OverdueAt: now, SortBy: EmployeeSummarySortActive, Desc: true, Limit: 2}
+		total, summaries, err := taskRepo.GetEmployeeSummaries(ctx, options)
+		require.NoError(t, err)
+		require.Equal(t, 3, total)
+		require.Equal(t, []models.UserID{employee1.ID, employee2.ID}, []models.UserID{summaries[0].Employee.ID, summaries[1].Employee.ID})
+
+		options.Offset = 2
+		total, summaries, err = taskRepo.GetEmployeeSummaries(ctx, options)
+		require.NoError(t, err)
+		require.Equal(t, 3, total)
+		require.Equal(t, employee3.ID, summaries[0].Employee.ID)
+
+		options.Offset = 3
+		total, summaries, err = taskRepo.GetEmployeeSummaries(ctx, options)
+		require.NoError(t, err)
+		require.Equal(t, 3, total)
+		require.Empty(t, summaries)
+	})
+}
+
+// requireDurations checks the count, average and percentiles of durations, in hours
+func requireDurations(t *testing.T, count int, average, p50, p90 float64, stats DurationStatistics) {
+	require.Equal(t, count, stats.Count)
+	require.InDelta(t, average, *stats.AverageHours, 1e-9)
+	require.InDelta(t, p50, *stats.P50Hours, 1e-9)
+	require.InDelta(t, p90, *stats.P90Hours, 1e-9)
+}
+
+func Test_taskRepoImpl_GetFlowMetrics(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	db := taskRepo.db(ctx)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer-flow@example.com", "Employer Flow", models.UserRoleEmployer)
+	otherEmployer := createTestUserForTask(t, ctx, userRepo, "employer-flow2@example.com", "Employer Flow 2", models.UserRoleEmployer)
+	employee1 := createTestUserForTask(t, ctx, userRepo, "employee-flow1@example.com", "Employee Flow 1", models.UserRoleEmployee)
+	employee2 := createTestUserForTask(t, ctx, userRepo, "employee-flow2@example.com", "Employee Flow 2", models.UserRoleEmployee)
+
+	day := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2023, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	createTask := func(employerID models.UserID, assigneeID *models.UserID, statuses ...models.TaskStatus) models.Task {
+		task, err := taskRepo.CreateTask(ctx, models.Task{Title: "Flow Task", EmployerID: employerID, AssigneeID: assigneeID})
+		require.NoError(t, err)
+		for _, status := range statuses {
+			_, err = taskRepo.UpdateTaskStatus(ctx, task.ID, status)
+			require.NoError(t, err)
+		}
+		return task
+	}
+	done := func(employerID models.UserID, assigneeID *models.UserID, times testutil.TaskTimes) {
+		statuses := []models.TaskStatus{models.TaskStatusInProgress, models.TaskStatusCompleted}
+		if times.StartedAt.IsZero() {
+			statuses = statuses[1:] // done without being started
+		}
+		task := createTask(employerID, assigneeID, statuses...)
+		testutil.SetTaskTimes(t, db, task.ID, times)
+	}
+
+	// The week of April 3rd: employee1 does two tasks, in 72 and 48 hours, after working on them for 48 and 24 hours
+	done(employer.ID, &employee1.ID, testutil.TaskTimes{CreatedAt: day(4, 1, 0), StartedAt: day(4, 2, 0), CompletedAt: day(4, 4, 0)})
+	done(employer.ID, &employee1.ID, testutil.TaskTimes{CreatedAt: day(4, 3, 0), StartedAt: day(4, 4, 0), CompletedAt: day(4, 5, 0)})
+	// The week of April 10th: employee2 does a task in 24 hours without starting it, an unassigned task is done in 12
+	done(employer.ID, &employee2.ID, testutil.TaskTimes{CreatedAt: day(4, 10, 0), CompletedAt: day(4, 11, 0)})
+	done(employer.ID, nil, testutil.TaskTimes{CreatedAt: day(4, 12, 0), CompletedAt: day(4, 12, 12)})
+	// Not measured: a task not done, one done after the range, and one of another employer
+	started := createTask(employer.ID, &employee1.ID, models.TaskStatusInProgress)
+	done(employer.ID, &employee1.ID, testutil.TaskTimes{CreatedAt: day(4, 20, 0), CompletedAt: day(5, 1, 0)})
+	done(otherEmployer.ID, &employee1.ID, testutil.TaskTimes{CreatedAt: day(4, 3, 0), CompletedAt: day(4, 4, 0)})
+
+	options := GetFlowMetricsOptions{
+		EmployerID:      employer.ID,
+		CompletedAfter:  day(4, 1, 0),
+		CompletedBefore: day(4, 17, 0),
+		Interval:        FlowIntervalWeek,
+	}
+
+	t.Run("team-wide and by assignee, over the range and by period", func(t *testing.T) {
+		groups, err := taskRepo.GetFlowMetrics(ctx, options)
+		require.NoError(t, err)
+		require.Len(t, groups, 7)
+
+		week1, week2 := day(4, 3, 0), day(4, 10, 0)
+		periodStarts := make([]*time.Time, len(groups))
+		assigneeIDs := make([]models.UserID, len(groups))
+		for i, group := range groups {
+			periodStarts[i] = group.PeriodStart
+			if group.Assignee != nil {
+				assigneeIDs[i] = group.Assignee.ID
+			}
+		}
+		require.Equal(t, []*time.Time{nil, &week1, &week2, nil, &week1, nil, &week2}, periodStarts)
+		require.Equal(t, []models.UserID{0, 0, 0, employee1.ID, employee1.ID, employee2.ID, employee2.ID}, assigneeIDs)
+		require.Equal(t, "Employee Flow 1", groups[3].Assignee.Name)
+
+		// The team, over the range
+		team := groups[0].Metrics
+		require.Equal(t, 4, team.Throughput)
+		requireDurations(t, 4, 39, 36, 64.8, team.LeadTime)
+		requireDurations(t, 2, 36, 36, 45.6, team.CycleTime)
+
+		// The team, in the second week, where no task was started
+		require.Equal(t, 2, groups[2].Metrics.Throughput)
+		requireDurations(t, 2, 18, 18, 22.8, groups[2].Metrics.LeadTime)
+		require.Equal(t, DurationStatistics{}, groups[2].Metrics.CycleTime)
+
+		// employee1, in the first week
+		require.Equal(t, 2, groups[4].Metrics.Throughput)
+		requireDurations(t, 2, 60, 60, 69.6, groups[4].Metrics.LeadTime)
+		requireDurations(t, 2, 36, 36, 45.6, groups[4].Metrics.CycleTime)
+
+		// employee2, over the range
+		require.Equal(t, 1, groups[5].Metrics.Throughput)
+		requireDurations(t, 1, 24, 24, 24, groups[5].Metrics.LeadTime)
+	})
 
-	// Create tasks for employee1
-	task1 := createTestTask(t, ctx, taskRepo, "Stat Task 1", "Stat Task 1 desc", employer.ID, &employee1.ID)
-	task2 := createTestTask(t, ctx, taskRepo, "Stat Task 2", "Stat Task 2 desc", employer.ID, &employee1.ID)
-	task3 := createTestTask(t, ctx, taskRepo, "Stat Task 3", "Stat Task 3 desc", employer.ID, &employee1.ID)
+	t.Run("by month", func(t *testing.T) {
+		options := options
+		options.Interval = FlowIntervalMonth
+		groups, err := taskRepo.GetFlowMetrics(ctx, options)
+		require.NoError(t, err)
+		require.Len(t, groups, 6)
+		require.Equal(t, day(4, 1, 0), *groups[1].PeriodStart)
+		require.Equal(t, 4, groups[1].Metrics.Throughput)
+	})
+
+	t.Run("no tasks done in the range", func(t *testing.T) {
+		options := options
+		options.CompletedAfter, options.CompletedBefore = day(6, 1, 0), day(7, 1, 0)
+		groups, err := taskRepo.GetFlowMetrics(ctx, options)
+		require.NoError(t, err)
+		require.Equal(t, []FlowMetricsGroup{{}}, groups, "only the team total, with nothing done")
+	})
+
+	t.Run("the tasks are started when they first get to an active status", func(t *testing.T) {
+		task, err := taskRepo.GetTaskByID(ctx, started.ID)
+		require.NoError(t, err)
+		require.NotNil(t, task.StartedAt)
+		startedAt := *task.StartedAt
+
+		// Reopening the task keeps the time it was started
+		_, err = taskRepo.UpdateTaskStatus(ctx, started.ID, models.TaskStatusPending)
+		require.NoError(t, err)
+		_, err = taskRepo.UpdateTaskStatus(ctx, started.ID, models.TaskStatusInProgress)
+		require.NoError(t, err)
+		task, err = taskRepo.GetTaskByID(ctx, started.ID)
+		require.NoError(t, err)
+		require.True(t, startedAt.Equal(*task.StartedAt))
+
+		task, err = taskRepo.GetTaskByID(ctx, createTask(employer.ID, nil).ID)
+		require.NoError(t, err)
+		require.Nil(t, task.StartedAt, "a pending task is not started")
+	})
+}
 
-	// Create tasks for employee2
-	task4 := createTestTask(t, ctx, taskRepo, "Stat Task 4", "Stat Task 4 desc", employer.ID, &employee2.ID)
-	task5 := createTestTask(t, ctx, taskRepo, "Stat Task 5", "Stat Task 5 desc", employer.ID, &employee2.ID)
+func Test_taskRepoImpl_PriorityAndLabels(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	labelRepo := NewLabelRepoImpl(taskRepo.db)
 
-	// Update task statuses - use the returned tasks for updates
-	_, err := taskRepo.UpdateTaskStatus(ctx, task1.ID, models.TaskStatusInProgress)
+	employer := createTestUserForTask(t, ctx, userRepo, "employer-labels@example.com", "Employer Labels", models.UserRoleEmployer)
+	employee := createTestUserForTask(t, ctx, userRepo, "employee-labels@example.com", "Employee Labels", models.UserRoleEmployee)
+	backend, err := labelRepo.CreateLabel(ctx, models.Label{EmployerID: employer.ID, Name: "backend"})
+	require.NoError(t, err)
+	bug, err := labelRepo.CreateLabel(ctx, models.Label{EmployerID: employer.ID, Name: "bug"})
 	require.NoError(t, err)
 
-	_, err = taskRepo.UpdateTaskStatus(ctx, task2.ID, models.TaskStatusCompleted)
+	createTask := func(title string, priority models.TaskPriority, labels ...models.Label) models.Task {
+		task, err := taskRepo.CreateTask(ctx, models.Task{
+			Title:      title,
+			Priority:   priority,
+			EmployerID: employer.ID,
+			AssigneeID: &employee.ID,
+			Labels:     labels,
+		})
+		require.NoError(t, err)
+		return task
+	}
+	urgent := createTask("Urgent", models.TaskPriorityUrgent, backend, bug)
+	low := createTask("Low", models.TaskPriorityLow, backend)
+	normal := createTask("Normal", "")
+
+	t.Run("priority defaults to normal", func(t *testing.T) {
+		task, err := taskRepo.GetTaskByID(ctx, normal.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.TaskPriorityNormal, task.Priority)
+		require.Empty(t, task.Labels)
+	})
+
+	t.Run("labels are loaded", func(t *testing.T) {
+		task, err := taskRepo.GetTaskByID(ctx, urgent.ID)
+		require.NoError(t, err)
+		require.Equal(t, []models.LabelID{backend.ID, bug.ID}, []models.LabelID{task.Labels[0].ID, task.Labels[1].ID})
+	})
+
+	t.Run("filter by priority", func(t *testing.T) {
+		count, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{Priority: models.TaskPriorityLow})
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+		require.Equal(t, low.ID, tasks[0].ID)
+	})
+
+	t.Run("filter by label and sort by priority", func(t *testing.T) {
+		count, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{LabelID: backend.ID, Sort: TaskSort{{Key: TaskSortPriority, Desc: true}}})
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+		require.Equal(t, urgent.ID, tasks[0].ID)
+		require.Equal(t, low.ID, tasks[1].ID)
+		require.Len(t, tasks[0].Labels, 2)
+		require.Len(t, tasks[1].Labels, 1)
+	})
+
+	t.Run("label counts", func(t *testing.T) {
+		stats := employeeStatistics(t, ctx, taskRepo, GetEmployeeSummariesOptions{}, employee.ID)
+		require.Equal(t, 3, stats.TotalTasks)
+		require.Equal(t, []LabelCount{
+			{LabelID: backend.ID, Name: "backend", Count: 2},
+			{LabelID: bug.ID, Name: "bug", Count: 1},
+		}, stats.Labels)
+	})
+}
+
+func Test_taskRepoImpl_Subtasks(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer-subtasks@example.com", "Employer Subtasks", models.UserRoleEmployer)
+	parent := createTestTask(t, ctx, taskRepo, "Parent", "", employer.ID, nil)
+	leaf := createTestTask(t, ctx, taskRepo, "Leaf", "", employer.ID, nil)
+
+	createSubtask := func(title string) models.Task {
+		task, err := taskRepo.CreateTask(ctx, models.Task{
+			Title:      title,
+			EmployerID: employer.ID,
+			ParentID:   &parent.ID,
+		})
+		require.NoError(t, err)
+		return task
+	}
+	first := createSubtask("First")
+	createSubtask("Second")
+	createSubtask("Third")
+	_, err := taskRepo.UpdateTaskStatus(ctx, first.ID, models.TaskStatusCompleted)
 	require.NoError(t, err)
 
-	_, err = taskRepo.UpdateTaskStatus(ctx, task4.ID, models.TaskStatusInProgress)
+	t.Run("progress of a parent", func(t *testing.T) {
+		task, err := taskRepo.GetTaskByID(ctx, parent.ID)
+		require.NoError(t, err)
+		require.Equal(t, &models.TaskProgress{Subtasks: 3, CompletedSubtasks: 1, Percent: 33}, task.Progress)
+	})
+
+	t.Run("no progress without subtasks", func(t *testing.T) {
+		task, err := taskRepo.GetTaskByID(ctx, leaf.ID)
+		require.NoError(t, err)
+		require.Nil(t, task.Progress)
+	})
+
+	t.Run("open subtasks", func(t *testing.T) {
+		count, err := taskRepo.CountOpenSubtasks(ctx, parent.ID)
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		count, err = taskRepo.CountOpenSubtasks(ctx, leaf.ID)
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+
+	t.Run("filter by parent", func(t *testing.T) {
+		count, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{ParentID: parent.ID})
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+		require.Equal(t, first.ID, tasks[0].ID)
+		require.Equal(t, parent.ID, *tasks[0].ParentID)
+	})
+
+	t.Run("subtasks are deleted with their parent", func(t *testing.T) {
+		require.NoError(t, taskRepo.db(ctx).Delete(&models.Task{}, parent.ID).Error)
+		count, _, err := taskRepo.GetTasks(ctx, GetTasksOptions{ParentID: parent.ID})
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+}
+
+func Test_taskRepoImpl_Blockers(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	dependencyRepo := NewTaskDependencyRepoImpl(taskRepo.db)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer-blockers@example.com", "Employer Blockers", models.UserRoleEmployer)
+	design := createTestTask(t, ctx, taskRepo, "Design", "", employer.ID, nil)
+	review := createTestTask(t, ctx, taskRepo, "Review", "", employer.ID, nil)
+	build := createTestTask(t, ctx, taskRepo, "Build", "", employer.ID, nil)
+	for _, blocker := range []models.Task{design, review} {
+		_, err := dependencyRepo.CreateTaskDependency(ctx, models.TaskDependency{TaskID: build.ID, BlockerID: blocker.ID})
+		require.NoError(t, err)
+	}
+	_, err := taskRepo.UpdateTaskStatus(ctx, design.ID, models.TaskStatusCompleted)
 	require.NoError(t, err)
 
-	// Use the tasks in tests to avoid unused variable warnings
-	_ = task3
-	_ = task5
+	t.Run("only open blockers are loaded", func(t *testing.T) {
+		task, err := taskRepo.GetTaskByID(ctx, build.ID)
+		require.NoError(t, err)
+		require.Equal(t, []models.TaskID{review.ID}, task.BlockedBy)
+		require.True(t, task.IsBlocked())
 
-	t.Run("get statistics for all users", func(t *testing.T) {
-		stats, err := taskRepo.GetTaskStatistics(ctx)
+		count, err := taskRepo.CountOpenBlockers(ctx, build.ID)
 		require.NoError(t, err)
-		require.Len(t, stats, 2) // Two employees have tasks
+		require.Equal(t, 1, count)
+	})
 
-		// Check employee1 stats
-		emp1Stats, ok := stats[employee1.ID]
-		require.True(t, ok)
-		require.Equal(t, 3, emp1Stats.TotalTasks)
-		require.Equal(t, 1, emp1Stats.Pending)
-		require.Equal(t, 1, emp1Stats.InProgress)
-		require.Equal(t, 1, emp1Stats.Completed)
+	t.Run("unblocked once the blockers are completed", func(t *testing.T) {
+		_, err := taskRepo.UpdateTaskStatus(ctx, review.ID, models.TaskStatusCompleted)
+		require.NoError(t, err)
 
-		// Check employee2 stats
-		emp2Stats, ok := stats[employee2.ID]
-		require.True(t, ok)
-		require.Equal(t, 2, emp2Stats.TotalTasks)
-		require.Equal(t, 1, emp2Stats.Pending)
-		require.Equal(t, 1, emp2Stats.InProgress)
-		require.Equal(t, 0, emp2Stats.Completed)
+		_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID})
+		require.NoError(t, err)
+		for _, task := range tasks {
+			require.False(t, task.IsBlocked(), task.Title)
+		}
+
+		count, err := taskRepo.CountOpenBlockers(ctx, build.ID)
+		require.NoError(t, err)
+		require.Zero(t, count)
 	})
+}
 
-	t.Run("get statistics for specific user", func(t *testing.T) {
-		stats, err := taskRepo.GetTaskStatistics(ctx, employee1.ID)
+func Test_taskRepoImpl_Search(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	other := createTestUserForTask(t, ctx, userRepo, "other@example.com", "Other", models.UserRoleEmployer)
+	employee := createTestUserForTask(t, ctx, userRepo, "employee@example.com", "Employee", models.UserRoleEmployee)
+
+	inTitle := createTestTask(t, ctx, taskRepo, "Send the invoices", "Fees & taxes of March", employer.ID, &employee.ID)
+	inDescription := createTestTask(t, ctx, taskRepo, "Close the month", "Check that the invoice was paid", employer.ID, nil)
+	_ = createTestTask(t, ctx, taskRepo, "Order supplies", "Paper and pens", employer.ID, &employee.ID)
+	_ = createTestTask(t, ctx, taskRepo, "Invoice the other customers", "", other.ID, nil)
+
+	t.Run("matches are ranked by relevance", func(t *testing.T) {
+		count, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, SearchQuery: "invoice"})
 		require.NoError(t, err)
-		require.Len(t, stats, 1) // Only one employee requested
+		require.Equal(t, 2, count)
+		require.Equal(t, inTitle.ID, tasks[0].ID, "the title weighs more than the description")
+		require.Equal(t, inDescription.ID, tasks[1].ID)
+		require.Greater(t, tasks[0].Match.Rank, tasks[1].Match.Rank)
+	})
 
-		// Check employee1 stats
-		emp1Stats, ok := stats[employee1.ID]
-		require.True(t, ok)
-		require.Equal(t, 3, emp1Stats.TotalTasks)
-		require.Equal(t, 1, emp1Stats.Pending)
-		require.Equal(t, 1, emp1Stats.InProgress)
-		require.Equal(t, 1, emp1Stats.Completed)
+	t.Run("matches are highlighted and escaped", func(t *testing.T) {
+		_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, SearchQuery: "invoices march"})
+		require.NoError(t, err)
+		require.Len(t, tasks, 1)
+		require.Equal(t, "Send the <mark>invoices</mark>", tasks[0].Match.Title)
+		require.Contains(t, tasks[0].Match.Snippet, "Fees &amp; taxes of <mark>March</mark>")
 	})
 
-	t.Run("get statistics for multiple users", func(t *testing.T) {
-		stats, err := taskRepo.GetTaskStatistics(ctx, employee1.ID, employee2.ID)
+	t.Run("marks cannot be injected", func(t *testing.T) {
+		injector := createTestUserForTask(t, ctx, userRepo, "injector@example.com", "Injector", models.UserRoleEmployer)
+		_ = createTestTask(t, ctx, taskRepo, "Pay the \uE000<img src=x onerror=alert(1)>\uE001 bonus",
+			"\uE000<script>alert(1)</script>\uE001 bonus", injector.ID, nil)
+
+		_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: injector.ID, SearchQuery: "bonus"})
 		require.NoError(t, err)
-		require.Len(t, stats, 2) // Two employees requested
+		require.Len(t, tasks, 1)
+		for _, headline := range []string{tasks[0].Match.Title, tasks[0].Match.Snippet} {
+			require.Equal(t, 1, strings.Count(headline, "<mark>"), headline)
+			require.Contains(t, headline, "<mark>bonus</mark>")
+			require.NotContains(t, headline, "<img")
+			require.NotContains(t, headline, "<script")
+		}
+	})
 
-		// Check stats for both employees
-		require.Equal(t, 3, stats[employee1.ID].TotalTasks)
-		require.Equal(t, 2, stats[employee2.ID].TotalTasks)
+	t.Run("web search syntax", func(t *testing.T) {
+		_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, SearchQuery: `invoice -paid`})
+		require.NoError(t, err)
+		require.Len(t, tasks, 1)
+		require.Equal(t, inTitle.ID, tasks[0].ID)
+
+		count, _, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, SearchQuery: `"paper and pens" OR month`})
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
 	})
 
-	t.Run("get statistics for user with no tasks", func(t *testing.T) {
-		// Create a new employee with no tasks
-		employee3 := createTestUserForTask(t, ctx, userRepo, "employee12@example.com", "Employee 12", models.UserRoleEmployee)
+	t.Run("other filters apply", func(t *testing.T) {
+		count, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{AssigneeID: employee.ID, SearchQuery: "invoice"})
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+		require.Equal(t, inTitle.ID, tasks[0].ID)
+
+		count, _, err = taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, SearchQuery: "unrelated"})
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
 
-		stats, err := taskRepo.GetTaskStatistics(ctx, employee3.ID)
+	t.Run("explicit sorting", func(t *testing.T) {
+		_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, SearchQuery: "invoice", Sort: TaskSort{{Key: TaskSortCreatedAt, Desc: true}}})
 		require.NoError(t, err)
-		require.Empty(t, stats) // No stats for this employee
+		require.Equal(t, inDescription.ID, tasks[0].ID)
+		require.NotNil(t, tasks[0].Match)
+	})
+
+	t.Run("lists without a search have no matches", func(t *testing.T) {
+		_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID})
+		require.NoError(t, err)
+		require.Nil(t, tasks[0].Match)
+	})
+}
+
+func Test_taskRepoImpl_GetTasks_Filters(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+
+	employer1 := createTestUserForTask(t, ctx, userRepo, "employer1@example.com", "Employer 1", models.UserRoleEmployer)
+	employer2 := createTestUserForTask(t, ctx, userRepo, "employer2@example.com", "Employer 2", models.UserRoleEmployer)
+	employer3 := createTestUserForTask(t, ctx, userRepo, "employer3@example.com", "Employer 3", models.UserRoleEmployer)
+	employee1 := createTestUserForTask(t, ctx, userRepo, "employee1@example.com", "Employee 1", models.UserRoleEmployee)
+	employee2 := createTestUserForTask(t, ctx, userRepo, "employee2@example.com", "Employee 2", models.UserRoleEmployee)
+
+	now := time.Now().Truncate(time.Second)
+	createTask := func(title string, employerID models.UserID, assigneeID *models.UserID, status models.TaskStatus, dueDate *time.Time, createdAt time.Time) models.Task {
+		task, err := taskRepo.CreateTask(ctx, models.Task{
+			Title:      title,
+			Status:     status,
+			DueDate:    dueDate,
+			EmployerID: employerID,
+			AssigneeID: assigneeID,
+			CreatedAt:  createdAt,
+		})
+		require.NoError(t, err)
+		return task
+	}
+	tomorrow, nextWeek := now.Add(24*time.Hour), now.Add(7*24*time.Hour)
+	pending := createTask("Pending", employer1.ID, &employee1.ID, models.TaskStatusPending, &tomorrow, now.Add(-48*time.Hour))
+	inProgress := createTask("In progress", employer1.ID, &employee2.ID, models.TaskStatusInProgress, &nextWeek, now.Add(-24*time.Hour))
+	unassigned := createTask("Unassigned", employer2.ID, nil, models.TaskStatusPending, nil, now)
+	completed := createTask("Completed", employer3.ID, &employee1.ID, models.TaskStatusCompleted, &tomorrow, now)
+
+	ids := func(tasks []models.Task) []models.TaskID {
+		var ids []models.TaskID
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+	getTasks := func(options GetTasksOptions) []models.TaskID {
+		count, tasks, err := taskRepo.GetTasks(ctx, options)
+		require.NoError(t, err)
+		require.Len(t, tasks, count)
+		return ids(tasks)
+	}
+
+	t.Run("several statuses", func(t *testing.T) {
+		require.Equal(t, []models.TaskID{pending.ID, inProgress.ID, unassigned.ID},
+			getTasks(GetTasksOptions{Statuses: []models.TaskStatus{models.TaskStatusPending, models.TaskStatusInProgress}}))
+	})
+
+	t.Run("several employers", func(t *testing.T) {
+		require.Equal(t, []models.TaskID{unassigned.ID, completed.ID},
+			getTasks(GetTasksOptions{EmployerIDs: []models.UserID{employer2.ID, employer3.ID}}))
+	})
+
+	t.Run("several assignees", func(t *testing.T) {
+		require.Equal(t, []models.TaskID{pending.ID, inProgress.ID, completed.ID},
+			getTasks(GetTasksOptions{AssigneeIDs: []models.UserID{employee1.ID, employee2.ID}}))
+	})
+
+	t.Run("unassigned", func(t *testing.T) {
+		require.Equal(t, []models.TaskID{unassigned.ID}, getTasks(GetTasksOptions{Unassigned: true}))
+		require.Equal(t, []models.TaskID{inProgress.ID, unassigned.ID},
+			getTasks(GetTasksOptions{AssigneeIDs: []models.UserID{employee2.ID}, Unassigned: true}))
+	})
+
+	t.Run("due date range", func(t *testing.T) {
+		require.Equal(t, []models.TaskID{pending.ID, completed.ID}, getTasks(GetTasksOptions{DueBefore: nextWeek}))
+		require.Equal(t, []models.TaskID{inProgress.ID}, getTasks(GetTasksOptions{DueAfter: nextWeek}))
+		require.Empty(t, getTasks(GetTasksOptions{DueAfter: tomorrow.Add(time.Second), DueBefore: nextWeek}))
+	})
+
+	t.Run("creation time range", func(t *testing.T) {
+		require.Equal(t, []models.TaskID{pending.ID, inProgress.ID}, getTasks(GetTasksOptions{CreatedBefore: now}))
+		require.Equal(t, []models.TaskID{inProgress.ID, unassigned.ID, completed.ID},
+			getTasks(GetTasksOptions{CreatedAfter: now.Add(-24 * time.Hour)}))
+	})
+
+	t.Run("filters narrow the scope", func(t *testing.T) {
+		require.Empty(t, getTasks(GetTasksOptions{EmployerID: employer1.ID, EmployerIDs: []models.UserID{employer2.ID}}))
+		require.Equal(t, []models.TaskID{pending.ID},
+			getTasks(GetTasksOptions{AssigneeID: employee1.ID, EmployerIDs: []models.UserID{employer1.ID, employer2.ID}}))
+		require.Empty(t, getTasks(GetTasksOptions{AssigneeID: employee1.ID, Unassigned: true}))
+	})
+}
+
+func Test_taskRepoImpl_GetTasks_Keyset(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+
+	// Tasks with equal sort keys, and without due date, to check the ties and the NULLs
+	now := time.Now().Truncate(time.Microsecond)
+	later := now.Add(time.Hour)
+	for i, due := range []*time.Time{&now, nil, &later, &now, nil, &later, &now} {
+		priorities := []models.TaskPriority{models.TaskPriorityLow, models.TaskPriorityUrgent, models.TaskPriorityNormal}
+		statuses := []models.TaskStatus{models.TaskStatusPending, models.TaskStatusCompleted, models.TaskStatusInProgress}
+		_, err := taskRepo.CreateTask(ctx, models.Task{
+			Title:       "Release notes",
+			Description: strings.Repeat("release ", i%3+1),
+			Status:      statuses[i/2%3],
+			Priority:    priorities[i%3],
+			DueDate:     due,
+			EmployerID:  employer.ID,
+			CreatedAt:   now.Add(-time.Duration(i%4) * time.Minute),
+		})
+		require.NoError(t, err)
+	}
+
+	ids := func(tasks []models.Task) []models.TaskID {
+		var ids []models.TaskID
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	keys := []TaskSortKey{TaskSortCreatedAt, TaskSortUpdatedAt, TaskSortDueDate, TaskSortStatusActiveFirst,
+		TaskSortStatusDoneFirst, TaskSortPriority, TaskSortRelevance}
+	var sorts []TaskSort
+	for _, key := range keys {
+		sorts = append(sorts, TaskSort{{Key: key}}, TaskSort{{Key: key, Desc: true}})
+	}
+	sorts = append(sorts,
+		TaskSort{{Key: TaskSortStatusActiveFirst}, {Key: TaskSortDueDate}},
+		TaskSort{{Key: TaskSortDueDate, Desc: true}, {Key: TaskSortPriority}, {Key: TaskSortCreatedAt, Desc: true}},
+		TaskSort{{Key: TaskSortPriority, Desc: true}, {Key: TaskSortStatusDoneFirst, Desc: true}})
+	for _, sort := range sorts {
+		t.Run(sort.String(), func(t *testing.T) {
+			options := GetTasksOptions{EmployerID: employer.ID, SearchQuery: "release", Sort: sort}
+			total, all, err := taskRepo.GetTasks(ctx, options)
+			require.NoError(t, err)
+			require.Equal(t, 7, total)
+
+			// Paginate 2 by 2, each page after the last task of the previous one
+			var paginated []models.Task
+			options.Limit, options.SkipCount = 2, true
+			for {
+				total, page, err := taskRepo.GetTasks(ctx, options)
+				require.NoError(t, err)
+				require.Zero(t, total, "not counted")
+				paginated = append(paginated, page...)
+				if len(page) < options.Limit {
+					break
+				}
+				after, err := taskRepo.GetTaskPosition(ctx, page[len(page)-1].ID, sort, options.SearchQuery)
+				require.NoError(t, err)
+				options.After = &after
+			}
+			require.Equal(t, ids(all), ids(paginated))
+		})
+	}
+
+	t.Run("the tasks without due date come last in both directions", func(t *testing.T) {
+		for _, desc := range []bool{false, true} {
+			_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, Sort: TaskSort{{Key: TaskSortDueDate, Desc: desc}}})
+			require.NoError(t, err)
+			require.NotNil(t, tasks[0].DueDate)
+			require.Nil(t, tasks[len(tasks)-1].DueDate)
+		}
+	})
+
+	t.Run("statuses are sorted by kind", func(t *testing.T) {
+		statuses := func(sort TaskSort) []models.TaskStatus {
+			_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, Sort: sort})
+			require.NoError(t, err)
+			var statuses []models.TaskStatus
+			for _, task := range tasks {
+				if len(statuses) == 0 || statuses[len(statuses)-1] != task.Status {
+					statuses = append(statuses, task.Status)
+				}
+			}
+			return statuses
+		}
+		require.Equal(t, []models.TaskStatus{models.TaskStatusInProgress, models.TaskStatusPending, models.TaskStatusCompleted},
+			statuses(TaskSort{{Key: TaskSortStatusActiveFirst}}))
+		require.Equal(t, []models.TaskStatus{models.TaskStatusCompleted, models.TaskStatusInProgress, models.TaskStatusPending},
+			statuses(TaskSort{{Key: TaskSortStatusDoneFirst}}))
+	})
+
+	t.Run("positions decode", func(t *testing.T) {
+		sort := TaskSort{{Key: TaskSortDueDate}, {Key: TaskSortRelevance, Desc: true}}
+		_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, SearchQuery: "release", Sort: sort})
+		require.NoError(t, err)
+		position, err := taskRepo.GetTaskPosition(ctx, tasks[len(tasks)-1].ID, sort, "release")
+		require.NoError(t, err)
+		require.Equal(t, true, position.Values[0])
+		require.Nil(t, position.Values[1])
+
+		values := make([]json.RawMessage, len(position.Values))
+		for i, value := range position.Values {
+			values[i], err = json.Marshal(value)
+			require.NoError(t, err)
+		}
+		decoded, err := sort.DecodePosition(values, position.ID)
+		require.NoError(t, err)
+		require.Equal(t, position, decoded)
+
+		_, err = sort.DecodePosition(values[:1], position.ID)
+		require.Error(t, err)
 	})
 }
@@ -0,0 +1,31 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+type TaskSeriesRepo interface {
+	// GetTaskSeriesByID retrieves a series by its ID, return nil if not found
+	GetTaskSeriesByID(ctx context.Context, id models.TaskSeriesID) (*models.TaskSeries, error)
+	// GetTaskSeriesByIDForUpdate retrieves a series by its ID and locks it until the end of the transaction,
+	// so the generator and the edits of the series do not create its tasks twice. It returns nil if not found.
+	GetTaskSeriesByIDForUpdate(ctx context.Context, id models.TaskSeriesID) (*models.TaskSeries, error)
+	// GetEmployerTaskSeries retrieves the series of an employer, in creation order
+	GetEmployerTaskSeries(ctx context.Context, employerID models.UserID) ([]models.TaskSeries, error)
+	// CreateTaskSeries creates a new series
+	CreateTaskSeries(ctx context.Context, series models.TaskSeries) (models.TaskSeries, error)
+	// UpdateTaskSeries saves the template, rule, state and generation progress of a series
+	UpdateTaskSeries(ctx context.Context, series models.TaskSeries) (_updated bool, _ error)
+	// LockTaskSeriesToGenerate retrieves up to limit active series whose tasks are created up to before until,
+	// and locks them until the end of the transaction. Series locked by another transaction are skipped.
+	LockTaskSeriesToGenerate(ctx context.Context, until time.Time, limit int) ([]models.TaskSeries, error)
+
+	// CreateSeriesTask creates the task of an occurrence of a series, unless the series already has a task due then
+	CreateSeriesTask(ctx context.Context, task models.Task) (_ models.Task, _created bool, _ error)
+	// DeleteUpcomingTasks deletes the tasks of a series due after a time that have not started yet,
+	// i.e. whose status is neither active nor terminal
+	DeleteUpcomingTasks(ctx context.Context, id models.TaskSeriesID, after time.Time) (_deleted int, _ error)
+}
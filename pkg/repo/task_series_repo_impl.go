@@ -0,0 +1,126 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ TaskSeriesRepo = (*taskSeriesRepoImpl)(nil)
+
+type taskSeriesRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewTaskSeriesRepoImpl creates a new task series repository implementation
+func NewTaskSeriesRepoImpl(db func(ctx context.Context) *gorm.DB) *taskSeriesRepoImpl {
+	return &taskSeriesRepoImpl{db: db}
+}
+
+// GetTaskSeriesByID retrieves a series by its ID, return nil if not found
+func (r *taskSeriesRepoImpl) GetTaskSeriesByID(ctx context.Context, id models.TaskSeriesID) (*models.TaskSeries, error) {
+	var series models.TaskSeries
+	if err := r.db(ctx).Table("task_series").Where("id = ?", id).Limit(1).Scan(&series).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get task series by id")
+	}
+	if series.ID == 0 {
+		return nil, nil // series not found
+	}
+	return &series, nil
+}
+
+// GetTaskSeriesByIDForUpdate retrieves a series by its ID and locks it, return nil if not found
+func (r *taskSeriesRepoImpl) GetTaskSeriesByIDForUpdate(ctx context.Context, id models.TaskSeriesID) (*models.TaskSeries, error) {
+	var series []models.TaskSeries
+	err := r.db(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		Limit(1).
+		Find(&series).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task series by id for update")
+	}
+	if len(series) == 0 {
+		return nil, nil // series not found
+	}
+	return &series[0], nil
+}
+
+// GetEmployerTaskSeries retrieves the series of an employer, in creation order
+func (r *taskSeriesRepoImpl) GetEmployerTaskSeries(ctx context.Context, employerID models.UserID) ([]models.TaskSeries, error) {
+	series := []models.TaskSeries{}
+	if err := r.db(ctx).Where("employer_id = ?", employerID).Order("id ASC").Find(&series).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get task series")
+	}
+	return series, nil
+}
+
+// CreateTaskSeries creates a new series
+func (r *taskSeriesRepoImpl) CreateTaskSeries(ctx context.Context, series models.TaskSeries) (models.TaskSeries, error) {
+	if err := r.db(ctx).Create(&series).Error; err != nil {
+		return models.TaskSeries{}, errors.Wrap(err, "failed to create task series")
+	}
+	return series, nil
+}
+
+// UpdateTaskSeries saves the template, rule, state and generation progress of a series
+func (r *taskSeriesRepoImpl) UpdateTaskSeries(ctx context.Context, series models.TaskSeries) (_updated bool, _ error) {
+	result := r.db(ctx).Model(&models.TaskSeries{}).
+		Where("id = ?", series.ID).
+		Updates(map[string]interface{}{
+			"title":           series.Title,
+			"description":     series.Description,
+			"assignee_id":     series.AssigneeID,
+			"priority":        series.Priority,
+			"rrule":           series.RRule,
+			"timezone":        series.Timezone,
+			"starts_at":       series.StartsAt,
+			"state":           series.State,
+			"generated_until": series.GeneratedUntil,
+		})
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to update task series")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// LockTaskSeriesToGenerate retrieves and locks the active series whose tasks are created up to before until
+func (r *taskSeriesRepoImpl) LockTaskSeriesToGenerate(ctx context.Context, until time.Time, limit int) ([]models.TaskSeries, error) {
+	var series []models.TaskSeries
+	err := r.db(ctx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("state = ? AND generated_until < ?", models.TaskSeriesStateActive, until).
+		Order("generated_until ASC").
+		Limit(limit).
+		Find(&series).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to lock task series to generate")
+	}
+	return series, nil
+}
+
+// CreateSeriesTask creates the task of an occurrence of a series, unless the series already has a task due then
+func (r *taskSeriesRepoImpl) CreateSeriesTask(ctx context.Context, task models.Task) (_ models.Task, _created bool, _ error) {
+	// idx_tasks_series_occurrence makes the insert a no-op for occurrences that have a task
+	result := r.db(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&task)
+	if err := result.Error; err != nil {
+		return models.Task{}, false, errors.Wrap(err, "failed to create series task")
+	}
+	return task, result.RowsAffected > 0, nil
+}
+
+// DeleteUpcomingTasks deletes the tasks of a series due after a time that have not started yet
+func (r *taskSeriesRepoImpl) DeleteUpcomingTasks(ctx context.Context, id models.TaskSeriesID, after time.Time) (_deleted int, _ error) {
+	upcoming := r.db(ctx).Table("tasks").
+		Select("tasks.id").
+		Joins(joinTaskStatuses).
+		Where("tasks.series_id = ? AND tasks.due_date > ?", id, after).
+		Where("employer_task_statuses.active IS NOT TRUE AND employer_task_statuses.terminal IS NOT TRUE")
+	result := r.db(ctx).Where("id IN (?)", upcoming).Delete(&models.Task{})
+	if err := result.Error; err != nil {
+		return 0, errors.Wrap(err, "failed to delete upcoming series tasks")
+	}
+	return int(result.RowsAffected), nil
+}
@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_taskSeriesRepoImpl(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	r := NewTaskSeriesRepoImpl(taskRepo.db)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	employee := createTestUserForTask(t, ctx, userRepo, "employee@example.com", "Employee", models.UserRoleEmployee)
+
+	now := time.Now().Truncate(time.Second)
+	series, err := r.CreateTaskSeries(ctx, models.TaskSeries{
+		EmployerID:     employer.ID,
+		Title:          "Inspection",
+		AssigneeID:     &employee.ID,
+		Priority:       models.TaskPriorityNormal,
+		RRule:          "FREQ=DAILY",
+		Timezone:       "UTC",
+		StartsAt:       now,
+		State:          models.TaskSeriesStateActive,
+		GeneratedUntil: now,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, series.ID)
+
+	t.Run("get", func(t *testing.T) {
+		got, err := r.GetTaskSeriesByID(ctx, series.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Inspection", got.Title)
+		require.Equal(t, employee.ID, *got.AssigneeID)
+		require.True(t, now.Equal(got.StartsAt))
+
+		got, err = r.GetTaskSeriesByID(ctx, series.ID+1000)
+		require.NoError(t, err)
+		require.Nil(t, got)
+
+		list, err := r.GetEmployerTaskSeries(ctx, employer.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		list, err = r.GetEmployerTaskSeries(ctx, employee.ID)
+		require.NoError(t, err)
+		require.Empty(t, list)
+	})
+
+	t.Run("lock the series to generate", func(t *testing.T) {
+		locked, err := r.LockTaskSeriesToGenerate(ctx, now.Add(time.Hour), 10)
+		require.NoError(t, err)
+		require.Len(t, locked, 1)
+
+		// the tasks are already created up to until
+		locked, err = r.LockTaskSeriesToGenerate(ctx, now, 10)
+		require.NoError(t, err)
+		require.Empty(t, locked)
+	})
+
+	t.Run("create the tasks of a series once", func(t *testing.T) {
+		for i := 1; i <= 3; i++ {
+			dueDate := now.AddDate(0, 0, i)
+			task, created, err := r.CreateSeriesTask(ctx, models.Task{
+				Title:      series.Title,
+				Status:     models.TaskStatusPending,
+				Priority:   series.Priority,
+				EmployerID: employer.ID,
+				AssigneeID: series.AssigneeID,
+				DueDate:    &dueDate,
+				SeriesID:   &series.ID,
+			})
+			require.NoError(t, err)
+			require.True(t, created)
+			require.Equal(t, series.ID, *task.SeriesID)
+		}
+
+		dueDate := now.AddDate(0, 0, 1)
+		_, created, err := r.CreateSeriesTask(ctx, models.Task{
+			Title:      series.Title,
+			Status:     models.TaskStatusPending,
+			Priority:   series.Priority,
+			EmployerID: employer.ID,
+			DueDate:    &dueDate,
+			SeriesID:   &series.ID,
+		})
+		require.NoError(t, err)
+		require.False(t, created, "the series already has a task due then")
+	})
+
+	t.Run("delete the upcoming tasks", func(t *testing.T) {
+		_, tasks, err := taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID, Sort: TaskSort{{Key: TaskSortDueDate}}})
+		require.NoError(t, err)
+		require.Len(t, tasks, 3)
+		_, err = taskRepo.UpdateTaskStatus(ctx, tasks[2].ID, models.TaskStatusInProgress)
+		require.NoError(t, err)
+
+		// the first task is due before after, the last one has started
+		deleted, err := r.DeleteUpcomingTasks(ctx, series.ID, now.AddDate(0, 0, 1))
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		_, tasks, err = taskRepo.GetTasks(ctx, GetTasksOptions{EmployerID: employer.ID})
+		require.NoError(t, err)
+		require.Len(t, tasks, 2)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		series.Title = "Weekly inspection"
+		series.RRule = "FREQ=WEEKLY"
+		series.State = models.TaskSeriesStatePaused
+		series.GeneratedUntil = now.AddDate(0, 0, 3)
+		updated, err := r.UpdateTaskSeries(ctx, series)
+		require.NoError(t, err)
+		require.True(t, updated)
+
+		got, err := r.GetTaskSeriesByIDForUpdate(ctx, series.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Weekly inspection", got.Title)
+		require.Equal(t, "FREQ=WEEKLY", got.RRule)
+		require.Equal(t, models.TaskSeriesStatePaused, got.State)
+		require.True(t, series.GeneratedUntil.Equal(got.GeneratedUntil))
+
+		// paused series are not generated
+		locked, err := r.LockTaskSeriesToGenerate(ctx, now.AddDate(1, 0, 0), 10)
+		require.NoError(t, err)
+		require.Empty(t, locked)
+	})
+}
@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+// TokenRepo stores the refresh tokens and the denylist of revoked access tokens
+type TokenRepo interface {
+	// CreateRefreshToken stores a new refresh token
+	CreateRefreshToken(ctx context.Context, token models.RefreshToken) (models.RefreshToken, error)
+	// GetRefreshTokenByHash retrieves a refresh token by its hash and locks it until the end of the transaction,
+	// so the same token cannot be rotated twice concurrently. It returns nil if not found.
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// RevokeRefreshToken revokes a refresh token of the given user. It returns false if there is no such active token.
+	RevokeRefreshToken(ctx context.Context, id int, userID models.UserID) (_revoked bool, _ error)
+	// RevokeUserRefreshTokens revokes all the active refresh tokens of a user
+	RevokeUserRefreshTokens(ctx context.Context, userID models.UserID) error
+
+	// RevokeAccessToken adds an access token (by its jti claim) to the denylist until it expires.
+	// Revoking an already revoked token is a no-op.
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// IsAccessTokenRevoked reports whether an access token (by its jti claim) is on the denylist
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// DeleteExpiredAccessTokens removes the access tokens expired before now from the denylist, their exp claim
+	// rejects them already. It returns the number of tokens removed.
+	DeleteExpiredAccessTokens(ctx context.Context, now time.Time) (int64, error)
+}
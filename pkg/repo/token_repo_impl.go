@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ TokenRepo = (*tokenRepoImpl)(nil)
+
+type tokenRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewTokenRepoImpl creates a new token repository implementation
+func NewTokenRepoImpl(db func(ctx context.Context) *gorm.DB) *tokenRepoImpl {
+	return &tokenRepoImpl{db: db}
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *tokenRepoImpl) CreateRefreshToken(ctx context.Context, token models.RefreshToken) (models.RefreshToken, error) {
+	if err := r.db(ctx).Create(&token).Error; err != nil {
+		return models.RefreshToken{}, errors.Wrap(err, "failed to create refresh token")
+	}
+	return token, nil
+}
+
+// GetRefreshTokenByHash retrieves a refresh token by its hash and locks it, return nil if not found
+func (r *tokenRepoImpl) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var tokens []models.RefreshToken
+	err := r.db(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", tokenHash).
+		Limit(1).
+		Find(&tokens).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get refresh token")
+	}
+	if len(tokens) == 0 {
+		return nil, nil // token not found
+	}
+	return &tokens[0], nil
+}
+
+// RevokeRefreshToken revokes an active refresh token of the given user
+func (r *tokenRepoImpl) RevokeRefreshToken(ctx context.Context, id int, userID models.UserID) (_revoked bool, _ error) {
+	result := r.db(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", gorm.Expr("CURRENT_TIMESTAMP"))
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to revoke refresh token")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeUserRefreshTokens revokes all the active refresh tokens of a user
+func (r *tokenRepoImpl) RevokeUserRefreshTokens(ctx context.Context, userID models.UserID) error {
+	err := r.db(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", gorm.Expr("CURRENT_TIMESTAMP")).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke refresh tokens")
+	}
+	return nil
+}
+
+// RevokeAccessToken adds an access token to the denylist until it expires
+func (r *tokenRepoImpl) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	err := r.db(ctx).Exec(
+		"INSERT INTO revoked_access_tokens (jti, expires_at) VALUES (?, ?) ON CONFLICT (jti) DO NOTHING",
+		jti, expiresAt,
+	).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke access token")
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether an access token is on the denylist
+func (r *tokenRepoImpl) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	err := r.db(ctx).Table("revoked_access_tokens").Where("jti = ?", jti).Count(&count).Error
+	if err != nil {
+		return false, errors.Wrap(err, "failed to check revoked access token")
+	}
+	return count > 0, nil
+}
+
+// DeleteExpiredAccessTokens removes the expired access tokens from the denylist
+func (r *tokenRepoImpl) DeleteExpiredAccessTokens(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db(ctx).Exec("DELETE FROM revoked_access_tokens WHERE expires_at < ?", now)
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "failed to delete expired access tokens")
+	}
+	return result.RowsAffected, nil
+}
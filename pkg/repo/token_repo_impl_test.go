@@ -0,0 +1,126 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/testutil"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupTokenTestRepo sets up a token repository and a user with a test database
+func setupTokenTestRepo(t *testing.T) (context.Context, *tokenRepoImpl, *userRepoImpl, models.User) {
+	db := testutil.CreateTestDB(t)
+	getDB := func(ctx context.Context) *gorm.DB {
+		return db.WithContext(ctx)
+	}
+	tokenRepo := NewTokenRepoImpl(getDB)
+	userRepo := NewUserRepoImpl(getDB)
+	user := createTestUser(t, t.Context(), userRepo, "session@example.com", "Session User", models.UserRoleEmployee)
+	return t.Context(), tokenRepo, userRepo, user
+}
+
+func Test_tokenRepoImpl_RefreshTokens(t *testing.T) {
+	ctx, r, _, user := setupTokenTestRepo(t)
+
+	created, err := r.CreateRefreshToken(ctx, models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: "hash-1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.ID)
+
+	t.Run("get by hash", func(t *testing.T) {
+		token, err := r.GetRefreshTokenByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		require.NotNil(t, token)
+		require.Equal(t, created.ID, token.ID)
+		require.True(t, token.IsActive(time.Now()))
+
+		token, err = r.GetRefreshTokenByHash(ctx, "unknown-hash")
+		require.NoError(t, err)
+		require.Nil(t, token)
+	})
+
+	t.Run("revoke once", func(t *testing.T) {
+		revoked, err := r.RevokeRefreshToken(ctx, created.ID, user.ID+1)
+		require.NoError(t, err)
+		require.False(t, revoked, "a token can only be revoked for its own user")
+
+		revoked, err = r.RevokeRefreshToken(ctx, created.ID, user.ID)
+		require.NoError(t, err)
+		require.True(t, revoked)
+
+		revoked, err = r.RevokeRefreshToken(ctx, created.ID, user.ID)
+		require.NoError(t, err)
+		require.False(t, revoked)
+
+		token, err := r.GetRefreshTokenByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		require.False(t, token.IsActive(time.Now()))
+	})
+
+	t.Run("revoke all tokens of a user", func(t *testing.T) {
+		for _, hash := range []string{"hash-2", "hash-3"} {
+			_, err := r.CreateRefreshToken(ctx, models.RefreshToken{UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour)})
+			require.NoError(t, err)
+		}
+		require.NoError(t, r.RevokeUserRefreshTokens(ctx, user.ID))
+
+		for _, hash := range []string{"hash-2", "hash-3"} {
+			token, err := r.GetRefreshTokenByHash(ctx, hash)
+			require.NoError(t, err)
+			require.NotNil(t, token.RevokedAt)
+		}
+	})
+}
+
+func Test_tokenRepoImpl_RevokeAccessToken(t *testing.T) {
+	ctx, r, _, _ := setupTokenTestRepo(t)
+
+	revoked, err := r.IsAccessTokenRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, r.RevokeAccessToken(ctx, "jti-1", time.Now().Add(time.Hour)))
+	require.NoError(t, r.RevokeAccessToken(ctx, "jti-1", time.Now().Add(time.Hour)), "revoking twice is a no-op")
+
+	revoked, err = r.IsAccessTokenRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	t.Run("expired tokens are pruned", func(t *testing.T) {
+		require.NoError(t, r.RevokeAccessToken(ctx, "jti-expired", time.Now().Add(-time.Minute)))
+		deleted, err := r.DeleteExpiredAccessTokens(ctx, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, int64(1), deleted)
+
+		revoked, err := r.IsAccessTokenRevoked(ctx, "jti-expired")
+		require.NoError(t, err)
+		require.False(t, revoked)
+		revoked, err = r.IsAccessTokenRevoked(ctx, "jti-1")
+		require.NoError(t, err)
+		require.True(t, revoked, "the tokens not expired yet stay revoked")
+	})
+}
+
+func Test_userRepoImpl_IncrementTokenVersion(t *testing.T) {
+	ctx, _, userRepo, user := setupTokenTestRepo(t)
+	require.Equal(t, 0, user.TokenVersion)
+
+	updated, err := userRepo.IncrementTokenVersion(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	fetched, err := userRepo.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, fetched.TokenVersion)
+
+	updated, err = userRepo.IncrementTokenVersion(ctx, models.UserID(999))
+	require.NoError(t, err)
+	require.False(t, updated)
+}
@@ -4,16 +4,25 @@ import (
 	"context"
 
 	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
 )
 
+// ErrDuplicateEmail is returned by CreateUser when the email is already registered
+var ErrDuplicateEmail = errors.New("duplicate email")
+
 type UserRepo interface {
 	// GetUserByID retrieves a user by their ID, return nil if not found
 	GetUserByID(ctx context.Context, id models.UserID) (*models.User, error)
-	// CreateUser creates a new user.
+	// GetUsersByIDs retrieves the users with the given IDs in one query, the missing ones are left out
+	GetUsersByIDs(ctx context.Context, ids []models.UserID) ([]models.User, error)
+	// CreateUser creates a new user. It returns ErrDuplicateEmail if the email is already registered.
 	CreateUser(ctx context.Context, user models.User) (models.User, error)
 	// GetUserByEmail retrieves a user by their email, return nil if not found.
 	// This is useful for login or registration processes.
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	// GetAllUsers retrieves all users.
 	GetAllUsers(ctx context.Context) ([]models.User, error)
+	// IncrementTokenVersion bumps the token version of a user, which invalidates all the tokens issued before.
+	// It returns false if the user does not exist.
+	IncrementTokenVersion(ctx context.Context, id models.UserID) (_updated bool, _ error)
 }
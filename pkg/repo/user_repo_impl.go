@@ -20,6 +20,9 @@ func NewUserRepoImpl(db func(ctx context.Context) *gorm.DB) *userRepoImpl {
 
 func (u userRepoImpl) CreateUser(ctx context.Context, user models.User) (models.User, error) {
 	if err := u.db(ctx).Create(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return models.User{}, ErrDuplicateEmail
+		}
 		return models.User{}, errors.Wrap(err, "failed to create user")
 	}
 	return user, nil
@@ -36,6 +39,17 @@ func (u userRepoImpl) GetUserByID(ctx context.Context, id models.UserID) (*model
 	return &user, nil
 }
 
+func (u userRepoImpl) GetUsersByIDs(ctx context.Context, ids []models.UserID) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := u.db(ctx).Table("users").Where("id IN ?", ids).Order("id asc").Scan(&users).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get users by ids")
+	}
+	return users, nil
+}
+
 func (u userRepoImpl) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
 	if err := u.db(ctx).Table("users").Where("email = ?", email).Limit(1).Scan(&user).Error; err != nil {
@@ -54,3 +68,13 @@ func (u userRepoImpl) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	}
 	return users, nil
 }
+
+func (u userRepoImpl) IncrementTokenVersion(ctx context.Context, id models.UserID) (_updated bool, _ error) {
+	result := u.db(ctx).Model(&models.User{}).
+		Where("id = ?", id).
+		Update("token_version", gorm.Expr("token_version + 1"))
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to increment token version")
+	}
+	return result.RowsAffected > 0, nil
+}
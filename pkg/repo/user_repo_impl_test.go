@@ -111,6 +111,27 @@ func Test_userRepoImpl_GetUserByID(t *testing.T) {
 	})
 }
 
+func Test_userRepoImpl_GetUsersByIDs(t *testing.T) {
+	ctx, r := setupTestRepo(t)
+
+	user1 := createTestUser(t, ctx, r, "batch1@example.com", "Batch User 1", models.UserRoleEmployee)
+	user2 := createTestUser(t, ctx, r, "batch2@example.com", "Batch User 2", models.UserRoleEmployee)
+
+	t.Run("missing users are left out", func(t *testing.T) {
+		users, err := r.GetUsersByIDs(ctx, []models.UserID{user2.ID, 999, user1.ID, user2.ID})
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		require.Equal(t, user1.ID, users[0].ID)
+		require.Equal(t, user2.ID, users[1].ID)
+	})
+
+	t.Run("no ids", func(t *testing.T) {
+		users, err := r.GetUsersByIDs(ctx, nil)
+		require.NoError(t, err)
+		require.Empty(t, users)
+	})
+}
+
 func Test_userRepoImpl_GetUserByEmail(t *testing.T) {
 	ctx, r := setupTestRepo(t)
 
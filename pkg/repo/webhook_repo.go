@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+type WebhookRepo interface {
+	// GetWebhookByID retrieves a webhook by its ID, return nil if not found
+	GetWebhookByID(ctx context.Context, id models.WebhookID) (*models.Webhook, error)
+	// GetWebhooks retrieves the webhooks of an employer, in creation order
+	GetWebhooks(ctx context.Context, employerID models.UserID) ([]models.Webhook, error)
+	// GetSubscribedWebhooks retrieves the active webhooks of an employer that subscribe to an event
+	GetSubscribedWebhooks(ctx context.Context, employerID models.UserID, event models.WebhookEventType) ([]models.Webhook, error)
+	// CreateWebhook creates a new webhook
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	// UpdateWebhook saves the URL, events and active flag of a webhook
+	UpdateWebhook(ctx context.Context, webhook models.Webhook) (_updated bool, _ error)
+	// DeleteWebhook deletes a webhook and its deliveries
+	DeleteWebhook(ctx context.Context, id models.WebhookID) (_deleted bool, _ error)
+
+	// CreateDeliveries creates pending deliveries
+	CreateDeliveries(ctx context.Context, deliveries ...models.WebhookDelivery) ([]models.WebhookDelivery, error)
+	// GetDeliveryByID retrieves a delivery by its ID, return nil if not found
+	GetDeliveryByID(ctx context.Context, id models.WebhookDeliveryID) (*models.WebhookDelivery, error)
+	// GetDeliveries retrieves the deliveries of a webhook, newest first
+	GetDeliveries(ctx context.Context, options GetDeliveriesOptions) (_total int, _ []models.WebhookDelivery, _ error)
+	// ClaimDueDeliveries retrieves up to limit pending deliveries due for an attempt at now, oldest first, and
+	// postpones their next attempt to leaseUntil, so no other dispatcher attempts them meanwhile. A dispatcher that
+	// stops before saving the outcome of an attempt leaves the delivery to be attempted again after leaseUntil.
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	// SaveDeliveryAttempt saves the outcome of an attempt: status, attempts, next attempt, response and error
+	SaveDeliveryAttempt(ctx context.Context, delivery models.WebhookDelivery) (_updated bool, _ error)
+}
+
+type GetDeliveriesOptions struct {
+	WebhookID models.WebhookID
+	Offset    int // offset for pagination
+	Limit     int // limit for pagination
+}
@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ WebhookRepo = (*webhookRepoImpl)(nil)
+
+type webhookRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewWebhookRepoImpl creates a new webhook repository implementation
+func NewWebhookRepoImpl(db func(ctx context.Context) *gorm.DB) *webhookRepoImpl {
+	return &webhookRepoImpl{db: db}
+}
+
+// GetWebhookByID retrieves a webhook by its ID, return nil if not found
+func (r *webhookRepoImpl) GetWebhookByID(ctx context.Context, id models.WebhookID) (*models.Webhook, error) {
+	var webhooks []models.Webhook
+	// Find rather than Scan, to decode the events with their serializer
+	if err := r.db(ctx).Where("id = ?", id).Limit(1).Find(&webhooks).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook by id")
+	}
+	if len(webhooks) == 0 {
+		return nil, nil // webhook not found
+	}
+	return &webhooks[0], nil
+}
+
+// GetWebhooks retrieves the webhooks of an employer
+func (r *webhookRepoImpl) GetWebhooks(ctx context.Context, employerID models.UserID) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := r.db(ctx).Where("employer_id = ?", employerID).Order("id ASC").Find(&webhooks).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get webhooks")
+	}
+	return webhooks, nil
+}
+
+// GetSubscribedWebhooks retrieves the active webhooks of an employer that subscribe to an event
+func (r *webhookRepoImpl) GetSubscribedWebhooks(ctx context.Context, employerID models.UserID, event models.WebhookEventType) ([]models.Webhook, error) {
+	// events is a JSON array, @> matches the arrays containing the event
+	subscribed, err := json.Marshal([]models.WebhookEventType{event})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode event")
+	}
+	var webhooks []models.Webhook
+	err = r.db(ctx).
+		Where("employer_id = ? AND active AND events @> ?", employerID, string(subscribed)).
+		Order("id ASC").
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get subscribed webhooks")
+	}
+	return webhooks, nil
+}
+
+// CreateWebhook creates a new webhook
+func (r *webhookRepoImpl) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	if err := r.db(ctx).Create(&webhook).Error; err != nil {
+		return models.Webhook{}, errors.Wrap(err, "failed to create webhook")
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook saves the URL, events and active flag of a webhook
+func (r *webhookRepoImpl) UpdateWebhook(ctx context.Context, webhook models.Webhook) (_updated bool, _ error) {
+	result := r.db(ctx).Model(&models.Webhook{}).
+		Where("id = ?", webhook.ID).
+		Select("url", "events", "active", "updated_at").
+		Updates(&models.Webhook{
+			URL:       webhook.URL,
+			Events:    webhook.Events,
+			Active:    webhook.Active,
+			UpdatedAt: time.Now(),
+		})
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to update webhook")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteWebhook deletes a webhook, its deliveries are deleted by cascade
+func (r *webhookRepoImpl) DeleteWebhook(ctx context.Context, id models.WebhookID) (_deleted bool, _ error) {
+	result := r.db(ctx).Where("id = ?", id).Delete(&models.Webhook{})
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to delete webhook")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CreateDeliveries creates pending deliveries
+func (r *webhookRepoImpl) CreateDeliveries(ctx context.Context, deliveries ...models.WebhookDelivery) ([]models.WebhookDelivery, error) {
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+	if err := r.db(ctx).Create(&deliveries).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to create webhook deliveries")
+	}
+	return deliveries, nil
+}
+
+// GetDeliveryByID retrieves a delivery by its ID, return nil if not found
+func (r *webhookRepoImpl) GetDeliveryByID(ctx context.Context, id models.WebhookDeliveryID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db(ctx).Table("webhook_deliveries").Where("id = ?", id).Limit(1).Scan(&delivery).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook delivery by id")
+	}
+	if delivery.ID == 0 {
+		return nil, nil // delivery not found
+	}
+	return &delivery, nil
+}
+
+// GetDeliveries retrieves the deliveries of a webhook, newest first
+func (r *webhookRepoImpl) GetDeliveries(ctx context.Context, options GetDeliveriesOptions) (int, []models.WebhookDelivery, error) {
+	db := r.db(ctx).Model(&models.WebhookDelivery{}).Where("webhook_id = ?", options.WebhookID)
+
+	// Count total results (before pagination)
+	var totalCount int64
+	if err := db.Count(&totalCount).Error; err != nil {
+		return 0, nil, errors.Wrap(err, "failed to count webhook deliveries")
+	}
+
+	db = db.Order("id DESC")
+	if options.Limit > 0 {
+		db = db.Limit(options.Limit)
+	}
+	if options.Offset > 0 {
+		db = db.Offset(options.Offset)
+	}
+	var deliveries []models.WebhookDelivery
+	if err := db.Find(&deliveries).Error; err != nil {
+		return 0, nil, errors.Wrap(err, "failed to get webhook deliveries")
+	}
+
+	return int(totalCount), deliveries, nil
+}
+
+// ClaimDueDeliveries retrieves the pending deliveries due for an attempt, and postpones their next attempt
+func (r *webhookRepoImpl) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	due := r.db(ctx).Table("webhook_deliveries").
+		Select("id").
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var deliveries []models.WebhookDelivery
+	err := r.db(ctx).Model(&deliveries).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Update("next_attempt_at", leaseUntil).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to claim due webhook deliveries")
+	}
+	return deliveries, nil
+}
+
+// SaveDeliveryAttempt saves the outcome of an attempt
+func (r *webhookRepoImpl) SaveDeliveryAttempt(ctx context.Context, delivery models.WebhookDelivery) (_updated bool, _ error) {
+	result := r.db(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"response_status": delivery.ResponseStatus,
+			"last_error":      delivery.LastError,
+			"delivered_at":    delivery.DeliveredAt,
+		})
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to save webhook delivery attempt")
+	}
+	return result.RowsAffected > 0, nil
+}
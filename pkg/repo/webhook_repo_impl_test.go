@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_webhookRepoImpl(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	r := NewWebhookRepoImpl(taskRepo.db)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	other := createTestUserForTask(t, ctx, userRepo, "other@example.com", "Other", models.UserRoleEmployer)
+
+	createWebhook := func(employerID models.UserID, events ...models.WebhookEventType) models.Webhook {
+		webhook, err := r.CreateWebhook(ctx, models.Webhook{
+			EmployerID: employerID,
+			URL:        "https://example.com/hooks",
+			Events:     events,
+			Secret:     "whsec_test",
+			Active:     true,
+		})
+		require.NoError(t, err)
+		return webhook
+	}
+	created := createWebhook(employer.ID, models.WebhookEventTaskCreated, models.WebhookEventTaskCompleted)
+	completed := createWebhook(employer.ID, models.WebhookEventTaskCompleted)
+	createWebhook(other.ID, models.WebhookEventTaskCreated)
+
+	t.Run("get", func(t *testing.T) {
+		webhook, err := r.GetWebhookByID(ctx, created.ID)
+		require.NoError(t, err)
+		require.Equal(t, []models.WebhookEventType{models.WebhookEventTaskCreated, models.WebhookEventTaskCompleted}, webhook.Events)
+		require.Equal(t, "whsec_test", webhook.Secret)
+
+		webhook, err = r.GetWebhookByID(ctx, 0)
+		require.NoError(t, err)
+		require.Nil(t, webhook)
+
+		webhooks, err := r.GetWebhooks(ctx, employer.ID)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 2)
+		require.Equal(t, created.ID, webhooks[0].ID)
+	})
+
+	t.Run("subscribed", func(t *testing.T) {
+		webhooks, err := r.GetSubscribedWebhooks(ctx, employer.ID, models.WebhookEventTaskCreated)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		require.Equal(t, created.ID, webhooks[0].ID)
+
+		webhooks, err = r.GetSubscribedWebhooks(ctx, employer.ID, models.WebhookEventTaskCompleted)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 2)
+
+		// disabled webhooks get no event
+		completed.Active = false
+		updated, err := r.UpdateWebhook(ctx, completed)
+		require.NoError(t, err)
+		require.True(t, updated)
+		webhooks, err = r.GetSubscribedWebhooks(ctx, employer.ID, models.WebhookEventTaskCompleted)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		require.Equal(t, created.ID, webhooks[0].ID)
+	})
+
+	t.Run("deliveries", func(t *testing.T) {
+		now := time.Now()
+		payload := json.RawMessage(`{"id":"event"}`)
+		deliveries, err := r.CreateDeliveries(ctx,
+			models.WebhookDelivery{WebhookID: created.ID, EventID: "first", Event: models.WebhookEventTaskCreated,
+				Payload: payload, Status: models.WebhookDeliveryPending, NextAttemptAt: now.Add(-time.Minute)},
+			models.WebhookDelivery{WebhookID: created.ID, EventID: "second", Event: models.WebhookEventTaskCreated,
+				Payload: payload, Status: models.WebhookDeliveryPending, NextAttemptAt: now.Add(time.Hour)},
+		)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		require.NotZero(t, deliveries[0].ID)
+
+		// only the due delivery is claimed, and only once
+		claimed, err := r.ClaimDueDeliveries(ctx, now, now.Add(5*time.Minute), 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		require.Equal(t, deliveries[0].ID, claimed[0].ID)
+		require.JSONEq(t, string(payload), string(claimed[0].Payload))
+		claimed, err = r.ClaimDueDeliveries(ctx, now, now.Add(5*time.Minute), 10)
+		require.NoError(t, err)
+		require.Empty(t, claimed)
+
+		status := 200
+		delivery := deliveries[0]
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.Attempts = 1
+		delivery.ResponseStatus = &status
+		delivery.DeliveredAt = &now
+		updated, err := r.SaveDeliveryAttempt(ctx, delivery)
+		require.NoError(t, err)
+		require.True(t, updated)
+
+		saved, err := r.GetDeliveryByID(ctx, delivery.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.WebhookDeliverySucceeded, saved.Status)
+		require.Equal(t, 1, saved.Attempts)
+		require.Equal(t, 200, *saved.ResponseStatus)
+		require.NotNil(t, saved.DeliveredAt)
+
+		// a succeeded delivery is not claimed again once its lease ends
+		claimed, err = r.ClaimDueDeliveries(ctx, now.Add(10*time.Minute), now.Add(15*time.Minute), 10)
+		require.NoError(t, err)
+		require.Empty(t, claimed)
+
+		total, log, err := r.GetDeliveries(ctx, GetDeliveriesOptions{WebhookID: created.ID, Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		require.Len(t, log, 1)
+		require.Equal(t, deliveries[1].ID, log[0].ID, "newest first")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		deleted, err := r.DeleteWebhook(ctx, created.ID)
+		require.NoError(t, err)
+		require.True(t, deleted)
+
+		total, _, err := r.GetDeliveries(ctx, GetDeliveriesOptions{WebhookID: created.ID})
+		require.NoError(t, err)
+		require.Zero(t, total, "the deliveries are deleted with their webhook")
+
+		deleted, err = r.DeleteWebhook(ctx, created.ID)
+		require.NoError(t, err)
+		require.False(t, deleted)
+	})
+}
@@ -0,0 +1,16 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+)
+
+type WorkflowRepo interface {
+	// GetWorkflow retrieves the workflow of an employer, or the default workflow if the employer has none
+	GetWorkflow(ctx context.Context, employerID models.UserID) (models.Workflow, error)
+	// SaveWorkflow replaces the workflow of an employer, the ID and the employer of workflow are ignored
+	SaveWorkflow(ctx context.Context, employerID models.UserID, workflow models.Workflow) (models.Workflow, error)
+	// DeleteWorkflow deletes the workflow of an employer, who uses the default workflow again
+	DeleteWorkflow(ctx context.Context, employerID models.UserID) (_deleted bool, _ error)
+}
@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var _ WorkflowRepo = (*workflowRepoImpl)(nil)
+
+type workflowRepoImpl struct {
+	db func(ctx context.Context) *gorm.DB
+}
+
+// NewWorkflowRepoImpl creates a new workflow repository implementation
+func NewWorkflowRepoImpl(db func(ctx context.Context) *gorm.DB) *workflowRepoImpl {
+	return &workflowRepoImpl{db: db}
+}
+
+// GetWorkflow retrieves the workflow of an employer, or the default workflow if the employer has none
+func (r *workflowRepoImpl) GetWorkflow(ctx context.Context, employerID models.UserID) (models.Workflow, error) {
+	var workflow models.Workflow
+	var row struct {
+		ID         models.WorkflowID
+		EmployerID *models.UserID
+		UpdatedAt  time.Time
+	}
+	err := r.db(ctx).Table("workflows").
+		Where("employer_id = ? OR employer_id IS NULL", employerID).
+		Order("employer_id NULLS LAST").
+		Limit(1).
+		Scan(&row).Error
+	if err != nil {
+		return workflow, errors.Wrap(err, "failed to get workflow")
+	}
+	if row.ID == 0 {
+		return workflow, errors.New("the default workflow is missing")
+	}
+	workflow.ID, workflow.EmployerID, workflow.UpdatedAt = row.ID, row.EmployerID, row.UpdatedAt
+
+	workflow.Statuses = []models.WorkflowStatus{}
+	err = r.db(ctx).Table("workflow_statuses").
+		Select("name, active, terminal").
+		Where("workflow_id = ?", workflow.ID).
+		Order("position ASC").
+		Scan(&workflow.Statuses).Error
+	if err != nil {
+		return workflow, errors.Wrap(err, "failed to get workflow statuses")
+	}
+
+	// One row per role, in the order of the statuses, employers first
+	var transitions []struct {
+		FromStatus models.TaskStatus
+		ToStatus   models.TaskStatus
+		Role       models.UserRole
+	}
+	err = r.db(ctx).Table("workflow_transitions").
+		Select("workflow_transitions.from_status, workflow_transitions.to_status, workflow_transitions.role").
+		Joins("JOIN workflow_statuses f ON f.workflow_id = workflow_transitions.workflow_id AND f.name = workflow_transitions.from_status").
+		Joins("JOIN workflow_statuses t ON t.workflow_id = workflow_transitions.workflow_id AND t.name = workflow_transitions.to_status").
+		Where("workflow_transitions.workflow_id = ?", workflow.ID).
+		Order("f.position ASC, t.position ASC, workflow_transitions.role DESC").
+		Scan(&transitions).Error
+	if err != nil {
+		return workflow, errors.Wrap(err, "failed to get workflow transitions")
+	}
+	workflow.Transitions = []models.WorkflowTransition{}
+	for _, row := range transitions {
+		last := len(workflow.Transitions) - 1
+		if last >= 0 && workflow.Transitions[last].From == row.FromStatus && workflow.Transitions[last].To == row.ToStatus {
+			workflow.Transitions[last].Roles = append(workflow.Transitions[last].Roles, row.Role)
+			continue
+		}
+		workflow.Transitions = append(workflow.Transitions, models.WorkflowTransition{
+			From:  row.FromStatus,
+			To:    row.ToStatus,
+			Roles: []models.UserRole{row.Role},
+		})
+	}
+	return workflow, nil
+}
+
+// SaveWorkflow replaces the workflow of an employer
+func (r *workflowRepoImpl) SaveWorkflow(ctx context.Context, employerID models.UserID, workflow models.Workflow) (models.Workflow, error) {
+	var workflowID models.WorkflowID
+	err := r.db(ctx).Raw(`
+	INSERT INTO workflows (employer_id, updated_at) VALUES (?, CURRENT_TIMESTAMP)
+	ON CONFLICT (employer_id) DO UPDATE SET updated_at = EXCLUDED.updated_at
+	RETURNING id`, employerID).
+		Scan(&workflowID).Error
+	if err != nil {
+		return models.Workflow{}, errors.Wrap(err, "failed to save workflow")
+	}
+
+	// Deleting the statuses deletes their transitions
+	if err := r.db(ctx).Exec("DELETE FROM workflow_statuses WHERE workflow_id = ?", workflowID).Error; err != nil {
+		return models.Workflow{}, errors.Wrap(err, "failed to delete workflow statuses")
+	}
+
+	statuses := make([]map[string]interface{}, len(workflow.Statuses))
+	for i, status := range workflow.Statuses {
+		statuses[i] = map[string]interface{}{
+			"workflow_id": workflowID,
+			"name":        status.Name,
+			"position":    i,
+			"active":      status.Active,
+			"terminal":    status.Terminal,
+		}
+	}
+	if err := r.db(ctx).Table("workflow_statuses").Create(statuses).Error; err != nil {
+		return models.Workflow{}, errors.Wrap(err, "failed to save workflow statuses")
+	}
+
+	var transitions []map[string]interface{}
+	for _, transition := range workflow.Transitions {
+		for _, role := range transition.Roles {
+			transitions = append(transitions, map[string]interface{}{
+				"workflow_id": workflowID,
+				"from_status": transition.From,
+				"to_status":   transition.To,
+				"role":        role,
+			})
+		}
+	}
+	if len(transitions) > 0 {
+		if err := r.db(ctx).Table("workflow_transitions").Create(transitions).Error; err != nil {
+			return models.Workflow{}, errors.Wrap(err, "failed to save workflow transitions")
+		}
+	}
+
+	return r.GetWorkflow(ctx, employerID)
+}
+
+// DeleteWorkflow deletes the workflow of an employer
+func (r *workflowRepoImpl) DeleteWorkflow(ctx context.Context, employerID models.UserID) (_deleted bool, _ error) {
+	result := r.db(ctx).Exec("DELETE FROM workflows WHERE employer_id = ?", employerID)
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, "failed to delete workflow")
+	}
+	return result.RowsAffected > 0, nil
+}
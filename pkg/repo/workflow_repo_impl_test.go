@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_workflowRepoImpl(t *testing.T) {
+	ctx, taskRepo, userRepo := setupTaskTestRepo(t)
+	r := NewWorkflowRepoImpl(taskRepo.db)
+
+	employer := createTestUserForTask(t, ctx, userRepo, "employer@example.com", "Employer", models.UserRoleEmployer)
+	employee := createTestUserForTask(t, ctx, userRepo, "employee@example.com", "Employee", models.UserRoleEmployee)
+
+	reviewWorkflow := models.Workflow{
+		Statuses: []models.WorkflowStatus{
+			{Name: "todo"},
+			{Name: "doing", Active: true},
+			{Name: "in_review", Active: true},
+			{Name: "done", Terminal: true},
+			{Name: "cancelled", Terminal: true},
+		},
+		Transitions: []models.WorkflowTransition{
+			{From: "todo", To: "doing", Roles: []models.UserRole{models.UserRoleEmployer, models.UserRoleEmployee}},
+			{From: "doing", To: "in_review", Roles: []models.UserRole{models.UserRoleEmployee}},
+			{From: "in_review", To: "done", Roles: []models.UserRole{models.UserRoleEmployer}},
+		},
+	}
+
+	t.Run("the default workflow is stored", func(t *testing.T) {
+		workflow, err := r.GetWorkflow(ctx, employer.ID)
+		require.NoError(t, err)
+		require.Nil(t, workflow.EmployerID)
+
+		expected := models.DefaultWorkflow()
+		require.Equal(t, expected.Statuses, workflow.Statuses)
+		require.Equal(t, expected.Transitions, workflow.Transitions)
+	})
+
+	t.Run("save", func(t *testing.T) {
+		saved, err := r.SaveWorkflow(ctx, employer.ID, reviewWorkflow)
+		require.NoError(t, err)
+		require.Equal(t, employer.ID, *saved.EmployerID)
+		require.Equal(t, reviewWorkflow.Statuses, saved.Statuses)
+		require.Equal(t, reviewWorkflow.Transitions, saved.Transitions)
+
+		// saving again replaces the workflow
+		reviewWorkflow.Transitions = reviewWorkflow.Transitions[:2]
+		saved, err = r.SaveWorkflow(ctx, employer.ID, reviewWorkflow)
+		require.NoError(t, err)
+		require.Len(t, saved.Transitions, 2)
+		require.Len(t, saved.Statuses, 5)
+	})
+
+	t.Run("terminal statuses drive progress and statistics", func(t *testing.T) {
+		parent := createTestTask(t, ctx, taskRepo, "Parent", "", employer.ID, &employee.ID)
+		for _, status := range []models.TaskStatus{"doing", "in_review", "done", "cancelled"} {
+			_, err := taskRepo.CreateTask(ctx, models.Task{
+				Title:      string(status),
+				Status:     status,
+				EmployerID: employer.ID,
+				AssigneeID: &employee.ID,
+				ParentID:   &parent.ID,
+			})
+			require.NoError(t, err)
+		}
+		_, err := taskRepo.UpdateTaskStatus(ctx, parent.ID, "todo")
+		require.NoError(t, err)
+
+		task, err := taskRepo.GetTaskByID(ctx, parent.ID)
+		require.NoError(t, err)
+		require.Equal(t, &models.TaskProgress{Subtasks: 4, CompletedSubtasks: 2, Percent: 50}, task.Progress)
+		open, err := taskRepo.CountOpenSubtasks(ctx, parent.ID)
+		require.NoError(t, err)
+		require.Equal(t, 2, open)
+
+		stats := employeeStatistics(t, ctx, taskRepo, GetEmployeeSummariesOptions{}, employee.ID)
+		require.Equal(t, 5, stats.TotalTasks)
+		require.Equal(t, 2, stats.Active)
+		require.Equal(t, 2, stats.Terminal)
+		require.Equal(t, map[models.TaskStatus]int{"todo": 1, "doing": 1, "in_review": 1, "done": 1, "cancelled": 1}, stats.Statuses)
+		// the counts named after the default workflow follow the kinds of the statuses
+		require.Equal(t, 1, stats.Pending)
+		require.Equal(t, 2, stats.InProgress)
+		require.Equal(t, 2, stats.Completed)
+
+		statuses, err := taskRepo.GetTaskStatuses(ctx, employer.ID)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []models.TaskStatus{"todo", "doing", "in_review", "done", "cancelled"}, statuses)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		deleted, err := r.DeleteWorkflow(ctx, employer.ID)
+		require.NoError(t, err)
+		require.True(t, deleted)
+
+		workflow, err := r.GetWorkflow(ctx, employer.ID)
+		require.NoError(t, err)
+		require.Nil(t, workflow.EmployerID)
+
+		deleted, err = r.DeleteWorkflow(ctx, employer.ID)
+		require.NoError(t, err)
+		require.False(t, deleted)
+	})
+}
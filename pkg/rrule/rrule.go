@@ -0,0 +1,404 @@
+// Package rrule computes the occurrences of recurrence rules, a subset of the RRULE of RFC 5545.
+//
+// Supported parts:
+//
+//	FREQ        DAILY, WEEKLY, MONTHLY or YEARLY, required
+//	INTERVAL    every n periods, defaults to 1
+//	COUNT       the number of occurrences, not with UNTIL
+//	UNTIL       the last possible occurrence, e.g. 20261231T235959Z, not with COUNT
+//	BYDAY       MO to SU. Expands WEEKLY and MONTHLY rules, limits DAILY rules.
+//	            MONTHLY rules accept an ordinal, e.g. 1MO for the first Monday, -1FR for the last Friday.
+//	BYMONTHDAY  1 to 31, or -31 to -1 from the end of the month. Expands MONTHLY and YEARLY rules,
+//	            limits DAILY rules.
+//	BYMONTH     1 to 12. Expands YEARLY rules, limits the others.
+//	WKST        the first day of the week of WEEKLY rules, defaults to MO
+//
+// The occurrences have the time of day of the start of the rule, in its time zone. The start is the first occurrence
+// only if it matches the rule. Months without the day of an occurrence, like the 31st of April, are skipped.
+package rrule
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones do not depend on the system
+
+	"github.com/pkg/errors"
+)
+
+// Frequency is the period a rule repeats over
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxEmptyPeriods stops rules that never match, like the 30th of February, instead of looping forever
+const maxEmptyPeriods = 1000
+
+// WeekdayNum is a BYDAY value: a day of the week, and for MONTHLY rules its position in the month
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int // 1 for the first one in the month, -1 for the last one, 0 for all of them
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int       // 0 when the rule has no COUNT
+	Until      time.Time // zero when the rule has no UNTIL
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parse parses a rule like "FREQ=WEEKLY;BYDAY=MO,TH". The "RRULE:" prefix is optional.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, errors.Errorf("invalid rule part %q", part)
+		}
+		name = strings.ToUpper(name)
+		if seen[name] {
+			return nil, errors.Errorf("duplicate %s", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				return nil, errors.Errorf("unsupported FREQ %s, expected DAILY, WEEKLY, MONTHLY or YEARLY", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = parseInt(name, value, 1, 1000)
+		case "COUNT":
+			rule.Count, err = parseInt(name, value, 1, 10000)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, err := parseInt(name, v, -31, 31)
+				if err != nil {
+					return nil, err
+				}
+				if day == 0 {
+					return nil, errors.New("BYMONTHDAY cannot be 0")
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				month, err := parseInt(name, v, 1, 12)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		case "WKST":
+			weekday, ok := weekdays[strings.ToUpper(value)]
+			if !ok {
+				return nil, errors.Errorf("invalid WKST %s", value)
+			}
+			rule.WeekStart = weekday
+		default:
+			return nil, errors.Errorf("unsupported rule part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL cannot be used together")
+	}
+	if rule.Freq == Weekly && len(rule.ByMonthDay) > 0 {
+		return nil, errors.New("BYMONTHDAY cannot be used with WEEKLY rules")
+	}
+	if rule.Freq == Yearly && len(rule.ByDay) > 0 {
+		return nil, errors.New("BYDAY cannot be used with YEARLY rules")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly {
+			return nil, errors.New("BYDAY ordinals can only be used with MONTHLY rules")
+		}
+	}
+	return rule, nil
+}
+
+func parseInt(name, value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, errors.Errorf("invalid %s %s, expected an integer from %d to %d", name, value, min, max)
+	}
+	return n, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if until, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// a date includes the whole day
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			return until, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid UNTIL %s, expected a UTC time like 20261231T235959Z or a date", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, v := range strings.Split(strings.ToUpper(value), ",") {
+		if len(v) < 2 {
+			return nil, errors.Errorf("invalid BYDAY %s", v)
+		}
+		weekday, ok := weekdays[v[len(v)-2:]]
+		if !ok {
+			return nil, errors.Errorf("invalid BYDAY %s", v)
+		}
+		day := WeekdayNum{Weekday: weekday}
+		if ordinal := v[:len(v)-2]; ordinal != "" {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, errors.Errorf("invalid BYDAY %s, the ordinal must be from -5 to 5", v)
+			}
+			day.N = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// Between returns the occurrences of the rule starting at start, that are after after and not after before.
+// At most limit occurrences are returned, the first ones.
+func (r *Rule) Between(start, after, before time.Time, limit int) []time.Time {
+	var occurrences []time.Time
+	r.iterate(start, func(t time.Time) bool {
+		if t.After(before) {
+			return false
+		}
+		if t.After(after) {
+			occurrences = append(occurrences, t)
+		}
+		return len(occurrences) < limit
+	})
+	return occurrences
+}
+
+// Next returns the first occurrence of the rule starting at start that is after after,
+// and false if there is none, e.g. after the last occurrence of a rule with COUNT.
+func (r *Rule) Next(start, after time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	r.iterate(start, func(t time.Time) bool {
+		if t.After(after) {
+			next, found = t, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// iterate calls fn with each occurrence in order, until fn returns false or the rule ends
+func (r *Rule) iterate(start time.Time, fn func(time.Time) bool) {
+	count := 0
+	empty := 0
+	for period := 0; empty < maxEmptyPeriods; period++ {
+		dates := r.dates(start, period)
+		if len(dates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, date := range dates {
+			t := time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+			if t.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return
+			}
+			count++
+			if !fn(t) || (r.Count > 0 && count >= r.Count) {
+				return
+			}
+		}
+	}
+}
+
+// dates returns the days of the occurrences in the n-th period of the rule, in order.
+// The days are midnight times in UTC, only their dates are used.
+func (r *Rule) dates(start time.Time, n int) []time.Time {
+	var dates []time.Time
+	switch r.Freq {
+	case Daily:
+		date := civilDate(start.Year(), start.Month(), start.Day()+n*r.Interval)
+		if r.matchesDay(date) {
+			dates = append(dates, date)
+		}
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := civilDate(start.Year(), start.Month(), start.Day()-offset+7*n*r.Interval)
+		weekdays := []time.Weekday{start.Weekday()}
+		if len(r.ByDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, day := range r.ByDay {
+				weekdays = append(weekdays, day.Weekday)
+			}
+		}
+		for _, weekday := range weekdays {
+			date := weekStart.AddDate(0, 0, (int(weekday)-int(r.WeekStart)+7)%7)
+			if r.matchesMonth(date.Month()) {
+				dates = append(dates, date)
+			}
+		}
+	case Monthly:
+		month := civilDate(start.Year(), start.Month()+time.Month(n*r.Interval), 1)
+		if r.matchesMonth(month.Month()) {
+			dates = r.monthDates(month, start)
+		}
+	case Yearly:
+		year := start.Year() + n*r.Interval
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, month := range months {
+			dates = append(dates, r.monthDates(civilDate(year, month, 1), start)...)
+		}
+	}
+	return sortDates(dates)
+}
+
+// monthDates returns the days of the occurrences in the month starting at first
+func (r *Rule) monthDates(first, start time.Time) []time.Time {
+	daysInMonth := first.AddDate(0, 1, -1).Day()
+	var dates []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, day := range r.ByMonthDay {
+			if day < 0 {
+				day = daysInMonth + day + 1
+			}
+			if day < 1 || day > daysInMonth {
+				continue
+			}
+			date := civilDate(first.Year(), first.Month(), day)
+			// BYDAY limits BYMONTHDAY, e.g. Friday the 13th
+			if len(r.ByDay) == 0 || r.matchesMonthWeekday(date, daysInMonth) {
+				dates = append(dates, date)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for day := 1; day <= daysInMonth; day++ {
+			date := civilDate(first.Year(), first.Month(), day)
+			if r.matchesMonthWeekday(date, daysInMonth) {
+				dates = append(dates, date)
+			}
+		}
+	default:
+		if start.Day() <= daysInMonth {
+			dates = append(dates, civilDate(first.Year(), first.Month(), start.Day()))
+		}
+	}
+	return dates
+}
+
+// matchesDay returns true if the date matches the BYDAY, BYMONTHDAY and BYMONTH limits of a DAILY rule
+func (r *Rule) matchesDay(date time.Time) bool {
+	if !r.matchesMonth(date.Month()) {
+		return false
+	}
+	if len(r.ByDay) > 0 {
+		found := false
+		for _, day := range r.ByDay {
+			found = found || day.Weekday == date.Weekday()
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.ByMonthDay) > 0 {
+		daysInMonth := civilDate(date.Year(), date.Month()+1, 0).Day()
+		found := false
+		for _, day := range r.ByMonthDay {
+			found = found || day == date.Day() || daysInMonth+day+1 == date.Day()
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesMonthWeekday returns true if the date matches a BYDAY value, with its ordinal in the month
+func (r *Rule) matchesMonthWeekday(date time.Time, daysInMonth int) bool {
+	for _, day := range r.ByDay {
+		if day.Weekday != date.Weekday() {
+			continue
+		}
+		switch {
+		case day.N == 0:
+			return true
+		case day.N > 0 && (date.Day()-1)/7+1 == day.N:
+			return true
+		case day.N < 0 && (daysInMonth-date.Day())/7+1 == -day.N:
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonth(month time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+// civilDate returns the date at midnight UTC, normalizing overflowing days and months like time.Date
+func civilDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// sortDates sorts the dates and removes duplicates, e.g. BYMONTHDAY=1,-31 in months of 31 days
+func sortDates(dates []time.Time) []time.Time {
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	unique := dates[:0]
+	for i, date := range dates {
+		if i == 0 || !date.Equal(dates[i-1]) {
+			unique = append(unique, date)
+		}
+	}
+	return unique
+}
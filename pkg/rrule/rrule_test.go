@@ -0,0 +1,104 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func dates(t *testing.T, rule string, start time.Time, n int) []string {
+	r, err := Parse(rule)
+	require.NoError(t, err)
+	var result []string
+	for _, occurrence := range r.Between(start, start.Add(-time.Second), start.AddDate(100, 0, 0), n) {
+		result = append(result, occurrence.Format("2006-01-02 Mon 15:04"))
+	}
+	return result
+}
+
+func TestRule_Between(t *testing.T) {
+	// a Wednesday
+	start := time.Date(2026, 1, 14, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		rule     string
+		expected []string
+	}{
+		{"FREQ=DAILY;COUNT=3", []string{"2026-01-14 Wed 09:30", "2026-01-15 Thu 09:30", "2026-01-16 Fri 09:30"}},
+		{"FREQ=DAILY;BYDAY=MO,FR", []string{"2026-01-16 Fri 09:30", "2026-01-19 Mon 09:30", "2026-01-23 Fri 09:30"}},
+		{"FREQ=WEEKLY", []string{"2026-01-14 Wed 09:30", "2026-01-21 Wed 09:30", "2026-01-28 Wed 09:30"}},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", []string{"2026-01-15 Thu 09:30", "2026-01-26 Mon 09:30", "2026-01-29 Thu 09:30"}},
+		{"FREQ=MONTHLY;UNTIL=20260401T000000Z", []string{"2026-01-14 Wed 09:30", "2026-02-14 Sat 09:30", "2026-03-14 Sat 09:30"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", []string{"2026-01-31 Sat 09:30", "2026-02-28 Sat 09:30", "2026-03-31 Tue 09:30"}},
+		{"FREQ=MONTHLY;BYDAY=1MO", []string{"2026-02-02 Mon 09:30", "2026-03-02 Mon 09:30", "2026-04-06 Mon 09:30"}},
+		{"FREQ=MONTHLY;BYDAY=-1FR", []string{"2026-01-30 Fri 09:30", "2026-02-27 Fri 09:30", "2026-03-27 Fri 09:30"}},
+		{"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", []string{"2026-02-13 Fri 09:30", "2026-03-13 Fri 09:30", "2026-11-13 Fri 09:30"}},
+		{"FREQ=YEARLY;BYMONTH=3,9;BYMONTHDAY=1", []string{"2026-03-01 Sun 09:30", "2026-09-01 Tue 09:30", "2027-03-01 Mon 09:30"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			require.Equal(t, tt.expected, dates(t, tt.rule, start, 3))
+		})
+	}
+
+	t.Run("months without the day are skipped", func(t *testing.T) {
+		require.Equal(t, []string{"2026-01-31 Sat 09:30", "2026-03-31 Tue 09:30", "2026-05-31 Sun 09:30"},
+			dates(t, "FREQ=MONTHLY", time.Date(2026, 1, 31, 9, 30, 0, 0, time.UTC), 3))
+	})
+
+	t.Run("rules that never match end", func(t *testing.T) {
+		require.Empty(t, dates(t, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", start, 3))
+	})
+
+	t.Run("the time of day is kept across daylight saving time", func(t *testing.T) {
+		paris, err := time.LoadLocation("Europe/Paris")
+		require.NoError(t, err)
+		r, err := Parse("FREQ=WEEKLY")
+		require.NoError(t, err)
+		start := time.Date(2026, 3, 23, 9, 0, 0, 0, paris)
+		occurrences := r.Between(start, start, start.AddDate(0, 0, 7), 10)
+		require.Len(t, occurrences, 1)
+		require.Equal(t, 9, occurrences[0].Hour())
+		// the clocks moved forward on March 29
+		require.Equal(t, 7*24*time.Hour-time.Hour, occurrences[0].Sub(start))
+	})
+}
+
+func TestRule_Next(t *testing.T) {
+	start := time.Date(2026, 1, 14, 9, 30, 0, 0, time.UTC)
+	r, err := Parse("FREQ=DAILY;COUNT=2")
+	require.NoError(t, err)
+
+	next, ok := r.Next(start, start)
+	require.True(t, ok)
+	require.Equal(t, start.AddDate(0, 0, 1), next)
+
+	_, ok = r.Next(start, next)
+	require.False(t, ok, "the rule ends after 2 occurrences")
+}
+
+func TestParse_errors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261231T000000Z",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=DAILY;BYHOUR=9",
+	} {
+		t.Run(rule, func(t *testing.T) {
+			_, err := Parse(rule)
+			require.Error(t, err)
+		})
+	}
+}
@@ -1,27 +1,201 @@
 package service
 
+import "net/http"
+
+// Error codes are stable, machine-readable identifiers returned to clients in the
+// "error_code" field of an error response. Clients branch on them, so a code must
+// never be renamed once it has been released.
+const (
+	ErrorCodeInternal             = "internal_error"
+	ErrorCodeInvalidInput         = "invalid_input"
+	ErrorCodeInvalidRequestBody   = "invalid_request_body"
+	ErrorCodeValidationFailed     = "validation_failed"
+	ErrorCodeUnauthorized         = "unauthorized"
+	ErrorCodeInvalidCredentials   = "invalid_credentials"
+	ErrorCodeInvalidRefreshToken  = "invalid_refresh_token"
+	ErrorCodeForbidden            = "forbidden"
+	ErrorCodeForbiddenRole        = "forbidden_role"
+	ErrorCodeNotFound             = "not_found"
+	ErrorCodeTaskNotFound         = "task_not_found"
+	ErrorCodeUserNotFound         = "user_not_found"
+	ErrorCodeCommentNotFound      = "comment_not_found"
+	ErrorCodeInvalidParent        = "invalid_parent_comment"
+	ErrorCodeAssigneeNotFound     = "assignee_not_found"
+	ErrorCodeInvalidAssignee      = "invalid_assignee"
+	ErrorCodeInvalidTaskStatus    = "invalid_task_status"
+	ErrorCodeInvalidPriority      = "invalid_task_priority"
+	ErrorCodeLabelNotFound        = "label_not_found"
+	ErrorCodeLabelTaken           = "label_taken"
+	ErrorCodeParentTaskNotFound   = "parent_task_not_found"
+	ErrorCodeOpenSubtasks         = "open_subtasks"
+	ErrorCodeChecklistNotFound    = "checklist_item_not_found"
+	ErrorCodeBlockerNotFound      = "blocker_not_found"
+	ErrorCodeDependencyCycle      = "dependency_cycle"
+	ErrorCodeDependencyNotFound   = "dependency_not_found"
+	ErrorCodeTaskBlocked          = "task_blocked"
+	ErrorCodeInvalidWorkflow      = "invalid_workflow"
+	ErrorCodeTransitionNotAllowed = "transition_not_allowed"
+	ErrorCodeStatusInUse          = "status_in_use"
+	ErrorCodeTaskSeriesNotFound   = "task_series_not_found"
+	ErrorCodeInvalidRecurrence    = "invalid_recurrence"
+	ErrorCodeTaskSeriesEnded      = "task_series_ended"
+	ErrorCodeWebhookNotFound      = "webhook_not_found"
+	ErrorCodeDeliveryNotFound     = "webhook_delivery_not_found"
+	ErrorCodeInvalidWebhookEvent  = "invalid_webhook_event"
+	ErrorCodeEmailTaken           = "email_taken"
+	ErrorCodeInvalidCursor        = "invalid_cursor"
+)
+
 var ErrNotFound = Error{
-	Code:    404,
-	Message: "not found",
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeNotFound,
+	Message:   "not found",
+}
+
+var ErrTaskNotFound = Error{
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeTaskNotFound,
+	Message:   "task not found",
+}
+
+var ErrUserNotFound = Error{
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeUserNotFound,
+	Message:   "user not found",
+}
+
+var ErrCommentNotFound = Error{
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeCommentNotFound,
+	Message:   "comment not found",
+}
+
+var ErrLabelNotFound = Error{
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeLabelNotFound,
+	Message:   "label not found",
+}
+
+var ErrChecklistItemNotFound = Error{
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeChecklistNotFound,
+	Message:   "checklist item not found",
+}
+
+var ErrDependencyNotFound = Error{
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeDependencyNotFound,
+	Message:   "dependency not found",
+}
+
+var ErrTaskSeriesNotFound = Error{
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeTaskSeriesNotFound,
+	Message:   "task series not found",
+}
+
+var ErrWebhookNotFound = Error{
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeWebhookNotFound,
+	Message:   "webhook not found",
+}
+
+var ErrDeliveryNotFound = Error{
+	Code:      http.StatusNotFound,
+	ErrorCode: ErrorCodeDeliveryNotFound,
+	Message:   "webhook delivery not found",
 }
 
 var ErrUnauthorized = Error{
-	Code:    401,
-	Message: "unauthorized",
+	Code:      http.StatusUnauthorized,
+	ErrorCode: ErrorCodeUnauthorized,
+	Message:   "unauthorized",
+}
+
+// ErrInvalidCredentials is returned for both unknown emails and wrong passwords,
+// so that clients cannot use the login endpoint to probe for registered emails.
+var ErrInvalidCredentials = Error{
+	Code:      http.StatusUnauthorized,
+	ErrorCode: ErrorCodeInvalidCredentials,
+	Message:   "invalid credentials",
+}
+
+// ErrInvalidRefreshToken is returned for unknown, expired, already used and revoked refresh tokens alike.
+// The client has to log in again.
+var ErrInvalidRefreshToken = Error{
+	Code:      http.StatusUnauthorized,
+	ErrorCode: ErrorCodeInvalidRefreshToken,
+	Message:   "invalid refresh token",
+}
+
+var ErrEmailTaken = Error{
+	Code:      http.StatusConflict,
+	ErrorCode: ErrorCodeEmailTaken,
+	Message:   "email is already registered",
+}
+
+var ErrLabelTaken = Error{
+	Code:      http.StatusConflict,
+	ErrorCode: ErrorCodeLabelTaken,
+	Message:   "a label with this name already exists",
 }
 
 func NewInvalidInputError(message string) Error {
+	return NewCodedError(http.StatusBadRequest, ErrorCodeInvalidInput, message)
+}
+
+// NewForbiddenRoleError is returned when the caller's role is not allowed to perform an action
+func NewForbiddenRoleError(message string) Error {
+	return NewCodedError(http.StatusForbidden, ErrorCodeForbiddenRole, message)
+}
+
+// NewForbiddenError is returned when the caller has the right role but no access to the resource
+func NewForbiddenError(message string) Error {
+	return NewCodedError(http.StatusForbidden, ErrorCodeForbidden, message)
+}
+
+// NewCodedError creates an Error with an explicit HTTP status and error code
+func NewCodedError(status int, errorCode, message string) Error {
 	return Error{
-		Code:    400,
-		Message: message,
+		Code:      status,
+		ErrorCode: errorCode,
+		Message:   message,
 	}
 }
 
+// NewValidationError is returned when one or more request fields are invalid
+func NewValidationError(fields []FieldError) Error {
+	err := NewCodedError(http.StatusBadRequest, ErrorCodeValidationFailed, "request validation failed")
+	err.Fields = fields
+	return err
+}
+
 type Error struct {
-	Code    int    `json:"code"` // HTTP status code
+	Code      int    `json:"code"`       // HTTP status code
+	ErrorCode string `json:"error_code"` // stable machine-readable code, see the ErrorCode* constants
+	Message   string `json:"message"`
+
+	// Fields lists every invalid field, only set for validation errors
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"` // JSON name of the field, nested fields are joined with "."
+	Rule    string `json:"rule"`  // the failed binding rule, e.g. "required" or "max"
 	Message string `json:"message"`
 }
 
 func (s Error) Error() string {
 	return s.Message
 }
+
+// Is reports whether target is a service Error with the same error code,
+// so errors.Is(err, ErrTaskNotFound) matches regardless of the message.
+func (s Error) Is(target error) bool {
+	t, ok := target.(Error)
+	if !ok {
+		return false
+	}
+	return t.ErrorCode == s.ErrorCode && t.Code == s.Code
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/llkhacquan/cisab/pkg/repo"
 )
 
-// TaskService defines the interface for task operations
+// TaskService defines the interface for task operations.
+// Role requirements (e.g. only employers can create tasks) are declared on the API endpoints,
+// see api.Endpoint.Roles. The service only enforces access to individual tasks.
 type TaskService interface {
 	// CreateTask creates a new task
 	CreateTask(ctx context.Context, request CreateTaskRequest) (*CreateTaskResponse, error)
@@ -27,15 +29,148 @@ type TaskService interface {
 
 	// GetEmployeeTaskSummary returns a summary of task statistics for each employee
 	GetEmployeeTaskSummary(ctx context.Context, request GetEmployeeTaskSummaryRequest) (*GetEmployeeTaskSummaryResponse, error)
+
+	// GetFlowAnalytics returns the throughput, lead time and cycle time of the tasks of the employer, team-wide and
+	// by employee, by week or month
+	GetFlowAnalytics(ctx context.Context, request GetFlowAnalyticsRequest) (*GetFlowAnalyticsResponse, error)
+
+	// Comments can only be read and written by the task's employer and assignee,
+	// the same users who can update its status.
+
+	// CreateComment starts a new thread on a task, or replies to a thread if ParentID is set
+	CreateComment(ctx context.Context, request CreateCommentRequest) (*CreateCommentResponse, error)
+
+	// GetComments returns the threads of a task, oldest first, with pagination
+	GetComments(ctx context.Context, request GetCommentsRequest) (*GetCommentsResponse, error)
+
+	// UpdateComment edits a comment, only its author can edit it
+	UpdateComment(ctx context.Context, request UpdateCommentRequest) (*UpdateCommentResponse, error)
+
+	// DeleteComment deletes a comment, its author and the task's employer can delete it
+	DeleteComment(ctx context.Context, request DeleteCommentRequest) (*DeleteCommentResponse, error)
+
+	// GetSubtasks returns the subtasks of a task, oldest first, with their own progress.
+	// Only the task's employer and assignee can list them.
+	GetSubtasks(ctx context.Context, request GetSubtasksRequest) (*GetSubtasksResponse, error)
+
+	// The checklist of a task can be read and changed by the task's employer and assignee.
+
+	// CreateChecklistItem adds an item to the checklist of a task
+	CreateChecklistItem(ctx context.Context, request CreateChecklistItemRequest) (*CreateChecklistItemResponse, error)
+
+	// GetChecklist returns the checklist of a task, in creation order
+	GetChecklist(ctx context.Context, request GetChecklistRequest) (*GetChecklistResponse, error)
+
+	// UpdateChecklistItem renames an item, or checks or unchecks it
+	UpdateChecklistItem(ctx context.Context, request UpdateChecklistItemRequest) (*UpdateChecklistItemResponse, error)
+
+	// DeleteChecklistItem removes an item from the checklist of a task
+	DeleteChecklistItem(ctx context.Context, request DeleteChecklistItemRequest) (*DeleteChecklistItemResponse, error)
+
+	// Dependencies are managed by the employer who created the task. A task cannot be started nor completed
+	// until its blockers are completed.
+
+	// CreateTaskDependency makes a task wait for a blocker of the same employer, unless it would make a cycle
+	CreateTaskDependency(ctx context.Context, request CreateTaskDependencyRequest) (*CreateTaskDependencyResponse, error)
+
+	// DeleteTaskDependency removes a blocker of a task
+	DeleteTaskDependency(ctx context.Context, request DeleteTaskDependencyRequest) (*DeleteTaskDependencyResponse, error)
+
+	// GetTaskHistory returns the activity history of a task, oldest first, with pagination.
+	// Only the task's employer and assignee can read it.
+	GetTaskHistory(ctx context.Context, request GetTaskHistoryRequest) (*GetTaskHistoryResponse, error)
+
+	// Each employer manages their own labels, and can only use them on their own tasks.
+
+	// CreateLabel creates a label for the authenticated employer
+	CreateLabel(ctx context.Context, request CreateLabelRequest) (*CreateLabelResponse, error)
+
+	// GetLabels returns the labels of the authenticated employer, by name
+	GetLabels(ctx context.Context, request GetLabelsRequest) (*GetLabelsResponse, error)
+
+	// UpdateLabel renames a label of the authenticated employer
+	UpdateLabel(ctx context.Context, request UpdateLabelRequest) (*UpdateLabelResponse, error)
+
+	// DeleteLabel deletes a label of the authenticated employer, and removes it from its tasks
+	DeleteLabel(ctx context.Context, request DeleteLabelRequest) (*DeleteLabelResponse, error)
+
+	// Each employer can configure the workflow of their tasks, the others use the default workflow.
+
+	// GetWorkflow returns the workflow of the authenticated employer
+	GetWorkflow(ctx context.Context, request GetWorkflowRequest) (*GetWorkflowResponse, error)
+
+	// UpdateWorkflow replaces the workflow of the authenticated employer.
+	// Statuses that tasks of the employer are in cannot be removed.
+	UpdateWorkflow(ctx context.Context, request UpdateWorkflowRequest) (*UpdateWorkflowResponse, error)
+
+	// ResetWorkflow makes the authenticated employer use the default workflow again
+	ResetWorkflow(ctx context.Context, request ResetWorkflowRequest) (*ResetWorkflowResponse, error)
+
+	// A series creates a task for each occurrence of its recurrence rule, ahead of time.
+	// Each employer manages their own series.
+
+	// CreateTaskSeries creates a recurring task, and its upcoming tasks
+	CreateTaskSeries(ctx context.Context, request CreateTaskSeriesRequest) (*CreateTaskSeriesResponse, error)
+
+	// GetTaskSeriesList returns the series of the authenticated employer, in creation order
+	GetTaskSeriesList(ctx context.Context, request GetTaskSeriesListRequest) (*GetTaskSeriesListResponse, error)
+
+	// GetTaskSeries returns a series of the authenticated employer
+	GetTaskSeries(ctx context.Context, request GetTaskSeriesRequest) (*GetTaskSeriesResponse, error)
+
+	// UpdateTaskSeries changes the template or the rule of a series.
+	// Its upcoming tasks that have not started are created again, from the new template and rule.
+	UpdateTaskSeries(ctx context.Context, request UpdateTaskSeriesRequest) (*UpdateTaskSeriesResponse, error)
+
+	// UpdateTaskSeriesState pauses, resumes or ends a series. Pausing or ending a series deletes its upcoming
+	// tasks that have not started, resuming it creates the tasks of the occurrences from now on.
+	UpdateTaskSeriesState(ctx context.Context, request UpdateTaskSeriesStateRequest) (*UpdateTaskSeriesStateResponse, error)
+
+	// GenerateRecurringTasks creates the upcoming tasks of the active series of all employers.
+	// It is not exposed by the API, a background worker runs it periodically.
+	GenerateRecurringTasks(ctx context.Context, request GenerateRecurringTasksRequest) (*GenerateRecurringTasksResponse, error)
+
+	// NotifyDueTasks records the reminders of the tasks due soon and the overdue notices of the tasks past their
+	// due date, then delivers the recorded events to the notifier.
+	// It is not exposed by the API, a background worker runs it periodically.
+	NotifyDueTasks(ctx context.Context, request NotifyDueTasksRequest) (*NotifyDueTasksResponse, error)
+
+	// Each employer can register webhooks, that receive the events of their tasks.
+
+	// CreateWebhook registers a webhook for the authenticated employer, the response has its secret
+	CreateWebhook(ctx context.Context, request CreateWebhookRequest) (*CreateWebhookResponse, error)
+
+	// GetWebhooks returns the webhooks of the authenticated employer, without their secret
+	GetWebhooks(ctx context.Context, request GetWebhooksRequest) (*GetWebhooksResponse, error)
+
+	// UpdateWebhook changes the URL or the events of a webhook, or enables or disables it
+	UpdateWebhook(ctx context.Context, request UpdateWebhookRequest) (*UpdateWebhookResponse, error)
+
+	// DeleteWebhook deletes a webhook and its delivery log
+	DeleteWebhook(ctx context.Context, request DeleteWebhookRequest) (*DeleteWebhookResponse, error)
+
+	// GetWebhookDeliveries returns the delivery log of a webhook, newest first
+	GetWebhookDeliveries(ctx context.Context, request GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesResponse, error)
+
+	// RedeliverWebhookDelivery sends the event of a delivery again, as a new delivery
+	RedeliverWebhookDelivery(ctx context.Context, request RedeliverWebhookDeliveryRequest) (*RedeliverWebhookDeliveryResponse, error)
 }
 
-// CreateTaskRequest represents the request to create a new task
+// CreateTaskRequest represents the request to create a new task.
+// The max lengths match the tasks table.
 type CreateTaskRequest struct {
-	Title       string     `json:"title" binding:"required"`
+	Title       string     `json:"title" binding:"required,max=255"`
 	Description string     `json:"description"`
-	Status      string     `json:"status"`
+	Status      string     `json:"status" binding:"max=20"` // a status of the employer's workflow, defaults to its first status
 	DueDate     *time.Time `json:"due_date,omitempty"`
-	AssigneeID  *int       `json:"assignee_id,omitempty"`
+	AssigneeID  *int       `json:"assignee_id,omitempty" binding:"min=1"`
+	Priority    string     `json:"priority,omitempty" binding:"oneof=low normal high urgent"` // defaults to normal
+	// LabelIDs are labels of the authenticated employer
+	LabelIDs []models.LabelID `json:"label_ids,omitempty" binding:"max=20"`
+	// ParentID makes the task a subtask of a task of the authenticated employer
+	ParentID *int `json:"parent_id,omitempty" binding:"min=1"`
+	// InheritAssignee assigns a subtask to the assignee of its parent, unless AssigneeID is set
+	InheritAssignee bool `json:"inherit_assignee,omitempty"`
 }
 
 // CreateTaskResponse represents the response after creating a task
@@ -47,6 +182,8 @@ type CreateTaskResponse struct {
 type UpdateTaskStatusRequest struct {
 	TaskID models.TaskID     `json:"task_id"`
 	Status models.TaskStatus `json:"status" binding:"required"`
+	// Force completes a task even though some of its subtasks are not done
+	Force bool `json:"force,omitempty"`
 }
 
 // UpdateTaskStatusResponse represents the response after updating a task's status
@@ -57,7 +194,7 @@ type UpdateTaskStatusResponse struct {
 // AssignTaskRequest represents the request to assign a task to an employee
 type AssignTaskRequest struct {
 	TaskID     models.TaskID `json:"task_id"`
-	AssigneeID models.UserID `json:"assignee_id" binding:"required"`
+	AssigneeID models.UserID `json:"assignee_id" binding:"required,min=1"`
 }
 
 // AssignTaskResponse represents the response after assigning a task
@@ -65,46 +202,447 @@ type AssignTaskResponse struct {
 	Task models.Task `json:"task"`
 }
 
+// TaskFilter represents the filters shared by the task lists. The filters with several values keep the tasks that
+// match any of them, and the tasks must match all the filters.
+type TaskFilter struct {
+	Statuses    []models.TaskStatus `json:"statuses,omitempty"`     // Filter by statuses
+	AssigneeIDs []models.UserID     `json:"assignee_ids,omitempty"` // Filter by assignees
+	Unassigned  bool                `json:"unassigned,omitempty"`   // Only the unassigned tasks, or also them with AssigneeIDs
+	EmployerIDs []models.UserID     `json:"employer_ids,omitempty"` // Filter by the employers who created the tasks
+	Priority    models.TaskPriority `json:"priority,omitempty"`     // Filter by priority
+	LabelID     *models.LabelID     `json:"label_id,omitempty"`     // Filter by label
+	Overdue     bool                `json:"overdue,omitempty"`      // Only tasks past their due date and not done
+
+	// Date ranges, the "after" bounds are inclusive and the "before" ones exclusive
+	DueBefore     *time.Time `json:"due_before,omitempty"`
+	DueAfter      *time.Time `json:"due_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+
+	Query string `json:"q,omitempty"` // Full-text search in the title and description
+}
+
 // GetAssignedTasksRequest represents the request for fetching assigned tasks
 type GetAssignedTasksRequest struct {
 	// Filtering
-	Status   models.TaskStatus   `json:"status,omitempty"`   // Filter by specific status (for backward compatibility)
-	Statuses []models.TaskStatus `json:"statuses,omitempty"` // Filter by specific statuses
+	TaskFilter
 
-	// Sorting
-	SortBy    string `json:"sort_by,omitempty"`    // Field to sort by: "created_at" or "updated_at"
+	// Sorting, by fields in turn or by a single field
+	Sort      string `json:"sort,omitempty"`       // Fields to sort by, like "status:active_first,due_date:asc"
+	SortBy    string `json:"sort_by,omitempty"`    // Field to sort by: "created_at", "updated_at", "due_date", "status" or "priority"
 	SortOrder string `json:"sort_order,omitempty"` // Sort order: "asc" or "desc"
 
-	// Pagination
-	Limit  int `json:"limit,omitempty"`  // Number of records to return
-	Offset int `json:"offset,omitempty"` // Number of records to skip
+	// Pagination, by offset or by cursor
+	Limit     int    `json:"limit,omitempty"`      // Number of records to return
+	Offset    int    `json:"offset,omitempty"`     // Number of records to skip
+	Cursor    string `json:"cursor,omitempty"`     // The NextCursor of the previous page
+	SkipCount bool   `json:"skip_count,omitempty"` // Do not count the tasks, TotalCount is then not set
 }
 
 // GetAssignedTasksResponse represents the response for fetching assigned tasks
 type GetAssignedTasksResponse struct {
 	Tasks      []models.Task `json:"tasks"`
-	TotalCount int           `json:"total_count"` // Total number of tasks matching the filters (before pagination)
+	TotalCount *int          `json:"total_count,omitempty"` // Total number of tasks matching the filters (before pagination)
+	NextCursor string        `json:"next_cursor,omitempty"` // Cursor of the next page, if any
 }
 
 // GetTasksRequest represents the request for fetching tasks (for employers)
 type GetTasksRequest struct {
 	// Filtering
-	Status     models.TaskStatus `json:"status,omitempty"`      // Filter by specific status
-	AssigneeID *models.UserID    `json:"assignee_id,omitempty"` // Filter by assignee
+	TaskFilter
 
-	// Sorting
-	SortBy    string `json:"sort_by,omitempty"`    // Field to sort by: "created_at", "updated_at", "due_date", or "status"
+	// Sorting, by fields in turn or by a single field
+	Sort      string `json:"sort,omitempty"`       // Fields to sort by, like "status:active_first,due_date:asc"
+	SortBy    string `json:"sort_by,omitempty"`    // Field to sort by: "created_at", "updated_at", "due_date", "status" or "priority"
 	SortOrder string `json:"sort_order,omitempty"` // Sort order: "asc" or "desc"
 
-	// Pagination
-	Limit  int `json:"limit,omitempty"`  // Number of records to return
-	Offset int `json:"offset,omitempty"` // Number of records to skip
+	// Pagination, by offset or by cursor
+	Limit     int    `json:"limit,omitempty"`      // Number of records to return
+	Offset    int    `json:"offset,omitempty"`     // Number of records to skip
+	Cursor    string `json:"cursor,omitempty"`     // The NextCursor of the previous page
+	SkipCount bool   `json:"skip_count,omitempty"` // Do not count the tasks, TotalCount is then not set
 }
 
 // GetTasksResponse represents the response for fetching tasks
 type GetTasksResponse struct {
 	Tasks      []models.Task `json:"tasks"`
-	TotalCount int           `json:"total_count"` // Total number of tasks matching the filters (before pagination)
+	TotalCount *int          `json:"total_count,omitempty"` // Total number of tasks matching the filters (before pagination)
+	NextCursor string        `json:"next_cursor,omitempty"` // Cursor of the next page, if any
+}
+
+// CreateCommentRequest represents the request to comment on a task
+type CreateCommentRequest struct {
+	TaskID models.TaskID `json:"task_id"`
+	// ParentID is the comment to reply to. Replying to a reply adds to the same thread.
+	ParentID *models.CommentID `json:"parent_id,omitempty" binding:"min=1"`
+	Body     string            `json:"body" binding:"required,max=10000"`
+}
+
+// CreateCommentResponse represents the response after commenting on a task
+type CreateCommentResponse struct {
+	Comment models.TaskComment `json:"comment"`
+}
+
+// GetCommentsRequest represents the request for fetching the comments of a task
+type GetCommentsRequest struct {
+	TaskID models.TaskID `json:"task_id"`
+
+	// Pagination, by thread
+	Limit  int `json:"limit,omitempty"`  // Number of threads to return, defaults to 50, at most 100
+	Offset int `json:"offset,omitempty"` // Number of threads to skip
+}
+
+// GetCommentsResponse represents the response for fetching the comments of a task
+type GetCommentsResponse struct {
+	Comments   []models.TaskComment `json:"comments"`    // The first comment of each thread, with its replies
+	TotalCount int                  `json:"total_count"` // Total number of threads (before pagination)
+}
+
+// UpdateCommentRequest represents the request to edit a comment
+type UpdateCommentRequest struct {
+	TaskID    models.TaskID    `json:"task_id"`
+	CommentID models.CommentID `json:"comment_id"`
+	Body      string           `json:"body" binding:"required,max=10000"`
+}
+
+// UpdateCommentResponse represents the response after editing a comment
+type UpdateCommentResponse struct {
+	Comment models.TaskComment `json:"comment"`
+}
+
+// DeleteCommentRequest represents the request to delete a comment
+type DeleteCommentRequest struct {
+	TaskID    models.TaskID    `json:"task_id"`
+	CommentID models.CommentID `json:"comment_id"`
+}
+
+// DeleteCommentResponse represents the response after deleting a comment
+type DeleteCommentResponse struct {
+	Comment models.TaskComment `json:"comment"`
+}
+
+// GetSubtasksRequest represents the request for fetching the subtasks of a task
+type GetSubtasksRequest struct {
+	TaskID models.TaskID `json:"task_id"`
+}
+
+// GetSubtasksResponse represents the response for fetching the subtasks of a task
+type GetSubtasksResponse struct {
+	Tasks []models.Task `json:"tasks"`
+}
+
+// CreateChecklistItemRequest represents the request to add an item to the checklist of a task
+type CreateChecklistItemRequest struct {
+	TaskID models.TaskID `json:"task_id"`
+	Title  string        `json:"title" binding:"required,max=255"`
+}
+
+// CreateChecklistItemResponse represents the response after adding a checklist item
+type CreateChecklistItemResponse struct {
+	Item models.ChecklistItem `json:"item"`
+}
+
+// GetChecklistRequest represents the request for fetching the checklist of a task
+type GetChecklistRequest struct {
+	TaskID models.TaskID `json:"task_id"`
+}
+
+// GetChecklistResponse represents the response for fetching the checklist of a task
+type GetChecklistResponse struct {
+	Items []models.ChecklistItem `json:"items"`
+	Done  int                    `json:"done"` // Number of items done
+}
+
+// UpdateChecklistItemRequest represents the request to change a checklist item, nil fields are left unchanged
+type UpdateChecklistItemRequest struct {
+	TaskID models.TaskID          `json:"task_id"`
+	ItemID models.ChecklistItemID `json:"item_id"`
+	Title  *string                `json:"title,omitempty" binding:"min=1,max=255"`
+	Done   *bool                  `json:"done,omitempty"`
+}
+
+// UpdateChecklistItemResponse represents the response after changing a checklist item
+type UpdateChecklistItemResponse struct {
+	Item models.ChecklistItem `json:"item"`
+}
+
+// DeleteChecklistItemRequest represents the request to remove a checklist item
+type DeleteChecklistItemRequest struct {
+	TaskID models.TaskID          `json:"task_id"`
+	ItemID models.ChecklistItemID `json:"item_id"`
+}
+
+// DeleteChecklistItemResponse represents the response after removing a checklist item
+type DeleteChecklistItemResponse struct {
+	Item models.ChecklistItem `json:"item"`
+}
+
+// CreateTaskDependencyRequest represents the request to make a task wait for another one
+type CreateTaskDependencyRequest struct {
+	TaskID    models.TaskID `json:"task_id"`
+	BlockerID models.TaskID `json:"blocker_id" binding:"required,min=1"`
+}
+
+// CreateTaskDependencyResponse represents the response after adding a dependency
+type CreateTaskDependencyResponse struct {
+	Dependency models.TaskDependency `json:"dependency"`
+}
+
+// DeleteTaskDependencyRequest represents the request to remove a blocker of a task
+type DeleteTaskDependencyRequest struct {
+	TaskID    models.TaskID `json:"task_id"`
+	BlockerID models.TaskID `json:"blocker_id"`
+}
+
+// DeleteTaskDependencyResponse represents the response after removing a dependency
+type DeleteTaskDependencyResponse struct {
+	Dependency models.TaskDependency `json:"dependency"`
+}
+
+// GetTaskHistoryRequest represents the request for fetching the activity history of a task
+type GetTaskHistoryRequest struct {
+	TaskID models.TaskID `json:"task_id"`
+
+	// Pagination
+	Limit  int `json:"limit,omitempty"`  // Number of events to return, defaults to 50, at most 100
+	Offset int `json:"offset,omitempty"` // Number of events to skip
+}
+
+// GetTaskHistoryResponse represents the response for fetching the activity history of a task
+type GetTaskHistoryResponse struct {
+	Events     []models.TaskEvent `json:"events"`
+	TotalCount int                `json:"total_count"` // Total number of events (before pagination)
+}
+
+// GetWorkflowRequest represents the request for fetching the workflow of the authenticated employer
+type GetWorkflowRequest struct {
+}
+
+// GetWorkflowResponse represents the response for fetching a workflow
+type GetWorkflowResponse struct {
+	Workflow models.Workflow `json:"workflow"`
+}
+
+// UpdateWorkflowRequest represents the request to replace the workflow of the authenticated employer
+type UpdateWorkflowRequest struct {
+	// Statuses in display order, the first one is the status of new tasks
+	Statuses    []models.WorkflowStatus     `json:"statuses" binding:"required,max=20"`
+	Transitions []models.WorkflowTransition `json:"transitions" binding:"max=400"`
+}
+
+// UpdateWorkflowResponse represents the response after replacing a workflow
+type UpdateWorkflowResponse struct {
+	Workflow models.Workflow `json:"workflow"`
+}
+
+// ResetWorkflowRequest represents the request to go back to the default workflow
+type ResetWorkflowRequest struct {
+}
+
+// ResetWorkflowResponse represents the response after going back to the default workflow
+type ResetWorkflowResponse struct {
+	Workflow models.Workflow `json:"workflow"`
+}
+
+// CreateTaskSeriesRequest represents the request to create a recurring task.
+// The max lengths match the task_series table.
+type CreateTaskSeriesRequest struct {
+	Title       string `json:"title" binding:"required,max=255"`
+	Description string `json:"description"`
+	AssigneeID  *int   `json:"assignee_id,omitempty" binding:"min=1"`
+	Priority    string `json:"priority,omitempty" binding:"oneof=low normal high urgent"` // defaults to normal
+	// RRule is the recurrence rule, e.g. "FREQ=WEEKLY;BYDAY=MO", see package rrule for the supported subset
+	RRule string `json:"rrule" binding:"required,max=255"`
+	// Timezone is the IANA time zone of the rule, e.g. "Europe/Paris", defaults to UTC
+	Timezone string `json:"timezone,omitempty" binding:"max=64"`
+	// StartsAt is the start of the rule, the tasks are due at its time of day
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+}
+
+// CreateTaskSeriesResponse represents the response after creating a series
+type CreateTaskSeriesResponse struct {
+	Series models.TaskSeries `json:"series"`
+}
+
+// GetTaskSeriesListRequest represents the request for fetching the series of the authenticated employer
+type GetTaskSeriesListRequest struct {
+}
+
+// GetTaskSeriesListResponse represents the response for fetching the series of an employer
+type GetTaskSeriesListResponse struct {
+	Series []models.TaskSeries `json:"series"`
+}
+
+// GetTaskSeriesRequest represents the request for fetching a series
+type GetTaskSeriesRequest struct {
+	SeriesID models.TaskSeriesID `json:"series_id"`
+}
+
+// GetTaskSeriesResponse represents the response for fetching a series
+type GetTaskSeriesResponse struct {
+	Series models.TaskSeries `json:"series"`
+}
+
+// UpdateTaskSeriesRequest represents the request to change a series, nil fields are left unchanged
+type UpdateTaskSeriesRequest struct {
+	SeriesID    models.TaskSeriesID `json:"series_id"`
+	Title       *string             `json:"title,omitempty" binding:"min=1,max=255"`
+	Description *string             `json:"description,omitempty"`
+	AssigneeID  *int                `json:"assignee_id,omitempty" binding:"min=1"`
+	Priority    *string             `json:"priority,omitempty" binding:"oneof=low normal high urgent"`
+	RRule       *string             `json:"rrule,omitempty" binding:"min=1,max=255"`
+	Timezone    *string             `json:"timezone,omitempty" binding:"min=1,max=64"`
+	StartsAt    *time.Time          `json:"starts_at,omitempty"`
+}
+
+// UpdateTaskSeriesResponse represents the response after changing a series
+type UpdateTaskSeriesResponse struct {
+	Series models.TaskSeries `json:"series"`
+}
+
+// UpdateTaskSeriesStateRequest represents the request to pause, resume or end a series
+type UpdateTaskSeriesStateRequest struct {
+	SeriesID models.TaskSeriesID    `json:"series_id"`
+	State    models.TaskSeriesState `json:"state" binding:"required,oneof=active paused ended"`
+}
+
+// UpdateTaskSeriesStateResponse represents the response after pausing, resuming or ending a series
+type UpdateTaskSeriesStateResponse struct {
+	Series models.TaskSeries `json:"series"`
+}
+
+// GenerateRecurringTasksRequest represents a run of the generator of recurring tasks
+type GenerateRecurringTasksRequest struct {
+	Now time.Time // the tasks are created up to the horizon after Now
+}
+
+// GenerateRecurringTasksResponse represents the result of a run of the generator of recurring tasks
+type GenerateRecurringTasksResponse struct {
+	Series int // number of series processed
+	Tasks  int // number of tasks created
+}
+
+// NotifyDueTasksRequest represents a run of the scheduler of the due date notifications
+type NotifyDueTasksRequest struct {
+	Now          time.Time
+	ReminderLead time.Duration // the tasks due within ReminderLead after Now get a reminder
+}
+
+// NotifyDueTasksResponse represents the result of a run of the scheduler of the due date notifications
+type NotifyDueTasksResponse struct {
+	Recorded int // number of reminders and overdue notices recorded
+	Notified int // number of events delivered
+	Failed   int // number of events the notifier failed to deliver, the next runs try again
+}
+
+// CreateWebhookRequest represents the request to register a webhook
+type CreateWebhookRequest struct {
+	URL string `json:"url" binding:"required,url,max=2048"`
+	// Events the webhook subscribes to, e.g. "task.created"
+	Events []models.WebhookEventType `json:"events" binding:"required,max=10"`
+}
+
+// CreateWebhookResponse represents the response after registering a webhook, with its secret
+type CreateWebhookResponse struct {
+	Webhook models.Webhook `json:"webhook"`
+}
+
+// GetWebhooksRequest represents the request for fetching the webhooks of the authenticated employer
+type GetWebhooksRequest struct {
+}
+
+// GetWebhooksResponse represents the response for fetching webhooks
+type GetWebhooksResponse struct {
+	Webhooks []models.Webhook `json:"webhooks"`
+}
+
+// UpdateWebhookRequest represents the request to change a webhook, nil fields are left unchanged
+type UpdateWebhookRequest struct {
+	WebhookID models.WebhookID          `json:"webhook_id"`
+	URL       *string                   `json:"url,omitempty" binding:"url,max=2048"`
+	Events    []models.WebhookEventType `json:"events,omitempty" binding:"max=10"`
+	Active    *bool                     `json:"active,omitempty"`
+}
+
+// UpdateWebhookResponse represents the response after changing a webhook
+type UpdateWebhookResponse struct {
+	Webhook models.Webhook `json:"webhook"`
+}
+
+// DeleteWebhookRequest represents the request to delete a webhook
+type DeleteWebhookRequest struct {
+	WebhookID models.WebhookID `json:"webhook_id"`
+}
+
+// DeleteWebhookResponse represents the response after deleting a webhook
+type DeleteWebhookResponse struct {
+	Webhook models.Webhook `json:"webhook"`
+}
+
+// GetWebhookDeliveriesRequest represents the request for fetching the delivery log of a webhook
+type GetWebhookDeliveriesRequest struct {
+	WebhookID models.WebhookID `json:"webhook_id"`
+
+	// Pagination
+	Limit  int `json:"limit,omitempty"`  // Number of deliveries to return, defaults to 50, at most 100
+	Offset int `json:"offset,omitempty"` // Number of deliveries to skip
+}
+
+// GetWebhookDeliveriesResponse represents the response for fetching the delivery log of a webhook
+type GetWebhookDeliveriesResponse struct {
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+	TotalCount int                      `json:"total_count"` // Total number of deliveries (before pagination)
+}
+
+// RedeliverWebhookDeliveryRequest represents the request to send the event of a delivery again
+type RedeliverWebhookDeliveryRequest struct {
+	WebhookID  models.WebhookID         `json:"webhook_id"`
+	DeliveryID models.WebhookDeliveryID `json:"delivery_id"`
+}
+
+// RedeliverWebhookDeliveryResponse represents the response after a redelivery, with the new delivery
+type RedeliverWebhookDeliveryResponse struct {
+	Delivery models.WebhookDelivery `json:"delivery"`
+}
+
+// CreateLabelRequest represents the request to create a label
+type CreateLabelRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// CreateLabelResponse represents the response after creating a label
+type CreateLabelResponse struct {
+	Label models.Label `json:"label"`
+}
+
+// GetLabelsRequest represents the request for fetching the labels of the authenticated employer
+type GetLabelsRequest struct {
+}
+
+// GetLabelsResponse represents the response for fetching labels
+type GetLabelsResponse struct {
+	Labels []models.Label `json:"labels"`
+}
+
+// UpdateLabelRequest represents the request to rename a label
+type UpdateLabelRequest struct {
+	LabelID models.LabelID `json:"label_id"`
+	Name    string         `json:"name" binding:"required,max=50"`
+}
+
+// UpdateLabelResponse represents the response after renaming a label
+type UpdateLabelResponse struct {
+	Label models.Label `json:"label"`
+}
+
+// DeleteLabelRequest represents the request to delete a label
+type DeleteLabelRequest struct {
+	LabelID models.LabelID `json:"label_id"`
+}
+
+// DeleteLabelResponse represents the response after deleting a label
+type DeleteLabelResponse struct {
+	Label models.Label `json:"label"`
 }
 
 // EmployeeSummary represents task statistics for an employee
@@ -115,10 +653,57 @@ type EmployeeSummary struct {
 
 // GetEmployeeTaskSummaryRequest represents the request for fetching employee task summaries
 type GetEmployeeTaskSummaryRequest struct {
-	// Currently empty, but can be extended in the future with filtering and pagination options
+	// Filtering of the tasks to count
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`  // Only count the tasks created at or after this time
+	CreatedBefore *time.Time `json:"created_before,omitempty"` // Only count the tasks created before this time
+	OwnTasks      bool       `json:"own_tasks,omitempty"`      // Only count the tasks created by the employer
+
+	// Sorting
+	SortBy    string `json:"sort_by,omitempty"`    // "name" or a statistic, e.g. "overdue" or "on_time_rate"
+	SortOrder string `json:"sort_order,omitempty"` // Sort order: "asc" or "desc", defaults to asc by name, desc by statistic
+
+	// Pagination
+	Limit  int `json:"limit,omitempty"`  // Number of employees to return, all by default
+	Offset int `json:"offset,omitempty"` // Number of employees to skip
 }
 
 // GetEmployeeTaskSummaryResponse represents the response for fetching employee task summaries
 type GetEmployeeTaskSummaryResponse struct {
-	Employees []EmployeeSummary `json:"employees"`
+	Employees  []EmployeeSummary `json:"employees"`
+	TotalCount int               `json:"total_count"` // Total number of employees (before pagination)
+}
+
+// GetFlowAnalyticsRequest represents the request for fetching the flow analytics of the employer's tasks
+type GetFlowAnalyticsRequest struct {
+	Interval string `json:"interval,omitempty"` // Length of the periods: "week", the default, or "month"
+	// Range of the times the tasks were done, the last 12 periods by default
+	CompletedAfter  *time.Time `json:"completed_after,omitempty"`  // Only the tasks done at or after this time
+	CompletedBefore *time.Time `json:"completed_before,omitempty"` // Only the tasks done before this time, defaults to now
+}
+
+// GetFlowAnalyticsResponse represents the flow analytics of the employer's tasks done in a range
+type GetFlowAnalyticsResponse struct {
+	Interval        string         `json:"interval"`
+	CompletedAfter  time.Time      `json:"completed_after"`
+	CompletedBefore time.Time      `json:"completed_before"`
+	Team            FlowSeries     `json:"team"`      // All the tasks, the unassigned ones included
+	Employees       []EmployeeFlow `json:"employees"` // The employees who did tasks in the range, by ID
+}
+
+// FlowSeries represents the flow metrics over a range, and in each of its periods
+type FlowSeries struct {
+	Total   repo.FlowMetrics `json:"total"`
+	Periods []FlowPeriod     `json:"periods"` // All the periods of the range, in order, the ones without tasks done included
+}
+
+// FlowPeriod represents the flow metrics of the tasks done in a week or a month
+type FlowPeriod struct {
+	Start time.Time `json:"start"` // Start of the period, in UTC
+	repo.FlowMetrics
+}
+
+// EmployeeFlow represents the flow metrics of the tasks done by an employee
+type EmployeeFlow struct {
+	Employee models.User `json:"employee"`
+	FlowSeries
 }
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultFlowPeriods is the number of periods the flow analytics cover when the range is not set
+	defaultFlowPeriods = 12
+	// maxFlowPeriods is the maximum number of periods of the flow analytics, two years of weeks
+	maxFlowPeriods = 104
+)
+
+var errTooManyFlowPeriods = NewInvalidInputError(fmt.Sprintf(
+	"the range cannot have more than %d periods, shorten it or use a longer interval", maxFlowPeriods))
+
+// GetFlowAnalytics returns the throughput, lead time and cycle time of the tasks of the employer done in a range,
+// team-wide and by employee, in each week or month of the range. The range defaults to the last 12 periods.
+func (s *taskService) GetFlowAnalytics(ctx context.Context, request GetFlowAnalyticsRequest) (*GetFlowAnalyticsResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	interval := repo.FlowInterval(request.Interval)
+	if interval == "" {
+		interval = repo.FlowIntervalWeek
+	}
+	if interval != repo.FlowIntervalWeek && interval != repo.FlowIntervalMonth {
+		return nil, NewInvalidInputError("interval must be 'week' or 'month'")
+	}
+
+	// The range ends now and starts with the 12th period before the end by default
+	after, before := request.CompletedAfter, request.CompletedBefore
+	if before == nil {
+		now := time.Now()
+		before = &now
+	}
+	if after == nil {
+		// The last period is the one of the last instant of the range, before is not in it
+		start := flowPeriodStart(before.Add(-time.Nanosecond), interval)
+		for i := 1; i < defaultFlowPeriods; i++ {
+			start = previousFlowPeriod(start, interval)
+		}
+		after = &start
+	}
+	from, to, err := timeRange("completed", after, before)
+	if err != nil {
+		return nil, err
+	}
+	periods := flowPeriods(from, to, interval)
+	if len(periods) > maxFlowPeriods {
+		return nil, errTooManyFlowPeriods
+	}
+
+	groups, err := s.taskRepo.GetFlowMetrics(ctx, repo.GetFlowMetricsOptions{
+		EmployerID:      authMD.User.ID,
+		CompletedAfter:  from,
+		CompletedBefore: to,
+		Interval:        interval,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get flow metrics")
+	}
+
+	// Build the response, every series has all the periods, the ones without tasks done included
+	response := &GetFlowAnalyticsResponse{
+		Interval:        string(interval),
+		CompletedAfter:  from,
+		CompletedBefore: to,
+		Team:            newFlowSeries(periods),
+		Employees:       []EmployeeFlow{},
+	}
+	for _, group := range groups {
+		series := &response.Team
+		if group.Assignee != nil {
+			// The groups of an employee follow each other
+			if n := len(response.Employees); n == 0 || response.Employees[n-1].Employee.ID != group.Assignee.ID {
+				response.Employees = append(response.Employees, EmployeeFlow{Employee: *group.Assignee, FlowSeries: newFlowSeries(periods)})
+			}
+			series = &response.Employees[len(response.Employees)-1].FlowSeries
+		}
+		setFlowMetrics(series, group)
+	}
+	return response, nil
+}
+
+// flowPeriodStart returns the start of the week, from Monday, or of the month of t, in UTC, like the database does
+func flowPeriodStart(t time.Time, interval repo.FlowInterval) time.Time {
+	t = t.UTC()
+	if interval == repo.FlowIntervalMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// nextFlowPeriod returns the start of the period after the one starting at start
+func nextFlowPeriod(start time.Time, interval repo.FlowInterval) time.Time {
+	if interval == repo.FlowIntervalMonth {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+// previousFlowPeriod returns the start of the period before the one starting at start
+func previousFlowPeriod(start time.Time, interval repo.FlowInterval) time.Time {
+	if interval == repo.FlowIntervalMonth {
+		return start.AddDate(0, -1, 0)
+	}
+	return start.AddDate(0, 0, -7)
+}
+
+// flowPeriods returns the starts of the periods of a range, in order. The first and the last ones may not be fully
+// in the range. It stops past maxFlowPeriods.
+func flowPeriods(from, to time.Time, interval repo.FlowInterval) []time.Time {
+	var periods []time.Time
+	for start := flowPeriodStart(from, interval); start.Before(to) && len(periods) <= maxFlowPeriods; start = nextFlowPeriod(start, interval) {
+		periods = append(periods, start)
+	}
+	return periods
+}
+
+// newFlowSeries returns a series of flow metrics of the periods, without tasks done yet
+func newFlowSeries(periods []time.Time) FlowSeries {
+	series := FlowSeries{Periods: make([]FlowPeriod, len(periods))}
+	for i, start := range periods {
+		series.Periods[i].Start = start
+	}
+	return series
+}
+
+// setFlowMetrics sets the metrics of a group in its series, as the total or as the metrics of its period
+func setFlowMetrics(series *FlowSeries, group repo.FlowMetricsGroup) {
+	if group.PeriodStart == nil {
+		series.Total = group.Metrics
+		return
+	}
+	i := sort.Search(len(series.Periods), func(i int) bool {
+		return !series.Periods[i].Start.Before(*group.PeriodStart)
+	})
+	if i < len(series.Periods) && series.Periods[i].Start.Equal(*group.PeriodStart) {
+		series.Periods[i].FlowMetrics = group.Metrics
+	}
+}
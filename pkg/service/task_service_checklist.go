@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/pkg/errors"
+)
+
+// GetSubtasks returns the subtasks of a task
+func (s *taskService) GetSubtasks(ctx context.Context, request GetSubtasksRequest) (*GetSubtasksResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	task, err := s.getParticipatingTask(ctx, request.TaskID, authMD.User, "read the subtasks of")
+	if err != nil {
+		return nil, err
+	}
+
+	_, tasks, err := s.taskRepo.GetTasks(ctx, repo.GetTasksOptions{
+		ParentID: task.ID,
+		Sort:     repo.TaskSort{{Key: repo.TaskSortCreatedAt}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get subtasks")
+	}
+
+	return &GetSubtasksResponse{
+		Tasks: tasks,
+	}, nil
+}
+
+// CreateChecklistItem adds an item to the checklist of a task
+func (s *taskService) CreateChecklistItem(ctx context.Context, request CreateChecklistItemRequest) (*CreateChecklistItemResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	task, err := s.getParticipatingTask(ctx, request.TaskID, authMD.User, "update")
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.checklistRepo.CreateChecklistItem(ctx, models.ChecklistItem{
+		TaskID: task.ID,
+		Title:  request.Title,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create checklist item")
+	}
+
+	return &CreateChecklistItemResponse{
+		Item: item,
+	}, nil
+}
+
+// GetChecklist returns the checklist of a task
+func (s *taskService) GetChecklist(ctx context.Context, request GetChecklistRequest) (*GetChecklistResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	task, err := s.getParticipatingTask(ctx, request.TaskID, authMD.User, "read the checklist of")
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.checklistRepo.GetChecklistItems(ctx, task.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get checklist")
+	}
+
+	done := 0
+	for _, item := range items {
+		if item.Done {
+			done++
+		}
+	}
+
+	return &GetChecklistResponse{
+		Items: items,
+		Done:  done,
+	}, nil
+}
+
+// UpdateChecklistItem renames an item, or checks or unchecks it
+func (s *taskService) UpdateChecklistItem(ctx context.Context, request UpdateChecklistItemRequest) (*UpdateChecklistItemResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	item, err := s.getTaskChecklistItem(ctx, request.TaskID, request.ItemID, authMD.User)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.checklistRepo.UpdateChecklistItem(ctx, item.ID, request.Title, request.Done); err != nil {
+		return nil, errors.Wrap(err, "failed to update checklist item")
+	}
+
+	// Retrieve the updated item
+	updatedItem, err := s.checklistRepo.GetChecklistItemByID(ctx, item.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get updated checklist item")
+	}
+
+	return &UpdateChecklistItemResponse{
+		Item: *updatedItem,
+	}, nil
+}
+
+// DeleteChecklistItem removes an item from the checklist of a task
+func (s *taskService) DeleteChecklistItem(ctx context.Context, request DeleteChecklistItemRequest) (*DeleteChecklistItemResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	item, err := s.getTaskChecklistItem(ctx, request.TaskID, request.ItemID, authMD.User)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.checklistRepo.DeleteChecklistItem(ctx, item.ID); err != nil {
+		return nil, errors.Wrap(err, "failed to delete checklist item")
+	}
+
+	return &DeleteChecklistItemResponse{
+		Item: *item,
+	}, nil
+}
+
+// getTaskChecklistItem returns an item of the checklist of a task the user participates in.
+// Items of other tasks are not found.
+func (s *taskService) getTaskChecklistItem(ctx context.Context, taskID models.TaskID, itemID models.ChecklistItemID, user models.User) (*models.ChecklistItem, error) {
+	task, err := s.getParticipatingTask(ctx, taskID, user, "update")
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.checklistRepo.GetChecklistItemByID(ctx, itemID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get checklist item")
+	}
+	if item == nil || item.TaskID != task.ID {
+		return nil, ErrChecklistItemNotFound
+	}
+	return item, nil
+}
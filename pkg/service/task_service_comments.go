@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/pkg/errors"
+)
+
+var errInvalidParentComment = NewCodedError(http.StatusBadRequest, ErrorCodeInvalidParent, "parent comment not found on this task")
+
+// CreateComment starts a new thread on a task, or replies to a thread
+func (s *taskService) CreateComment(ctx context.Context, request CreateCommentRequest) (*CreateCommentResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	task, err := s.getParticipatingTask(ctx, request.TaskID, authMD.User, "comment on")
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies go to the first comment of the thread, threads are one level deep
+	var parentID *models.CommentID
+	if request.ParentID != nil {
+		parent, err := s.commentRepo.GetCommentByID(ctx, *request.ParentID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get parent comment")
+		}
+		if parent == nil || parent.TaskID != task.ID {
+			return nil, errInvalidParentComment
+		}
+		threadID := parent.ID
+		if parent.ParentID != nil {
+			threadID = *parent.ParentID
+		}
+		parentID = &threadID
+	}
+
+	comment, err := s.commentRepo.CreateComment(ctx, models.TaskComment{
+		TaskID:   task.ID,
+		ParentID: parentID,
+		AuthorID: authMD.User.ID,
+		Body:     request.Body,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create comment")
+	}
+
+	return &CreateCommentResponse{
+		Comment: comment,
+	}, nil
+}
+
+// GetComments returns the threads of a task with pagination
+func (s *taskService) GetComments(ctx context.Context, request GetCommentsRequest) (*GetCommentsResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	if request.Limit > maxCommentsLimit {
+		return nil, NewInvalidInputError("limit must be at most 100")
+	}
+	if request.Limit <= 0 {
+		request.Limit = defaultCommentsLimit
+	}
+
+	task, err := s.getParticipatingTask(ctx, request.TaskID, authMD.User, "read comments of")
+	if err != nil {
+		return nil, err
+	}
+
+	totalCount, comments, err := s.commentRepo.GetComments(ctx, repo.GetCommentsOptions{
+		TaskID: task.ID,
+		Offset: request.Offset,
+		Limit:  request.Limit,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get comments")
+	}
+
+	return &GetCommentsResponse{
+		Comments:   comments,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// UpdateComment edits a comment, only its author can edit it
+func (s *taskService) UpdateComment(ctx context.Context, request UpdateCommentRequest) (*UpdateCommentResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	comment, err := s.getTaskComment(ctx, request.TaskID, request.CommentID, authMD.User)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != authMD.User.ID {
+		return nil, NewForbiddenError("you can only edit your own comments")
+	}
+
+	updated, err := s.commentRepo.UpdateCommentBody(ctx, comment.ID, request.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update comment")
+	}
+	if !updated {
+		return nil, ErrCommentNotFound
+	}
+
+	// Retrieve the updated comment
+	updatedComment, err := s.commentRepo.GetCommentByID(ctx, comment.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get updated comment")
+	}
+
+	return &UpdateCommentResponse{
+		Comment: *updatedComment,
+	}, nil
+}
+
+// DeleteComment deletes a comment, its author and the task's employer can delete it
+func (s *taskService) DeleteComment(ctx context.Context, request DeleteCommentRequest) (*DeleteCommentResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	comment, err := s.getTaskComment(ctx, request.TaskID, request.CommentID, authMD.User)
+	if err != nil {
+		return nil, err
+	}
+	// getTaskComment checked that the user participates in the task, so an employer here created it
+	if comment.AuthorID != authMD.User.ID && !authMD.User.IsEmployer() {
+		return nil, NewForbiddenError("you can only delete your own comments")
+	}
+
+	deleted, err := s.commentRepo.DeleteComment(ctx, comment.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete comment")
+	}
+	if !deleted {
+		return nil, ErrCommentNotFound
+	}
+
+	// Retrieve the deleted comment
+	deletedComment, err := s.commentRepo.GetCommentByID(ctx, comment.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get deleted comment")
+	}
+
+	return &DeleteCommentResponse{
+		Comment: *deletedComment,
+	}, nil
+}
+
+// getTaskComment returns a comment of a task the user participates in.
+// Deleted comments and comments of other tasks are not found.
+func (s *taskService) getTaskComment(ctx context.Context, taskID models.TaskID, commentID models.CommentID, user models.User) (*models.TaskComment, error) {
+	task, err := s.getParticipatingTask(ctx, taskID, user, "comment on")
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.commentRepo.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get comment")
+	}
+	if comment == nil || comment.TaskID != task.ID || comment.IsDeleted() {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
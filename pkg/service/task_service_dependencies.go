@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+)
+
+var (
+	errBlockerNotFound = NewCodedError(http.StatusBadRequest, ErrorCodeBlockerNotFound, "blocker not found")
+	errDependencyCycle = NewCodedError(http.StatusConflict, ErrorCodeDependencyCycle, "the blocker already depends on the task")
+)
+
+// CreateTaskDependency makes a task wait for a blocker of the same employer, unless it would make a cycle
+func (s *taskService) CreateTaskDependency(ctx context.Context, request CreateTaskDependencyRequest) (*CreateTaskDependencyResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	task, err := s.getParticipatingTask(ctx, request.TaskID, authMD.User, "add dependencies to")
+	if err != nil {
+		return nil, err
+	}
+
+	// Tasks can only wait for tasks of the same employer, which the user can see as well
+	blocker, err := s.taskRepo.GetTaskByID(ctx, request.BlockerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get blocker")
+	}
+	if blocker == nil || blocker.EmployerID != task.EmployerID {
+		return nil, errBlockerNotFound
+	}
+	if _, err := checkParticipant(blocker, authMD.User, "wait for"); err != nil {
+		return nil, err
+	}
+	if blocker.ID == task.ID {
+		return nil, NewCodedError(http.StatusConflict, ErrorCodeDependencyCycle, "a task cannot block itself")
+	}
+
+	// Serialize the changes of the employer's dependencies, so the cycle check sees all of them
+	if err := s.dependencyRepo.LockTaskDependencies(ctx, task.EmployerID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.dependencyRepo.GetTaskDependency(ctx, task.ID, blocker.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task dependency")
+	}
+	if existing != nil {
+		return &CreateTaskDependencyResponse{
+			Dependency: *existing,
+		}, nil
+	}
+
+	// The new dependency makes a cycle if the blocker already waits for the task
+	cycle, err := s.dependencyRepo.IsBlockedBy(ctx, blocker.ID, task.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check for a dependency cycle")
+	}
+	if cycle {
+		return nil, errDependencyCycle
+	}
+
+	dependency, err := s.dependencyRepo.CreateTaskDependency(ctx, models.TaskDependency{
+		TaskID:    task.ID,
+		BlockerID: blocker.ID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create task dependency")
+	}
+
+	return &CreateTaskDependencyResponse{
+		Dependency: dependency,
+	}, nil
+}
+
+// DeleteTaskDependency removes a blocker of a task
+func (s *taskService) DeleteTaskDependency(ctx context.Context, request DeleteTaskDependencyRequest) (*DeleteTaskDependencyResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	task, err := s.getParticipatingTask(ctx, request.TaskID, authMD.User, "remove dependencies from")
+	if err != nil {
+		return nil, err
+	}
+
+	dependency, err := s.dependencyRepo.GetTaskDependency(ctx, task.ID, request.BlockerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task dependency")
+	}
+	if dependency == nil {
+		return nil, ErrDependencyNotFound
+	}
+
+	deleted, err := s.dependencyRepo.DeleteTaskDependency(ctx, task.ID, request.BlockerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete task dependency")
+	}
+	if !deleted {
+		return nil, ErrDependencyNotFound
+	}
+
+	return &DeleteTaskDependencyResponse{
+		Dependency: *dependency,
+	}, nil
+}
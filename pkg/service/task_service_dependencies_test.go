@@ -0,0 +1,66 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_taskService_CreateTaskDependency(t *testing.T) {
+	ctx, taskService, userRepo := setupTaskService(t)
+
+	employer := createTestUser(t, ctx, userRepo, "employer@example.com", models.UserRoleEmployer)
+	otherEmployer := createTestUser(t, ctx, userRepo, "other@example.com", models.UserRoleEmployer)
+	employee := createTestUser(t, ctx, userRepo, "employee@example.com", models.UserRoleEmployee)
+
+	design := createTestTask(t, ctx, taskService, employer, "Design", &employee)
+	build := createTestTask(t, ctx, taskService, employer, "Build", &employee)
+	ship := createTestTask(t, ctx, taskService, employer, "Ship", &employee)
+	for _, dependency := range []CreateTaskDependencyRequest{{TaskID: build.ID, BlockerID: design.ID}, {TaskID: ship.ID, BlockerID: build.ID}} {
+		_, err := taskService.CreateTaskDependency(asUser(ctx, employer), dependency)
+		require.NoError(t, err)
+	}
+
+	errDependencyCycle := NewCodedError(http.StatusConflict, ErrorCodeDependencyCycle, "")
+
+	t.Run("a task cannot block itself", func(t *testing.T) {
+		_, err := taskService.CreateTaskDependency(asUser(ctx, employer), CreateTaskDependencyRequest{TaskID: design.ID, BlockerID: design.ID})
+		require.True(t, errors.Is(err, errDependencyCycle), err)
+	})
+
+	t.Run("nor a task that waits for it", func(t *testing.T) {
+		// ship waits for build, which waits for design
+		_, err := taskService.CreateTaskDependency(asUser(ctx, employer), CreateTaskDependencyRequest{TaskID: design.ID, BlockerID: ship.ID})
+		require.True(t, errors.Is(err, errDependencyCycle), err)
+		_, err = taskService.CreateTaskDependency(asUser(ctx, employer), CreateTaskDependencyRequest{TaskID: build.ID, BlockerID: ship.ID})
+		require.True(t, errors.Is(err, errDependencyCycle), err)
+	})
+
+	t.Run("adding a dependency again has no effect", func(t *testing.T) {
+		dependency, err := taskService.CreateTaskDependency(asUser(ctx, employer), CreateTaskDependencyRequest{TaskID: build.ID, BlockerID: design.ID})
+		require.NoError(t, err)
+		require.Equal(t, design.ID, dependency.Dependency.BlockerID)
+	})
+
+	t.Run("tasks only wait for the tasks of the same employer", func(t *testing.T) {
+		foreign := createTestTask(t, ctx, taskService, otherEmployer, "Foreign", &employee)
+
+		_, err := taskService.CreateTaskDependency(asUser(ctx, employer), CreateTaskDependencyRequest{TaskID: ship.ID, BlockerID: foreign.ID})
+		require.True(t, errors.Is(err, errBlockerNotFound), err)
+		_, err = taskService.CreateTaskDependency(asUser(ctx, employer), CreateTaskDependencyRequest{TaskID: ship.ID, BlockerID: 9999})
+		require.True(t, errors.Is(err, errBlockerNotFound), err)
+		// nor add dependencies to the tasks of other employers
+		_, err = taskService.CreateTaskDependency(asUser(ctx, otherEmployer), CreateTaskDependencyRequest{TaskID: ship.ID, BlockerID: design.ID})
+		require.True(t, errors.Is(err, NewForbiddenError("")), err)
+	})
+
+	t.Run("the blocker is as private as the task", func(t *testing.T) {
+		unassigned := createTestTask(t, ctx, taskService, employer, "Unassigned", nil)
+
+		_, err := taskService.CreateTaskDependency(asUser(ctx, employee), CreateTaskDependencyRequest{TaskID: ship.ID, BlockerID: unassigned.ID})
+		require.True(t, errors.Is(err, NewForbiddenError("")), err)
+	})
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/notify"
+	"github.com/pkg/errors"
+)
+
+// dueEventsBatchSize is the number of events a run of the scheduler delivers, the next runs deliver the others
+const dueEventsBatchSize = 100
+
+// NotifyDueTasks records the reminders and overdue notices of the tasks, then delivers them.
+// Events locked by a concurrent run are skipped. An event the notifier fails to deliver stays pending,
+// the next runs try again.
+func (s *taskService) NotifyDueTasks(ctx context.Context, request NotifyDueTasksRequest) (*NotifyDueTasksResponse, error) {
+	recorded, err := s.dueEventRepo.RecordDueEvents(ctx, request.Now, request.Now.Add(request.ReminderLead))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to record task due events")
+	}
+
+	events, err := s.dueEventRepo.LockPendingDueEvents(ctx, dueEventsBatchSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pending task due events")
+	}
+
+	response := &NotifyDueTasksResponse{Recorded: recorded}
+	for _, event := range events {
+		notification, err := s.dueNotification(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.notifier.Notify(ctx, notification); err != nil {
+			response.Failed++
+			continue
+		}
+		if _, err := s.dueEventRepo.MarkDueEventNotified(ctx, event.ID, request.Now); err != nil {
+			return nil, errors.Wrap(err, "failed to mark task due event as notified")
+		}
+		response.Notified++
+	}
+	return response, nil
+}
+
+// dueNotification returns the notification of an event. Reminders go to the assignee of the task, or to its employer
+// if it is not assigned. Overdue notices go to both.
+func (s *taskService) dueNotification(ctx context.Context, event models.TaskDueEvent) (notify.Notification, error) {
+	task, err := s.taskRepo.GetTaskByID(ctx, event.TaskID)
+	if err != nil {
+		return notify.Notification{}, errors.Wrap(err, "failed to get task")
+	}
+	if task == nil {
+		return notify.Notification{}, ErrTaskNotFound
+	}
+
+	notification := notify.Notification{Kind: event.Kind, Task: *task}
+	var recipientIDs []models.UserID
+	if task.AssigneeID != nil {
+		recipientIDs = append(recipientIDs, *task.AssigneeID)
+	}
+	if task.AssigneeID == nil || event.Kind == models.TaskDueEventOverdue {
+		recipientIDs = append(recipientIDs, task.EmployerID)
+	}
+	for _, id := range recipientIDs {
+		user, err := s.userRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return notify.Notification{}, errors.Wrap(err, "failed to get recipient")
+		}
+		if user != nil {
+			notification.Recipients = append(notification.Recipients, *user)
+		}
+	}
+	return notification, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/outbox"
+	"github.com/pkg/errors"
+)
+
+// taskDomainEvent is a domain event of a task to record, with the changed field if any
+type taskDomainEvent struct {
+	Type   models.DomainEventType
+	Change *models.DomainEventChange
+}
+
+// recordTaskEvents records domain events of a task in the outbox, with the task after the change. They are recorded
+// in the transaction of the change, and published once it commits.
+func (s *taskService) recordTaskEvents(ctx context.Context, task models.Task, actorID models.UserID, events ...taskDomainEvent) error {
+	outboxEvents := make([]models.OutboxEvent, 0, len(events))
+	for _, event := range events {
+		outboxEvent, err := outbox.NewEvent(event.Type, &task.EmployerID, &actorID, models.DomainEventData{
+			Task:   &task,
+			Change: event.Change,
+		})
+		if err != nil {
+			return err
+		}
+		outboxEvents = append(outboxEvents, outboxEvent)
+	}
+	if err := s.relay.Record(ctx, outboxEvents...); err != nil {
+		return errors.Wrap(err, "failed to record domain events")
+	}
+	return nil
+}
+
+// taskCreatedDomainEvents returns the domain events of a new task: created, and assigned when it has an assignee
+func taskCreatedDomainEvents(task models.Task) []taskDomainEvent {
+	events := []taskDomainEvent{{Type: models.DomainEventTaskCreated}}
+	if task.AssigneeID != nil {
+		events = append(events, taskDomainEvent{
+			Type:   models.DomainEventTaskAssigned,
+			Change: &models.DomainEventChange{Field: models.TaskFieldAssigneeID, NewValue: userIDValue(task.AssigneeID)},
+		})
+	}
+	return events
+}
+
+// changeOf returns the change of a field recorded in the task history
+func changeOf(event models.TaskEvent) *models.DomainEventChange {
+	return &models.DomainEventChange{Field: event.Field, OldValue: event.OldValue, NewValue: event.NewValue}
+}
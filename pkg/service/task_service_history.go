@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/pkg/errors"
+)
+
+// GetTaskHistory returns the activity history of a task with pagination
+func (s *taskService) GetTaskHistory(ctx context.Context, request GetTaskHistoryRequest) (*GetTaskHistoryResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	if request.Limit > maxHistoryLimit {
+		return nil, NewInvalidInputError("limit must be at most 100")
+	}
+	if request.Limit <= 0 {
+		request.Limit = defaultHistoryLimit
+	}
+
+	task, err := s.getParticipatingTask(ctx, request.TaskID, authMD.User, "read the history of")
+	if err != nil {
+		return nil, err
+	}
+
+	totalCount, events, err := s.taskEventRepo.GetTaskEvents(ctx, repo.GetTaskEventsOptions{
+		TaskID: task.ID,
+		Offset: request.Offset,
+		Limit:  request.Limit,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task history")
+	}
+
+	return &GetTaskHistoryResponse{
+		Events:     events,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// taskCreatedEvent records the creation of a task by actorID
+func taskCreatedEvent(task models.Task, actorID models.UserID) models.TaskEvent {
+	return models.TaskEvent{
+		TaskID:  task.ID,
+		ActorID: actorID,
+		Type:    models.TaskEventCreated,
+	}
+}
+
+// taskUpdatedEvent records the change of a field of a task by actorID
+func taskUpdatedEvent(taskID models.TaskID, actorID models.UserID, field string, oldValue, newValue *string) models.TaskEvent {
+	return models.TaskEvent{
+		TaskID:   taskID,
+		ActorID:  actorID,
+		Type:     models.TaskEventUpdated,
+		Field:    field,
+		OldValue: oldValue,
+		NewValue: newValue,
+	}
+}
+
+func statusValue(status models.TaskStatus) *string {
+	value := string(status)
+	return &value
+}
+
+func userIDValue(id *models.UserID) *string {
+	if id == nil {
+		return nil
+	}
+	value := strconv.Itoa(int(*id))
+	return &value
+}
@@ -2,25 +2,130 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/llkhacquan/cisab/pkg/authctx"
 	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/notify"
+	"github.com/llkhacquan/cisab/pkg/outbox"
 	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/llkhacquan/cisab/pkg/webhook"
 	"github.com/pkg/errors"
 )
 
+var (
+	errAssigneeNotFound    = NewCodedError(http.StatusBadRequest, ErrorCodeAssigneeNotFound, "assignee not found")
+	errAssigneeNotEmployee = NewCodedError(http.StatusBadRequest, ErrorCodeInvalidAssignee, "tasks can only be assigned to employees")
+	errTaskLabelNotFound   = NewCodedError(http.StatusBadRequest, ErrorCodeLabelNotFound, "label not found")
+	errInvalidPriority     = NewCodedError(http.StatusBadRequest, ErrorCodeInvalidPriority, "invalid task priority")
+	errParentTaskNotFound  = NewCodedError(http.StatusBadRequest, ErrorCodeParentTaskNotFound, "parent task not found")
+	errInvalidTaskStatus   = NewCodedError(http.StatusBadRequest, ErrorCodeInvalidTaskStatus, "invalid task status")
+	errSearchQueryTooLong  = NewInvalidInputError(fmt.Sprintf("q must be at most %d characters", maxSearchQueryLength))
+	errTooManyFilterValues = NewInvalidInputError(fmt.Sprintf("a filter takes at most %d values", maxFilterValues))
+)
+
+const (
+	// maxSearchQueryLength is the maximum length of the full-text search queries, in characters
+	maxSearchQueryLength = 200
+	// maxFilterValues is the maximum number of values of a filter of the task lists
+	maxFilterValues = 50
+)
+
+const (
+	defaultCommentsLimit = 50
+	maxCommentsLimit     = 100
+	defaultHistoryLimit  = 50
+	maxHistoryLimit      = 100
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 100
+)
+
 // taskService implements the TaskService interface
 type taskService struct {
-	taskRepo repo.TaskRepo
-	userRepo repo.UserRepo
+	taskRepo       repo.TaskRepo
+	userRepo       repo.UserRepo
+	commentRepo    repo.CommentRepo
+	taskEventRepo  repo.TaskEventRepo
+	labelRepo      repo.LabelRepo
+	checklistRepo  repo.ChecklistRepo
+	dependencyRepo repo.TaskDependencyRepo
+	workflowRepo   repo.WorkflowRepo
+	taskSeriesRepo repo.TaskSeriesRepo
+	dueEventRepo   repo.TaskDueEventRepo
+	notifier       notify.Notifier
+	webhookRepo    repo.WebhookRepo
+	// webhookDispatcher sends the redeliveries once their transaction commits
+	webhookDispatcher *webhook.Dispatcher
+	// relay records the domain events in the outbox, and publishes them once their transaction commits
+	relay *outbox.Relay
 }
 
 // NewTaskService creates a new TaskService
-func NewTaskService(taskRepo repo.TaskRepo, userRepo repo.UserRepo) TaskService {
+func NewTaskService(taskRepo repo.TaskRepo, userRepo repo.UserRepo, commentRepo repo.CommentRepo, taskEventRepo repo.TaskEventRepo,
+	labelRepo repo.LabelRepo, checklistRepo repo.ChecklistRepo, dependencyRepo repo.TaskDependencyRepo, workflowRepo repo.WorkflowRepo,
+	taskSeriesRepo repo.TaskSeriesRepo, dueEventRepo repo.TaskDueEventRepo, notifier notify.Notifier, webhookRepo repo.WebhookRepo,
+	webhookDispatcher *webhook.Dispatcher, relay *outbox.Relay) TaskService {
 	return &taskService{
-		taskRepo: taskRepo,
-		userRepo: userRepo,
+		taskRepo:          taskRepo,
+		userRepo:          userRepo,
+		commentRepo:       commentRepo,
+		taskEventRepo:     taskEventRepo,
+		labelRepo:         labelRepo,
+		checklistRepo:     checklistRepo,
+		dependencyRepo:    dependencyRepo,
+		workflowRepo:      workflowRepo,
+		taskSeriesRepo:    taskSeriesRepo,
+		dueEventRepo:      dueEventRepo,
+		notifier:          notifier,
+		webhookRepo:       webhookRepo,
+		webhookDispatcher: webhookDispatcher,
+		relay:             relay,
+	}
+}
+
+// getParticipatingTask returns a task the user participates in: as the employer who created it,
+// or as the employee it is assigned to. Other users get a forbidden error, saying they can only
+// perform the action (e.g. "update") on their own tasks.
+func (s *taskService) getParticipatingTask(ctx context.Context, taskID models.TaskID, user models.User, action string) (*models.Task, error) {
+	task, err := s.taskRepo.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task")
 	}
+	return checkParticipant(task, user, action)
+}
+
+// lockParticipatingTask is getParticipatingTask for tasks about to change: the task is locked until the end of the
+// transaction, so the changes recorded in its history apply one after the other.
+func (s *taskService) lockParticipatingTask(ctx context.Context, taskID models.TaskID, user models.User, action string) (*models.Task, error) {
+	task, err := s.taskRepo.GetTaskByIDForUpdate(ctx, taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task")
+	}
+	return checkParticipant(task, user, action)
+}
+
+func checkParticipant(task *models.Task, user models.User, action string) (*models.Task, error) {
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+
+	if user.Role == models.UserRoleEmployee {
+		// Make sure task has assignee and it's the current user
+		if task.AssigneeID == nil || *task.AssigneeID != user.ID {
+			return nil, NewForbiddenError("you can only " + action + " tasks assigned to you")
+		}
+	} else if user.Role == models.UserRoleEmployer {
+		// Employers can only access tasks they created
+		if task.EmployerID != user.ID {
+			return nil, NewForbiddenError("you can only " + action + " tasks you created")
+		}
+	}
+	return task, nil
 }
 
 // CreateTask creates a new task
@@ -29,52 +134,84 @@ func (s *taskService) CreateTask(ctx context.Context, request CreateTaskRequest)
 	if authMD.User.ID == 0 {
 		return nil, ErrUnauthorized
 	}
-	if authMD.User.Role != models.UserRoleEmployer {
-		return nil, NewInvalidInputError("only employers can create tasks")
-	}
-
-	status := models.TaskStatusPending
-	if request.Status != "" {
-		switch models.TaskStatus(request.Status) {
-		case models.TaskStatusPending, models.TaskStatusInProgress, models.TaskStatusCompleted:
-			status = models.TaskStatus(request.Status)
-		default:
-			return nil, NewInvalidInputError("invalid task status")
-		}
-	}
 
 	// Check if the assignee exists if provided
 	var assigneeID *models.UserID
 	if request.AssigneeID != nil {
-		id := models.UserID(*request.AssigneeID)
-		assignee, err := s.userRepo.GetUserByID(ctx, id)
+		id, err := s.checkAssignee(ctx, models.UserID(*request.AssigneeID))
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to get assignee")
-		}
-		if assignee == nil {
-			return nil, NewInvalidInputError("assignee not found")
-		}
-		// Verify the assignee is an employee
-		if !assignee.IsEmployee() {
-			return nil, errors.New("task can only be assigned to employees")
+			return nil, err
 		}
 		assigneeID = &id
 	}
 
+	priority := models.TaskPriorityNormal
+	if request.Priority != "" {
+		priority = models.TaskPriority(request.Priority)
+		if !priority.IsValid() {
+			return nil, errInvalidPriority
+		}
+	}
+
+	// Employers can only use their own labels
+	labels, err := s.getEmployerLabels(ctx, authMD.User.ID, request.LabelIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the task
 	task := models.Task{
 		Title:       request.Title,
 		Description: request.Description,
-		Status:      status,
+		Priority:    priority,
 		DueDate:     request.DueDate,
 		EmployerID:  authMD.User.ID,
 		AssigneeID:  assigneeID,
+		Labels:      labels,
+	}
+
+	// Subtasks belong to the employer of their parent, and may inherit its assignee
+	if request.ParentID != nil {
+		parent, err := s.taskRepo.GetTaskByID(ctx, models.TaskID(*request.ParentID))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get parent task")
+		}
+		if parent == nil {
+			return nil, errParentTaskNotFound
+		}
+		if _, err := checkParticipant(parent, authMD.User, "add subtasks to"); err != nil {
+			return nil, err
+		}
+		task.ParentID = &parent.ID
+		task.EmployerID = parent.EmployerID
+		if task.AssigneeID == nil && request.InheritAssignee {
+			task.AssigneeID = parent.AssigneeID
+		}
+	}
+
+	// The status must be one of the employer's workflow, new tasks start in its first status by default
+	workflow, err := s.workflowRepo.GetWorkflow(ctx, task.EmployerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get workflow")
+	}
+	task.Status = workflow.InitialStatus()
+	if request.Status != "" {
+		task.Status = models.TaskStatus(request.Status)
+		if _, ok := workflow.Status(task.Status); !ok {
+			return nil, errInvalidTaskStatus
+		}
 	}
 
 	createdTask, err := s.taskRepo.CreateTask(ctx, task)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create task")
 	}
+	if err := s.taskEventRepo.CreateTaskEvents(ctx, taskCreatedEvent(createdTask, authMD.User.ID)); err != nil {
+		return nil, errors.Wrap(err, "failed to record task history")
+	}
+	if err := s.recordTaskEvents(ctx, createdTask, authMD.User.ID, taskCreatedDomainEvents(createdTask)...); err != nil {
+		return nil, err
+	}
 
 	return &CreateTaskResponse{
 		Task: createdTask,
@@ -89,36 +226,49 @@ func (s *taskService) UpdateTaskStatus(ctx context.Context, request UpdateTaskSt
 		return nil, ErrUnauthorized
 	}
 
-	// Get the task
-	task, err := s.taskRepo.GetTaskByID(ctx, request.TaskID)
+	// Get the task, only its assignee and the employer who created it can update its status
+	task, err := s.lockParticipatingTask(ctx, request.TaskID, authMD.User, "update")
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get task")
+		return nil, err
 	}
 
-	if task == nil {
-		return nil, ErrNotFound
+	// The employer's workflow defines the statuses, and who can move a task from one to another
+	workflow, err := s.workflowRepo.GetWorkflow(ctx, task.EmployerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get workflow")
+	}
+	status, ok := workflow.Status(request.Status)
+	if !ok {
+		return nil, errInvalidTaskStatus
+	}
+	changed := task.Status != request.Status
+	if changed && !workflow.CanTransition(task.Status, request.Status, authMD.User.Role) {
+		return nil, NewCodedError(http.StatusConflict, ErrorCodeTransitionNotAllowed,
+			fmt.Sprintf("%ss cannot move a task from %s to %s", authMD.User.Role, task.Status, request.Status))
 	}
 
-	// Verify the user is allowed to update the task status
-	// Only employees assigned to the task can update its status
-	if authMD.User.Role == models.UserRoleEmployee {
-		// Make sure task has assignee and it's the current user
-		if task.AssigneeID == nil || *task.AssigneeID != authMD.User.ID {
-			return nil, NewInvalidInputError("you can only update tasks assigned to you")
+	// A task cannot start, nor be done, until its blockers are done
+	if changed && (status.Active || status.Terminal) {
+		openBlockers, err := s.taskRepo.CountOpenBlockers(ctx, task.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to count open blockers")
 		}
-	} else if authMD.User.Role == models.UserRoleEmployer {
-		// Employers can only update tasks they created
-		if task.EmployerID != authMD.User.ID {
-			return nil, NewInvalidInputError("you can only update tasks you created")
+		if openBlockers > 0 {
+			return nil, NewCodedError(http.StatusConflict, ErrorCodeTaskBlocked,
+				fmt.Sprintf("the task is blocked by %d open tasks, complete them first", openBlockers))
 		}
 	}
 
-	// Validate the new status
-	switch request.Status {
-	case models.TaskStatusPending, models.TaskStatusInProgress, models.TaskStatusCompleted:
-		// Valid status
-	default:
-		return nil, NewInvalidInputError("invalid task status")
+	// A task is done once all its subtasks are, unless forced
+	if status.Terminal && !workflow.IsTerminal(task.Status) && !request.Force {
+		openSubtasks, err := s.taskRepo.CountOpenSubtasks(ctx, task.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to count open subtasks")
+		}
+		if openSubtasks > 0 {
+			return nil, NewCodedError(http.StatusConflict, ErrorCodeOpenSubtasks,
+				fmt.Sprintf("the task has %d open subtasks, complete them first or force the completion", openSubtasks))
+		}
 	}
 
 	// Update the task status
@@ -129,11 +279,27 @@ func (s *taskService) UpdateTaskStatus(ctx context.Context, request UpdateTaskSt
 
 	// Note: We're ignoring the 'updated' boolean return value since we always fetch the task afterward
 
+	var domainEvents []taskDomainEvent
+	if task.Status != request.Status {
+		event := taskUpdatedEvent(task.ID, authMD.User.ID, models.TaskFieldStatus,
+			statusValue(task.Status), statusValue(request.Status))
+		if err := s.taskEventRepo.CreateTaskEvents(ctx, event); err != nil {
+			return nil, errors.Wrap(err, "failed to record task history")
+		}
+		domainEvents = append(domainEvents, taskDomainEvent{Type: models.DomainEventTaskStatusChanged, Change: changeOf(event)})
+		if status.Terminal && !workflow.IsTerminal(task.Status) {
+			domainEvents = append(domainEvents, taskDomainEvent{Type: models.DomainEventTaskCompleted, Change: changeOf(event)})
+		}
+	}
+
 	// Retrieve the updated task
 	updatedTask, err := s.taskRepo.GetTaskByID(ctx, task.ID)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get updated task")
 	}
+	if err := s.recordTaskEvents(ctx, *updatedTask, authMD.User.ID, domainEvents...); err != nil {
+		return nil, err
+	}
 
 	return &UpdateTaskStatusResponse{
 		Task: *updatedTask,
@@ -148,58 +314,39 @@ func (s *taskService) GetAssignedTasks(ctx context.Context, request GetAssignedT
 		return nil, ErrUnauthorized
 	}
 
-	// Employee can only view their assigned tasks
-	if authMD.User.Role != models.UserRoleEmployee {
-		return nil, NewInvalidInputError("only employees can view their assigned tasks")
-	}
-
-	// Build query options
+	// Build query options, employees only see the tasks assigned to them
 	options := repo.GetTasksOptions{
 		AssigneeID: authMD.User.ID,
-		Offset:     request.Offset,
-		Limit:      request.Limit,
 	}
 
-	// Add status filter if provided
-	if len(request.Statuses) > 0 {
-		// Note: The current repo implementation only supports filtering by a single status,
-		// so we're using the first status in the list
-		options.Status = request.Statuses[0]
-	} else if request.Status != "" {
-		// For backward compatibility
-		options.Status = request.Status
+	// Add the filters if provided
+	if err := setTaskFilters(&options, request.TaskFilter); err != nil {
+		return nil, err
 	}
 
-	// Add sorting options
-	if request.SortBy != "" {
-		orderBy := request.SortBy
-
-		// Add sort order
-		if request.SortOrder != "" {
-			if request.SortOrder != "asc" && request.SortOrder != "desc" {
-				return nil, NewInvalidInputError("sort_order must be 'asc' or 'desc'")
-			}
-			orderBy += " " + request.SortOrder
-		} else {
-			// Default to descending order
-			orderBy += " DESC"
-		}
-
-		options.OrderBy = []string{orderBy}
-	} else {
-		// Default sort order: created_at descending (newest first)
-		options.OrderBy = []string{"created_at DESC"}
+	// Add the full-text search if provided
+	if err := setSearchQuery(&options, request.Query); err != nil {
+		return nil, err
 	}
 
-	// Get tasks from repository
-	totalCount, tasks, err := s.taskRepo.GetTasks(ctx, options)
+	// Get a page of tasks from repository
+	page, err := s.getTaskPage(ctx, options, taskPageRequest{
+		Sort:      request.Sort,
+		SortBy:    request.SortBy,
+		SortOrder: request.SortOrder,
+		Cursor:    request.Cursor,
+		Limit:     request.Limit,
+		Offset:    request.Offset,
+		SkipCount: request.SkipCount,
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get assigned tasks")
 	}
 
 	return &GetAssignedTasksResponse{
-		Tasks:      tasks,
-		TotalCount: totalCount,
+		Tasks:      page.Tasks,
+		TotalCount: page.TotalCount,
+		NextCursor: page.NextCursor,
 	}, nil
 }
 
@@ -211,78 +358,41 @@ func (s *taskService) GetTasks(ctx context.Context, request GetTasksRequest) (*G
 		return nil, ErrUnauthorized
 	}
 
-	// Only employers can access this endpoint
-	if authMD.User.Role != models.UserRoleEmployer {
-		return nil, NewInvalidInputError("only employers can view all tasks")
-	}
+	// Build query options, employers see the tasks of every employer unless they filter them by employer
+	options := repo.GetTasksOptions{}
 
-	// Build query options
-	options := repo.GetTasksOptions{
-		Offset: request.Offset,
-		Limit:  request.Limit,
+	// Add the filters if provided
+	if err := setTaskFilters(&options, request.TaskFilter); err != nil {
+		return nil, err
 	}
-
-	// Add status filter if provided
-	if request.Status != "" {
-		options.Status = request.Status
+	// The assigned tasks skip this check, the filter can only narrow the employee's own tasks there
+	if err := s.verifyAssignees(ctx, request.AssigneeIDs); err != nil {
+		return nil, err
 	}
 
-	// Add assignee filter if provided
-	if request.AssigneeID != nil {
-		options.AssigneeID = *request.AssigneeID
-
-		// Verify the assignee exists
-		assignee, err := s.userRepo.GetUserByID(ctx, options.AssigneeID)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to verify assignee")
-		}
-		if assignee == nil {
-			return nil, NewInvalidInputError("assignee not found")
-		}
+	// Add the full-text search if provided
+	if err := setSearchQuery(&options, request.Query); err != nil {
+		return nil, err
 	}
 
-	// Add sorting options
-	if request.SortBy != "" {
-		orderBy := request.SortBy
-
-		// Validate sort field
-		validSortFields := map[string]bool{
-			"created_at": true,
-			"updated_at": true,
-			"due_date":   true,
-			"status":     true,
-		}
-
-		if !validSortFields[orderBy] {
-			return nil, NewInvalidInputError("sort_by must be one of: created_at, updated_at, due_date, status")
-		}
-
-		// Add sort order
-		if request.SortOrder != "" {
-			if request.SortOrder != "asc" && request.SortOrder != "desc" {
-				return nil, NewInvalidInputError("sort_order must be 'asc' or 'desc'")
-			}
-			orderBy += " " + request.SortOrder
-		} else {
-			// Default to descending order
-			orderBy += " DESC"
-		}
-
-		options.OrderBy = []string{orderBy}
-	} else {
-		// Default sort order: created_at descending (newest first)
-		options.OrderBy = []string{"created_at DESC"}
-	}
-
-	// Get tasks from repository
-	totalCount, tasks, err := s.taskRepo.GetTasks(ctx, options)
+	// Get a page of tasks from repository
+	page, err := s.getTaskPage(ctx, options, taskPageRequest{
+		Sort:      request.Sort,
+		SortBy:    request.SortBy,
+		SortOrder: request.SortOrder,
+		Cursor:    request.Cursor,
+		Limit:     request.Limit,
+		Offset:    request.Offset,
+		SkipCount: request.SkipCount,
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get tasks")
 	}
 
 	return &GetTasksResponse{
-		Tasks:      tasks,
-		TotalCount: totalCount,
+		Tasks:      page.Tasks,
+		TotalCount: page.TotalCount,
+		NextCursor: page.NextCursor,
 	}, nil
 }
 
@@ -294,35 +404,24 @@ func (s *taskService) AssignTask(ctx context.Context, request AssignTaskRequest)
 		return nil, ErrUnauthorized
 	}
 
-	// Only employers can assign tasks
-	if authMD.User.Role != models.UserRoleEmployer {
-		return nil, NewInvalidInputError("only employers can assign tasks")
-	}
-
-	// Get the task
-	task, err := s.taskRepo.GetTaskByID(ctx, request.TaskID)
+	// Get the task, and lock it for its history. Employers can only assign the tasks they created.
+	task, err := s.lockParticipatingTask(ctx, request.TaskID, authMD.User, "assign")
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get task")
+		return nil, err
 	}
 
-	if task == nil {
-		return nil, ErrNotFound
-	}
-
-	// In this implementation, we allow any employer to assign any task
-
 	// Check if the assignee exists and is an employee
 	assignee, err := s.userRepo.GetUserByID(ctx, request.AssigneeID)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get assignee")
 	}
 	if assignee == nil {
-		return nil, NewInvalidInputError("assignee not found")
+		return nil, errAssigneeNotFound
 	}
 
 	// Verify the assignee is an employee
 	if !assignee.IsEmployee() {
-		return nil, NewInvalidInputError("tasks can only be assigned to employees")
+		return nil, errAssigneeNotEmployee
 	}
 
 	// Assign the task
@@ -331,18 +430,47 @@ func (s *taskService) AssignTask(ctx context.Context, request AssignTaskRequest)
 		return nil, errors.Wrap(err, "failed to assign task")
 	}
 
+	var domainEvents []taskDomainEvent
+	if task.AssigneeID == nil || *task.AssigneeID != request.AssigneeID {
+		event := taskUpdatedEvent(task.ID, authMD.User.ID, models.TaskFieldAssigneeID,
+			userIDValue(task.AssigneeID), userIDValue(&request.AssigneeID))
+		if err := s.taskEventRepo.CreateTaskEvents(ctx, event); err != nil {
+			return nil, errors.Wrap(err, "failed to record task history")
+		}
+		domainEvents = append(domainEvents, taskDomainEvent{Type: models.DomainEventTaskAssigned, Change: changeOf(event)})
+	}
+
 	// Retrieve the updated task
 	updatedTask, err := s.taskRepo.GetTaskByID(ctx, request.TaskID)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get updated task")
 	}
+	if err := s.recordTaskEvents(ctx, *updatedTask, authMD.User.ID, domainEvents...); err != nil {
+		return nil, err
+	}
 
 	return &AssignTaskResponse{
 		Task: *updatedTask,
 	}, nil
 }
 
-// GetEmployeeTaskSummary returns a summary of task statistics for each employee
+// EmployeeSummarySortFields are the sort_by values of the employee summary, the name or a statistic to sort by.
+// The API documents them as the values of its sort_by parameter.
+var EmployeeSummarySortFields = []string{
+	string(repo.EmployeeSummarySortName),
+	string(repo.EmployeeSummarySortTotalTasks),
+	string(repo.EmployeeSummarySortActive),
+	string(repo.EmployeeSummarySortTerminal),
+	string(repo.EmployeeSummarySortPending),
+	string(repo.EmployeeSummarySortInProgress),
+	string(repo.EmployeeSummarySortCompleted),
+	string(repo.EmployeeSummarySortOverdue),
+	string(repo.EmployeeSummarySortCompletedOnTime),
+	string(repo.EmployeeSummarySortOnTimeRate),
+}
+
+// GetEmployeeTaskSummary returns a summary of task statistics for each employee, including the employees without
+// tasks. They are sorted by name by default.
 func (s *taskService) GetEmployeeTaskSummary(ctx context.Context, request GetEmployeeTaskSummaryRequest) (*GetEmployeeTaskSummaryResponse, error) {
 	// Check authentication
 	authMD := authctx.Get(ctx)
@@ -350,43 +478,139 @@ func (s *taskService) GetEmployeeTaskSummary(ctx context.Context, request GetEmp
 		return nil, ErrUnauthorized
 	}
 
-	// Only employers can access this endpoint
-	if authMD.User.Role != models.UserRoleEmployer {
-		return nil, NewInvalidInputError("only employers can view employee task summaries")
+	options := repo.GetEmployeeSummariesOptions{
+		OverdueAt: time.Now(),
+		SortBy:    repo.EmployeeSummarySortName,
+		Offset:    request.Offset,
+		Limit:     request.Limit,
+	}
+	if request.OwnTasks {
+		options.EmployerID = authMD.User.ID
+	}
+	var err error
+	if options.CreatedAfter, options.CreatedBefore, err = timeRange("created", request.CreatedAfter, request.CreatedBefore); err != nil {
+		return nil, err
 	}
 
-	// Get statistics for all users (the repo method returns only employees with tasks)
-	statistics, err := s.taskRepo.GetTaskStatistics(ctx)
+	// Sort by name, A to Z, or by a statistic, highest first by default
+	if request.SortBy != "" {
+		if !slices.Contains(EmployeeSummarySortFields, request.SortBy) {
+			return nil, NewInvalidInputError("sort_by must be one of: " + strings.Join(EmployeeSummarySortFields, ", "))
+		}
+		key := repo.EmployeeSummarySortKey(request.SortBy)
+		options.SortBy, options.Desc = key, key != repo.EmployeeSummarySortName
+	}
+	switch request.SortOrder {
+	case "":
+	case "asc":
+		options.Desc = false
+	case "desc":
+		options.Desc = true
+	default:
+		return nil, NewInvalidInputError("sort_order must be 'asc' or 'desc'")
+	}
+
+	total, summaries, err := s.taskRepo.GetEmployeeSummaries(ctx, options)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get task statistics")
+		return nil, errors.Wrap(err, "failed to get employee summaries")
 	}
 
 	// Build the response
-	var employeeSummaries []EmployeeSummary
-	for userID, stats := range statistics {
-		// Get employee details
-		employee, err := s.userRepo.GetUserByID(ctx, userID)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to get employee details")
+	employeeSummaries := make([]EmployeeSummary, len(summaries))
+	for i, summary := range summaries {
+		employeeSummaries[i] = EmployeeSummary{
+			Employee:   summary.Employee,
+			Statistics: summary.Statistics,
 		}
+	}
+
+	return &GetEmployeeTaskSummaryResponse{
+		Employees:  employeeSummaries,
+		TotalCount: total,
+	}, nil
+}
 
-		if employee == nil {
-			// This should not happen, but let's handle it anyway
-			continue
+// setTaskFilters validates the filters of the task lists and adds them to options, the full-text search aside.
+// They narrow down the tasks options is scoped to. The assignees are not verified here, see verifyAssignees.
+func setTaskFilters(options *repo.GetTasksOptions, filter TaskFilter) error {
+	if len(filter.Statuses) > maxFilterValues || len(filter.AssigneeIDs) > maxFilterValues ||
+		len(filter.EmployerIDs) > maxFilterValues {
+		return errTooManyFilterValues
+	}
+	options.Statuses = filter.Statuses
+	options.EmployerIDs = filter.EmployerIDs
+	options.Unassigned = filter.Unassigned
+	options.AssigneeIDs = filter.AssigneeIDs
+
+	if filter.Priority != "" {
+		if !filter.Priority.IsValid() {
+			return errInvalidPriority
 		}
+		options.Priority = filter.Priority
+	}
+	if filter.LabelID != nil {
+		options.LabelID = *filter.LabelID
+	}
+	if filter.Overdue {
+		options.OverdueAt = time.Now()
+	}
 
-		// Skip non-employees (just to be safe)
-		if employee.Role != models.UserRoleEmployee {
-			continue
+	var err error
+	if options.DueAfter, options.DueBefore, err = timeRange("due", filter.DueAfter, filter.DueBefore); err != nil {
+		return err
+	}
+	if options.CreatedAfter, options.CreatedBefore, err = timeRange("created", filter.CreatedAfter, filter.CreatedBefore); err != nil {
+		return err
+	}
+	return nil
+}
+
+// verifyAssignees checks that the assignees of the task list filter exist, with one query
+func (s *taskService) verifyAssignees(ctx context.Context, assigneeIDs []models.UserID) error {
+	if len(assigneeIDs) == 0 {
+		return nil
+	}
+	assignees, err := s.userRepo.GetUsersByIDs(ctx, assigneeIDs)
+	if err != nil {
+		return errors.Wrap(err, "failed to verify assignees")
+	}
+	found := make(map[models.UserID]bool, len(assignees))
+	for _, assignee := range assignees {
+		found[assignee.ID] = true
+	}
+	for _, assigneeID := range assigneeIDs {
+		if !found[assigneeID] {
+			return errAssigneeNotFound
 		}
+	}
+	return nil
+}
 
-		employeeSummaries = append(employeeSummaries, EmployeeSummary{
-			Employee:   *employee,
-			Statistics: stats,
-		})
+// timeRange validates a range of the task list filters, the zero time stands for an unset bound
+func timeRange(name string, after, before *time.Time) (time.Time, time.Time, error) {
+	var from, to time.Time
+	if after != nil {
+		from = *after
+	}
+	if before != nil {
+		to = *before
 	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return time.Time{}, time.Time{}, NewInvalidInputError(fmt.Sprintf("%s_after must be before %s_before", name, name))
+	}
+	return from, to, nil
+}
 
-	return &GetEmployeeTaskSummaryResponse{
-		Employees: employeeSummaries,
-	}, nil
+// setSearchQuery adds the full-text search of the task lists to options.
+// The matches are ranked by relevance, unless they are sorted explicitly, see taskSort.
+func setSearchQuery(options *repo.GetTasksOptions, query string) error {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		return errSearchQueryTooLong
+	}
+	options.SearchQuery = query
+	return nil
 }
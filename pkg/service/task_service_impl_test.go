@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/dbctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/notify"
+	"github.com/llkhacquan/cisab/pkg/outbox"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/llkhacquan/cisab/pkg/testutil"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/llkhacquan/cisab/pkg/webhook"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTaskService creates a test database and a task service backed by it.
+// The returned context holds the database, the calls are made as a user with asUser.
+func setupTaskService(t *testing.T) (context.Context, TaskService, repo.UserRepo) {
+	db := testutil.CreateTestDB(t)
+	log := logger.New(logger.Config{Output: io.Discard})
+
+	userRepo := repo.NewUserRepoImpl(dbctx.Get)
+	webhookRepo := repo.NewWebhookRepoImpl(dbctx.Get)
+	relay := outbox.NewRelay(repo.NewOutboxRepoImpl(dbctx.Get), db, log)
+	taskService := NewTaskService(repo.NewTaskRepoImpl(dbctx.Get), userRepo, repo.NewCommentRepoImpl(dbctx.Get),
+		repo.NewTaskEventRepoImpl(dbctx.Get), repo.NewLabelRepoImpl(dbctx.Get), repo.NewChecklistRepoImpl(dbctx.Get),
+		repo.NewTaskDependencyRepoImpl(dbctx.Get), repo.NewWorkflowRepoImpl(dbctx.Get), repo.NewTaskSeriesRepoImpl(dbctx.Get),
+		repo.NewTaskDueEventRepoImpl(dbctx.Get), notify.NewLogNotifier(log), webhookRepo,
+		webhook.NewDispatcher(webhookRepo, db, log), relay)
+	return dbctx.Set(t.Context(), db), taskService, userRepo
+}
+
+// createTestUser creates a user with the given role
+func createTestUser(t *testing.T, ctx context.Context, userRepo repo.UserRepo, email string, role models.UserRole) models.User {
+	user, err := userRepo.CreateUser(ctx, models.User{
+		Email:        email,
+		PasswordHash: "test-password-hash",
+		Name:         string(role) + " " + email,
+		Role:         role,
+	})
+	require.NoError(t, err)
+	return user
+}
+
+// asUser returns a context authenticated as user, like the API does for each request
+func asUser(ctx context.Context, user models.User) context.Context {
+	return authctx.Set(ctx, authctx.AuthMD{User: user})
+}
+
+// createTestTask creates a task of employer, assigned to assignee if set
+func createTestTask(t *testing.T, ctx context.Context, taskService TaskService, employer models.User, title string, assignee *models.User) models.Task {
+	request := CreateTaskRequest{Title: title}
+	if assignee != nil {
+		request.AssigneeID = (*int)(&assignee.ID)
+	}
+	created, err := taskService.CreateTask(asUser(ctx, employer), request)
+	require.NoError(t, err)
+	return created.Task
+}
+
+func Test_taskService_UpdateTaskStatus(t *testing.T) {
+	ctx, taskService, userRepo := setupTaskService(t)
+
+	employer := createTestUser(t, ctx, userRepo, "employer@example.com", models.UserRoleEmployer)
+	employee := createTestUser(t, ctx, userRepo, "employee@example.com", models.UserRoleEmployee)
+
+	t.Run("blocked tasks cannot start nor be done", func(t *testing.T) {
+		design := createTestTask(t, ctx, taskService, employer, "Design", &employee)
+		build := createTestTask(t, ctx, taskService, employer, "Build", &employee)
+		_, err := taskService.CreateTaskDependency(asUser(ctx, employer), CreateTaskDependencyRequest{TaskID: build.ID, BlockerID: design.ID})
+		require.NoError(t, err)
+
+		errTaskBlocked := NewCodedError(http.StatusConflict, ErrorCodeTaskBlocked, "")
+		for _, status := range []models.TaskStatus{models.TaskStatusInProgress, models.TaskStatusCompleted} {
+			_, err = taskService.UpdateTaskStatus(asUser(ctx, employee), UpdateTaskStatusRequest{TaskID: build.ID, Status: status})
+			require.True(t, errors.Is(err, errTaskBlocked), err)
+		}
+
+		// Done once the blocker is
+		_, err = taskService.UpdateTaskStatus(asUser(ctx, employee), UpdateTaskStatusRequest{TaskID: design.ID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+		updated, err := taskService.UpdateTaskStatus(asUser(ctx, employee), UpdateTaskStatusRequest{TaskID: build.ID, Status: models.TaskStatusInProgress})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskStatusInProgress, updated.Task.Status)
+	})
+
+	errTransitionNotAllowed := NewCodedError(http.StatusConflict, ErrorCodeTransitionNotAllowed, "")
+
+	t.Run("only the employer reopens a completed task", func(t *testing.T) {
+		task := createTestTask(t, ctx, taskService, employer, "Reopened", &employee)
+		_, err := taskService.UpdateTaskStatus(asUser(ctx, employee), UpdateTaskStatusRequest{TaskID: task.ID, Status: models.TaskStatusCompleted})
+		require.NoError(t, err)
+
+		_, err = taskService.UpdateTaskStatus(asUser(ctx, employee), UpdateTaskStatusRequest{TaskID: task.ID, Status: models.TaskStatusPending})
+		require.True(t, errors.Is(err, errTransitionNotAllowed), err)
+		reopened, err := taskService.UpdateTaskStatus(asUser(ctx, employer), UpdateTaskStatusRequest{TaskID: task.ID, Status: models.TaskStatusPending})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskStatusPending, reopened.Task.Status)
+	})
+
+	t.Run("the employer's workflow decides who moves a task where", func(t *testing.T) {
+		reviewer := createTestUser(t, ctx, userRepo, "reviewer@example.com", models.UserRoleEmployer)
+		_, err := taskService.UpdateWorkflow(asUser(ctx, reviewer), UpdateWorkflowRequest{
+			Statuses: []models.WorkflowStatus{{Name: "todo"}, {Name: "doing", Active: true}, {Name: "in_review", Active: true}, {Name: "done", Terminal: true}},
+			Transitions: []models.WorkflowTransition{
+				{From: "todo", To: "doing", Roles: []models.UserRole{models.UserRoleEmployer, models.UserRoleEmployee}},
+				{From: "doing", To: "in_review", Roles: []models.UserRole{models.UserRoleEmployee}},
+				{From: "in_review", To: "done", Roles: []models.UserRole{models.UserRoleEmployer}},
+			},
+		})
+		require.NoError(t, err)
+		task := createTestTask(t, ctx, taskService, reviewer, "Reviewed", &employee)
+		require.Equal(t, models.TaskStatus("todo"), task.Status)
+
+		move := func(user models.User, status models.TaskStatus) error {
+			_, err := taskService.UpdateTaskStatus(asUser(ctx, user), UpdateTaskStatusRequest{TaskID: task.ID, Status: status})
+			return err
+		}
+		// No transition from todo to done, for anyone
+		require.True(t, errors.Is(move(reviewer, "done"), errTransitionNotAllowed))
+		require.True(t, errors.Is(move(employee, models.TaskStatusCompleted), errInvalidTaskStatus), "not a status of the workflow")
+		require.NoError(t, move(employee, "doing"))
+		// Only the employee sends the task to review, and only the employer approves it
+		require.True(t, errors.Is(move(reviewer, "in_review"), errTransitionNotAllowed))
+		require.NoError(t, move(employee, "in_review"))
+		require.True(t, errors.Is(move(employee, "done"), errTransitionNotAllowed))
+		require.NoError(t, move(reviewer, "done"))
+	})
+
+	t.Run("tasks with open subtasks are only done when forced", func(t *testing.T) {
+		parent := createTestTask(t, ctx, taskService, employer, "Parent", &employee)
+		_, err := taskService.CreateTask(asUser(ctx, employer), CreateTaskRequest{Title: "Subtask", ParentID: (*int)(&parent.ID)})
+		require.NoError(t, err)
+
+		_, err = taskService.UpdateTaskStatus(asUser(ctx, employee), UpdateTaskStatusRequest{TaskID: parent.ID, Status: models.TaskStatusCompleted})
+		require.True(t, errors.Is(err, NewCodedError(http.StatusConflict, ErrorCodeOpenSubtasks, "")), err)
+		// Starting it is fine
+		_, err = taskService.UpdateTaskStatus(asUser(ctx, employee), UpdateTaskStatusRequest{TaskID: parent.ID, Status: models.TaskStatusInProgress})
+		require.NoError(t, err)
+
+		completed, err := taskService.UpdateTaskStatus(asUser(ctx, employee), UpdateTaskStatusRequest{
+			TaskID: parent.ID, Status: models.TaskStatusCompleted, Force: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, models.TaskStatusCompleted, completed.Task.Status)
+	})
+}
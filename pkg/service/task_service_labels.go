@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/pkg/errors"
+)
+
+// CreateLabel creates a label for the authenticated employer
+func (s *taskService) CreateLabel(ctx context.Context, request CreateLabelRequest) (*CreateLabelResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	label, err := s.labelRepo.CreateLabel(ctx, models.Label{
+		EmployerID: authMD.User.ID,
+		Name:       strings.TrimSpace(request.Name),
+	})
+	if errors.Is(err, repo.ErrDuplicateLabel) {
+		return nil, ErrLabelTaken
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create label")
+	}
+
+	return &CreateLabelResponse{
+		Label: label,
+	}, nil
+}
+
+// GetLabels returns the labels of the authenticated employer
+func (s *taskService) GetLabels(ctx context.Context, request GetLabelsRequest) (*GetLabelsResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	labels, err := s.labelRepo.GetLabels(ctx, authMD.User.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get labels")
+	}
+
+	return &GetLabelsResponse{
+		Labels: labels,
+	}, nil
+}
+
+// UpdateLabel renames a label of the authenticated employer
+func (s *taskService) UpdateLabel(ctx context.Context, request UpdateLabelRequest) (*UpdateLabelResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	label, err := s.getOwnLabel(ctx, request.LabelID, authMD.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.labelRepo.UpdateLabelName(ctx, label.ID, strings.TrimSpace(request.Name))
+	if errors.Is(err, repo.ErrDuplicateLabel) {
+		return nil, ErrLabelTaken
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update label")
+	}
+
+	// Retrieve the updated label
+	updatedLabel, err := s.labelRepo.GetLabelByID(ctx, label.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get updated label")
+	}
+
+	return &UpdateLabelResponse{
+		Label: *updatedLabel,
+	}, nil
+}
+
+// DeleteLabel deletes a label of the authenticated employer
+func (s *taskService) DeleteLabel(ctx context.Context, request DeleteLabelRequest) (*DeleteLabelResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	label, err := s.getOwnLabel(ctx, request.LabelID, authMD.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.labelRepo.DeleteLabel(ctx, label.ID); err != nil {
+		return nil, errors.Wrap(err, "failed to delete label")
+	}
+
+	return &DeleteLabelResponse{
+		Label: *label,
+	}, nil
+}
+
+// getOwnLabel returns a label of the employer. Labels of other employers are not found.
+func (s *taskService) getOwnLabel(ctx context.Context, labelID models.LabelID, employerID models.UserID) (*models.Label, error) {
+	label, err := s.labelRepo.GetLabelByID(ctx, labelID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get label")
+	}
+	if label == nil || label.EmployerID != employerID {
+		return nil, ErrLabelNotFound
+	}
+	return label, nil
+}
+
+// getEmployerLabels returns the labels with the given IDs, which must all be labels of the employer
+func (s *taskService) getEmployerLabels(ctx context.Context, employerID models.UserID, labelIDs []models.LabelID) ([]models.Label, error) {
+	if len(labelIDs) == 0 {
+		return nil, nil
+	}
+
+	// Ignore duplicates
+	unique := make([]models.LabelID, 0, len(labelIDs))
+	seen := make(map[models.LabelID]bool, len(labelIDs))
+	for _, id := range labelIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	labels, err := s.labelRepo.GetLabelsByIDs(ctx, unique)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get labels")
+	}
+	if len(labels) != len(unique) {
+		return nil, errTaskLabelNotFound
+	}
+	for _, label := range labels {
+		if label.EmployerID != employerID {
+			return nil, errTaskLabelNotFound
+		}
+	}
+	return labels, nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/pkg/errors"
+)
+
+var (
+	errInvalidCursor     = NewCodedError(http.StatusBadRequest, ErrorCodeInvalidCursor, "invalid cursor")
+	errCursorSortChanged = NewCodedError(http.StatusBadRequest, ErrorCodeInvalidCursor,
+		"the cursor is from a list in another order, start from the first page again")
+	errCursorWithOffset = NewInvalidInputError("cursor and offset cannot be used together")
+	errSortWithSortBy   = NewInvalidInputError("sort cannot be used together with sort_by or sort_order")
+)
+
+// maxSortFields is the maximum number of fields to sort the task lists by
+const maxSortFields = 4
+
+// taskSortKeys maps the fields the task lists are sorted by to the keys to sort by, except the status, which is
+// sorted by the kind of the status in the workflow, see taskStatusSort
+var taskSortKeys = map[string]repo.TaskSortKey{
+	"created_at": repo.TaskSortCreatedAt,
+	"updated_at": repo.TaskSortUpdatedAt,
+	"due_date":   repo.TaskSortDueDate,  // the tasks without due date come last
+	"priority":   repo.TaskSortPriority, // by urgency, not alphabetically
+}
+
+// taskStatusSorts maps the orders of the statuses to the keys to sort by
+var taskStatusSorts = map[string]repo.TaskSortKey{
+	"active_first": repo.TaskSortStatusActiveFirst,
+	"done_first":   repo.TaskSortStatusDoneFirst,
+}
+
+// taskPageRequest holds the sorting and pagination options of a task list request
+type taskPageRequest struct {
+	Sort      string
+	SortBy    string
+	SortOrder string
+	Cursor    string
+	Limit     int
+	Offset    int
+	SkipCount bool
+}
+
+// taskPage is a page of a task list
+type taskPage struct {
+	Tasks      []models.Task
+	TotalCount *int   // nil if not counted
+	NextCursor string // empty on the last page
+}
+
+// getTaskPage gets a page of the tasks of options. A page continues after the cursor of the previous one, in the
+// order of the tasks at the time: unlike offsets, cursors neither skip nor repeat tasks as tasks are created.
+func (s *taskService) getTaskPage(ctx context.Context, options repo.GetTasksOptions, request taskPageRequest) (*taskPage, error) {
+	sort, err := taskSort(request, options.SearchQuery != "")
+	if err != nil {
+		return nil, err
+	}
+	options.Sort = sort
+	if request.Cursor != "" {
+		if request.Offset > 0 {
+			return nil, errCursorWithOffset
+		}
+		after, err := decodeTaskCursor(request.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		options.After = &after
+	}
+	options.Offset = request.Offset
+	options.SkipCount = request.SkipCount
+	if request.Limit > 0 {
+		options.Limit = request.Limit + 1 // one more, to know whether there is a next page
+	}
+
+	total, tasks, err := s.taskRepo.GetTasks(ctx, options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get tasks")
+	}
+
+	page := &taskPage{Tasks: tasks}
+	if !request.SkipCount {
+		page.TotalCount = &total
+	}
+	if request.Limit > 0 && len(tasks) > request.Limit {
+		page.Tasks = tasks[:request.Limit]
+		last := page.Tasks[request.Limit-1]
+		position, err := s.taskRepo.GetTaskPosition(ctx, last.ID, sort, options.SearchQuery)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the position of the last task")
+		}
+		page.NextCursor = encodeTaskCursor(sort, position)
+	}
+	return page, nil
+}
+
+// taskSort validates the sorting options of the task lists and returns the repo's Sort. Tasks are sorted by creation
+// time, newest first, by default, and the matches of a full-text search by relevance.
+// The fields to sort by are either in sort, like "status:active_first,due_date:asc", or a single one in sort_by.
+func taskSort(request taskPageRequest, searching bool) (repo.TaskSort, error) {
+	if request.Sort != "" {
+		if request.SortBy != "" || request.SortOrder != "" {
+			return nil, errSortWithSortBy
+		}
+		return parseTaskSort(request.Sort, searching)
+	}
+	if request.SortBy == "" {
+		if searching {
+			return repo.TaskSort{{Key: repo.TaskSortRelevance, Desc: true}}, nil
+		}
+		// Default sort order: created_at descending (newest first)
+		return repo.TaskSort{{Key: repo.TaskSortCreatedAt, Desc: true}}, nil
+	}
+
+	// Validate sort order
+	if request.SortOrder != "" && request.SortOrder != "asc" && request.SortOrder != "desc" {
+		return nil, NewInvalidInputError("sort_order must be 'asc' or 'desc'")
+	}
+	desc := request.SortOrder != "asc" // Default to descending order
+
+	// Validate sort field
+	if request.SortBy == "status" {
+		// Descending shows the tasks to work on first, ascending the done ones
+		if desc {
+			return repo.TaskSort{{Key: repo.TaskSortStatusActiveFirst}}, nil
+		}
+		return repo.TaskSort{{Key: repo.TaskSortStatusDoneFirst}}, nil
+	}
+	key, ok := taskSortKeys[request.SortBy]
+	if !ok {
+		return nil, NewInvalidInputError("sort_by must be one of: created_at, updated_at, due_date, status, priority")
+	}
+	return repo.TaskSort{{Key: key, Desc: desc}}, nil
+}
+
+// parseTaskSort parses the fields to sort the task lists by, separated by commas, each followed by ":asc" or ":desc",
+// descending by default. The status is followed by ":active_first", the default, or ":done_first".
+// The relevance is only a field to sort the matches of a search by.
+func parseTaskSort(value string, searching bool) (repo.TaskSort, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) > maxSortFields {
+		return nil, NewInvalidInputError(fmt.Sprintf("sort cannot have more than %d fields", maxSortFields))
+	}
+
+	sort := make(repo.TaskSort, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		name, direction, _ := strings.Cut(strings.TrimSpace(field), ":")
+		if seen[name] {
+			return nil, NewInvalidInputError(fmt.Sprintf("sort has %s twice", name))
+		}
+		seen[name] = true
+
+		if name == "status" {
+			if direction == "" {
+				direction = "active_first"
+			}
+			key, ok := taskStatusSorts[direction]
+			if !ok {
+				return nil, NewInvalidInputError("status must be sorted active_first or done_first")
+			}
+			sort = append(sort, repo.TaskSortField{Key: key})
+			continue
+		}
+
+		key, ok := taskSortKeys[name]
+		if name == "relevance" && searching {
+			key, ok = repo.TaskSortRelevance, true
+		}
+		if !ok {
+			return nil, NewInvalidInputError(fmt.Sprintf("cannot sort by %q, the fields are: "+
+				"created_at, updated_at, due_date, status, priority, and relevance with q", name))
+		}
+		switch direction {
+		case "", "desc":
+			sort = append(sort, repo.TaskSortField{Key: key, Desc: true})
+		case "asc":
+			sort = append(sort, repo.TaskSortField{Key: key})
+		default:
+			return nil, NewInvalidInputError(fmt.Sprintf("%s must be sorted asc or desc", name))
+		}
+	}
+	return sort, nil
+}
+
+// taskCursor is the content of the cursors of the task lists: the position of the last task of a page, and the
+// sort order it is a position in. Clients get it encoded, as an opaque string.
+type taskCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+	ID     models.TaskID     `json:"id"`
+}
+
+// encodeTaskCursor encodes the cursor of a position in the order of sort
+func encodeTaskCursor(sort repo.TaskSort, position repo.TaskPosition) string {
+	c := taskCursor{Sort: sort.String(), Values: make([]json.RawMessage, len(position.Values)), ID: position.ID}
+	for i, value := range position.Values {
+		// The values of the positions are times, bools and numbers, they always encode
+		c.Values[i], _ = json.Marshal(value)
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTaskCursor decodes a cursor, it must be from a list in the order of sort
+func decodeTaskCursor(cursor string, sort repo.TaskSort) (repo.TaskPosition, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return repo.TaskPosition{}, errInvalidCursor
+	}
+	var c taskCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return repo.TaskPosition{}, errInvalidCursor
+	}
+	if c.Sort != sort.String() {
+		return repo.TaskPosition{}, errCursorSortChanged
+	}
+	position, err := sort.DecodePosition(c.Values, c.ID)
+	if err != nil {
+		return repo.TaskPosition{}, errInvalidCursor
+	}
+	return position, nil
+}
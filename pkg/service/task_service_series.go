@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/rrule"
+	"github.com/pkg/errors"
+)
+
+const (
+	// recurringTasksHorizon is how far ahead the tasks of the series are created
+	recurringTasksHorizon = 14 * 24 * time.Hour
+	// maxSeriesTasksPerRun bounds the tasks created for a series at once, the next runs create the others
+	maxSeriesTasksPerRun = 100
+	// seriesBatchSize is the number of series a run of the generator processes
+	seriesBatchSize = 100
+)
+
+var errTaskSeriesEnded = NewCodedError(http.StatusConflict, ErrorCodeTaskSeriesEnded, "the series has ended")
+
+// CreateTaskSeries creates a recurring task, and its upcoming tasks
+func (s *taskService) CreateTaskSeries(ctx context.Context, request CreateTaskSeriesRequest) (*CreateTaskSeriesResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	series := models.TaskSeries{
+		EmployerID:  authMD.User.ID,
+		Title:       request.Title,
+		Description: request.Description,
+		Priority:    models.TaskPriorityNormal,
+		RRule:       strings.TrimSpace(request.RRule),
+		Timezone:    "UTC",
+		StartsAt:    request.StartsAt,
+		State:       models.TaskSeriesStateActive,
+	}
+	if request.Priority != "" {
+		series.Priority = models.TaskPriority(request.Priority)
+		if !series.Priority.IsValid() {
+			return nil, errInvalidPriority
+		}
+	}
+	if request.Timezone != "" {
+		series.Timezone = request.Timezone
+	}
+	if request.AssigneeID != nil {
+		assigneeID, err := s.checkAssignee(ctx, models.UserID(*request.AssigneeID))
+		if err != nil {
+			return nil, err
+		}
+		series.AssigneeID = &assigneeID
+	}
+	if _, _, err := parseSchedule(series); err != nil {
+		return nil, err
+	}
+
+	// Occurrences before the creation of the series have no task
+	now := time.Now()
+	series.GeneratedUntil = now
+	series, err := s.taskSeriesRepo.CreateTaskSeries(ctx, series)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create task series")
+	}
+	if _, err := s.generateSeriesTasks(ctx, &series, now); err != nil {
+		return nil, err
+	}
+
+	return &CreateTaskSeriesResponse{
+		Series: series,
+	}, nil
+}
+
+// GetTaskSeriesList returns the series of the authenticated employer
+func (s *taskService) GetTaskSeriesList(ctx context.Context, request GetTaskSeriesListRequest) (*GetTaskSeriesListResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	series, err := s.taskSeriesRepo.GetEmployerTaskSeries(ctx, authMD.User.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task series")
+	}
+
+	return &GetTaskSeriesListResponse{
+		Series: series,
+	}, nil
+}
+
+// GetTaskSeries returns a series of the authenticated employer
+func (s *taskService) GetTaskSeries(ctx context.Context, request GetTaskSeriesRequest) (*GetTaskSeriesResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	series, err := s.taskSeriesRepo.GetTaskSeriesByID(ctx, request.SeriesID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task series")
+	}
+	if series == nil || series.EmployerID != authMD.User.ID {
+		return nil, ErrTaskSeriesNotFound
+	}
+
+	return &GetTaskSeriesResponse{
+		Series: *series,
+	}, nil
+}
+
+// UpdateTaskSeries changes the template or the rule of a series, and creates its upcoming tasks again
+func (s *taskService) UpdateTaskSeries(ctx context.Context, request UpdateTaskSeriesRequest) (*UpdateTaskSeriesResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	series, err := s.lockOwnTaskSeries(ctx, request.SeriesID, authMD.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	if series.State == models.TaskSeriesStateEnded {
+		return nil, errTaskSeriesEnded
+	}
+
+	if request.Title != nil {
+		series.Title = *request.Title
+	}
+	if request.Description != nil {
+		series.Description = *request.Description
+	}
+	if request.Priority != nil {
+		series.Priority = models.TaskPriority(*request.Priority)
+		if !series.Priority.IsValid() {
+			return nil, errInvalidPriority
+		}
+	}
+	if request.RRule != nil {
+		series.RRule = strings.TrimSpace(*request.RRule)
+	}
+	if request.Timezone != nil {
+		series.Timezone = *request.Timezone
+	}
+	if request.StartsAt != nil {
+		series.StartsAt = *request.StartsAt
+	}
+	if request.AssigneeID != nil {
+		assigneeID, err := s.checkAssignee(ctx, models.UserID(*request.AssigneeID))
+		if err != nil {
+			return nil, err
+		}
+		series.AssigneeID = &assigneeID
+	}
+	if _, _, err := parseSchedule(*series); err != nil {
+		return nil, err
+	}
+
+	// The upcoming tasks are replaced by tasks of the new template and rule
+	now := time.Now()
+	if err := s.deleteUpcomingSeriesTasks(ctx, series, now); err != nil {
+		return nil, err
+	}
+	if err := s.saveTaskSeries(ctx, series); err != nil {
+		return nil, err
+	}
+	if series.IsActive() {
+		if _, err := s.generateSeriesTasks(ctx, series, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &UpdateTaskSeriesResponse{
+		Series: *series,
+	}, nil
+}
+
+// UpdateTaskSeriesState pauses, resumes or ends a series
+func (s *taskService) UpdateTaskSeriesState(ctx context.Context, request UpdateTaskSeriesStateRequest) (*UpdateTaskSeriesStateResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	series, err := s.lockOwnTaskSeries(ctx, request.SeriesID, authMD.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	if series.State == request.State {
+		return &UpdateTaskSeriesStateResponse{Series: *series}, nil
+	}
+	if series.State == models.TaskSeriesStateEnded {
+		return nil, errTaskSeriesEnded
+	}
+
+	now := time.Now()
+	series.State = request.State
+	if series.IsActive() {
+		// Resuming skips the occurrences of the pause
+		if err := s.saveTaskSeries(ctx, series); err != nil {
+			return nil, err
+		}
+		if _, err := s.generateSeriesTasks(ctx, series, now); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := s.deleteUpcomingSeriesTasks(ctx, series, now); err != nil {
+			return nil, err
+		}
+		if err := s.saveTaskSeries(ctx, series); err != nil {
+			return nil, err
+		}
+	}
+
+	return &UpdateTaskSeriesStateResponse{
+		Series: *series,
+	}, nil
+}
+
+// GenerateRecurringTasks creates the upcoming tasks of the active series of all employers.
+// Series locked by a concurrent run or edit are skipped, the next run processes them.
+func (s *taskService) GenerateRecurringTasks(ctx context.Context, request GenerateRecurringTasksRequest) (*GenerateRecurringTasksResponse, error) {
+	seriesList, err := s.taskSeriesRepo.LockTaskSeriesToGenerate(ctx, request.Now.Add(recurringTasksHorizon), seriesBatchSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task series to generate")
+	}
+
+	response := &GenerateRecurringTasksResponse{Series: len(seriesList)}
+	for i := range seriesList {
+		created, err := s.generateSeriesTasks(ctx, &seriesList[i], request.Now)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to generate the tasks of series %d", seriesList[i].ID)
+		}
+		response.Tasks += created
+	}
+	return response, nil
+}
+
+// generateSeriesTasks creates the tasks of the occurrences of an active series up to the horizon, and saves how far
+// they are created. The series ends once its rule has no more occurrences.
+func (s *taskService) generateSeriesTasks(ctx context.Context, series *models.TaskSeries, now time.Time) (int, error) {
+	rule, location, err := parseSchedule(*series)
+	if err != nil {
+		return 0, err
+	}
+	workflow, err := s.workflowRepo.GetWorkflow(ctx, series.EmployerID)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get workflow")
+	}
+
+	start := series.StartsAt.In(location)
+	until := now.Add(recurringTasksHorizon)
+	// Occurrences in the past get no task, e.g. those of a pause
+	if series.GeneratedUntil.Before(now) {
+		series.GeneratedUntil = now
+	}
+	occurrences := rule.Between(start, series.GeneratedUntil, until, maxSeriesTasksPerRun)
+	created := 0
+	for _, occurrence := range occurrences {
+		dueDate := occurrence
+		task, ok, err := s.taskSeriesRepo.CreateSeriesTask(ctx, models.Task{
+			Title:       series.Title,
+			Description: series.Description,
+			Status:      workflow.InitialStatus(),
+			Priority:    series.Priority,
+			DueDate:     &dueDate,
+			EmployerID:  series.EmployerID,
+			AssigneeID:  series.AssigneeID,
+			SeriesID:    &series.ID,
+		})
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to create series task")
+		}
+		if !ok {
+			continue // the occurrence has a task already
+		}
+		// the employer who scheduled the series created its tasks
+		if err := s.taskEventRepo.CreateTaskEvents(ctx, taskCreatedEvent(task, series.EmployerID)); err != nil {
+			return 0, errors.Wrap(err, "failed to record task history")
+		}
+		if err := s.recordTaskEvents(ctx, task, series.EmployerID, taskCreatedDomainEvents(task)...); err != nil {
+			return 0, err
+		}
+		created++
+	}
+
+	if len(occurrences) == maxSeriesTasksPerRun {
+		until = occurrences[len(occurrences)-1]
+	}
+	// Stop right before the next occurrence, so the series is not processed again until it gets close
+	if next, ok := rule.Next(start, until); ok {
+		series.GeneratedUntil = next.Add(-time.Microsecond)
+	} else {
+		series.GeneratedUntil = until
+		series.State = models.TaskSeriesStateEnded
+	}
+	if err := s.saveTaskSeries(ctx, series); err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// deleteUpcomingSeriesTasks deletes the tasks of a series due after now that have not started,
+// the series creates them again from now on
+func (s *taskService) deleteUpcomingSeriesTasks(ctx context.Context, series *models.TaskSeries, now time.Time) error {
+	if _, err := s.taskSeriesRepo.DeleteUpcomingTasks(ctx, series.ID, now); err != nil {
+		return errors.Wrap(err, "failed to delete upcoming series tasks")
+	}
+	series.GeneratedUntil = now
+	return nil
+}
+
+func (s *taskService) saveTaskSeries(ctx context.Context, series *models.TaskSeries) error {
+	updated, err := s.taskSeriesRepo.UpdateTaskSeries(ctx, *series)
+	if err != nil {
+		return errors.Wrap(err, "failed to update task series")
+	}
+	if !updated {
+		return ErrTaskSeriesNotFound
+	}
+	return nil
+}
+
+// lockOwnTaskSeries returns a series of the employer, locked until the end of the transaction
+func (s *taskService) lockOwnTaskSeries(ctx context.Context, id models.TaskSeriesID, employerID models.UserID) (*models.TaskSeries, error) {
+	series, err := s.taskSeriesRepo.GetTaskSeriesByIDForUpdate(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task series")
+	}
+	if series == nil || series.EmployerID != employerID {
+		return nil, ErrTaskSeriesNotFound
+	}
+	return series, nil
+}
+
+// checkAssignee checks that the user exists and is an employee, tasks can only be assigned to employees
+func (s *taskService) checkAssignee(ctx context.Context, id models.UserID) (models.UserID, error) {
+	assignee, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get assignee")
+	}
+	if assignee == nil {
+		return 0, errAssigneeNotFound
+	}
+	if !assignee.IsEmployee() {
+		return 0, errAssigneeNotEmployee
+	}
+	return id, nil
+}
+
+// parseSchedule parses the recurrence rule and the time zone of a series
+func parseSchedule(series models.TaskSeries) (*rrule.Rule, *time.Location, error) {
+	rule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		return nil, nil, NewCodedError(http.StatusBadRequest, ErrorCodeInvalidRecurrence, err.Error())
+	}
+	location, err := time.LoadLocation(series.Timezone)
+	if err != nil {
+		return nil, nil, NewCodedError(http.StatusBadRequest, ErrorCodeInvalidRecurrence, "unknown time zone "+series.Timezone)
+	}
+	return rule, location, nil
+}
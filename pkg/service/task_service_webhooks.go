@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/dbctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/llkhacquan/cisab/pkg/webhook"
+	"github.com/pkg/errors"
+)
+
+var errInvalidWebhookEvent = NewCodedError(http.StatusBadRequest, ErrorCodeInvalidWebhookEvent,
+	"events must be task.created, task.assigned or task.completed")
+
+// CreateWebhook registers a webhook for the authenticated employer, with a new secret
+func (s *taskService) CreateWebhook(ctx context.Context, request CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	events, err := webhookEvents(request.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.webhookRepo.CreateWebhook(ctx, models.Webhook{
+		EmployerID: authMD.User.ID,
+		URL:        request.URL,
+		Events:     events,
+		Secret:     webhook.NewSecret(),
+		Active:     true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create webhook")
+	}
+
+	return &CreateWebhookResponse{
+		Webhook: created,
+	}, nil
+}
+
+// GetWebhooks returns the webhooks of the authenticated employer
+func (s *taskService) GetWebhooks(ctx context.Context, request GetWebhooksRequest) (*GetWebhooksResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	webhooks, err := s.webhookRepo.GetWebhooks(ctx, authMD.User.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get webhooks")
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = "" // only returned on creation
+	}
+
+	return &GetWebhooksResponse{
+		Webhooks: webhooks,
+	}, nil
+}
+
+// UpdateWebhook changes a webhook of the authenticated employer
+func (s *taskService) UpdateWebhook(ctx context.Context, request UpdateWebhookRequest) (*UpdateWebhookResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	hook, err := s.getOwnWebhook(ctx, request.WebhookID, authMD.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	if request.URL != nil {
+		hook.URL = *request.URL
+	}
+	if request.Events != nil {
+		if hook.Events, err = webhookEvents(request.Events); err != nil {
+			return nil, err
+		}
+	}
+	if request.Active != nil {
+		hook.Active = *request.Active
+	}
+
+	updated, err := s.webhookRepo.UpdateWebhook(ctx, *hook)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update webhook")
+	}
+	if !updated {
+		return nil, ErrWebhookNotFound
+	}
+
+	// Retrieve the updated webhook
+	updatedWebhook, err := s.webhookRepo.GetWebhookByID(ctx, hook.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get updated webhook")
+	}
+	updatedWebhook.Secret = ""
+
+	return &UpdateWebhookResponse{
+		Webhook: *updatedWebhook,
+	}, nil
+}
+
+// DeleteWebhook deletes a webhook of the authenticated employer, and its delivery log
+func (s *taskService) DeleteWebhook(ctx context.Context, request DeleteWebhookRequest) (*DeleteWebhookResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	hook, err := s.getOwnWebhook(ctx, request.WebhookID, authMD.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := s.webhookRepo.DeleteWebhook(ctx, hook.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete webhook")
+	}
+	if !deleted {
+		return nil, ErrWebhookNotFound
+	}
+	hook.Secret = ""
+
+	return &DeleteWebhookResponse{
+		Webhook: *hook,
+	}, nil
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook of the authenticated employer
+func (s *taskService) GetWebhookDeliveries(ctx context.Context, request GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	if request.Limit > maxDeliveryLimit {
+		return nil, NewInvalidInputError("limit must be at most 100")
+	}
+	if request.Limit <= 0 {
+		request.Limit = defaultDeliveryLimit
+	}
+
+	hook, err := s.getOwnWebhook(ctx, request.WebhookID, authMD.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	totalCount, deliveries, err := s.webhookRepo.GetDeliveries(ctx, repo.GetDeliveriesOptions{
+		WebhookID: hook.ID,
+		Offset:    request.Offset,
+		Limit:     request.Limit,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook deliveries")
+	}
+
+	return &GetWebhookDeliveriesResponse{
+		Deliveries: deliveries,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// RedeliverWebhookDelivery sends the event of a delivery again, as a new delivery with the same event ID
+func (s *taskService) RedeliverWebhookDelivery(ctx context.Context, request RedeliverWebhookDeliveryRequest) (*RedeliverWebhookDeliveryResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	hook, err := s.getOwnWebhook(ctx, request.WebhookID, authMD.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := s.webhookRepo.GetDeliveryByID(ctx, request.DeliveryID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook delivery")
+	}
+	if delivery == nil || delivery.WebhookID != hook.ID {
+		return nil, ErrDeliveryNotFound
+	}
+
+	deliveries, err := s.webhookRepo.CreateDeliveries(ctx, models.WebhookDelivery{
+		WebhookID:     hook.ID,
+		EventID:       delivery.EventID,
+		Event:         delivery.Event,
+		Payload:       delivery.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create webhook delivery")
+	}
+	dbctx.AfterCommit(ctx, s.webhookDispatcher.Wake)
+
+	return &RedeliverWebhookDeliveryResponse{
+		Delivery: deliveries[0],
+	}, nil
+}
+
+// getOwnWebhook returns a webhook of the employer, the webhooks of the other employers are not found
+func (s *taskService) getOwnWebhook(ctx context.Context, id models.WebhookID, employerID models.UserID) (*models.Webhook, error) {
+	hook, err := s.webhookRepo.GetWebhookByID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook")
+	}
+	if hook == nil || hook.EmployerID != employerID {
+		return nil, ErrWebhookNotFound
+	}
+	return hook, nil
+}
+
+// webhookEvents checks the events of a webhook and removes the duplicates
+func webhookEvents(events []models.WebhookEventType) ([]models.WebhookEventType, error) {
+	if len(events) == 0 {
+		return nil, errInvalidWebhookEvent
+	}
+	result := make([]models.WebhookEventType, 0, len(events))
+	seen := make(map[models.WebhookEventType]bool, len(events))
+	for _, event := range events {
+		if !event.IsValid() {
+			return nil, errInvalidWebhookEvent
+		}
+		if !seen[event] {
+			seen[event] = true
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/llkhacquan/cisab/pkg/authctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+)
+
+// GetWorkflow returns the workflow of the authenticated employer
+func (s *taskService) GetWorkflow(ctx context.Context, request GetWorkflowRequest) (*GetWorkflowResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	workflow, err := s.workflowRepo.GetWorkflow(ctx, authMD.User.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get workflow")
+	}
+
+	return &GetWorkflowResponse{
+		Workflow: workflow,
+	}, nil
+}
+
+// UpdateWorkflow replaces the workflow of the authenticated employer
+func (s *taskService) UpdateWorkflow(ctx context.Context, request UpdateWorkflowRequest) (*UpdateWorkflowResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	workflow := models.Workflow{
+		Statuses:    request.Statuses,
+		Transitions: request.Transitions,
+	}
+	if err := workflow.Validate(); err != nil {
+		return nil, NewCodedError(http.StatusBadRequest, ErrorCodeInvalidWorkflow, err.Error())
+	}
+	if err := s.checkStatusesInUse(ctx, authMD.User.ID, workflow); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.workflowRepo.SaveWorkflow(ctx, authMD.User.ID, workflow)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to save workflow")
+	}
+
+	return &UpdateWorkflowResponse{
+		Workflow: saved,
+	}, nil
+}
+
+// ResetWorkflow makes the authenticated employer use the default workflow again
+func (s *taskService) ResetWorkflow(ctx context.Context, request ResetWorkflowRequest) (*ResetWorkflowResponse, error) {
+	// Check authentication
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	if err := s.checkStatusesInUse(ctx, authMD.User.ID, models.DefaultWorkflow()); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.workflowRepo.DeleteWorkflow(ctx, authMD.User.ID); err != nil {
+		return nil, errors.Wrap(err, "failed to delete workflow")
+	}
+
+	workflow, err := s.workflowRepo.GetWorkflow(ctx, authMD.User.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get workflow")
+	}
+
+	return &ResetWorkflowResponse{
+		Workflow: workflow,
+	}, nil
+}
+
+// checkStatusesInUse checks that the workflow keeps the statuses the tasks of the employer are in,
+// so no task is left in a status that does not exist anymore
+func (s *taskService) checkStatusesInUse(ctx context.Context, employerID models.UserID, workflow models.Workflow) error {
+	statuses, err := s.taskRepo.GetTaskStatuses(ctx, employerID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get task statuses")
+	}
+	for _, status := range statuses {
+		if _, ok := workflow.Status(status); !ok {
+			return NewCodedError(http.StatusConflict, ErrorCodeStatusInUse,
+				fmt.Sprintf("some tasks are in the %s status, move them to another status before removing it", status))
+		}
+	}
+	return nil
+}
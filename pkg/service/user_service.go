@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/llkhacquan/cisab/pkg/models"
 )
 
-// UserService defines the interface for user operations
+// UserService defines the interface for user operations.
+// Role requirements are declared on the API endpoints, see api.Endpoint.Roles.
 type UserService interface {
 	// GetUserByID returns a user by ID
 	GetUserByID(ctx context.Context, request GetUserByIDRequest) (*GetUserByIDResponse, error)
@@ -17,9 +19,25 @@ type UserService interface {
 	// CreateUser creates a new user
 	CreateUser(ctx context.Context, request CreateUserRequest) (*CreateUserResponse, error)
 
-	// GetJWTToken generates and returns a JWT token for authentication
+	// GetJWTToken authenticates a user by email and password, and returns a short-lived access token (JWT)
+	// and a refresh token
 	GetJWTToken(ctx context.Context, request GetJWTRequest) (*GetJWTResponse, error)
 
+	// RefreshToken exchanges a refresh token for a new access token and a new refresh token.
+	// Refresh tokens are single-use: the given token is revoked.
+	RefreshToken(ctx context.Context, request RefreshTokenRequest) (*GetJWTResponse, error)
+
+	// Logout revokes the access token of the current request and the given refresh token,
+	// or all the tokens of the current user if AllSessions is set
+	Logout(ctx context.Context, request LogoutRequest) (*LogoutResponse, error)
+
+	// RevokeUserSessions revokes all the access and refresh tokens of a user, e.g. when an employee leaves
+	RevokeUserSessions(ctx context.Context, request RevokeUserSessionsRequest) (*RevokeUserSessionsResponse, error)
+
+	// PruneRevokedTokens removes the expired access tokens from the denylist, their exp claim rejects them anyway.
+	// It is not exposed by the API, a background worker runs it periodically.
+	PruneRevokedTokens(ctx context.Context, request PruneRevokedTokensRequest) (*PruneRevokedTokensResponse, error)
+
 	// GetUsers returns all users (only accessible by employers)
 	GetUsers(ctx context.Context, request GetUsersRequest) (*GetUsersResponse, error)
 }
@@ -36,11 +54,14 @@ type GetMeResponse struct {
 	User *models.User `json:"user"`
 }
 
+// CreateUserRequest represents the request to create a new user.
+// The max lengths match the users table, and bcrypt rejects the passwords longer than 72 bytes, which can be fewer
+// than 72 characters.
 type CreateUserRequest struct {
-	Name     string          `json:"name" binding:"required"`
-	Email    string          `json:"email" binding:"required,email"`
-	Password string          `json:"password" binding:"required,min=8"`
-	Role     models.UserRole `json:"role" binding:"required"`
+	Name     string          `json:"name" binding:"required,max=200"`
+	Email    string          `json:"email" binding:"required,email,max=254"`
+	Password string          `json:"password" binding:"required,min=8,max=72,maxbytes=72"`
+	Role     models.UserRole `json:"role" binding:"required,oneof=employer employee"`
 }
 
 type CreateUserResponse struct {
@@ -53,9 +74,44 @@ type GetJWTRequest struct {
 }
 
 type GetJWTResponse struct {
-	Token       string      `json:"token"`
-	User        models.User `json:"user"`
-	TokenExpiry int64       `json:"token_expiry"`
+	Token              string      `json:"token"` // the access token, sent as "Authorization: Bearer {token}"
+	User               models.User `json:"user"`
+	TokenExpiry        int64       `json:"token_expiry"`
+	RefreshToken       string      `json:"refresh_token"` // exchanged for new tokens at POST /api/v1/token/refresh
+	RefreshTokenExpiry int64       `json:"refresh_token_expiry"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	// RefreshToken of the session to log out, revoked along with the access token of the request
+	RefreshToken string `json:"refresh_token"`
+	// AllSessions revokes all the tokens of the user, on every device
+	AllSessions bool `json:"all_sessions"`
+}
+
+type LogoutResponse struct {
+	AllSessions bool `json:"all_sessions"`
+}
+
+type RevokeUserSessionsRequest struct {
+	UserID models.UserID
+}
+
+type RevokeUserSessionsResponse struct {
+	UserID models.UserID `json:"user_id"`
+}
+
+// PruneRevokedTokensRequest represents a run of the pruning of the revoked access tokens
+type PruneRevokedTokensRequest struct {
+	Now time.Time // the tokens expired before Now are removed
+}
+
+// PruneRevokedTokensResponse represents the result of a run of the pruning of the revoked access tokens
+type PruneRevokedTokensResponse struct {
+	Deleted int64 // number of tokens removed from the denylist
 }
 
 type GetUsersRequest struct {
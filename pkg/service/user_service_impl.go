@@ -2,12 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/llkhacquan/cisab/pkg/authctx"
 	"github.com/llkhacquan/cisab/pkg/config"
+	"github.com/llkhacquan/cisab/pkg/jwtkeys"
 	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/outbox"
 	"github.com/llkhacquan/cisab/pkg/repo"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
@@ -15,15 +21,23 @@ import (
 
 // userService implements the UserService interface
 type userService struct {
-	userRepo repo.UserRepo
-	jwt      config.JWTConfig
+	userRepo  repo.UserRepo
+	tokenRepo repo.TokenRepo
+	jwt       config.JWTConfig
+	keys      *jwtkeys.KeySet
+	relay     *outbox.Relay
 }
 
-// NewUserService creates a new UserService
-func NewUserService(userRepo repo.UserRepo, jwt config.JWTConfig) UserService {
+// NewUserService creates a new UserService. Access tokens are signed with keys, and expire after jwt.TTLInSecond.
+// The domain events of the users are recorded in the outbox of relay.
+func NewUserService(userRepo repo.UserRepo, tokenRepo repo.TokenRepo, jwt config.JWTConfig, keys *jwtkeys.KeySet,
+	relay *outbox.Relay) UserService {
 	return &userService{
-		userRepo: userRepo,
-		jwt:      jwt,
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		jwt:       jwt,
+		keys:      keys,
+		relay:     relay,
 	}
 }
 
@@ -33,7 +47,7 @@ func (u *userService) GetUserByID(ctx context.Context, request GetUserByIDReques
 		return nil, errors.Wrap(err, "failed to get user by ID")
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	return &GetUserByIDResponse{
 		User: user,
@@ -51,7 +65,7 @@ func (u *userService) GetMe(ctx context.Context) (*GetMeResponse, error) {
 }
 
 func (u *userService) CreateUser(ctx context.Context, request CreateUserRequest) (*CreateUserResponse, error) {
-	// we should check if the user already exists (by email). But let it be for now, the DB will handle it
+	// we don't check if the user already exists (by email), the unique index on users.email handles it
 	hashedPassword, err := hashPassword(request.Password)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to hash password")
@@ -64,9 +78,16 @@ func (u *userService) CreateUser(ctx context.Context, request CreateUserRequest)
 		Role:         request.Role,
 	}
 	createdUser, err := u.userRepo.CreateUser(ctx, newUser)
+	if errors.Is(err, repo.ErrDuplicateEmail) {
+		return nil, ErrEmailTaken
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create user")
 	}
+	// users register themselves, the event has no actor
+	if err := u.recordUserEvent(ctx, models.DomainEventUserCreated, createdUser, nil); err != nil {
+		return nil, err
+	}
 	return &CreateUserResponse{
 		User: createdUser,
 	}, nil
@@ -79,43 +100,220 @@ func (u *userService) GetJWTToken(ctx context.Context, request GetJWTRequest) (*
 		return nil, errors.Wrap(err, "failed to find user")
 	}
 	if user == nil {
-		return nil, errors.New("invalid credentials: user not found")
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verify password
 	if !comparePasswords(user.PasswordHash, request.Password) {
-		return nil, errors.New("invalid credentials: password mismatch")
+		return nil, ErrInvalidCredentials
+	}
+
+	response, err := u.issueTokens(ctx, *user)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to issue tokens")
+	}
+	return response, nil
+}
+
+func (u *userService) RefreshToken(ctx context.Context, request RefreshTokenRequest) (*GetJWTResponse, error) {
+	stored, err := u.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(request.RefreshToken))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get refresh token")
+	}
+	if stored == nil || !stored.IsActive(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Tokens issued before the user's sessions were revoked carry an old token version
+	user, err := u.userRepo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user")
+	}
+	if user == nil || user.TokenVersion != stored.TokenVersion {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Rotate: the token is locked by GetRefreshTokenByHash, so concurrent refreshes with it wait here and then fail
+	revoked, err := u.tokenRepo.RevokeRefreshToken(ctx, stored.ID, stored.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to revoke refresh token")
+	}
+	if !revoked {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	response, err := u.issueTokens(ctx, *user)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to issue tokens")
+	}
+	return response, nil
+}
+
+func (u *userService) Logout(ctx context.Context, request LogoutRequest) (*LogoutResponse, error) {
+	authMD := authctx.Get(ctx)
+	if authMD.User.ID == 0 {
+		return nil, ErrUnauthorized
 	}
 
-	// Define token expiration time (e.g., 24 hours)
-	expirationTime := time.Now().Add(time.Duration(u.jwt.TTLInSecond) * time.Second)
+	if request.AllSessions {
+		if err := u.revokeAllTokens(ctx, authMD.User.ID); err != nil {
+			return nil, err
+		}
+		return &LogoutResponse{AllSessions: true}, nil
+	}
 
-	// Create claims with user information
+	if authMD.TokenID != "" {
+		if err := u.tokenRepo.RevokeAccessToken(ctx, authMD.TokenID, authMD.TokenExpiry); err != nil {
+			return nil, errors.Wrap(err, "failed to revoke access token")
+		}
+	}
+	if request.RefreshToken != "" {
+		stored, err := u.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(request.RefreshToken))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get refresh token")
+		}
+		// logging out is idempotent: unknown, already revoked and other users' tokens are ignored
+		if stored != nil && stored.UserID == authMD.User.ID {
+			if _, err := u.tokenRepo.RevokeRefreshToken(ctx, stored.ID, stored.UserID); err != nil {
+				return nil, errors.Wrap(err, "failed to revoke refresh token")
+			}
+		}
+	}
+	return &LogoutResponse{}, nil
+}
+
+// errRevokeNonEmployee is returned when an employer tries to revoke the sessions of another employer, or their own
+var errRevokeNonEmployee = NewForbiddenError("only the sessions of employees can be revoked")
+
+func (u *userService) RevokeUserSessions(ctx context.Context, request RevokeUserSessionsRequest) (*RevokeUserSessionsResponse, error) {
+	// Employers manage the employees, not each other: their own sessions end with Logout
+	user, err := u.userRepo.GetUserByID(ctx, request.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user")
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Role != models.UserRoleEmployee {
+		return nil, errRevokeNonEmployee
+	}
+	if err := u.revokeAllTokens(ctx, request.UserID); err != nil {
+		return nil, err
+	}
+	var actorID *models.UserID
+	if authMD := authctx.Get(ctx); authMD.User.ID != 0 {
+		actorID = &authMD.User.ID
+	}
+	if err := u.recordUserEvent(ctx, models.DomainEventUserSessionsRevoked, *user, actorID); err != nil {
+		return nil, err
+	}
+	return &RevokeUserSessionsResponse{UserID: request.UserID}, nil
+}
+
+func (u *userService) PruneRevokedTokens(ctx context.Context, request PruneRevokedTokensRequest) (*PruneRevokedTokensResponse, error) {
+	deleted, err := u.tokenRepo.DeleteExpiredAccessTokens(ctx, request.Now)
+	if err != nil {
+		return nil, err
+	}
+	return &PruneRevokedTokensResponse{Deleted: deleted}, nil
+}
+
+// recordUserEvent records a domain event of a user in the outbox, in the transaction of the change
+func (u *userService) recordUserEvent(ctx context.Context, eventType models.DomainEventType, user models.User, actorID *models.UserID) error {
+	event, err := outbox.NewEvent(eventType, nil, actorID, models.DomainEventData{User: &user})
+	if err != nil {
+		return err
+	}
+	if err := u.relay.Record(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to record domain event")
+	}
+	return nil
+}
+
+// revokeAllTokens bumps the token version of a user, which invalidates all their access tokens,
+// and revokes their refresh tokens
+func (u *userService) revokeAllTokens(ctx context.Context, userID models.UserID) error {
+	updated, err := u.userRepo.IncrementTokenVersion(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to increment token version")
+	}
+	if !updated {
+		return ErrUserNotFound
+	}
+	if err := u.tokenRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return errors.Wrap(err, "failed to revoke refresh tokens")
+	}
+	return nil
+}
+
+// issueTokens creates a new access token and a new refresh token for the user
+func (u *userService) issueTokens(ctx context.Context, user models.User) (*GetJWTResponse, error) {
+	now := time.Now()
+	expirationTime := now.Add(time.Duration(u.jwt.TTLInSecond) * time.Second)
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate token ID")
+	}
+
+	// Create claims with user information. jti identifies the token on logout,
+	// and ver must match users.token_version for the token to be accepted.
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
 		"role":    user.Role,
 		"exp":     expirationTime.Unix(),
-		"iat":     time.Now().Unix(),
+		"iat":     now.Unix(),
+		"jti":     tokenID,
+		"ver":     user.TokenVersion,
 	}
 
-	// Create token with claims and signing method
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign token with secret key
-	tokenString, err := token.SignedString([]byte(u.jwt.Secret))
+	// Sign token with the current signing key
+	tokenString, err := u.keys.Sign(claims)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to sign token")
 	}
 
-	// Return response with token and user information
+	// Create the refresh token, only its hash is stored
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate refresh token")
+	}
+	refreshExpirationTime := now.Add(time.Duration(u.jwt.RefreshTTLInSecond) * time.Second)
+	_, err = u.tokenRepo.CreateRefreshToken(ctx, models.RefreshToken{
+		UserID:       user.ID,
+		TokenHash:    hashToken(refreshToken),
+		TokenVersion: user.TokenVersion,
+		ExpiresAt:    refreshExpirationTime,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store refresh token")
+	}
+
 	return &GetJWTResponse{
-		Token:       tokenString,
-		User:        *user,
-		TokenExpiry: expirationTime.Unix(),
+		Token:              tokenString,
+		User:               user,
+		TokenExpiry:        expirationTime.Unix(),
+		RefreshToken:       refreshToken,
+		RefreshTokenExpiry: refreshExpirationTime.Unix(),
 	}, nil
 }
 
+// randomToken returns n random bytes, URL-safe base64 encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex encoded SHA-256 of a refresh token. Refresh tokens are random and long,
+// so unlike passwords they do not need a slow hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // hashPassword is a simple password hashing function using bcrypt with default cost
 func hashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -139,11 +337,6 @@ func (u *userService) GetUsers(ctx context.Context, request GetUsersRequest) (*G
 		return nil, ErrUnauthorized
 	}
 
-	// Only employers can access this endpoint
-	if authMD.User.Role != models.UserRoleEmployer {
-		return nil, NewInvalidInputError("only employers can view all users")
-	}
-
 	// Get all users from repository
 	users, err := u.userRepo.GetAllUsers(ctx)
 	if err != nil {
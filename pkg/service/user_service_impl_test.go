@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/config"
+	"github.com/llkhacquan/cisab/pkg/dbctx"
+	"github.com/llkhacquan/cisab/pkg/jwtkeys"
+	"github.com/llkhacquan/cisab/pkg/outbox"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/llkhacquan/cisab/pkg/testutil"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupUserService creates a test database and a user service backed by it
+func setupUserService(t *testing.T) (context.Context, *gorm.DB, UserService, repo.TokenRepo) {
+	db := testutil.CreateTestDB(t)
+	jwtConfig := config.JWTConfig{Secret: "test-secret", TTLInSecond: 3600, RefreshTTLInSecond: 86400}
+	keys, err := jwtkeys.Load(jwtConfig)
+	require.NoError(t, err)
+
+	tokenRepo := repo.NewTokenRepoImpl(dbctx.Get)
+	relay := outbox.NewRelay(repo.NewOutboxRepoImpl(dbctx.Get), db, logger.New(logger.Config{Output: io.Discard}))
+	userService := NewUserService(repo.NewUserRepoImpl(dbctx.Get), tokenRepo, jwtConfig, keys, relay)
+	return t.Context(), db, userService, tokenRepo
+}
+
+func Test_userService_PruneRevokedTokens(t *testing.T) {
+	ctx, db, userService, tokenRepo := setupUserService(t)
+
+	now := time.Now()
+	require.NoError(t, dbctx.Transaction(ctx, db, func(ctx context.Context) error {
+		if err := tokenRepo.RevokeAccessToken(ctx, "expired", now.Add(-time.Minute)); err != nil {
+			return err
+		}
+		return tokenRepo.RevokeAccessToken(ctx, "live", now.Add(time.Hour))
+	}))
+
+	// Like the background worker of main.go, which runs it in a transaction of its own
+	var response *PruneRevokedTokensResponse
+	require.NoError(t, dbctx.Transaction(ctx, db, func(ctx context.Context) error {
+		var err error
+		response, err = userService.PruneRevokedTokens(ctx, PruneRevokedTokensRequest{Now: now})
+		return err
+	}))
+	require.Equal(t, int64(1), response.Deleted)
+
+	ctx = dbctx.Set(ctx, db)
+	revoked, err := tokenRepo.IsAccessTokenRevoked(ctx, "live")
+	require.NoError(t, err)
+	require.True(t, revoked)
+	revoked, err = tokenRepo.IsAccessTokenRevoked(ctx, "expired")
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -0,0 +1,278 @@
+// Package stream pushes the published task events to the connected clients, e.g. over server-sent events.
+//
+// The hub reads the events from the outbox, in the order of their positions, so every instance streams the events
+// published by any of them. It never holds a transaction: the events are read once they are committed. A client that
+// reconnects resumes after the position of the last event it got, see Hub.Replay.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/dbctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// batchSize is the number of events read at once
+	batchSize = 100
+	// pollInterval is how often the hub looks for the events published by the other instances.
+	// The events published by the same instance are streamed right away, see Wake.
+	pollInterval = time.Second
+	// bufferSize is the number of events a subscription holds for a client that is slow to read them.
+	// A client that falls further behind is disconnected, and resumes with Replay when it reconnects.
+	bufferSize = 100
+)
+
+// Types are the types of the streamed events
+var Types = []models.DomainEventType{
+	models.DomainEventTaskCreated,
+	models.DomainEventTaskAssigned,
+	models.DomainEventTaskStatusChanged,
+}
+
+// Event is a streamed event
+type Event struct {
+	// Position is the position of the event in the outbox, clients resume after it
+	Position int64
+	Message  EventMessage
+}
+
+// EventMessage is the data of a streamed event
+type EventMessage struct {
+	// ID identifies the event, it is the EventID of the domain event, like the ID of the webhook payloads
+	ID        string                 `json:"id"`
+	Event     models.DomainEventType `json:"event"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      EventMessageData       `json:"data"`
+}
+
+// EventMessageData is the object of the event
+type EventMessageData struct {
+	Task   models.Task               `json:"task"`             // the task after the change
+	Change *models.DomainEventChange `json:"change,omitempty"` // set for task.assigned and task.status_changed
+}
+
+// Visible returns true if the user can see the task of the event: employers see the tasks they created,
+// employees the tasks assigned to them
+func Visible(user models.User, event Event) bool {
+	task := event.Message.Data.Task
+	switch user.Role {
+	case models.UserRoleEmployer:
+		return task.EmployerID == user.ID
+	case models.UserRoleEmployee:
+		return task.AssigneeID != nil && *task.AssigneeID == user.ID
+	}
+	return false
+}
+
+// Subscription receives the events visible to a user
+type Subscription struct {
+	user   models.User
+	events chan Event
+}
+
+// Events returns the events of the subscription. The channel is closed when the subscription ends: when the client
+// is too slow to read the events, or when the hub is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Hub streams the published events to the subscriptions
+type Hub struct {
+	outboxRepo repo.OutboxRepo
+	db         *gorm.DB
+	logger     *logger.Logger
+	wake       chan struct{}
+
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+	closed        bool
+}
+
+// NewHub creates a hub, Run starts it
+func NewHub(outboxRepo repo.OutboxRepo, db *gorm.DB, logger *logger.Logger) *Hub {
+	return &Hub{
+		outboxRepo:    outboxRepo,
+		db:            db,
+		logger:        logger,
+		wake:          make(chan struct{}, 1),
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// HandleEvent is the outbox subscriber of the hub: it wakes the hub up once the publication of event is committed
+func (h *Hub) HandleEvent(ctx context.Context, _ models.OutboxEvent) error {
+	dbctx.AfterCommit(ctx, h.Wake)
+	return nil
+}
+
+// Wake tells the hub that there are new events to stream. It does not block.
+func (h *Hub) Wake() {
+	select {
+	case h.wake <- struct{}{}:
+	default: // the hub is already woken up
+	}
+}
+
+// Subscribe starts a subscription to the events visible to user, published from now on. Unsubscribe ends it.
+func (h *Hub) Subscribe(user models.User) *Subscription {
+	s := &Subscription{user: user, events: make(chan Event, bufferSize)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(s.events)
+		return s
+	}
+	h.subscriptions[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe ends a subscription
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+// Close ends all the subscriptions, so the streams return on shutdown instead of holding it for the grace period
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subscriptions {
+		h.remove(s)
+	}
+}
+
+// remove ends a subscription, h.mu must be held
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subscriptions[s]; ok {
+		delete(h.subscriptions, s)
+		close(s.events)
+	}
+}
+
+// Replay calls send with the events visible to the subscription published after afterPosition, in order, and
+// returns the position of the last event it read. Subscribe first and then Replay: the events published in between
+// are then both replayed and received by the subscription, the caller skips the ones it sent already.
+func (h *Hub) Replay(ctx context.Context, s *Subscription, afterPosition int64, send func(Event) error) (int64, error) {
+	ctx = dbctx.Set(ctx, h.db)
+	for {
+		events, err := h.outboxRepo.GetPublishedEvents(ctx, Types, afterPosition, batchSize)
+		if err != nil {
+			return afterPosition, err
+		}
+		for _, e := range events {
+			afterPosition = *e.Position
+			event, err := decode(e)
+			if err != nil {
+				h.logger.Error("failed to decode a streamed event", "event_id", e.EventID, "error", err)
+				continue
+			}
+			if !Visible(s.user, event) {
+				continue
+			}
+			if err := send(event); err != nil {
+				return afterPosition, err
+			}
+		}
+		if len(events) < batchSize {
+			return afterPosition, nil
+		}
+	}
+}
+
+// Run streams the published events to the subscriptions until ctx is canceled
+func (h *Hub) Run(ctx context.Context) error {
+	ctx = dbctx.Set(ctx, h.db)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	// The hub streams the events published after it started, the position is unknown until then
+	position := int64(-1)
+	for {
+		var err error
+		if position, err = h.poll(ctx, position); err != nil && ctx.Err() == nil {
+			h.logger.Error("failed to read the published events", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-h.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll broadcasts the events published after position, and returns the position of the last one
+func (h *Hub) poll(ctx context.Context, position int64) (int64, error) {
+	if position < 0 {
+		last, err := h.outboxRepo.GetLastEventPosition(ctx)
+		if err != nil {
+			return position, err
+		}
+		return last, nil
+	}
+	for {
+		events, err := h.outboxRepo.GetPublishedEvents(ctx, Types, position, batchSize)
+		if err != nil {
+			return position, err
+		}
+		for _, e := range events {
+			position = *e.Position
+			event, err := decode(e)
+			if err != nil {
+				h.logger.Error("failed to decode a streamed event", "event_id", e.EventID, "error", err)
+				continue
+			}
+			h.broadcast(event)
+		}
+		if len(events) < batchSize {
+			return position, nil
+		}
+	}
+}
+
+// broadcast sends an event to the subscriptions that can see it, and ends the ones that are too slow to keep up
+func (h *Hub) broadcast(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subscriptions {
+		if !Visible(s.user, event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			h.logger.Info("event stream subscriber is too slow, disconnecting it", "user_id", s.user.ID)
+			h.remove(s)
+		}
+	}
+}
+
+// decode turns a published outbox event into a streamed event
+func decode(e models.OutboxEvent) (Event, error) {
+	var data models.DomainEventData
+	if err := json.Unmarshal(e.Payload, &data); err != nil {
+		return Event{}, errors.Wrap(err, "failed to decode domain event")
+	}
+	if data.Task == nil {
+		return Event{}, errors.Errorf("event %s has no task", e.EventID)
+	}
+	return Event{
+		Position: *e.Position,
+		Message: EventMessage{
+			ID:        e.EventID,
+			Event:     e.Type,
+			CreatedAt: e.CreatedAt,
+			Data:      EventMessageData{Task: *data.Task, Change: data.Change},
+		},
+	}, nil
+}
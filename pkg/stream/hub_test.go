@@ -0,0 +1,71 @@
+package stream
+
+import (
+	"io"
+	"testing"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func taskEvent(position int64, employerID models.UserID, assigneeID *models.UserID) Event {
+	return Event{
+		Position: position,
+		Message: EventMessage{
+			Event: models.DomainEventTaskCreated,
+			Data:  EventMessageData{Task: models.Task{EmployerID: employerID, AssigneeID: assigneeID}},
+		},
+	}
+}
+
+func TestVisible(t *testing.T) {
+	employer := models.User{ID: 1, Role: models.UserRoleEmployer}
+	employee := models.User{ID: 2, Role: models.UserRoleEmployee}
+	other := models.UserID(3)
+
+	require.True(t, Visible(employer, taskEvent(1, employer.ID, nil)))
+	require.False(t, Visible(employer, taskEvent(1, other, nil)))
+	require.True(t, Visible(employee, taskEvent(1, employer.ID, &employee.ID)))
+	require.False(t, Visible(employee, taskEvent(1, employer.ID, &other)))
+	require.False(t, Visible(employee, taskEvent(1, employer.ID, nil)))
+}
+
+func TestHub_broadcast(t *testing.T) {
+	hub := NewHub(nil, nil, logger.New(logger.Config{Output: io.Discard}))
+	employer := models.User{ID: 1, Role: models.UserRoleEmployer}
+
+	t.Run("subscriptions get the events they can see", func(t *testing.T) {
+		s := hub.Subscribe(employer)
+		defer hub.Unsubscribe(s)
+
+		hub.broadcast(taskEvent(1, 3, nil))
+		hub.broadcast(taskEvent(2, employer.ID, nil))
+		event := <-s.Events()
+		require.Equal(t, int64(2), event.Position)
+		require.Empty(t, s.Events())
+	})
+
+	t.Run("slow subscriptions are ended", func(t *testing.T) {
+		s := hub.Subscribe(employer)
+		for i := 0; i <= bufferSize; i++ {
+			hub.broadcast(taskEvent(int64(i), employer.ID, nil))
+		}
+		received := 0
+		for range s.Events() {
+			received++
+		}
+		require.Equal(t, bufferSize, received)
+		hub.Unsubscribe(s) // ended already, it is a no-op
+	})
+
+	t.Run("close ends the subscriptions", func(t *testing.T) {
+		s := hub.Subscribe(employer)
+		hub.Close()
+		_, ok := <-s.Events()
+		require.False(t, ok)
+
+		_, ok = <-hub.Subscribe(employer).Events()
+		require.False(t, ok, "no subscription starts once the hub is closed")
+	})
+}
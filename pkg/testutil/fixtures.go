@@ -0,0 +1,38 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TaskTimes are the times of the life of a task, the zero ones are left as they are
+type TaskTimes struct {
+	CreatedAt   time.Time
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+// SetTaskTimes backdates a task, for the tests of what depends on when tasks were created, started and done.
+// The database only sets started_at and completed_at when the status of a task changes, so the task keeps these
+// times until its status changes again: set the status first.
+func SetTaskTimes(t *testing.T, db *gorm.DB, taskID models.TaskID, times TaskTimes) {
+	columns := map[string]interface{}{}
+	if !times.CreatedAt.IsZero() {
+		columns["created_at"] = times.CreatedAt
+	}
+	if !times.StartedAt.IsZero() {
+		columns["started_at"] = times.StartedAt
+	}
+	if !times.CompletedAt.IsZero() {
+		columns["completed_at"] = times.CompletedAt
+	}
+	require.NotEmpty(t, columns, "no task times to set")
+
+	result := db.Table("tasks").Where("id = ?", taskID).Updates(columns)
+	require.NoError(t, result.Error, "failed to set task times")
+	require.Equal(t, int64(1), result.RowsAffected, "task %d not found", taskID)
+}
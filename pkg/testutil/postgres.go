@@ -60,7 +60,8 @@ func CreateTestDB(t *testing.T, options ...DBOption) *gorm.DB {
 
 	// Create the GORM configuration with provided options
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info), // Default to info level
+		Logger:         logger.Default.LogMode(logger.Info), // Default to info level
+		TranslateError: true,                                // Same as the application, see main.go
 	}
 
 	// Apply custom options
@@ -0,0 +1,262 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/dbctx"
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/llkhacquan/cisab/pkg/repo"
+	"github.com/llkhacquan/cisab/pkg/utils/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxAttempts is the number of attempts of a delivery before it fails
+	MaxAttempts = 10
+	// requestTimeout bounds an attempt, slow endpoints count as failed
+	requestTimeout = 10 * time.Second
+	// leaseDuration is how long a dispatcher owns the deliveries it claimed, longer than the attempts of a batch
+	leaseDuration = 5 * time.Minute
+	// batchSize is the number of deliveries claimed at once
+	batchSize = 20
+	// pollInterval is how often the dispatcher looks for the retries that are due, and the deliveries of the other
+	// instances. Deliveries of the same instance are sent right away, see Wake.
+	pollInterval = 5 * time.Second
+	// maxErrorLength bounds the error saved for an attempt
+	maxErrorLength = 1000
+)
+
+// Backoff returns the delay before the next attempt of a delivery that failed attempts times:
+// 30 seconds after the first attempt, doubling each time, at most 6 hours.
+func Backoff(attempts int) time.Duration {
+	const first, max = 30 * time.Second, 6 * time.Hour
+	if attempts < 1 {
+		return first
+	}
+	if attempts > 10 {
+		return max
+	}
+	return min(first<<(attempts-1), max)
+}
+
+// Dispatcher sends the pending deliveries to their webhooks. Deliveries are created from the domain events of the
+// outbox, see HandleEvent, so the dispatcher only sends the changes that are committed.
+type Dispatcher struct {
+	webhookRepo repo.WebhookRepo
+	db          *gorm.DB
+	client      *http.Client
+	logger      *logger.Logger
+	wake        chan struct{}
+}
+
+// DispatcherOption configures a Dispatcher
+type DispatcherOption func(*dispatcherOptions)
+
+type dispatcherOptions struct {
+	allowPrivateDestinations bool
+}
+
+// WithPrivateDestinations lets the webhooks reach loopback and private addresses, for the tests and the
+// deployments whose webhook receivers are on the same network
+func WithPrivateDestinations() DispatcherOption {
+	return func(o *dispatcherOptions) {
+		o.allowPrivateDestinations = true
+	}
+}
+
+// NewDispatcher creates a dispatcher, Run starts it. The webhooks can only reach public addresses by default.
+func NewDispatcher(webhookRepo repo.WebhookRepo, db *gorm.DB, logger *logger.Logger, options ...DispatcherOption) *Dispatcher {
+	var o dispatcherOptions
+	for _, option := range options {
+		option(&o)
+	}
+	return &Dispatcher{
+		webhookRepo: webhookRepo,
+		db:          db,
+		client:      newHTTPClient(o.allowPrivateDestinations),
+		logger:      logger,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Wake tells the dispatcher that there are new deliveries to send. It does not block.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default: // the dispatcher is already woken up
+	}
+}
+
+// Run sends the deliveries until ctx is canceled
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			sent, err := d.DeliverDue(ctx, time.Now())
+			if err != nil && ctx.Err() == nil {
+				d.logger.Error("failed to deliver webhooks", "error", err)
+			}
+			if err != nil || sent < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// HandleEvent is the outbox subscriber of the webhooks: it creates a delivery of the event for each active webhook
+// of the employer subscribed to it. The deliveries are created in the transaction that publishes the event, so each
+// event is delivered once per webhook, and sent once it commits.
+func (d *Dispatcher) HandleEvent(ctx context.Context, event models.OutboxEvent) error {
+	webhookEvent := models.WebhookEventType(event.Type)
+	if !webhookEvent.IsValid() || event.EmployerID == nil {
+		return nil // not an event of the webhooks
+	}
+	var data models.DomainEventData
+	if err := json.Unmarshal(event.Payload, &data); err != nil {
+		return errors.Wrap(err, "failed to decode domain event")
+	}
+	if data.Task == nil {
+		return errors.Errorf("event %s has no task", event.EventID)
+	}
+
+	webhooks, err := d.webhookRepo.GetSubscribedWebhooks(ctx, *event.EmployerID, webhookEvent)
+	if err != nil {
+		return errors.Wrap(err, "failed to get subscribed webhooks")
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(Payload{
+		ID:        event.EventID,
+		Event:     webhookEvent,
+		CreatedAt: event.CreatedAt,
+		Data:      PayloadData{Task: *data.Task},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode webhook payload")
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.EventID,
+			Event:         webhookEvent,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if _, err := d.webhookRepo.CreateDeliveries(ctx, deliveries...); err != nil {
+		return errors.Wrap(err, "failed to create webhook deliveries")
+	}
+	dbctx.AfterCommit(ctx, d.Wake)
+	return nil
+}
+
+// DeliverDue claims the deliveries due for an attempt at now, attempts them and saves the outcomes.
+// It returns the number of attempts.
+func (d *Dispatcher) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	var deliveries []models.WebhookDelivery
+	err := dbctx.Transaction(ctx, d.db, func(ctx context.Context) error {
+		var err error
+		deliveries, err = d.webhookRepo.ClaimDueDeliveries(ctx, now, now.Add(leaseDuration), batchSize)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// The attempts run outside of a transaction, the claim keeps the other dispatchers away
+	ctx = dbctx.Set(ctx, d.db)
+	for _, delivery := range deliveries {
+		webhook, err := d.webhookRepo.GetWebhookByID(ctx, delivery.WebhookID)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to get webhook")
+		}
+		if webhook == nil {
+			continue // deleted with its deliveries
+		}
+		d.attempt(ctx, *webhook, &delivery)
+		if _, err := d.webhookRepo.SaveDeliveryAttempt(ctx, delivery); err != nil {
+			return 0, errors.Wrap(err, "failed to save webhook delivery attempt")
+		}
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends a delivery, and sets its outcome
+func (d *Dispatcher) attempt(ctx context.Context, webhook models.Webhook, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.ResponseStatus = nil
+	delivery.LastError = ""
+
+	var err error
+	if webhook.Active {
+		delivery.ResponseStatus, err = d.send(ctx, webhook, *delivery)
+	} else {
+		err = errors.New("the webhook is disabled")
+		delivery.Attempts = MaxAttempts
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+	default:
+		delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+	}
+	if err != nil {
+		delivery.LastError = err.Error()
+		if len(delivery.LastError) > maxErrorLength {
+			delivery.LastError = delivery.LastError[:maxErrorLength]
+		}
+	}
+}
+
+// send posts a delivery to its webhook, and returns the status of the response if any
+func (d *Dispatcher) send(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (*int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid webhook request")
+	}
+	timestamp := time.Now()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "cisab-webhooks")
+	request.Header.Set(HeaderEvent, string(delivery.Event))
+	request.Header.Set(HeaderDelivery, strconv.FormatInt(int64(delivery.ID), 10))
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	request.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	// Drain a bit of the body, so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	status := response.StatusCode
+	if status < 200 || status >= 300 {
+		return &status, errors.Errorf("unexpected response status %d", status)
+	}
+	return &status, nil
+}
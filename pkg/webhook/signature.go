@@ -0,0 +1,92 @@
+// Package webhook sends the events of the tasks to the webhooks of their employer.
+//
+// Each delivery is a POST of a JSON Payload, with the headers:
+//
+//	X-Webhook-Event: task.created
+//	X-Webhook-Delivery: 42
+//	X-Webhook-Timestamp: 1767948000
+//	X-Webhook-Signature: sha256=<hex encoded HMAC-SHA256 of "<timestamp>.<body>" with the webhook secret>
+//
+// Receivers check the signature with Verify, and ignore the events whose ID they already processed:
+// a delivery can be attempted again after a timeout, and redelivered on demand.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/llkhacquan/cisab/pkg/models"
+	"github.com/pkg/errors"
+)
+
+// Headers of the deliveries
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// Payload is the body of a delivery
+type Payload struct {
+	// ID identifies the event, it is the same for all the deliveries of the event, and is the EventID of the
+	// domain event it comes from
+	ID        string                  `json:"id"`
+	Event     models.WebhookEventType `json:"event"`
+	CreatedAt time.Time               `json:"created_at"`
+	Data      PayloadData             `json:"data"`
+}
+
+// PayloadData is the object of the event
+type PayloadData struct {
+	Task models.Task `json:"task"`
+}
+
+// NewSecret returns a random secret to sign the deliveries of a webhook
+func NewSecret() string {
+	return "whsec_" + randomHex(24)
+}
+
+func randomHex(size int) string {
+	b := make([]byte, size)
+	// rand.Read never returns an error, see its documentation
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Sign returns the X-Webhook-Signature header of a delivery sent at timestamp
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery from its X-Webhook-Timestamp and X-Webhook-Signature headers.
+// Deliveries older than tolerance are rejected, so a captured delivery cannot be replayed later; a zero tolerance
+// accepts any age.
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp")
+	}
+	timestamp := time.Unix(unix, 0)
+	if tolerance > 0 && time.Since(timestamp) > tolerance {
+		return errors.New("the delivery is too old")
+	}
+	if !strings.HasPrefix(signatureHeader, signaturePrefix) {
+		return errors.New("unsupported signature")
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signatureHeader)) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
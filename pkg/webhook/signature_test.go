@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":"1","event":"task.created"}`)
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := Sign("secret", now, body)
+
+	require.NoError(t, Verify("secret", timestamp, signature, body, time.Minute))
+	require.ErrorContains(t, Verify("other secret", timestamp, signature, body, time.Minute), "invalid signature")
+	require.ErrorContains(t, Verify("secret", timestamp, signature, []byte(`{}`), time.Minute), "invalid signature")
+	require.ErrorContains(t, Verify("secret", timestamp, "md5=abc", body, time.Minute), "unsupported signature")
+	require.ErrorContains(t, Verify("secret", "yesterday", signature, body, time.Minute), "invalid timestamp")
+
+	// the timestamp is signed, it cannot be changed to replay an old delivery
+	old := now.Add(-time.Hour)
+	oldSignature := Sign("secret", old, body)
+	require.ErrorContains(t, Verify("secret", strconv.FormatInt(old.Unix(), 10), oldSignature, body, time.Minute), "too old")
+	require.ErrorContains(t, Verify("secret", timestamp, oldSignature, body, time.Minute), "invalid signature")
+	require.NoError(t, Verify("secret", strconv.FormatInt(old.Unix(), 10), oldSignature, body, 0))
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(1))
+	require.Equal(t, time.Minute, Backoff(2))
+	require.Equal(t, 4*time.Minute, Backoff(4))
+	require.Equal(t, 512*30*time.Second, Backoff(10))
+	require.Equal(t, 6*time.Hour, Backoff(11))
+	require.Equal(t, 6*time.Hour, Backoff(100))
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, private to the networks of the providers
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// checkDestination rejects the addresses that are not on the public internet: loopback, private, link-local, like
+// the metadata endpoint of the cloud providers at 169.254.169.254, and the like. Webhook URLs are chosen by the
+// employers, they must not reach the network of the server.
+func checkDestination(address string) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return errors.Wrapf(err, "invalid webhook destination %q", address)
+	}
+	addr := addrPort.Addr().Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() || sharedAddressSpace.Contains(addr) {
+		return errors.Errorf("webhook destination %s is not a public address", addr)
+	}
+	return nil
+}
+
+// newHTTPClient returns the client the deliveries are sent with. It checks every address it connects to, after the
+// name is resolved so that a name cannot resolve to another address later, unless allowPrivate is set. It does not
+// follow redirects, which would lead it anywhere, a redirect is a failed attempt.
+func newHTTPClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			return checkDestination(address)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would connect to the destination without the check
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDestination(t *testing.T) {
+	for _, address := range []string{
+		"127.0.0.1:80", "[::1]:443", "10.1.2.3:80", "172.16.0.1:80", "192.168.1.1:8080", "169.254.169.254:80",
+		"[fe80::1]:80", "0.0.0.0:80", "100.64.0.1:80", "[::ffff:127.0.0.1]:80", "[fd00::1]:80",
+	} {
+		require.Error(t, checkDestination(address), address)
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1::1]:443"} {
+		require.NoError(t, checkDestination(address), address)
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	t.Run("private destinations are rejected", func(t *testing.T) {
+		_, err := newHTTPClient(false).Post(target.URL, "application/json", nil)
+		require.ErrorContains(t, err, "is not a public address")
+	})
+
+	t.Run("redirects are not followed", func(t *testing.T) {
+		response, err := newHTTPClient(true).Post(redirect.URL, "application/json", nil)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusFound, response.StatusCode)
+	})
+}